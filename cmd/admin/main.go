@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
+	catalog "github.com/GameComponent/economy-service/pkg/catalog"
+)
+
+type command struct {
+	usage string
+	run   func(ctx context.Context, client v1.EconomyServiceClient, args []string) error
+}
+
+var commands = map[string]command{
+	"export-player": {
+		usage: "export-player [-format json|zip] [-out file] <player_id>",
+		run:   exportPlayer,
+	},
+	"erase-player": {
+		usage: "erase-player <player_id>",
+		run:   erasePlayer,
+	},
+	"export-catalog": {
+		usage: "export-catalog [-format yaml|json] [-out file]",
+		run:   exportCatalog,
+	},
+	"import-catalog": {
+		usage: "import-catalog [-dry-run] [-prune] <file>",
+		run:   importCatalog,
+	},
+	"publish-catalog": {
+		usage: "publish-catalog <catalog_version_id>",
+		run:   publishCatalog,
+	},
+	"rollback-catalog": {
+		usage: "rollback-catalog",
+		run:   rollbackCatalog,
+	},
+}
+
+func main() {
+	// get configuration
+	address := flag.String("server", "127.0.0.1:3000", "gRPC server in format host:port")
+	token := flag.String("token", os.Getenv("ECONOMY_TOKEN"), "token used to authorize the requests")
+	tenantID := flag.String("tenant", os.Getenv("ECONOMY_TENANT"), "tenant to run the command against, tokens of the default tenant only")
+	timeout := flag.Duration("timeout", 30*time.Second, "timeout of a command")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+
+	// Set up a connection to the server.
+	conn, err := grpc.Dial(*address, grpc.WithInsecure())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "did not connect: %v\n", err)
+		os.Exit(1)
+	}
+	defer conn.Close()
+
+	client := v1.NewEconomyServiceClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	// Pass the token along with every request
+	if *token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+*token)
+	}
+
+	if *tenantID != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "x-tenant-id", *tenantID)
+	}
+
+	if err := cmd.run(ctx, client, flag.Args()[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "%s failed: %v\n", flag.Arg(0), err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [flags] <command> [arguments]\n\nCommands:\n", os.Args[0])
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %s\n", cmd.usage)
+	}
+	fmt.Fprintf(os.Stderr, "\nFlags:\n")
+	flag.PrintDefaults()
+}
+
+func exportPlayer(ctx context.Context, client v1.EconomyServiceClient, args []string) error {
+	flags := flag.NewFlagSet("export-player", flag.ExitOnError)
+	format := flags.String("format", "json", "format of the export, json or zip")
+	out := flags.String("out", "", "file to write the export to, defaults to the filename returned by the server")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("expected exactly one player_id")
+	}
+
+	exportFormat, ok := v1.ExportFormat_value[strings.ToUpper(*format)]
+	if !ok {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	res, err := client.ExportPlayerData(ctx, &v1.ExportPlayerDataRequest{
+		PlayerId: flags.Arg(0),
+		Format:   v1.ExportFormat(exportFormat),
+	})
+	if err != nil {
+		return err
+	}
+
+	filename := *out
+	if filename == "" {
+		filename = res.GetFilename()
+	}
+
+	if err := ioutil.WriteFile(filename, res.GetData(), 0600); err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported player %s to %s\n", flags.Arg(0), filename)
+	return nil
+}
+
+func erasePlayer(ctx context.Context, client v1.EconomyServiceClient, args []string) error {
+	flags := flag.NewFlagSet("erase-player", flag.ExitOnError)
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("expected exactly one player_id")
+	}
+
+	res, err := client.ErasePlayer(ctx, &v1.ErasePlayerRequest{
+		PlayerId: flags.Arg(0),
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Erased player %s\n", res.GetPlayer().GetId())
+	return nil
+}
+
+func exportCatalog(ctx context.Context, client v1.EconomyServiceClient, args []string) error {
+	flags := flag.NewFlagSet("export-catalog", flag.ExitOnError)
+	format := flags.String("format", "yaml", "format of the catalog, yaml or json")
+	out := flags.String("out", "", "file to write the catalog to, defaults to stdout")
+	flags.Parse(args)
+
+	catalogFormat, ok := v1.CatalogFormat_value["CATALOG_"+strings.ToUpper(*format)]
+	if !ok {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	res, err := client.ExportCatalog(ctx, &v1.ExportCatalogRequest{
+		Format: v1.CatalogFormat(catalogFormat),
+	})
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		fmt.Print(res.GetCatalog())
+		return nil
+	}
+
+	return ioutil.WriteFile(*out, []byte(res.GetCatalog()), 0644)
+}
+
+func importCatalog(ctx context.Context, client v1.EconomyServiceClient, args []string) error {
+	flags := flag.NewFlagSet("import-catalog", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "only show the plan without applying it")
+	prune := flags.Bool("prune", false, "archive everything that is not in the catalog")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("expected exactly one file")
+	}
+
+	document, err := ioutil.ReadFile(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	res, err := client.ImportCatalog(ctx, &v1.ImportCatalogRequest{
+		Catalog: string(document),
+		DryRun:  *dryRun,
+		Prune:   *prune,
+	})
+	if err != nil {
+		return err
+	}
+
+	printChanges(res.GetChanges())
+
+	if res.GetApplied() {
+		fmt.Printf("Applied %d changes\n", len(res.GetChanges()))
+	}
+
+	return nil
+}
+
+func publishCatalog(ctx context.Context, client v1.EconomyServiceClient, args []string) error {
+	flags := flag.NewFlagSet("publish-catalog", flag.ExitOnError)
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("expected exactly one catalog_version_id")
+	}
+
+	res, err := client.PublishCatalogVersion(ctx, &v1.PublishCatalogVersionRequest{
+		CatalogVersionId: flags.Arg(0),
+	})
+	if err != nil {
+		return err
+	}
+
+	printChanges(res.GetChanges())
+	fmt.Printf("Published catalog version %s\n", res.GetCatalogVersion().GetId())
+	return nil
+}
+
+func rollbackCatalog(ctx context.Context, client v1.EconomyServiceClient, args []string) error {
+	flags := flag.NewFlagSet("rollback-catalog", flag.ExitOnError)
+	flags.Parse(args)
+
+	res, err := client.RollbackCatalogVersion(ctx, &v1.RollbackCatalogVersionRequest{})
+	if err != nil {
+		return err
+	}
+
+	printChanges(res.GetChanges())
+	fmt.Printf("Rolled back to catalog version %s\n", res.GetCatalogVersion().GetId())
+	return nil
+}
+
+// printChanges prints the changes of a plan the same way the catalog package does
+func printChanges(changes []*v1.CatalogChange) {
+	plan := &catalog.Plan{}
+	for _, change := range changes {
+		plan.Changes = append(plan.Changes, &catalog.Change{
+			Action: catalog.Action(change.GetAction()),
+			Kind:   change.GetKind(),
+			Key:    change.GetKey(),
+		})
+	}
+
+	fmt.Print(plan.String())
+}
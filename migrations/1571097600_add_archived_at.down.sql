@@ -0,0 +1,6 @@
+ALTER TABLE product DROP COLUMN IF EXISTS archived_at;
+ALTER TABLE shop DROP COLUMN IF EXISTS archived_at;
+ALTER TABLE currency DROP COLUMN IF EXISTS archived_at;
+ALTER TABLE item DROP COLUMN IF EXISTS archived_at;
+ALTER TABLE storage DROP COLUMN IF EXISTS archived_at;
+ALTER TABLE player DROP COLUMN IF EXISTS archived_at;
@@ -0,0 +1,3 @@
+ALTER TABLE player DROP COLUMN IF EXISTS erased_at;
+DROP TABLE IF EXISTS ledger_entry;
+DROP TABLE IF EXISTS purchase;
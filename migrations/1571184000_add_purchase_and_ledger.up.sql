@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS purchase (
+  id UUID DEFAULT gen_random_uuid() NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp(),
+  player_id STRING NOT NULL,
+  product_id UUID NOT NULL,
+  price_id UUID NOT NULL,
+  receiving_storage_id UUID NOT NULL,
+  paying_storage_id UUID NOT NULL,
+
+  PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS index_player_id ON purchase(player_id);
+
+CREATE TABLE IF NOT EXISTS ledger_entry (
+  id UUID DEFAULT gen_random_uuid() NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp(),
+  player_id STRING NOT NULL,
+  storage_id UUID NOT NULL,
+  item_id UUID NULL,
+  currency_id UUID NULL,
+  amount INT64 DEFAULT 0 NOT NULL,
+  reason STRING DEFAULT '' NOT NULL,
+  purchase_id UUID NULL,
+
+  PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS index_player_id ON ledger_entry(player_id);
+
+ALTER TABLE player ADD COLUMN IF NOT EXISTS erased_at TIMESTAMPTZ NULL;
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS price@index_key CASCADE;
+DROP INDEX IF EXISTS shop@index_key CASCADE;
+DROP INDEX IF EXISTS product@index_key CASCADE;
+DROP INDEX IF EXISTS currency@index_key CASCADE;
+DROP INDEX IF EXISTS item@index_key CASCADE;
+
+ALTER TABLE price DROP COLUMN IF EXISTS key;
+ALTER TABLE shop DROP COLUMN IF EXISTS key;
+ALTER TABLE product DROP COLUMN IF EXISTS key;
+ALTER TABLE currency DROP COLUMN IF EXISTS key;
+ALTER TABLE item DROP COLUMN IF EXISTS key;
@@ -0,0 +1,11 @@
+ALTER TABLE item ADD COLUMN IF NOT EXISTS key STRING NULL;
+ALTER TABLE currency ADD COLUMN IF NOT EXISTS key STRING NULL;
+ALTER TABLE product ADD COLUMN IF NOT EXISTS key STRING NULL;
+ALTER TABLE shop ADD COLUMN IF NOT EXISTS key STRING NULL;
+ALTER TABLE price ADD COLUMN IF NOT EXISTS key STRING NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS index_key ON item(key);
+CREATE UNIQUE INDEX IF NOT EXISTS index_key ON currency(key);
+CREATE UNIQUE INDEX IF NOT EXISTS index_key ON product(key);
+CREATE UNIQUE INDEX IF NOT EXISTS index_key ON shop(key);
+CREATE UNIQUE INDEX IF NOT EXISTS index_key ON price(key);
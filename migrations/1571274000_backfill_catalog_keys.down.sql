@@ -0,0 +1,5 @@
+UPDATE price SET key = NULL WHERE key = id::STRING;
+UPDATE shop SET key = NULL WHERE key = id::STRING;
+UPDATE product SET key = NULL WHERE key = id::STRING;
+UPDATE currency SET key = NULL WHERE key = id::STRING;
+UPDATE item SET key = NULL WHERE key = id::STRING;
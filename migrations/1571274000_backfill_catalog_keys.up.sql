@@ -0,0 +1,6 @@
+-- Existing rows use their id as key
+UPDATE item SET key = id::STRING WHERE key IS NULL;
+UPDATE currency SET key = id::STRING WHERE key IS NULL;
+UPDATE product SET key = id::STRING WHERE key IS NULL;
+UPDATE shop SET key = id::STRING WHERE key IS NULL;
+UPDATE price SET key = id::STRING WHERE key IS NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE purchase DROP COLUMN IF EXISTS catalog_version_id;
+DROP TABLE IF EXISTS catalog_version;
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS catalog_version (
+  id UUID DEFAULT gen_random_uuid() NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp(),
+  published_at TIMESTAMPTZ NULL,
+  name STRING DEFAULT '' NOT NULL,
+  state INT64 DEFAULT 0 NOT NULL,
+  document STRING DEFAULT '' NOT NULL,
+
+  PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS index_state ON catalog_version(state);
+
+ALTER TABLE purchase ADD COLUMN IF NOT EXISTS catalog_version_id UUID NULL;
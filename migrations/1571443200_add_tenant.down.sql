@@ -0,0 +1,39 @@
+DROP TABLE IF EXISTS config_tenant;
+DROP TABLE IF EXISTS player_tenant;
+
+DROP INDEX IF EXISTS catalog_version@index_tenant_state CASCADE;
+DROP INDEX IF EXISTS ledger_entry@index_tenant_player_id CASCADE;
+DROP INDEX IF EXISTS purchase@index_tenant_player_id CASCADE;
+DROP INDEX IF EXISTS storage@index_tenant_player_id CASCADE;
+DROP INDEX IF EXISTS account@index_tenant_email CASCADE;
+DROP INDEX IF EXISTS currency@index_tenant_symbol CASCADE;
+DROP INDEX IF EXISTS currency@index_tenant_short_name CASCADE;
+DROP INDEX IF EXISTS currency@index_tenant_name CASCADE;
+DROP INDEX IF EXISTS price@index_tenant_key CASCADE;
+DROP INDEX IF EXISTS shop@index_tenant_key CASCADE;
+DROP INDEX IF EXISTS product@index_tenant_key CASCADE;
+DROP INDEX IF EXISTS currency@index_tenant_key CASCADE;
+DROP INDEX IF EXISTS item@index_tenant_key CASCADE;
+
+CREATE UNIQUE INDEX IF NOT EXISTS account_email_key ON account(email);
+CREATE UNIQUE INDEX IF NOT EXISTS currency_symbol_key ON currency(symbol);
+CREATE UNIQUE INDEX IF NOT EXISTS currency_short_name_key ON currency(short_name);
+CREATE UNIQUE INDEX IF NOT EXISTS currency_name_key ON currency(name);
+CREATE UNIQUE INDEX IF NOT EXISTS index_key ON price(key);
+CREATE UNIQUE INDEX IF NOT EXISTS index_key ON shop(key);
+CREATE UNIQUE INDEX IF NOT EXISTS index_key ON product(key);
+CREATE UNIQUE INDEX IF NOT EXISTS index_key ON currency(key);
+CREATE UNIQUE INDEX IF NOT EXISTS index_key ON item(key);
+
+ALTER TABLE catalog_version DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE ledger_entry DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE purchase DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE price DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE product DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE shop DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE account DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE storage DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE currency DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE item DROP COLUMN IF EXISTS tenant_id;
+
+DROP TABLE IF EXISTS tenant;
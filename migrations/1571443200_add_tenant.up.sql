@@ -0,0 +1,65 @@
+CREATE TABLE IF NOT EXISTS tenant (
+  id STRING NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp(),
+  name STRING DEFAULT '' NOT NULL,
+
+  PRIMARY KEY (id)
+);
+
+ALTER TABLE item ADD COLUMN IF NOT EXISTS tenant_id STRING NOT NULL DEFAULT 'default';
+ALTER TABLE currency ADD COLUMN IF NOT EXISTS tenant_id STRING NOT NULL DEFAULT 'default';
+ALTER TABLE storage ADD COLUMN IF NOT EXISTS tenant_id STRING NOT NULL DEFAULT 'default';
+ALTER TABLE account ADD COLUMN IF NOT EXISTS tenant_id STRING NOT NULL DEFAULT 'default';
+ALTER TABLE shop ADD COLUMN IF NOT EXISTS tenant_id STRING NOT NULL DEFAULT 'default';
+ALTER TABLE product ADD COLUMN IF NOT EXISTS tenant_id STRING NOT NULL DEFAULT 'default';
+ALTER TABLE price ADD COLUMN IF NOT EXISTS tenant_id STRING NOT NULL DEFAULT 'default';
+ALTER TABLE purchase ADD COLUMN IF NOT EXISTS tenant_id STRING NOT NULL DEFAULT 'default';
+ALTER TABLE ledger_entry ADD COLUMN IF NOT EXISTS tenant_id STRING NOT NULL DEFAULT 'default';
+ALTER TABLE catalog_version ADD COLUMN IF NOT EXISTS tenant_id STRING NOT NULL DEFAULT 'default';
+
+DROP INDEX IF EXISTS item@index_key CASCADE;
+DROP INDEX IF EXISTS currency@index_key CASCADE;
+DROP INDEX IF EXISTS product@index_key CASCADE;
+DROP INDEX IF EXISTS shop@index_key CASCADE;
+DROP INDEX IF EXISTS price@index_key CASCADE;
+DROP INDEX IF EXISTS currency@currency_name_key CASCADE;
+DROP INDEX IF EXISTS currency@currency_short_name_key CASCADE;
+DROP INDEX IF EXISTS currency@currency_symbol_key CASCADE;
+DROP INDEX IF EXISTS account@account_email_key CASCADE;
+
+CREATE UNIQUE INDEX IF NOT EXISTS index_tenant_key ON item(tenant_id, key);
+CREATE UNIQUE INDEX IF NOT EXISTS index_tenant_key ON currency(tenant_id, key);
+CREATE UNIQUE INDEX IF NOT EXISTS index_tenant_key ON product(tenant_id, key);
+CREATE UNIQUE INDEX IF NOT EXISTS index_tenant_key ON shop(tenant_id, key);
+CREATE UNIQUE INDEX IF NOT EXISTS index_tenant_key ON price(tenant_id, key);
+CREATE UNIQUE INDEX IF NOT EXISTS index_tenant_name ON currency(tenant_id, name);
+CREATE UNIQUE INDEX IF NOT EXISTS index_tenant_short_name ON currency(tenant_id, short_name);
+CREATE UNIQUE INDEX IF NOT EXISTS index_tenant_symbol ON currency(tenant_id, symbol);
+CREATE UNIQUE INDEX IF NOT EXISTS index_tenant_email ON account(tenant_id, email);
+CREATE INDEX IF NOT EXISTS index_tenant_player_id ON storage(tenant_id, player_id);
+CREATE INDEX IF NOT EXISTS index_tenant_player_id ON purchase(tenant_id, player_id);
+CREATE INDEX IF NOT EXISTS index_tenant_player_id ON ledger_entry(tenant_id, player_id);
+CREATE INDEX IF NOT EXISTS index_tenant_state ON catalog_version(tenant_id, state);
+
+-- Player ids and config keys are chosen by the game, so they are only unique
+-- within a tenant. The primary keys can not be altered in place, the data is
+-- copied to these tables and they take over from the old ones afterwards.
+CREATE TABLE IF NOT EXISTS player_tenant (
+  tenant_id STRING NOT NULL DEFAULT 'default',
+  id STRING NOT NULL,
+  name STRING NOT NULL,
+  metadata JSONB DEFAULT '{}' NOT NULL,
+  archived_at TIMESTAMPTZ NULL,
+  erased_at TIMESTAMPTZ NULL,
+
+  PRIMARY KEY (tenant_id, id)
+);
+
+CREATE TABLE IF NOT EXISTS config_tenant (
+  tenant_id STRING NOT NULL DEFAULT 'default',
+  key STRING NOT NULL,
+  value JSONB,
+
+  PRIMARY KEY (tenant_id, key)
+);
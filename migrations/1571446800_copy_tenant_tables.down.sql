@@ -0,0 +1,5 @@
+-- Only the default tenant fits the old table, the players are copied back
+-- by the down migration of the untenanted tables
+INSERT INTO config(key, value)
+SELECT key, value FROM config_tenant WHERE tenant_id = 'default'
+ON CONFLICT (key) DO NOTHING;
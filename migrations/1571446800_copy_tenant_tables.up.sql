@@ -0,0 +1,9 @@
+INSERT INTO tenant(id, name) VALUES ('default', 'Default') ON CONFLICT (id) DO NOTHING;
+
+INSERT INTO player_tenant(tenant_id, id, name, metadata, archived_at, erased_at)
+SELECT 'default', id, name, metadata, archived_at, erased_at FROM player
+ON CONFLICT (tenant_id, id) DO NOTHING;
+
+INSERT INTO config_tenant(tenant_id, key, value)
+SELECT 'default', key, value FROM config
+ON CONFLICT (tenant_id, key) DO NOTHING;
@@ -0,0 +1,28 @@
+ALTER TABLE storage DROP CONSTRAINT IF EXISTS fk_tenant_player;
+
+CREATE TABLE IF NOT EXISTS player (
+  id STRING NOT NULL,
+  name STRING NOT NULL,
+  metadata JSONB DEFAULT '{}' NOT NULL,
+  archived_at TIMESTAMPTZ NULL,
+  erased_at TIMESTAMPTZ NULL,
+
+  PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS index_name ON player(name);
+
+CREATE TABLE IF NOT EXISTS config (
+  key STRING NOT NULL,
+  value JSONB,
+
+  PRIMARY KEY (key)
+);
+
+-- The players have to be back before storages can reference them again,
+-- only the default tenant fits the old table
+INSERT INTO player(id, name, metadata, archived_at, erased_at)
+SELECT id, name, metadata, archived_at, erased_at FROM player_tenant WHERE tenant_id = 'default'
+ON CONFLICT (id) DO NOTHING;
+
+ALTER TABLE storage ADD CONSTRAINT fk_player_id FOREIGN KEY (player_id) REFERENCES player(id);
@@ -0,0 +1,6 @@
+-- Dropping the old player table takes the foreign key of storage with it,
+-- storages reference the player within their tenant from now on
+DROP TABLE IF EXISTS player CASCADE;
+DROP TABLE IF EXISTS config;
+
+ALTER TABLE storage ADD CONSTRAINT fk_tenant_player FOREIGN KEY (tenant_id, player_id) REFERENCES player_tenant(tenant_id, id);
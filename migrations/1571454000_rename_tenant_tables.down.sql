@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS player@index_name;
+
+ALTER TABLE config RENAME TO config_tenant;
+ALTER TABLE player RENAME TO player_tenant;
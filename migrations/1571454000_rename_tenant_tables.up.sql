@@ -0,0 +1,4 @@
+ALTER TABLE player_tenant RENAME TO player;
+ALTER TABLE config_tenant RENAME TO config;
+
+CREATE INDEX IF NOT EXISTS index_name ON player(tenant_id, name);
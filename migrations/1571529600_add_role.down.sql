@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS account_role;
+DROP TABLE IF EXISTS role;
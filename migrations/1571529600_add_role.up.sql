@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS role (
+  tenant_id STRING NOT NULL DEFAULT 'default',
+  name STRING NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp(),
+  permissions STRING[] NOT NULL DEFAULT ARRAY[],
+
+  PRIMARY KEY (tenant_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS account_role (
+  account_id UUID NOT NULL,
+  role STRING NOT NULL,
+
+  PRIMARY KEY (account_id, role),
+  FOREIGN KEY (account_id) REFERENCES account(id)
+);
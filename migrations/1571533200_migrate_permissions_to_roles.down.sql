@@ -0,0 +1,5 @@
+INSERT INTO account_permission(account_id, permission)
+SELECT account_id, '*' FROM account_role WHERE role = 'admin'
+ON CONFLICT (account_id, permission) DO NOTHING;
+
+DELETE FROM account_role WHERE role = 'admin';
@@ -0,0 +1,6 @@
+-- Accounts that could do everything become admins
+INSERT INTO account_role(account_id, role)
+SELECT account_id, 'admin' FROM account_permission WHERE permission = '*'
+ON CONFLICT (account_id, role) DO NOTHING;
+
+DELETE FROM account_permission WHERE permission = '*';
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS api_key (
+  id UUID DEFAULT gen_random_uuid() NOT NULL,
+  tenant_id STRING NOT NULL DEFAULT 'default',
+  account_id UUID NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp(),
+  expires_at TIMESTAMPTZ NULL,
+  last_used_at TIMESTAMPTZ NULL,
+  revoked_at TIMESTAMPTZ NULL,
+  name STRING DEFAULT '' NOT NULL,
+  prefix STRING NOT NULL,
+  hash STRING NOT NULL,
+  permissions STRING[] NOT NULL DEFAULT ARRAY[],
+  roles STRING[] NOT NULL DEFAULT ARRAY[],
+
+  PRIMARY KEY (id),
+  FOREIGN KEY (account_id) REFERENCES account(id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS index_hash ON api_key(hash);
+CREATE INDEX IF NOT EXISTS index_tenant_account_id ON api_key(tenant_id, account_id);
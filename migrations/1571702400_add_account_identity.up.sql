@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS account_identity (
+  account_id UUID NOT NULL,
+  issuer STRING NOT NULL,
+  subject STRING NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp(),
+
+  PRIMARY KEY (account_id, issuer, subject),
+  FOREIGN KEY (account_id) REFERENCES account(id)
+);
+
+CREATE INDEX IF NOT EXISTS index_issuer_subject ON account_identity(issuer, subject);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS account_verification;
+ALTER TABLE account DROP COLUMN IF EXISTS email_verified_at;
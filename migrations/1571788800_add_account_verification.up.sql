@@ -0,0 +1,13 @@
+ALTER TABLE account ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMPTZ NULL;
+
+CREATE TABLE IF NOT EXISTS account_verification (
+  hash STRING NOT NULL,
+  account_id UUID NOT NULL,
+  purpose STRING NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp(),
+  expires TIMESTAMPTZ NOT NULL,
+  used_at TIMESTAMPTZ NULL,
+
+  PRIMARY KEY (hash),
+  FOREIGN KEY (account_id) REFERENCES account(id)
+);
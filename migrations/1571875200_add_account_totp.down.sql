@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS account_recovery_code;
+ALTER TABLE account_token DROP COLUMN IF EXISTS amr;
+ALTER TABLE account DROP COLUMN IF EXISTS totp_last_step;
+ALTER TABLE account DROP COLUMN IF EXISTS totp_enabled_at;
+ALTER TABLE account DROP COLUMN IF EXISTS totp_secret;
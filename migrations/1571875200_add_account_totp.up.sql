@@ -0,0 +1,14 @@
+ALTER TABLE account ADD COLUMN IF NOT EXISTS totp_secret STRING NULL;
+ALTER TABLE account ADD COLUMN IF NOT EXISTS totp_enabled_at TIMESTAMPTZ NULL;
+ALTER TABLE account ADD COLUMN IF NOT EXISTS totp_last_step INT NULL;
+ALTER TABLE account_token ADD COLUMN IF NOT EXISTS amr STRING[] NULL;
+
+CREATE TABLE IF NOT EXISTS account_recovery_code (
+  account_id UUID NOT NULL,
+  hash STRING NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp(),
+  used_at TIMESTAMPTZ NULL,
+
+  PRIMARY KEY (account_id, hash),
+  FOREIGN KEY (account_id) REFERENCES account(id)
+);
@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS account_refresh_token;
+DROP TABLE IF EXISTS account_session;
+
+CREATE TABLE IF NOT EXISTS account_token (
+  token UUID DEFAULT gen_random_uuid() NOT NULL,
+  account_id UUID NOT NULL,
+  expires TIMESTAMPTZ NOT NULL,
+  amr STRING[] NULL,
+
+  PRIMARY KEY (token),
+  FOREIGN KEY (account_id) REFERENCES account(id)
+);
@@ -0,0 +1,31 @@
+DROP TABLE IF EXISTS account_token;
+
+CREATE TABLE IF NOT EXISTS account_session (
+  id UUID DEFAULT gen_random_uuid() NOT NULL,
+  account_id UUID NOT NULL,
+  tenant_id STRING NOT NULL,
+  amr STRING[] NOT NULL DEFAULT ARRAY[]::STRING[],
+  user_agent STRING NOT NULL DEFAULT '',
+  ip STRING NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp(),
+  last_used_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp(),
+  expires_at TIMESTAMPTZ NOT NULL,
+  revoked_at TIMESTAMPTZ NULL,
+
+  PRIMARY KEY (id),
+  FOREIGN KEY (account_id) REFERENCES account(id)
+);
+
+CREATE INDEX IF NOT EXISTS index_account_id ON account_session(tenant_id, account_id);
+
+CREATE TABLE IF NOT EXISTS account_refresh_token (
+  hash STRING NOT NULL,
+  session_id UUID NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp(),
+  used_at TIMESTAMPTZ NULL,
+
+  PRIMARY KEY (hash),
+  FOREIGN KEY (session_id) REFERENCES account_session(id)
+);
+
+CREATE INDEX IF NOT EXISTS index_session_id ON account_refresh_token(session_id);
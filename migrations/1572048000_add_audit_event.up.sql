@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS audit_event (
+  id UUID DEFAULT gen_random_uuid() NOT NULL,
+  tenant_id STRING NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp(),
+  account_id STRING NOT NULL DEFAULT '',
+  api_key_id STRING NOT NULL DEFAULT '',
+  method STRING NOT NULL,
+  request STRING NOT NULL DEFAULT '',
+  outcome STRING NOT NULL,
+  message STRING NOT NULL DEFAULT '',
+  request_id STRING NOT NULL DEFAULT '',
+  ip STRING NOT NULL DEFAULT '',
+
+  PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS index_created_at ON audit_event(tenant_id, created_at);
+CREATE INDEX IF NOT EXISTS index_account_id ON audit_event(tenant_id, account_id, created_at);
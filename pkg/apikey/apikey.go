@@ -0,0 +1,116 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
+)
+
+// Prefix is put in front of every key so keys are easy to recognize
+const Prefix = "esk_"
+
+// prefixLength is the amount of characters of a key that are stored in plain text
+const prefixLength = len(Prefix) + 6
+
+// Generate creates a new key and the prefix it can be recognized by
+func Generate() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	key := Prefix + base64.RawURLEncoding.EncodeToString(b)
+	return key, key[:prefixLength], nil
+}
+
+// IsKey checks if a credential looks like an API key
+func IsKey(key string) bool {
+	return strings.HasPrefix(key, Prefix) && len(key) > prefixLength
+}
+
+// Hash returns the hash a key is stored by, keys are random enough to not need a salt
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Valid checks if a key is not revoked or expired
+func Valid(key *v1.ApiKey, now time.Time) bool {
+	if key.GetRevokedAt() != nil {
+		return false
+	}
+
+	if key.GetExpiresAt() != nil && key.GetExpiresAt().GetSeconds() <= now.Unix() {
+		return false
+	}
+
+	return true
+}
+
+type entry struct {
+	key     *v1.ApiKey
+	expires time.Time
+}
+
+// Cache keeps recently used keys in memory so not every request hits the database,
+// revoking a key removes it right away, other instances pick it up within the ttl
+type Cache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]entry
+}
+
+// NewCache creates a cache that keeps keys for the given duration
+func NewCache(ttl time.Duration) *Cache {
+	return &Cache{
+		ttl:     ttl,
+		entries: map[string]entry{},
+	}
+}
+
+// Get a key by its hash
+func (c *Cache) Get(hash string) (*v1.ApiKey, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, ok := c.entries[hash]
+	if !ok {
+		return nil, false
+	}
+
+	if time.Now().After(cached.expires) {
+		delete(c.entries, hash)
+		return nil, false
+	}
+
+	return cached.key, true
+}
+
+// Set a key by its hash
+func (c *Cache) Set(hash string, key *v1.ApiKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[hash] = entry{
+		key:     key,
+		expires: time.Now().Add(c.ttl),
+	}
+}
+
+// Revoke removes a key from the cache by its id
+func (c *Cache) Revoke(apiKeyID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for hash, cached := range c.entries {
+		if cached.key.GetId() == apiKeyID {
+			delete(c.entries, hash)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package apikey_test
+
+import (
+	"testing"
+	"time"
+
+	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
+	apikey "github.com/GameComponent/economy-service/pkg/apikey"
+	ptypes "github.com/golang/protobuf/ptypes"
+)
+
+func TestGenerate(t *testing.T) {
+	key, prefix, err := apikey.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !apikey.IsKey(key) {
+		t.Errorf("generated key %q is not recognized as a key", key)
+	}
+
+	if key[:len(prefix)] != prefix {
+		t.Errorf("prefix %q does not match key %q", prefix, key)
+	}
+
+	other, _, _ := apikey.Generate()
+	if apikey.Hash(key) == apikey.Hash(other) {
+		t.Error("two keys have the same hash")
+	}
+}
+
+func TestValid(t *testing.T) {
+	now := time.Now()
+	past, _ := ptypes.TimestampProto(now.Add(-time.Minute))
+	future, _ := ptypes.TimestampProto(now.Add(time.Minute))
+
+	cases := []struct {
+		key  *v1.ApiKey
+		want bool
+	}{
+		{&v1.ApiKey{}, true},
+		{&v1.ApiKey{ExpiresAt: future}, true},
+		{&v1.ApiKey{ExpiresAt: past}, false},
+		{&v1.ApiKey{RevokedAt: past}, false},
+	}
+
+	for i, c := range cases {
+		if got := apikey.Valid(c.key, now); got != c.want {
+			t.Errorf("case %d: Valid = %v, want %v", i, got, c.want)
+		}
+	}
+}
+
+func TestCacheRevoke(t *testing.T) {
+	cache := apikey.NewCache(time.Minute)
+	cache.Set("hash", &v1.ApiKey{Id: "key"})
+
+	if _, ok := cache.Get("hash"); !ok {
+		t.Fatal("key should be cached")
+	}
+
+	cache.Revoke("key")
+
+	if _, ok := cache.Get("hash"); ok {
+		t.Error("revoked key should not be cached")
+	}
+}
+
+func TestCacheExpires(t *testing.T) {
+	cache := apikey.NewCache(-time.Second)
+	cache.Set("hash", &v1.ApiKey{Id: "key"})
+
+	if _, ok := cache.Get("hash"); ok {
+		t.Error("expired entries should not be returned")
+	}
+}
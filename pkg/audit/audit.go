@@ -0,0 +1,93 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	jsonpb "github.com/golang/protobuf/jsonpb"
+	proto "github.com/golang/protobuf/proto"
+)
+
+// Redacted is the placeholder of values that never end up in the audit log
+const Redacted = "[redacted]"
+
+// maxValueLength is the longest string value that is kept as is,
+// documents like catalogs are replaced by their size
+const maxValueLength = 1024
+
+// secretFields are the field names, or suffixes of them, holding credentials
+var secretFields = []string{
+	"password",
+	"token",
+	"code",
+	"secret",
+}
+
+// Sanitize encodes a request as JSON without its credentials and large values
+func Sanitize(req interface{}) string {
+	message, ok := req.(proto.Message)
+	if !ok || message == nil {
+		return ""
+	}
+
+	marshaler := jsonpb.Marshaler{
+		OrigName: true,
+	}
+
+	document, err := marshaler.MarshalToString(message)
+	if err != nil {
+		return ""
+	}
+
+	var value interface{}
+	err = json.Unmarshal([]byte(document), &value)
+	if err != nil {
+		return ""
+	}
+
+	sanitized, err := json.Marshal(sanitize("", value))
+	if err != nil {
+		return ""
+	}
+
+	return string(sanitized)
+}
+
+func sanitize(field string, value interface{}) interface{} {
+	if secret(field) {
+		return Redacted
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			v[key] = sanitize(key, child)
+		}
+	case []interface{}:
+		for i, child := range v {
+			v[i] = sanitize(field, child)
+		}
+	case string:
+		if len(v) > maxValueLength {
+			return fmt.Sprintf("[%d bytes]", len(v))
+		}
+	}
+
+	return value
+}
+
+// secret checks if a field holds credentials, page tokens are harmless
+func secret(field string) bool {
+	if field == "" || field == "page_token" {
+		return false
+	}
+
+	for _, name := range secretFields {
+		if field == name || strings.HasSuffix(field, "_"+name) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,54 @@
+package audit_test
+
+import (
+	"strings"
+	"testing"
+
+	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
+	audit "github.com/GameComponent/economy-service/pkg/audit"
+)
+
+func TestSanitizeRedactsCredentials(t *testing.T) {
+	got := audit.Sanitize(&v1.ChangePasswordRequest{
+		Email:       "admin@example.com",
+		Password:    "hunter2",
+		NewPassword: "hunter3",
+		Code:        "123456",
+	})
+
+	want := `{"code":"[redacted]","email":"admin@example.com","new_password":"[redacted]","password":"[redacted]"}`
+	if got != want {
+		t.Errorf("Sanitize() = %s, want %s", got, want)
+	}
+}
+
+func TestSanitizeKeepsValues(t *testing.T) {
+	got := audit.Sanitize(&v1.GiveCurrencyRequest{
+		StorageId:  "storage",
+		CurrencyId: "currency",
+		Amount:     &v1.Amount{MinAmount: 100, MaxAmount: 100},
+	})
+
+	want := `{"amount":{"max_amount":"100","min_amount":"100"},"currency_id":"currency","storage_id":"storage"}`
+	if got != want {
+		t.Errorf("Sanitize() = %s, want %s", got, want)
+	}
+}
+
+func TestSanitizeShortensLargeValues(t *testing.T) {
+	got := audit.Sanitize(&v1.ImportCatalogRequest{
+		Catalog: strings.Repeat("a", 2000),
+		DryRun:  true,
+	})
+
+	want := `{"catalog":"[2000 bytes]","dry_run":true}`
+	if got != want {
+		t.Errorf("Sanitize() = %s, want %s", got, want)
+	}
+}
+
+func TestSanitizeWithoutMessage(t *testing.T) {
+	if got := audit.Sanitize(nil); got != "" {
+		t.Errorf("Sanitize(nil) = %q, want empty", got)
+	}
+}
@@ -0,0 +1,272 @@
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Supported formats of a catalog document
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+)
+
+// Catalog describes the items, currencies, products and shops of a game.
+// Entries are identified by designer-facing keys instead of database ids.
+type Catalog struct {
+	Items      []*Item     `json:"items,omitempty" yaml:"items,omitempty"`
+	Currencies []*Currency `json:"currencies,omitempty" yaml:"currencies,omitempty"`
+	Products   []*Product  `json:"products,omitempty" yaml:"products,omitempty"`
+	Shops      []*Shop     `json:"shops,omitempty" yaml:"shops,omitempty"`
+}
+
+// Item in the catalog
+type Item struct {
+	Key                  string `json:"key" yaml:"key"`
+	Name                 string `json:"name" yaml:"name"`
+	Stackable            bool   `json:"stackable,omitempty" yaml:"stackable,omitempty"`
+	StackMaxAmount       int64  `json:"stack_max_amount,omitempty" yaml:"stack_max_amount,omitempty"`
+	StackBalancingMethod string `json:"stack_balancing_method,omitempty" yaml:"stack_balancing_method,omitempty"`
+	Metadata             string `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+}
+
+// Currency in the catalog
+type Currency struct {
+	Key       string `json:"key" yaml:"key"`
+	Name      string `json:"name" yaml:"name"`
+	ShortName string `json:"short_name,omitempty" yaml:"short_name,omitempty"`
+	Symbol    string `json:"symbol,omitempty" yaml:"symbol,omitempty"`
+}
+
+// Product in the catalog, the items and currencies are what the buyer receives
+type Product struct {
+	Key        string            `json:"key" yaml:"key"`
+	Name       string            `json:"name" yaml:"name"`
+	Items      []*ItemAmount     `json:"items,omitempty" yaml:"items,omitempty"`
+	Currencies []*CurrencyAmount `json:"currencies,omitempty" yaml:"currencies,omitempty"`
+	Prices     []*Price          `json:"prices,omitempty" yaml:"prices,omitempty"`
+}
+
+// Price of a product, the items and currencies are what the buyer pays
+type Price struct {
+	Key        string            `json:"key" yaml:"key"`
+	Items      []*ItemAmount     `json:"items,omitempty" yaml:"items,omitempty"`
+	Currencies []*CurrencyAmount `json:"currencies,omitempty" yaml:"currencies,omitempty"`
+}
+
+// Shop in the catalog, products are referenced by their key
+type Shop struct {
+	Key      string   `json:"key" yaml:"key"`
+	Name     string   `json:"name" yaml:"name"`
+	Metadata string   `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	Products []string `json:"products,omitempty" yaml:"products,omitempty"`
+}
+
+// ItemAmount references an item by its key
+type ItemAmount struct {
+	Item   string `json:"item" yaml:"item"`
+	Amount int64  `json:"amount" yaml:"amount"`
+}
+
+// CurrencyAmount references a currency by its key
+type CurrencyAmount struct {
+	Currency string `json:"currency" yaml:"currency"`
+	Amount   int64  `json:"amount" yaml:"amount"`
+}
+
+// Parse a YAML or JSON catalog document
+func Parse(document []byte) (*Catalog, error) {
+	catalog := &Catalog{}
+
+	// JSON is a subset of YAML so both can be parsed the same way
+	err := yaml.UnmarshalStrict(document, catalog)
+	if err != nil {
+		return nil, err
+	}
+
+	return catalog, nil
+}
+
+// Marshal a catalog into a document of the given format
+func Marshal(catalog *Catalog, format string) ([]byte, error) {
+	switch strings.ToLower(format) {
+	case FormatYAML:
+		return yaml.Marshal(catalog)
+	case FormatJSON:
+		return json.MarshalIndent(catalog, "", "  ")
+	}
+
+	return nil, fmt.Errorf("unknown catalog format %q", format)
+}
+
+// Validate checks that all keys are set and unique
+func (c *Catalog) Validate() error {
+	items := map[string]bool{}
+	for _, item := range c.Items {
+		if err := checkKey("item", item.Key, items); err != nil {
+			return err
+		}
+
+		if item.Name == "" {
+			return fmt.Errorf("item %s has no name", item.Key)
+		}
+
+		if _, ok := v1.StackBalancingMethod_value[item.stackBalancingMethod()]; !ok {
+			return fmt.Errorf("item %s has an unknown stack_balancing_method %q", item.Key, item.StackBalancingMethod)
+		}
+
+		if !isMetadata(item.Metadata) {
+			return fmt.Errorf("item %s has metadata that is not a JSON object", item.Key)
+		}
+	}
+
+	currencies := map[string]bool{}
+	for _, currency := range c.Currencies {
+		if err := checkKey("currency", currency.Key, currencies); err != nil {
+			return err
+		}
+
+		if currency.Name == "" {
+			return fmt.Errorf("currency %s has no name", currency.Key)
+		}
+	}
+
+	products := map[string]bool{}
+	prices := map[string]bool{}
+	for _, product := range c.Products {
+		if err := checkKey("product", product.Key, products); err != nil {
+			return err
+		}
+
+		if product.Name == "" {
+			return fmt.Errorf("product %s has no name", product.Key)
+		}
+
+		if err := checkAmounts("product "+product.Key, product.Items, product.Currencies); err != nil {
+			return err
+		}
+
+		// Price keys are unique across all products
+		for _, price := range product.Prices {
+			if err := checkKey("price", price.Key, prices); err != nil {
+				return err
+			}
+
+			if err := checkAmounts("price "+price.Key, price.Items, price.Currencies); err != nil {
+				return err
+			}
+		}
+	}
+
+	shops := map[string]bool{}
+	for _, shop := range c.Shops {
+		if err := checkKey("shop", shop.Key, shops); err != nil {
+			return err
+		}
+
+		if shop.Name == "" {
+			return fmt.Errorf("shop %s has no name", shop.Key)
+		}
+
+		if !isMetadata(shop.Metadata) {
+			return fmt.Errorf("shop %s has metadata that is not a JSON object", shop.Key)
+		}
+
+		seen := map[string]bool{}
+		for _, product := range shop.Products {
+			if seen[product] {
+				return fmt.Errorf("product %s is listed twice in shop %s", product, shop.Key)
+			}
+			seen[product] = true
+		}
+	}
+
+	return nil
+}
+
+func (i *Item) stackBalancingMethod() string {
+	if i.StackBalancingMethod == "" {
+		return v1.StackBalancingMethod_DEFAULT.String()
+	}
+
+	return strings.ToUpper(i.StackBalancingMethod)
+}
+
+// StackBalancingMethodValue returns the stack balancing method as it is stored
+func (i *Item) StackBalancingMethodValue() v1.StackBalancingMethod {
+	return v1.StackBalancingMethod(v1.StackBalancingMethod_value[i.stackBalancingMethod()])
+}
+
+func checkKey(kind string, key string, seen map[string]bool) error {
+	if key == "" {
+		return fmt.Errorf("%s without a key", kind)
+	}
+
+	if seen[key] {
+		return fmt.Errorf("%s %s is defined twice", kind, key)
+	}
+	seen[key] = true
+
+	return nil
+}
+
+func checkAmounts(owner string, items []*ItemAmount, currencies []*CurrencyAmount) error {
+	seenItems := map[string]bool{}
+	for _, item := range items {
+		if seenItems[item.Item] {
+			return fmt.Errorf("item %s is listed twice in %s", item.Item, owner)
+		}
+		seenItems[item.Item] = true
+
+		if item.Amount <= 0 {
+			return fmt.Errorf("item %s in %s needs a positive amount", item.Item, owner)
+		}
+	}
+
+	seenCurrencies := map[string]bool{}
+	for _, currency := range currencies {
+		if seenCurrencies[currency.Currency] {
+			return fmt.Errorf("currency %s is listed twice in %s", currency.Currency, owner)
+		}
+		seenCurrencies[currency.Currency] = true
+
+		if currency.Amount <= 0 {
+			return fmt.Errorf("currency %s in %s needs a positive amount", currency.Currency, owner)
+		}
+	}
+
+	return nil
+}
+
+func isMetadata(metadata string) bool {
+	if metadata == "" {
+		return true
+	}
+
+	object := map[string]interface{}{}
+	return json.Unmarshal([]byte(metadata), &object) == nil
+}
+
+// sameMetadata compares metadata by value, the database does not keep the formatting
+func sameMetadata(a string, b string) bool {
+	var left, right interface{}
+
+	if a == "" {
+		a = "{}"
+	}
+
+	if b == "" {
+		b = "{}"
+	}
+
+	if json.Unmarshal([]byte(a), &left) != nil || json.Unmarshal([]byte(b), &right) != nil {
+		return a == b
+	}
+
+	return reflect.DeepEqual(left, right)
+}
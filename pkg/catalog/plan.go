@@ -0,0 +1,338 @@
+package catalog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Action taken for an entry of the catalog
+type Action string
+
+// Actions of a plan
+const (
+	Create  Action = "create"
+	Update  Action = "update"
+	Archive Action = "archive"
+)
+
+// Kinds of entries in the catalog
+const (
+	KindItem     = "item"
+	KindCurrency = "currency"
+	KindProduct  = "product"
+	KindPrice    = "price"
+	KindShop     = "shop"
+)
+
+// Change to a single entry of the catalog
+type Change struct {
+	Action Action `json:"action"`
+	Kind   string `json:"kind"`
+	Key    string `json:"key"`
+}
+
+// Plan lists the changes needed to turn one catalog into another,
+// the changes are ordered so that references are created before they are used
+type Plan struct {
+	Changes []*Change `json:"changes"`
+}
+
+// Empty returns true if there is nothing to change
+func (p *Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// String renders the plan in a human readable way
+func (p *Plan) String() string {
+	if p.Empty() {
+		return "No changes\n"
+	}
+
+	symbols := map[Action]string{
+		Create:  "+",
+		Update:  "~",
+		Archive: "-",
+	}
+
+	builder := strings.Builder{}
+	for _, change := range p.Changes {
+		fmt.Fprintf(&builder, "%s %s %s\n", symbols[change.Action], change.Kind, change.Key)
+	}
+
+	return builder.String()
+}
+
+// Diff plans the changes to go from the current to the desired catalog.
+// Entries missing from the desired catalog are only archived when prune is set.
+func Diff(current *Catalog, desired *Catalog, prune bool) (*Plan, error) {
+	if err := desired.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := checkReferences(current, desired, prune); err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{}
+
+	// Items
+	currentItems := map[string]*Item{}
+	currentItemKeys := []string{}
+	for _, item := range current.Items {
+		currentItems[item.Key] = item
+		currentItemKeys = append(currentItemKeys, item.Key)
+	}
+
+	desiredItems := map[string]bool{}
+	for _, item := range desired.Items {
+		desiredItems[item.Key] = true
+		plan.add(KindItem, item.Key, currentItems[item.Key] != nil, !sameItem(currentItems[item.Key], item))
+	}
+
+	// Currencies
+	currentCurrencies := map[string]*Currency{}
+	currentCurrencyKeys := []string{}
+	for _, currency := range current.Currencies {
+		currentCurrencies[currency.Key] = currency
+		currentCurrencyKeys = append(currentCurrencyKeys, currency.Key)
+	}
+
+	desiredCurrencies := map[string]bool{}
+	for _, currency := range desired.Currencies {
+		desiredCurrencies[currency.Key] = true
+		plan.add(KindCurrency, currency.Key, currentCurrencies[currency.Key] != nil, !sameCurrency(currentCurrencies[currency.Key], currency))
+	}
+
+	// Products, the prices are handled separately
+	currentProducts := map[string]*Product{}
+	currentPrices := map[string]*Price{}
+	currentPriceProducts := map[string]string{}
+	currentProductKeys := []string{}
+	currentPriceKeys := []string{}
+	for _, product := range current.Products {
+		currentProducts[product.Key] = product
+		currentProductKeys = append(currentProductKeys, product.Key)
+
+		for _, price := range product.Prices {
+			currentPrices[price.Key] = price
+			currentPriceProducts[price.Key] = product.Key
+			currentPriceKeys = append(currentPriceKeys, price.Key)
+		}
+	}
+
+	desiredProducts := map[string]bool{}
+	for _, product := range desired.Products {
+		desiredProducts[product.Key] = true
+		plan.add(KindProduct, product.Key, currentProducts[product.Key] != nil, !sameProduct(currentProducts[product.Key], product))
+	}
+
+	// Prices
+	desiredPrices := map[string]bool{}
+	for _, product := range desired.Products {
+		for _, price := range product.Prices {
+			desiredPrices[price.Key] = true
+
+			changed := currentPriceProducts[price.Key] != product.Key || !samePrice(currentPrices[price.Key], price)
+			plan.add(KindPrice, price.Key, currentPrices[price.Key] != nil, changed)
+		}
+	}
+
+	// Shops
+	currentShops := map[string]*Shop{}
+	currentShopKeys := []string{}
+	for _, shop := range current.Shops {
+		currentShops[shop.Key] = shop
+		currentShopKeys = append(currentShopKeys, shop.Key)
+	}
+
+	desiredShops := map[string]bool{}
+	for _, shop := range desired.Shops {
+		desiredShops[shop.Key] = true
+		plan.add(KindShop, shop.Key, currentShops[shop.Key] != nil, !sameShop(currentShops[shop.Key], shop))
+	}
+
+	if !prune {
+		return plan, nil
+	}
+
+	// Archive whatever is no longer in the catalog, starting with the entries that reference others
+	plan.archive(KindShop, currentShopKeys, desiredShops)
+	plan.archive(KindPrice, currentPriceKeys, desiredPrices)
+	plan.archive(KindProduct, currentProductKeys, desiredProducts)
+	plan.archive(KindCurrency, currentCurrencyKeys, desiredCurrencies)
+	plan.archive(KindItem, currentItemKeys, desiredItems)
+
+	return plan, nil
+}
+
+func (p *Plan) add(kind string, key string, exists bool, changed bool) {
+	if !exists {
+		p.Changes = append(p.Changes, &Change{Action: Create, Kind: kind, Key: key})
+		return
+	}
+
+	if changed {
+		p.Changes = append(p.Changes, &Change{Action: Update, Kind: kind, Key: key})
+	}
+}
+
+func (p *Plan) archive(kind string, current []string, desired map[string]bool) {
+	// Keep the plan stable
+	keys := append([]string{}, current...)
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if !desired[key] {
+			p.Changes = append(p.Changes, &Change{Action: Archive, Kind: kind, Key: key})
+		}
+	}
+}
+
+// checkReferences makes sure all referenced keys exist after the plan is applied
+func checkReferences(current *Catalog, desired *Catalog, prune bool) error {
+	catalogs := []*Catalog{desired}
+	if !prune {
+		catalogs = append(catalogs, current)
+	}
+
+	items := map[string]bool{}
+	currencies := map[string]bool{}
+	products := map[string]bool{}
+	for _, catalog := range catalogs {
+		for _, item := range catalog.Items {
+			items[item.Key] = true
+		}
+
+		for _, currency := range catalog.Currencies {
+			currencies[currency.Key] = true
+		}
+
+		for _, product := range catalog.Products {
+			products[product.Key] = true
+		}
+	}
+
+	check := func(owner string, itemAmounts []*ItemAmount, currencyAmounts []*CurrencyAmount) error {
+		for _, itemAmount := range itemAmounts {
+			if !items[itemAmount.Item] {
+				return fmt.Errorf("%s references unknown item %s", owner, itemAmount.Item)
+			}
+		}
+
+		for _, currencyAmount := range currencyAmounts {
+			if !currencies[currencyAmount.Currency] {
+				return fmt.Errorf("%s references unknown currency %s", owner, currencyAmount.Currency)
+			}
+		}
+
+		return nil
+	}
+
+	for _, product := range desired.Products {
+		if err := check("product "+product.Key, product.Items, product.Currencies); err != nil {
+			return err
+		}
+
+		for _, price := range product.Prices {
+			if err := check("price "+price.Key, price.Items, price.Currencies); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, shop := range desired.Shops {
+		for _, product := range shop.Products {
+			if !products[product] {
+				return fmt.Errorf("shop %s references unknown product %s", shop.Key, product)
+			}
+		}
+	}
+
+	return nil
+}
+
+func sameItem(a *Item, b *Item) bool {
+	return a != nil &&
+		a.Name == b.Name &&
+		a.Stackable == b.Stackable &&
+		a.StackMaxAmount == b.StackMaxAmount &&
+		a.stackBalancingMethod() == b.stackBalancingMethod() &&
+		sameMetadata(a.Metadata, b.Metadata)
+}
+
+func sameCurrency(a *Currency, b *Currency) bool {
+	return a != nil &&
+		a.Name == b.Name &&
+		a.ShortName == b.ShortName &&
+		a.Symbol == b.Symbol
+}
+
+func sameProduct(a *Product, b *Product) bool {
+	return a != nil &&
+		a.Name == b.Name &&
+		sameItemAmounts(a.Items, b.Items) &&
+		sameCurrencyAmounts(a.Currencies, b.Currencies)
+}
+
+func samePrice(a *Price, b *Price) bool {
+	return a != nil &&
+		sameItemAmounts(a.Items, b.Items) &&
+		sameCurrencyAmounts(a.Currencies, b.Currencies)
+}
+
+func sameShop(a *Shop, b *Shop) bool {
+	if a == nil || a.Name != b.Name || !sameMetadata(a.Metadata, b.Metadata) {
+		return false
+	}
+
+	return sameStrings(a.Products, b.Products)
+}
+
+// The order of amounts is not significant
+func sameItemAmounts(a []*ItemAmount, b []*ItemAmount) bool {
+	left := []string{}
+	for _, amount := range a {
+		left = append(left, fmt.Sprintf("%s:%d", amount.Item, amount.Amount))
+	}
+
+	right := []string{}
+	for _, amount := range b {
+		right = append(right, fmt.Sprintf("%s:%d", amount.Item, amount.Amount))
+	}
+
+	return sameStrings(left, right)
+}
+
+func sameCurrencyAmounts(a []*CurrencyAmount, b []*CurrencyAmount) bool {
+	left := []string{}
+	for _, amount := range a {
+		left = append(left, fmt.Sprintf("%s:%d", amount.Currency, amount.Amount))
+	}
+
+	right := []string{}
+	for _, amount := range b {
+		right = append(right, fmt.Sprintf("%s:%d", amount.Currency, amount.Amount))
+	}
+
+	return sameStrings(left, right)
+}
+
+func sameStrings(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	left := append([]string{}, a...)
+	right := append([]string{}, b...)
+	sort.Strings(left)
+	sort.Strings(right)
+
+	for i := range left {
+		if left[i] != right[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,243 @@
+package catalog_test
+
+import (
+	"testing"
+
+	catalog "github.com/GameComponent/economy-service/pkg/catalog"
+)
+
+const document = `
+items:
+  - key: sword
+    name: Sword
+  - key: potion
+    name: Potion
+    stackable: true
+    stack_max_amount: 10
+    metadata: '{"color": "red"}'
+currencies:
+  - key: gold
+    name: Gold
+    short_name: GLD
+    symbol: G
+products:
+  - key: starter-pack
+    name: Starter pack
+    items:
+      - item: sword
+        amount: 1
+      - item: potion
+        amount: 5
+    prices:
+      - key: starter-pack-gold
+        currencies:
+          - currency: gold
+            amount: 100
+shops:
+  - key: general-store
+    name: General store
+    products:
+      - starter-pack
+`
+
+func parse(t *testing.T, document string) *catalog.Catalog {
+	result, err := catalog.Parse([]byte(document))
+	if err != nil {
+		t.Fatalf("unable to parse catalog: %v", err)
+	}
+
+	return result
+}
+
+func changes(plan *catalog.Plan) []string {
+	result := []string{}
+	for _, change := range plan.Changes {
+		result = append(result, string(change.Action)+" "+change.Kind+" "+change.Key)
+	}
+
+	return result
+}
+
+func assertChanges(t *testing.T, plan *catalog.Plan, expected ...string) {
+	actual := changes(plan)
+	if len(actual) != len(expected) {
+		t.Fatalf("expected changes %v, got %v", expected, actual)
+	}
+
+	for i := range expected {
+		if actual[i] != expected[i] {
+			t.Fatalf("expected changes %v, got %v", expected, actual)
+		}
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	result := parse(t, `{"items": [{"key": "sword", "name": "Sword"}]}`)
+
+	if len(result.Items) != 1 || result.Items[0].Key != "sword" {
+		t.Errorf("expected the sword item, got %v", result.Items)
+	}
+}
+
+func TestParseUnknownField(t *testing.T) {
+	_, err := catalog.Parse([]byte(`{"items": [{"key": "sword", "nmae": "Sword"}]}`))
+	if err == nil {
+		t.Errorf("expected unknown fields to be rejected")
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	original := parse(t, document)
+
+	for _, format := range []string{catalog.FormatYAML, catalog.FormatJSON} {
+		data, err := catalog.Marshal(original, format)
+		if err != nil {
+			t.Fatalf("unable to marshal %s: %v", format, err)
+		}
+
+		plan, err := catalog.Diff(original, parse(t, string(data)), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !plan.Empty() {
+			t.Errorf("expected no changes after a %s round trip, got %v", format, changes(plan))
+		}
+	}
+}
+
+func TestDiffCreatesEverything(t *testing.T) {
+	plan, err := catalog.Diff(&catalog.Catalog{}, parse(t, document), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertChanges(
+		t,
+		plan,
+		"create item sword",
+		"create item potion",
+		"create currency gold",
+		"create product starter-pack",
+		"create price starter-pack-gold",
+		"create shop general-store",
+	)
+}
+
+func TestDiffIgnoresOrderAndFormatting(t *testing.T) {
+	current := parse(t, document)
+	desired := parse(t, document)
+
+	// Reorder the product items and reformat the metadata
+	items := desired.Products[0].Items
+	items[0], items[1] = items[1], items[0]
+	desired.Items[1].Metadata = `{ "color":"red" }`
+
+	plan, err := catalog.Diff(current, desired, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertChanges(t, plan)
+}
+
+func TestDiffUpdates(t *testing.T) {
+	current := parse(t, document)
+	desired := parse(t, document)
+
+	desired.Items[0].Name = "Long sword"
+	desired.Products[0].Prices[0].Currencies[0].Amount = 150
+
+	plan, err := catalog.Diff(current, desired, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertChanges(t, plan, "update item sword", "update price starter-pack-gold")
+}
+
+func TestDiffPrune(t *testing.T) {
+	current := parse(t, document)
+	desired := parse(t, `
+items:
+  - key: sword
+    name: Sword
+`)
+
+	plan, err := catalog.Diff(current, desired, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertChanges(t, plan)
+
+	plan, err = catalog.Diff(current, desired, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertChanges(
+		t,
+		plan,
+		"archive shop general-store",
+		"archive price starter-pack-gold",
+		"archive product starter-pack",
+		"archive currency gold",
+		"archive item potion",
+	)
+}
+
+func TestDiffUnknownReference(t *testing.T) {
+	desired := parse(t, `
+products:
+  - key: starter-pack
+    name: Starter pack
+    items:
+      - item: shield
+        amount: 1
+`)
+
+	_, err := catalog.Diff(&catalog.Catalog{}, desired, false)
+	if err == nil {
+		t.Errorf("expected an error for the unknown item")
+	}
+}
+
+func TestDiffKeepsReferencesToExistingEntries(t *testing.T) {
+	current := parse(t, document)
+	desired := parse(t, `
+products:
+  - key: potion-pack
+    name: Potion pack
+    items:
+      - item: potion
+        amount: 10
+`)
+
+	plan, err := catalog.Diff(current, desired, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertChanges(t, plan, "create product potion-pack")
+
+	// Pruning would archive the potion the product depends on
+	_, err = catalog.Diff(current, desired, true)
+	if err == nil {
+		t.Errorf("expected an error for the pruned item")
+	}
+}
+
+func TestValidateDuplicateKey(t *testing.T) {
+	desired := parse(t, `
+items:
+  - key: sword
+    name: Sword
+  - key: sword
+    name: Other sword
+`)
+
+	if err := desired.Validate(); err == nil {
+		t.Errorf("expected an error for the duplicate key")
+	}
+}
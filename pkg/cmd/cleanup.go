@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"context"
+	"time"
+
+	repository "github.com/GameComponent/economy-service/pkg/repository"
+	"go.uber.org/zap"
+)
+
+// runCleanup removes expired sessions and verification tokens of all tenants on an interval
+// until the context is done, running it on several instances at once is harmless
+func runCleanup(ctx context.Context, logger *zap.Logger, interval time.Duration, sessionRepository repository.SessionRepository, accountRepository repository.AccountRepository) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now().UTC()
+
+		sessions, err := sessionRepository.DeleteExpired(ctx, now)
+		if err != nil {
+			logger.Error("Unable to delete expired sessions", zap.Error(err))
+		}
+
+		tokens, err := accountRepository.DeleteExpiredVerificationTokens(ctx, now)
+		if err != nil {
+			logger.Error("Unable to delete expired verification tokens", zap.Error(err))
+		}
+
+		logger.Info("Cleaned up expired rows", zap.Int64("sessions", sessions), zap.Int64("verification_tokens", tokens))
+	}
+}
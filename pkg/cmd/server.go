@@ -3,32 +3,56 @@ package cmd
 import (
 	"context"
 	"flag"
-	"log"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
 
 	config "github.com/GameComponent/economy-service/pkg/config"
 	database "github.com/GameComponent/economy-service/pkg/database"
+	health "github.com/GameComponent/economy-service/pkg/health"
+	logging "github.com/GameComponent/economy-service/pkg/logging"
+	mail "github.com/GameComponent/economy-service/pkg/mail"
+	metrics "github.com/GameComponent/economy-service/pkg/metrics"
+	oidc "github.com/GameComponent/economy-service/pkg/oidc"
+	pagetoken "github.com/GameComponent/economy-service/pkg/pagetoken"
 	grpc "github.com/GameComponent/economy-service/pkg/protocol/grpc"
 	rest "github.com/GameComponent/economy-service/pkg/protocol/rest"
+	ratelimit "github.com/GameComponent/economy-service/pkg/ratelimit"
 	accountrepository "github.com/GameComponent/economy-service/pkg/repository/account"
+	apikeyrepository "github.com/GameComponent/economy-service/pkg/repository/apikey"
+	auditrepository "github.com/GameComponent/economy-service/pkg/repository/audit"
+	catalogrepository "github.com/GameComponent/economy-service/pkg/repository/catalog"
 	configrepository "github.com/GameComponent/economy-service/pkg/repository/config"
 	currencyrepository "github.com/GameComponent/economy-service/pkg/repository/currency"
 	itemrepository "github.com/GameComponent/economy-service/pkg/repository/item"
+	ledgerrepository "github.com/GameComponent/economy-service/pkg/repository/ledger"
 	playerrepository "github.com/GameComponent/economy-service/pkg/repository/player"
 	pricerepository "github.com/GameComponent/economy-service/pkg/repository/price"
 	productrepository "github.com/GameComponent/economy-service/pkg/repository/product"
+	rolerepository "github.com/GameComponent/economy-service/pkg/repository/role"
+	sessionrepository "github.com/GameComponent/economy-service/pkg/repository/session"
 	shoprepository "github.com/GameComponent/economy-service/pkg/repository/shop"
 	storagerepository "github.com/GameComponent/economy-service/pkg/repository/storage"
+	tenantrepository "github.com/GameComponent/economy-service/pkg/repository/tenant"
 	v1 "github.com/GameComponent/economy-service/pkg/service/v1"
+	signing "github.com/GameComponent/economy-service/pkg/signing"
+	tracing "github.com/GameComponent/economy-service/pkg/tracing"
+	prometheus "github.com/prometheus/client_golang/prometheus"
 	pflag "github.com/spf13/pflag"
 	viper "github.com/spf13/viper"
 	"go.uber.org/zap"
 )
 
+// defaultJWTSecret is the secret used when none is configured
+const defaultJWTSecret = "my_secret_key"
+
 // RunServer runs gRPC server and HTTP gateway
 func RunServer() error {
 	ctx := context.Background()
 
-	// Create the logger
+	// Create the logger, it is replaced by one with the configured level once the config is loaded
 	logger, _ := zap.NewProduction()
 
 	// Set the configuration
@@ -40,17 +64,49 @@ func RunServer() error {
 	// Set the defaults
 	v.SetDefault("grpc_port", "3000")
 	v.SetDefault("http_port", "8080")
+	v.SetDefault("metrics_port", "9090")
+	v.SetDefault("shutdown_drain_delay", 5) // 5 seconds
 	v.SetDefault("db_host", "127.0.0.1")
 	v.SetDefault("db_port", "26257")
 	v.SetDefault("db_user", "root")
 	v.SetDefault("db_password", "")
+	v.SetDefault("db_service_user", "")
+	v.SetDefault("db_service_password", "")
 	v.SetDefault("db_name", "economy")
 	v.SetDefault("db_ssl", "disable")
 	v.SetDefault("log_level", "0")
 	v.SetDefault("log_time_format", "")
-	v.SetDefault("jwt_secret", "my_secret_key")
+	v.SetDefault("jwt_secret", defaultJWTSecret)
 	v.SetDefault("jwt_expiration", 300)             // 5 minutes
 	v.SetDefault("jwt_refresh_expiration", 2592000) // 30 days
+	v.SetDefault("jwt_private_key", "")
+	v.SetDefault("jwt_public_keys", "")
+	v.SetDefault("player_expiration", 900) // 15 minutes
+	v.SetDefault("page_token_secret", "")
+	v.SetDefault("dev", false)
+	v.SetDefault("oidc_issuer", "")
+	v.SetDefault("oidc_audience", "")
+	v.SetDefault("oidc_groups_claim", "groups")
+	v.SetDefault("oidc_group_roles", "")
+	v.SetDefault("mail_backend", "log")
+	v.SetDefault("mail_from", "economy@localhost")
+	v.SetDefault("mail_dir", "mail")
+	v.SetDefault("mail_smtp_host", "")
+	v.SetDefault("mail_smtp_port", "587")
+	v.SetDefault("mail_smtp_user", "")
+	v.SetDefault("mail_smtp_password", "")
+	v.SetDefault("mail_link_url", "")
+	v.SetDefault("totp_issuer", "Economy Service")
+	v.SetDefault("totp_require_sensitive", false)
+	v.SetDefault("cleanup_interval", 3600) // 1 hour
+	v.SetDefault("auth_rate_ip", "30/1m")
+	v.SetDefault("auth_rate_email", "10/1m")
+	v.SetDefault("lockout_threshold", 5)
+	v.SetDefault("lockout_duration", 30)       // 30 seconds
+	v.SetDefault("lockout_max_duration", 3600) // 1 hour
+	v.SetDefault("trace_exporter", "")
+	v.SetDefault("trace_otlp_endpoint", "")
+	v.SetDefault("trace_sample_ratio", 1.0)
 
 	// Set potential config locations
 	v.SetConfigName("config")
@@ -72,17 +128,49 @@ func RunServer() error {
 	// Parse the flags
 	flag.String("grpc_port", "3000", "gRPC port to bind")
 	flag.String("http_port", "8080", "http port to bind")
+	flag.String("metrics_port", "9090", "port to serve the Prometheus metrics on, empty disables it")
+	flag.Int("shutdown_drain_delay", 5, "seconds to keep serving after the readiness check started failing on shutdown")
 	flag.String("db_host", "127.0.0.1", "host of the database")
 	flag.String("db_port", "26257", "port of the database")
 	flag.String("db_user", "root", "user of the database")
 	flag.String("db_password", "", "password of the database")
+	flag.String("db_service_user", "", "user the service runs as after db_user migrated the database, it can't update or delete audit events, empty runs as db_user")
+	flag.String("db_service_password", "", "password of db_service_user")
 	flag.String("db_name", "economy", "name of the database")
 	flag.String("db_ssl", "disable", "ssl settings of the database")
-	flag.Int("log_level", 0, "level of the logger")
-	flag.String("log_time_format", "", "time format of the logger")
-	flag.String("jwt_secret", "my_secret_key", "secret used to sign JWT tokens")
+	flag.Int("log_level", 0, "level of the logger, from -1 (debug) to 5 (fatal)")
+	flag.String("log_time_format", "", "Go time layout of the log timestamps, like 2006-01-02T15:04:05.999Z07:00, empty logs seconds since the epoch")
+	flag.String("jwt_secret", defaultJWTSecret, "secret used to sign JWT tokens when no private key is set")
 	flag.Int("jwt_expiration", 300, "seconds before the JWT expires")
 	flag.Int("jwt_refresh_expiration", 2592000, "seconds before the refresh token expires")
+	flag.String("jwt_private_key", "", "path of a PEM encoded RSA or P-256 EC key to sign JWT tokens with")
+	flag.String("jwt_public_keys", "", "comma separated paths of PEM encoded public keys of previous signing keys that are still accepted")
+	flag.Int("player_expiration", 900, "maximum seconds before a player token expires")
+	flag.String("page_token_secret", "", "secret used to sign page tokens, all instances need the same one, empty uses a random secret")
+	flag.Bool("dev", false, "allow insecure defaults for local development, like the default jwt_secret")
+	flag.String("oidc_issuer", "", "issuer URL of the OpenID Connect provider admins log in with")
+	flag.String("oidc_audience", "", "client id ID tokens of the OpenID Connect provider are issued for")
+	flag.String("oidc_groups_claim", "groups", "claim of the ID token holding the groups of the user")
+	flag.String("oidc_group_roles", "", "roles granted by groups of the OpenID Connect provider, like admins=admin,staff=support;designer")
+	flag.String("mail_backend", "log", "how emails are sent: smtp, or log and file for local development")
+	flag.String("mail_from", "economy@localhost", "sender address of emails")
+	flag.String("mail_dir", "mail", "directory the file mail backend writes emails to")
+	flag.String("mail_smtp_host", "", "host of the SMTP server")
+	flag.String("mail_smtp_port", "587", "port of the SMTP server")
+	flag.String("mail_smtp_user", "", "user of the SMTP server")
+	flag.String("mail_smtp_password", "", "password of the SMTP server")
+	flag.String("mail_link_url", "", "base URL of the page handling password reset and email verification links")
+	flag.String("totp_issuer", "Economy Service", "name authenticator apps show for two-factor authentication")
+	flag.Bool("totp_require_sensitive", false, "require two-factor authentication for accounts allowed to execute sensitive methods")
+	flag.Int("cleanup_interval", 3600, "seconds between removing expired sessions and tokens, 0 disables it")
+	flag.String("auth_rate_ip", "30/1m", "requests to the authentication methods per client address, like 30/1m, empty disables it")
+	flag.String("auth_rate_email", "10/1m", "requests to the authentication methods per email, like 10/1m, empty disables it")
+	flag.Int("lockout_threshold", 5, "failed logins before an account is locked, 0 disables it")
+	flag.Int("lockout_duration", 30, "seconds of the first lock, every following failed login doubles it")
+	flag.Int("lockout_max_duration", 3600, "maximum seconds of a lock")
+	flag.String("trace_exporter", "", "where to send traces, stdout or otlp, empty disables it")
+	flag.String("trace_otlp_endpoint", "", "host:port of the OTLP/HTTP collector, empty uses OTEL_EXPORTER_OTLP_ENDPOINT or localhost:4318")
+	flag.Float64("trace_sample_ratio", 1.0, "share of the traces started by this service that is sampled")
 
 	// Add flags to Viper
 	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
@@ -97,11 +185,64 @@ func RunServer() error {
 		return err
 	}
 
+	configuredLogger, err := logging.New(cfg.LogLevel, cfg.LogTimeFormat)
+	if err != nil {
+		logger.Error("Unable to configure the logger", zap.Error(err))
+		return err
+	}
+	logger = configuredLogger
+	defer logger.Sync()
+
 	if len(cfg.GRPCPort) == 0 {
 		logger.Error("invalid TCP port for gRPC server", zap.String("port", cfg.GRPCPort))
 		return err
 	}
 
+	// Load the keys to sign and verify tokens with
+	keyring, err := loadKeyring(cfg)
+	if err != nil {
+		logger.Error("Unable to load the signing keys", zap.Error(err))
+		return err
+	}
+
+	// Setup the signer of page tokens
+	pageTokens, err := loadPageTokens(cfg, logger)
+	if err != nil {
+		logger.Error("Unable to create the page token secret", zap.Error(err))
+		return err
+	}
+
+	// Setup the OpenID Connect provider
+	oidcProvider, err := loadOIDCProvider(cfg)
+	if err != nil {
+		logger.Error("Unable to configure the OpenID Connect provider", zap.Error(err))
+		return err
+	}
+
+	// Setup the mail sender
+	mailer, err := loadMailer(cfg, logger)
+	if err != nil {
+		logger.Error("Unable to configure the mail sender", zap.Error(err))
+		return err
+	}
+
+	// Setup the rate limits of the authentication methods
+	authIPLimiter, authEmailLimiter, lockout, err := loadAuthLimits(cfg, ratelimit.NewMemoryStore())
+	if err != nil {
+		logger.Error("Unable to configure the rate limits", zap.Error(err))
+		return err
+	}
+
+	// Setup the tracing, before the database so its queries are traced
+	shutdownTracing, err := tracing.Init(ctx, cfg.TraceExporter, cfg.TraceOTLPEndpoint, cfg.TraceSampleRatio)
+	if err != nil {
+		logger.Error("Unable to configure the tracing", zap.Error(err))
+		return err
+	}
+	defer func() {
+		_ = shutdownTracing(context.Background())
+	}()
+
 	// Setup database & migrate
 	_, err = database.Init(
 		cfg.DatabaseHost,
@@ -110,16 +251,25 @@ func RunServer() error {
 		cfg.DatabasePassword,
 		cfg.DatabaseName,
 		cfg.DatabaseSsl,
+		cfg.DatabaseServiceUser,
+		logger,
 	)
 	if err != nil {
-		log.Fatal(err)
+		logger.Error("Unable to migrate the database", zap.Error(err))
+		return err
+	}
+
+	// Run as the service user when there is one, db_user only migrates the database
+	databaseUser, databasePassword := cfg.DatabaseUser, cfg.DatabasePassword
+	if cfg.DatabaseServiceUser != "" {
+		databaseUser, databasePassword = cfg.DatabaseServiceUser, cfg.DatabaseServicePass
 	}
 
 	db, err := database.Connect(
 		cfg.DatabaseHost,
 		cfg.DatabasePort,
-		cfg.DatabaseUser,
-		cfg.DatabasePassword,
+		databaseUser,
+		databasePassword,
 		cfg.DatabaseName,
 		cfg.DatabaseSsl,
 	)
@@ -129,6 +279,14 @@ func RunServer() error {
 		logger.Fatal("Could create a database connection", zap.Error(err))
 	}
 
+	// The audit log can only be trusted when the service can't change it
+	immutable, err := database.AuditEventsImmutable(ctx, db)
+	if err != nil {
+		logger.Error("Unable to check the privileges on audit events", zap.Error(err))
+	} else if !immutable {
+		logger.Warn("Audit events are not immutable, the database user can update or delete them, set db_service_user to run as a user that can't")
+	}
+
 	// Setup the repositories
 	itemRepository := itemrepository.NewItemRepository(db, logger)
 	playerRepository := playerrepository.NewPlayerRepository(db, logger)
@@ -136,34 +294,201 @@ func RunServer() error {
 	storageRepository := storagerepository.NewStorageRepository(db, logger)
 	configRepository := configrepository.NewConfigRepository(db, logger)
 	accountRepository := accountrepository.NewAccountRepository(db, logger)
+	apiKeyRepository := apikeyrepository.NewAPIKeyRepository(db, logger)
+	auditRepository := auditrepository.NewAuditRepository(db, logger)
 	shopRepository := shoprepository.NewShopRepository(db, logger)
 	productRepository := productrepository.NewProductRepository(db, logger)
 	priceRepository := pricerepository.NewPriceRepository(db, logger)
+	ledgerRepository := ledgerrepository.NewLedgerRepository(db, logger)
+	catalogRepository := catalogrepository.NewCatalogRepository(db, logger)
+	roleRepository := rolerepository.NewRoleRepository(db, logger)
+	sessionRepository := sessionrepository.NewSessionRepository(db, logger)
+	tenantRepository := tenantrepository.NewTenantRepository(db, logger)
 
 	// Create the config
 	config := v1.Config{
 		DB:                 db,
 		Logger:             logger,
 		Config:             &cfg,
+		Keyring:            keyring,
+		PageTokens:         pageTokens,
+		OIDCProvider:       oidcProvider,
+		Mailer:             mailer,
+		AuthIPLimiter:      authIPLimiter,
+		AuthEmailLimiter:   authEmailLimiter,
+		Lockout:            lockout,
 		ItemRepository:     itemRepository,
 		PlayerRepository:   playerRepository,
 		CurrencyRepository: currencyRepository,
 		StorageRepository:  storageRepository,
 		ConfigRepository:   configRepository,
 		AccountRepository:  accountRepository,
+		APIKeyRepository:   apiKeyRepository,
+		AuditRepository:    auditRepository,
 		ShopRepository:     shopRepository,
 		ProductRepository:  productRepository,
 		PriceRepository:    priceRepository,
+		LedgerRepository:   ledgerRepository,
+		CatalogRepository:  catalogRepository,
+		RoleRepository:     roleRepository,
+		SessionRepository:  sessionRepository,
+		TenantRepository:   tenantRepository,
+	}
+
+	// Remove expired rows in the background
+	if cfg.CleanupInterval > 0 {
+		go runCleanup(ctx, logger, time.Duration(cfg.CleanupInterval)*time.Second, sessionRepository, accountRepository)
 	}
 
 	// Start the service
 	v1API := v1.NewEconomyServiceServer(config)
 
+	// Start the metrics server
+	if cfg.MetricsPort != "" {
+		prometheus.MustRegister(metrics.NewDBStatsCollector(db))
+
+		go func() {
+			err := metrics.RunServer(ctx, logger, cfg.MetricsPort)
+			if err != nil && err != http.ErrServerClosed {
+				logger.Error("Metrics server stopped", zap.Error(err))
+			}
+		}()
+	}
+
+	// Check the readiness against the newest migration shipped with the service
+	migrationVersion, err := database.LatestMigration(database.MigrationsDir)
+	if err != nil {
+		logger.Warn("Unable to read the migrations, readiness does not check them", zap.Error(err))
+	}
+
+	checker := health.NewChecker(db, migrationVersion, time.Duration(cfg.ShutdownDrainDelay)*time.Second)
+
 	// Start the REST server
 	go func() {
-		_ = rest.RunServer(ctx, logger, cfg.GRPCPort, cfg.HTTPPort)
+		_ = rest.RunServer(ctx, logger, keyring, checker, cfg.GRPCPort, cfg.HTTPPort)
 	}()
 
 	// Start the GRCP server
-	return grpc.RunServer(ctx, v1API, logger, cfg.GRPCPort)
+	return grpc.RunServer(ctx, v1API, checker, logger, cfg.GRPCPort)
+}
+
+// loadKeyring loads the signing keys from the config, the default secret is
+// known to everyone so it is only accepted for local development
+func loadKeyring(cfg config.Config) (*signing.Keyring, error) {
+	secret := cfg.JWTSecret
+	if secret == defaultJWTSecret && !cfg.Dev {
+		if cfg.JWTPrivateKey == "" {
+			return nil, fmt.Errorf("refusing to start with the default jwt_secret, set jwt_secret or jwt_private_key or run with dev for local development")
+		}
+
+		secret = ""
+	}
+
+	var privateKey []byte
+	if cfg.JWTPrivateKey != "" {
+		data, err := ioutil.ReadFile(cfg.JWTPrivateKey)
+		if err != nil {
+			return nil, err
+		}
+
+		privateKey = data
+	}
+
+	publicKeys := [][]byte{}
+	for _, path := range strings.Split(cfg.JWTPublicKeys, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		publicKeys = append(publicKeys, data)
+	}
+
+	return signing.NewKeyring(secret, privateKey, publicKeys...)
+}
+
+// loadPageTokens creates the signer of page tokens, without a secret the tokens
+// only continue lists on this instance until it restarts
+func loadPageTokens(cfg config.Config, logger *zap.Logger) (*pagetoken.Signer, error) {
+	if cfg.PageTokenSecret != "" {
+		return pagetoken.New([]byte(cfg.PageTokenSecret)), nil
+	}
+
+	logger.Warn("no page_token_secret set, page tokens only work on this instance until it restarts")
+
+	return pagetoken.NewRandom()
+}
+
+// loadOIDCProvider creates the OpenID Connect provider, nil when it is not configured
+func loadOIDCProvider(cfg config.Config) (*oidc.Provider, error) {
+	if cfg.OIDCIssuer == "" {
+		return nil, nil
+	}
+
+	if cfg.OIDCAudience == "" {
+		return nil, fmt.Errorf("oidc_audience is required when oidc_issuer is set")
+	}
+
+	groupRoles, err := oidc.ParseGroupRoles(cfg.OIDCGroupRoles)
+	if err != nil {
+		return nil, err
+	}
+
+	return oidc.NewProvider(oidc.Config{
+		Issuer:      cfg.OIDCIssuer,
+		Audience:    cfg.OIDCAudience,
+		GroupsClaim: cfg.OIDCGroupsClaim,
+		GroupRoles:  groupRoles,
+	}, &http.Client{Timeout: 10 * time.Second}), nil
+}
+
+// loadMailer creates the sender of emails for the configured backend
+func loadMailer(cfg config.Config, logger *zap.Logger) (mail.Sender, error) {
+	switch cfg.MailBackend {
+	case "smtp":
+		if cfg.MailSMTPHost == "" {
+			return nil, fmt.Errorf("mail_smtp_host is required for the smtp mail backend")
+		}
+
+		return mail.NewSMTPSender(
+			cfg.MailSMTPHost,
+			cfg.MailSMTPPort,
+			cfg.MailSMTPUser,
+			cfg.MailSMTPPassword,
+			cfg.MailFrom,
+		), nil
+	case "file":
+		return mail.NewFileSender(cfg.MailDir, cfg.MailFrom), nil
+	case "log", "":
+		return mail.NewLogSender(logger), nil
+	}
+
+	return nil, fmt.Errorf("unknown mail_backend %q", cfg.MailBackend)
+}
+
+// loadAuthLimits creates the limiters of the authentication methods, they share a store
+func loadAuthLimits(cfg config.Config, store ratelimit.Store) (*ratelimit.Limiter, *ratelimit.Limiter, *ratelimit.Lockout, error) {
+	ipRate, err := ratelimit.ParseRate(cfg.AuthRateIP)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	emailRate, err := ratelimit.ParseRate(cfg.AuthRateEmail)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	lockout := ratelimit.NewLockout(
+		store,
+		int64(cfg.LockoutThreshold),
+		time.Duration(cfg.LockoutDuration)*time.Second,
+		time.Duration(cfg.LockoutMaxDuration)*time.Second,
+	)
+
+	return ratelimit.NewLimiter(store, "auth-ip", ipRate), ratelimit.NewLimiter(store, "auth-email", emailRate), lockout, nil
 }
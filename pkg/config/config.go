@@ -2,17 +2,49 @@ package config
 
 // Config for the server
 type Config struct {
-	GRPCPort             string `mapstructure:"grpc_port"`
-	HTTPPort             string `mapstructure:"http_port"`
-	DatabaseHost         string `mapstructure:"db_host"`
-	DatabasePort         string `mapstructure:"db_port"`
-	DatabaseUser         string `mapstructure:"db_user"`
-	DatabasePassword     string `mapstructure:"db_password"`
-	DatabaseName         string `mapstructure:"db_name"`
-	DatabaseSsl          string `mapstructure:"db_ssl"`
-	LogLevel             int    `mapstructure:"log_level"`
-	LogTimeFormat        string `mapstructure:"log_time_format"`
-	JWTSecret            string `mapstructure:"jwt_secret"`
-	JWTExpiration        int    `mapstructure:"jwt_expiration"`
-	JWTRefreshExpiration int    `mapstructure:"jwt_refresh_expiration"`
+	GRPCPort             string  `mapstructure:"grpc_port"`
+	HTTPPort             string  `mapstructure:"http_port"`
+	MetricsPort          string  `mapstructure:"metrics_port"`
+	ShutdownDrainDelay   int     `mapstructure:"shutdown_drain_delay"`
+	DatabaseHost         string  `mapstructure:"db_host"`
+	DatabasePort         string  `mapstructure:"db_port"`
+	DatabaseUser         string  `mapstructure:"db_user"`
+	DatabasePassword     string  `mapstructure:"db_password"`
+	DatabaseServiceUser  string  `mapstructure:"db_service_user"`
+	DatabaseServicePass  string  `mapstructure:"db_service_password"`
+	DatabaseName         string  `mapstructure:"db_name"`
+	DatabaseSsl          string  `mapstructure:"db_ssl"`
+	LogLevel             int     `mapstructure:"log_level"`
+	LogTimeFormat        string  `mapstructure:"log_time_format"`
+	JWTSecret            string  `mapstructure:"jwt_secret"`
+	JWTExpiration        int     `mapstructure:"jwt_expiration"`
+	JWTRefreshExpiration int     `mapstructure:"jwt_refresh_expiration"`
+	JWTPrivateKey        string  `mapstructure:"jwt_private_key"`
+	JWTPublicKeys        string  `mapstructure:"jwt_public_keys"`
+	PlayerExpiration     int     `mapstructure:"player_expiration"`
+	PageTokenSecret      string  `mapstructure:"page_token_secret"`
+	Dev                  bool    `mapstructure:"dev"`
+	OIDCIssuer           string  `mapstructure:"oidc_issuer"`
+	OIDCAudience         string  `mapstructure:"oidc_audience"`
+	OIDCGroupsClaim      string  `mapstructure:"oidc_groups_claim"`
+	OIDCGroupRoles       string  `mapstructure:"oidc_group_roles"`
+	MailBackend          string  `mapstructure:"mail_backend"`
+	MailFrom             string  `mapstructure:"mail_from"`
+	MailDir              string  `mapstructure:"mail_dir"`
+	MailSMTPHost         string  `mapstructure:"mail_smtp_host"`
+	MailSMTPPort         string  `mapstructure:"mail_smtp_port"`
+	MailSMTPUser         string  `mapstructure:"mail_smtp_user"`
+	MailSMTPPassword     string  `mapstructure:"mail_smtp_password"`
+	MailLinkURL          string  `mapstructure:"mail_link_url"`
+	TotpIssuer           string  `mapstructure:"totp_issuer"`
+	TotpRequireSensitive bool    `mapstructure:"totp_require_sensitive"`
+	CleanupInterval      int     `mapstructure:"cleanup_interval"`
+	AuthRateIP           string  `mapstructure:"auth_rate_ip"`
+	AuthRateEmail        string  `mapstructure:"auth_rate_email"`
+	LockoutThreshold     int     `mapstructure:"lockout_threshold"`
+	LockoutDuration      int     `mapstructure:"lockout_duration"`
+	LockoutMaxDuration   int     `mapstructure:"lockout_max_duration"`
+	TraceExporter        string  `mapstructure:"trace_exporter"`
+	TraceOTLPEndpoint    string  `mapstructure:"trace_otlp_endpoint"`
+	TraceSampleRatio     float64 `mapstructure:"trace_sample_ratio"`
 }
@@ -3,6 +3,9 @@ package database
 import (
 	"database/sql"
 	"fmt"
+
+	otelsql "github.com/XSAM/otelsql"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
 )
 
 // Connect to a database
@@ -30,10 +33,16 @@ func Connect(
 		password,
 	)
 
-	// Setup the database
-	db, err := sql.Open(
+	// Setup the database, every query is traced as part of the request making it
+	db, err := otelsql.Open(
 		"postgres",
 		connectString,
+		otelsql.WithAttributes(semconv.DBSystemCockroachdb),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			OmitConnResetSession: true,
+			OmitConnectorConnect: true,
+			OmitRows:             true,
+		}),
 	)
 
 	if err != nil {
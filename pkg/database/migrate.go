@@ -11,6 +11,7 @@ import (
 	// Needed for the source file driver
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	pq "github.com/lib/pq"
+	"go.uber.org/zap"
 )
 
 const (
@@ -20,8 +21,8 @@ const (
 	defaultLimit             = -1
 )
 
-// Init the migration process
-func Init(host string, port string, user string, password string, dbname string, sslmode string) (bool, error) {
+// Init the migration process, when a service user is given it gets access to the tables
+func Init(host string, port string, user string, password string, dbname string, sslmode string, serviceUser string, logger *zap.Logger) (bool, error) {
 	connectStringBase := fmt.Sprintf(
 		"host=%s port=%s sslmode=%s",
 		host,
@@ -37,7 +38,7 @@ func Init(host string, port string, user string, password string, dbname string,
 	)
 
 	// Setup the database
-	_, err := setupDatabase(connectString, dbname)
+	_, err := setupDatabase(connectString, dbname, logger)
 	if err != nil {
 		return false, err
 	}
@@ -56,10 +57,17 @@ func Init(host string, port string, user string, password string, dbname string,
 		return false, err
 	}
 
+	if serviceUser != "" {
+		err = grantServiceUser(databaseConnectString, dbname, serviceUser, logger)
+		if err != nil {
+			return false, err
+		}
+	}
+
 	return true, nil
 }
 
-func setupDatabase(connectString string, databaseName string) (bool, error) {
+func setupDatabase(connectString string, databaseName string, logger *zap.Logger) (bool, error) {
 	// Setup the database
 	db, err := sql.Open(
 		"postgres",
@@ -73,12 +81,12 @@ func setupDatabase(connectString string, databaseName string) (bool, error) {
 
 	if _, err = db.Exec(fmt.Sprintf("CREATE DATABASE %s", databaseName)); err != nil {
 		if e, ok := err.(*pq.Error); ok && e.Code == dbErrorDuplicateDatabase {
-			fmt.Println("Using existing database")
+			logger.Info("Using existing database", zap.String("database", databaseName))
 		} else {
 			return false, err
 		}
 	} else {
-		fmt.Println("Creating new database")
+		logger.Info("Creating new database", zap.String("database", databaseName))
 	}
 
 	return true, nil
@@ -97,7 +105,7 @@ func migrateDatabase(connectString string) (bool, error) {
 	}
 
 	instance, err := migrate.NewWithDatabaseInstance(
-		"file://../../migrations/",
+		"file://"+MigrationsDir,
 		"cockroachdb",
 		driver,
 	)
@@ -109,3 +117,33 @@ func migrateDatabase(connectString string) (bool, error) {
 
 	return true, nil
 }
+
+// grantServiceUser lets the service user read and change all tables except audit events, which it can
+// only insert and read, the grants are renewed after every migration so new tables are included
+func grantServiceUser(connectString string, databaseName string, serviceUser string, logger *zap.Logger) error {
+	db, err := sql.Open("postgres", connectString)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	user := pq.QuoteIdentifier(serviceUser)
+	tables := pq.QuoteIdentifier(databaseName) + ".*"
+	auditEvents := pq.QuoteIdentifier(databaseName) + ".audit_event"
+
+	queries := []string{
+		fmt.Sprintf("CREATE USER IF NOT EXISTS %s", user),
+		fmt.Sprintf("GRANT SELECT, INSERT, UPDATE, DELETE ON TABLE %s TO %s", tables, user),
+		fmt.Sprintf("REVOKE UPDATE, DELETE ON TABLE %s FROM %s", auditEvents, user),
+	}
+
+	for _, query := range queries {
+		if _, err = db.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	logger.Info("Granted the service user access to the tables", zap.String("user", serviceUser))
+
+	return nil
+}
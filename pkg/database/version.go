@@ -0,0 +1,75 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4/database/cockroachdb"
+)
+
+// MigrationsDir holds the migrations, relative to the directory the server runs in
+const MigrationsDir = "../../migrations/"
+
+// LatestMigration returns the version of the newest migration in a directory
+func LatestMigration(dir string) (uint, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	latest := uint64(0)
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), ".up.sql") {
+			continue
+		}
+
+		version, err := strconv.ParseUint(strings.SplitN(file.Name(), "_", 2)[0], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		if version > latest {
+			latest = version
+		}
+	}
+
+	return uint(latest), nil
+}
+
+// MigrationVersion returns the version of the applied migrations
+// and whether the last one failed halfway
+func MigrationVersion(ctx context.Context, db *sql.DB) (uint, bool, error) {
+	version := int64(0)
+	dirty := false
+
+	err := db.QueryRowContext(
+		ctx,
+		`SELECT version, dirty FROM `+cockroachdb.DefaultMigrationsTable+` LIMIT 1`,
+	).Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	return uint(version), dirty, nil
+}
+
+// AuditEventsImmutable checks that the user of the connection can't update or delete audit events
+func AuditEventsImmutable(ctx context.Context, db *sql.DB) (bool, error) {
+	mutable := false
+
+	err := db.QueryRowContext(
+		ctx,
+		`SELECT has_table_privilege('audit_event', 'UPDATE') OR has_table_privilege('audit_event', 'DELETE')`,
+	).Scan(&mutable)
+	if err != nil {
+		return false, err
+	}
+
+	return !mutable, nil
+}
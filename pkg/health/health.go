@@ -0,0 +1,77 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	database "github.com/GameComponent/economy-service/pkg/database"
+)
+
+// ErrShuttingDown is returned by the readiness check once the service is shutting down
+var ErrShuttingDown = errors.New("shutting down")
+
+// Checker checks if the service can take requests
+type Checker struct {
+	db               *sql.DB
+	migrationVersion uint
+	drainDelay       time.Duration
+	shuttingDown     int32
+}
+
+// NewChecker creates a checker, the service is ready when the database is reachable and
+// at least at migrationVersion, 0 skips the migration check
+func NewChecker(db *sql.DB, migrationVersion uint, drainDelay time.Duration) *Checker {
+	return &Checker{
+		db:               db,
+		migrationVersion: migrationVersion,
+		drainDelay:       drainDelay,
+	}
+}
+
+// Live checks if the process should keep running, it only fails when it can not answer at all
+func (c *Checker) Live() error {
+	return nil
+}
+
+// Ready checks if the service can take requests
+func (c *Checker) Ready(ctx context.Context) error {
+	if atomic.LoadInt32(&c.shuttingDown) == 1 {
+		return ErrShuttingDown
+	}
+
+	err := c.db.PingContext(ctx)
+	if err != nil {
+		return fmt.Errorf("database unreachable: %v", err)
+	}
+
+	version, dirty, err := database.MigrationVersion(ctx, c.db)
+	if err != nil {
+		return fmt.Errorf("unable to read the migration version: %v", err)
+	}
+
+	if dirty {
+		return fmt.Errorf("migration %d failed", version)
+	}
+
+	if version < c.migrationVersion {
+		return fmt.Errorf("migrations pending, at %d of %d", version, c.migrationVersion)
+	}
+
+	return nil
+}
+
+// Shutdown makes the readiness check fail from now on
+func (c *Checker) Shutdown() {
+	atomic.StoreInt32(&c.shuttingDown, 1)
+}
+
+// Drain makes the readiness check fail and waits for load balancers to notice,
+// requests keep being served in the meantime
+func (c *Checker) Drain() {
+	c.Shutdown()
+	time.Sleep(c.drainDelay)
+}
@@ -0,0 +1,66 @@
+package health_test
+
+import (
+	"context"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	health "github.com/GameComponent/economy-service/pkg/health"
+)
+
+func TestReady(t *testing.T) {
+	tests := []struct {
+		name    string
+		version int64
+		dirty   bool
+		ready   bool
+	}{
+		{"up to date", 1572048000, false, true},
+		{"ahead", 1572134400, false, true},
+		{"pending", 1571961600, false, false},
+		{"dirty", 1572048000, true, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			mock.ExpectQuery("SELECT version, dirty FROM schema_migrations").
+				WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(test.version, test.dirty))
+
+			checker := health.NewChecker(db, 1572048000, 0)
+			err = checker.Ready(context.Background())
+
+			if test.ready && err != nil {
+				t.Errorf("Ready() = %v, want ready", err)
+			}
+
+			if !test.ready && err == nil {
+				t.Error("Ready() = nil, want not ready")
+			}
+		})
+	}
+}
+
+func TestReadyShuttingDown(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	checker := health.NewChecker(db, 0, 0)
+	checker.Drain()
+
+	if err := checker.Ready(context.Background()); err != health.ErrShuttingDown {
+		t.Errorf("Ready() = %v, want %v", err, health.ErrShuttingDown)
+	}
+
+	if err := checker.Live(); err != nil {
+		t.Errorf("Live() = %v, want live while shutting down", err)
+	}
+}
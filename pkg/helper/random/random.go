@@ -23,7 +23,7 @@ func GenerateRandomInt(min int64, max int64) int64 {
 // GenerateRandomBytes returns securely generated random bytes
 func GenerateRandomBytes(n int) ([]byte, error) {
 	b := make([]byte, n)
-	_, err := rand.Read(b)
+	_, err := crand.Read(b)
 
 	if err != nil {
 		return nil, err
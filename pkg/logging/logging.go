@@ -0,0 +1,28 @@
+package logging
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// New creates a JSON logger writing to stderr, level goes from -1 (debug) to 5 (fatal),
+// timeFormat is a Go time layout like 2006-01-02T15:04:05.999Z07:00, empty logs seconds since the epoch
+func New(level int, timeFormat string) (*zap.Logger, error) {
+	if level < int(zapcore.DebugLevel) || level > int(zapcore.FatalLevel) {
+		return nil, fmt.Errorf("invalid log level %d, use -1 (debug) to 5 (fatal)", level)
+	}
+
+	cfg := zap.NewProductionConfig()
+	cfg.Level = zap.NewAtomicLevelAt(zapcore.Level(level))
+
+	if timeFormat != "" {
+		cfg.EncoderConfig.EncodeTime = func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+			enc.AppendString(t.Format(timeFormat))
+		}
+	}
+
+	return cfg.Build()
+}
@@ -0,0 +1,31 @@
+package logging_test
+
+import (
+	"testing"
+
+	logging "github.com/GameComponent/economy-service/pkg/logging"
+	"go.uber.org/zap"
+)
+
+func TestNew(t *testing.T) {
+	l, err := logging.New(1, "2006-01-02")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if l.Core().Enabled(zap.InfoLevel) {
+		t.Error("info enabled at level 1, want only warnings and up")
+	}
+
+	if !l.Core().Enabled(zap.WarnLevel) {
+		t.Error("warn disabled at level 1")
+	}
+}
+
+func TestNewInvalidLevel(t *testing.T) {
+	for _, level := range []int{-2, 6} {
+		if _, err := logging.New(level, ""); err == nil {
+			t.Errorf("New(%d) = nil error, want invalid level", level)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender sends emails
+type Sender interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// render encodes a message with its headers
+func render(from string, message Message) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", message.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", strings.Replace(message.Subject, "\n", " ", -1))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().UTC().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.Replace(message.Body, "\n", "\r\n", -1))
+	return b.Bytes()
+}
+
+// SMTPSender sends emails through an SMTP server
+type SMTPSender struct {
+	address string
+	auth    smtp.Auth
+	from    string
+}
+
+// NewSMTPSender creates a sender for an SMTP server, authentication is skipped without a username
+func NewSMTPSender(host string, port string, username string, password string, from string) *SMTPSender {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &SMTPSender{
+		address: net.JoinHostPort(host, port),
+		auth:    auth,
+		from:    from,
+	}
+}
+
+// Send an email
+func (s *SMTPSender) Send(ctx context.Context, message Message) error {
+	return smtp.SendMail(s.address, s.auth, s.from, []string{message.To}, render(s.from, message))
+}
+
+// LogSender writes emails to the log, for local development
+type LogSender struct {
+	logger *zap.Logger
+}
+
+// NewLogSender creates a sender that logs emails
+func NewLogSender(logger *zap.Logger) *LogSender {
+	return &LogSender{
+		logger: logger,
+	}
+}
+
+// Send an email
+func (s *LogSender) Send(ctx context.Context, message Message) error {
+	s.logger.Info(
+		"email",
+		zap.String("to", message.To),
+		zap.String("subject", message.Subject),
+		zap.String("body", message.Body),
+	)
+
+	return nil
+}
+
+// FileSender writes every email to its own file in a directory, for local development
+type FileSender struct {
+	dir  string
+	from string
+}
+
+// NewFileSender creates a sender that writes emails to a directory
+func NewFileSender(dir string, from string) *FileSender {
+	return &FileSender{
+		dir:  dir,
+		from: from,
+	}
+}
+
+// Send an email
+func (s *FileSender) Send(ctx context.Context, message Message) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), sanitize(message.To))
+	return ioutil.WriteFile(filepath.Join(s.dir, name), render(s.from, message), 0644)
+}
+
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '@' || r == '.' || r == '-' || r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+
+		return '_'
+	}, s)
+}
@@ -0,0 +1,42 @@
+package mail_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	mail "github.com/GameComponent/economy-service/pkg/mail"
+)
+
+func TestFileSender(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sender := mail.NewFileSender(dir, "economy@example.com")
+	err = sender.Send(context.Background(), mail.Message{
+		To:      "player/../admin@example.com",
+		Subject: "Reset your password",
+		Body:    "Your token is abc",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) != 1 {
+		t.Fatalf("found %d emails, want 1", len(files))
+	}
+
+	content, _ := ioutil.ReadFile(files[0])
+	for _, want := range []string{"From: economy@example.com", "Subject: Reset your password", "Your token is abc"} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("email does not contain %q", want)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package metrics
+
+import (
+	"database/sql"
+
+	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
+	prometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "economy"
+
+// Business counters, labeled by tenant and the ids of the catalog,
+// which keeps their number bounded by the size of the catalogs
+var (
+	Purchases = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "purchases_total",
+			Help:      "Products bought.",
+		},
+		[]string{"tenant", "product_id"},
+	)
+
+	CurrencyGranted = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "currency_granted_total",
+			Help:      "Amount of currency added to storages.",
+		},
+		[]string{"tenant", "currency_id", "reason"},
+	)
+
+	CurrencySpent = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "currency_spent_total",
+			Help:      "Amount of currency taken from storages.",
+		},
+		[]string{"tenant", "currency_id", "reason"},
+	)
+
+	ItemsGranted = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "items_granted_total",
+			Help:      "Items added to storages.",
+		},
+		[]string{"tenant", "item_id", "reason"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(Purchases, CurrencyGranted, CurrencySpent, ItemsGranted)
+}
+
+// Ledger counts the currency or items a ledger entry moved
+func Ledger(tenantID string, entry *v1.LedgerEntry) {
+	amount := entry.GetAmount()
+
+	switch {
+	case entry.GetCurrencyId() != "" && amount > 0:
+		CurrencyGranted.WithLabelValues(tenantID, entry.GetCurrencyId(), entry.GetReason()).Add(float64(amount))
+	case entry.GetCurrencyId() != "" && amount < 0:
+		CurrencySpent.WithLabelValues(tenantID, entry.GetCurrencyId(), entry.GetReason()).Add(float64(-amount))
+	case entry.GetItemId() != "" && amount > 0:
+		ItemsGranted.WithLabelValues(tenantID, entry.GetItemId(), entry.GetReason()).Add(float64(amount))
+	}
+}
+
+// Purchase counts a bought product together with the currency paid for it
+// and the currency and items it gave
+func Purchase(tenantID string, product *v1.Product, price *v1.Price, reason string) {
+	Purchases.WithLabelValues(tenantID, product.GetId()).Inc()
+
+	for _, priceCurrency := range price.GetCurrencies() {
+		CurrencySpent.WithLabelValues(tenantID, priceCurrency.GetCurrency().GetId(), reason).Add(float64(priceCurrency.GetAmount()))
+	}
+
+	for _, productCurrency := range product.GetCurrencies() {
+		CurrencyGranted.WithLabelValues(tenantID, productCurrency.GetCurrency().GetId(), reason).Add(float64(productCurrency.GetAmount()))
+	}
+
+	for _, productItem := range product.GetItems() {
+		ItemsGranted.WithLabelValues(tenantID, productItem.GetItem().GetId(), reason).Add(float64(productItem.GetAmount()))
+	}
+}
+
+// DBStatsCollector exposes the connection pool statistics of a database
+type DBStatsCollector struct {
+	db *sql.DB
+
+	maxOpen           *prometheus.Desc
+	open              *prometheus.Desc
+	inUse             *prometheus.Desc
+	idle              *prometheus.Desc
+	waitCount         *prometheus.Desc
+	waitDuration      *prometheus.Desc
+	maxIdleClosed     *prometheus.Desc
+	maxLifetimeClosed *prometheus.Desc
+}
+
+// NewDBStatsCollector creates a collector reading sql.DB.Stats() on every scrape
+func NewDBStatsCollector(db *sql.DB) *DBStatsCollector {
+	desc := func(name string, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db", name), help, nil, nil)
+	}
+
+	return &DBStatsCollector{
+		db:                db,
+		maxOpen:           desc("max_open_connections", "Maximum number of open connections to the database."),
+		open:              desc("open_connections", "Established connections, in use and idle."),
+		inUse:             desc("in_use_connections", "Connections currently in use."),
+		idle:              desc("idle_connections", "Idle connections."),
+		waitCount:         desc("wait_count_total", "Connections waited for."),
+		waitDuration:      desc("wait_duration_seconds_total", "Time blocked waiting for a new connection."),
+		maxIdleClosed:     desc("max_idle_closed_total", "Connections closed because of the maximum of idle connections."),
+		maxLifetimeClosed: desc("max_lifetime_closed_total", "Connections closed because of their maximum lifetime."),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *DBStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.maxOpen
+	ch <- c.open
+	ch <- c.inUse
+	ch <- c.idle
+	ch <- c.waitCount
+	ch <- c.waitDuration
+	ch <- c.maxIdleClosed
+	ch <- c.maxLifetimeClosed
+}
+
+// Collect implements prometheus.Collector
+func (c *DBStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.db.Stats()
+
+	ch <- prometheus.MustNewConstMetric(c.maxOpen, prometheus.GaugeValue, float64(stats.MaxOpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.open, prometheus.GaugeValue, float64(stats.OpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.inUse, prometheus.GaugeValue, float64(stats.InUse))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(stats.Idle))
+	ch <- prometheus.MustNewConstMetric(c.waitCount, prometheus.CounterValue, float64(stats.WaitCount))
+	ch <- prometheus.MustNewConstMetric(c.waitDuration, prometheus.CounterValue, stats.WaitDuration.Seconds())
+	ch <- prometheus.MustNewConstMetric(c.maxIdleClosed, prometheus.CounterValue, float64(stats.MaxIdleClosed))
+	ch <- prometheus.MustNewConstMetric(c.maxLifetimeClosed, prometheus.CounterValue, float64(stats.MaxLifetimeClosed))
+}
@@ -0,0 +1,87 @@
+package metrics_test
+
+import (
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
+	metrics "github.com/GameComponent/economy-service/pkg/metrics"
+	prometheus "github.com/prometheus/client_golang/prometheus"
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestLedger(t *testing.T) {
+	metrics.Ledger("ledger", &v1.LedgerEntry{CurrencyId: "gold", Amount: 10, Reason: "give_currency"})
+	metrics.Ledger("ledger", &v1.LedgerEntry{CurrencyId: "gold", Amount: -4, Reason: "purchase"})
+	metrics.Ledger("ledger", &v1.LedgerEntry{ItemId: "sword", Amount: 2, Reason: "give_item"})
+
+	if got := testutil.ToFloat64(metrics.CurrencyGranted.WithLabelValues("ledger", "gold", "give_currency")); got != 10 {
+		t.Errorf("currency granted = %v, want 10", got)
+	}
+
+	if got := testutil.ToFloat64(metrics.CurrencySpent.WithLabelValues("ledger", "gold", "purchase")); got != 4 {
+		t.Errorf("currency spent = %v, want 4", got)
+	}
+
+	if got := testutil.ToFloat64(metrics.ItemsGranted.WithLabelValues("ledger", "sword", "give_item")); got != 2 {
+		t.Errorf("items granted = %v, want 2", got)
+	}
+}
+
+func TestPurchase(t *testing.T) {
+	product := &v1.Product{
+		Id:         "bundle",
+		Currencies: []*v1.ProductCurrency{{Currency: &v1.Currency{Id: "gems"}, Amount: 5}},
+		Items:      []*v1.ProductItem{{Item: &v1.Item{Id: "shield"}, Amount: 1}},
+	}
+	price := &v1.Price{
+		Currencies: []*v1.PriceCurrency{{Currency: &v1.Currency{Id: "coins"}, Amount: 100}},
+	}
+
+	metrics.Purchase("purchase", product, price, "purchase")
+	metrics.Purchase("purchase", product, price, "purchase")
+
+	if got := testutil.ToFloat64(metrics.Purchases.WithLabelValues("purchase", "bundle")); got != 2 {
+		t.Errorf("purchases = %v, want 2", got)
+	}
+
+	if got := testutil.ToFloat64(metrics.CurrencySpent.WithLabelValues("purchase", "coins", "purchase")); got != 200 {
+		t.Errorf("currency spent = %v, want 200", got)
+	}
+
+	if got := testutil.ToFloat64(metrics.CurrencyGranted.WithLabelValues("purchase", "gems", "purchase")); got != 10 {
+		t.Errorf("currency granted = %v, want 10", got)
+	}
+
+	if got := testutil.ToFloat64(metrics.ItemsGranted.WithLabelValues("purchase", "shield", "purchase")); got != 2 {
+		t.Errorf("items granted = %v, want 2", got)
+	}
+}
+
+func TestDBStatsCollector(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	db.SetMaxOpenConns(7)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(metrics.NewDBStatsCollector(db))
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(families) != 8 {
+		t.Errorf("gathered %d metrics, want 8", len(families))
+	}
+
+	for _, family := range families {
+		if family.GetName() == "economy_db_max_open_connections" && family.GetMetric()[0].GetGauge().GetValue() != 7 {
+			t.Errorf("max open connections = %v, want 7", family.GetMetric()[0].GetGauge().GetValue())
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	promhttp "github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+)
+
+// RunServer serves the metrics on /metrics, on a port of its own so it can stay internal
+func RunServer(ctx context.Context, logger *zap.Logger, port string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	srv := &http.Server{
+		Addr:    ":" + port,
+		Handler: mux,
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	logger.Info("starting metrics server", zap.String("port", port))
+	return srv.ListenAndServe()
+}
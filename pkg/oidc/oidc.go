@@ -0,0 +1,340 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+// refreshInterval is the minimum time between two fetches of the keys of the issuer
+const refreshInterval = time.Minute
+
+// Config of an OpenID Connect identity provider
+type Config struct {
+	// Issuer is the URL of the identity provider, the discovery document is read from it
+	Issuer string
+	// Audience is the client id ID tokens have to be issued for
+	Audience string
+	// GroupsClaim is the claim holding the groups of a user
+	GroupsClaim string
+	// GroupRoles maps groups of the identity provider to roles
+	GroupRoles map[string][]string
+}
+
+// IDToken holds the verified claims of an ID token
+type IDToken struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Groups        []string
+}
+
+// Provider verifies ID tokens of an identity provider, the discovery document
+// and the keys are fetched on first use and the keys are fetched again when
+// a token is signed with a key that is not known yet
+type Provider struct {
+	config Config
+	client *http.Client
+
+	mu          sync.Mutex
+	jwksURI     string
+	keys        map[string]interface{}
+	lastFetched time.Time
+}
+
+// NewProvider creates a provider, client defaults to http.DefaultClient
+func NewProvider(config Config, client *http.Client) *Provider {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	if config.GroupsClaim == "" {
+		config.GroupsClaim = "groups"
+	}
+
+	return &Provider{
+		config: config,
+		client: client,
+		keys:   map[string]interface{}{},
+	}
+}
+
+// ParseGroupRoles parses a mapping like "admins=admin,support=support;designer"
+func ParseGroupRoles(mapping string) (map[string][]string, error) {
+	groupRoles := map[string][]string{}
+
+	for _, pair := range strings.Split(mapping, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid group mapping %q, expected group=role", pair)
+		}
+
+		for _, role := range strings.Split(parts[1], ";") {
+			if role = strings.TrimSpace(role); role != "" {
+				groupRoles[parts[0]] = append(groupRoles[parts[0]], role)
+			}
+		}
+	}
+
+	return groupRoles, nil
+}
+
+// Issuer returns the issuer of the provider
+func (p *Provider) Issuer() string {
+	return p.config.Issuer
+}
+
+// Roles returns the roles granted by the groups of a token and
+// every role the mapping manages, granted or not
+func (p *Provider) Roles(token *IDToken) ([]string, []string) {
+	granted := []string{}
+	managed := []string{}
+	seen := map[string]bool{}
+	grantedSeen := map[string]bool{}
+
+	groups := map[string]bool{}
+	for _, group := range token.Groups {
+		groups[group] = true
+	}
+
+	for group, roles := range p.config.GroupRoles {
+		for _, role := range roles {
+			if !seen[role] {
+				seen[role] = true
+				managed = append(managed, role)
+			}
+
+			if groups[group] && !grantedSeen[role] {
+				grantedSeen[role] = true
+				granted = append(granted, role)
+			}
+		}
+	}
+
+	return granted, managed
+}
+
+// Verify checks the signature, issuer, audience and expiry of an ID token
+func (p *Provider) Verify(ctx context.Context, rawIDToken string) (*IDToken, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		switch token.Method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA:
+		default:
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+
+		kid, _ := token.Header["kid"].(string)
+		return p.key(ctx, kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Expiry is checked by the parser, an ID token without one is not accepted
+	if _, ok := claims["exp"]; !ok {
+		return nil, fmt.Errorf("token does not expire")
+	}
+
+	issuer, _ := claims["iss"].(string)
+	if issuer != p.config.Issuer {
+		return nil, fmt.Errorf("token issued by %q", issuer)
+	}
+
+	if !hasAudience(claims["aud"], p.config.Audience) {
+		return nil, fmt.Errorf("token not issued for this service")
+	}
+
+	token := &IDToken{
+		Issuer: issuer,
+	}
+	token.Subject, _ = claims["sub"].(string)
+	token.Email, _ = claims["email"].(string)
+	token.EmailVerified, _ = claims["email_verified"].(bool)
+	token.Groups = stringList(claims[p.config.GroupsClaim])
+
+	if token.Subject == "" {
+		return nil, fmt.Errorf("token has no subject")
+	}
+
+	return token, nil
+}
+
+// key returns the key with the given id, fetching the keys when it is unknown
+func (p *Provider) key(ctx context.Context, kid string) (interface{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+
+	if time.Since(p.lastFetched) < refreshInterval {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+
+	if err := p.fetch(ctx); err != nil {
+		return nil, err
+	}
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+
+	// Issuers with a single key do not always set a kid
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown key %q", kid)
+}
+
+// fetch reads the discovery document when needed and the keys of the issuer
+func (p *Provider) fetch(ctx context.Context) error {
+	p.lastFetched = time.Now()
+
+	if p.jwksURI == "" {
+		discovery := struct {
+			Issuer  string `json:"issuer"`
+			JWKSURI string `json:"jwks_uri"`
+		}{}
+
+		url := strings.TrimSuffix(p.config.Issuer, "/") + "/.well-known/openid-configuration"
+		if err := p.get(ctx, url, &discovery); err != nil {
+			return err
+		}
+
+		if discovery.Issuer != p.config.Issuer {
+			return fmt.Errorf("discovery document is for issuer %q", discovery.Issuer)
+		}
+
+		p.jwksURI = discovery.JWKSURI
+	}
+
+	set := struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}{}
+
+	if err := p.get(ctx, p.jwksURI, &set); err != nil {
+		return err
+	}
+
+	keys := map[string]interface{}{}
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		switch jwk.Kty {
+		case "RSA":
+			n, errN := decode(jwk.N)
+			e, errE := decode(jwk.E)
+			if errN != nil || errE != nil {
+				continue
+			}
+
+			keys[jwk.Kid] = &rsa.PublicKey{
+				N: new(big.Int).SetBytes(n),
+				E: int(new(big.Int).SetBytes(e).Int64()),
+			}
+		case "EC":
+			x, errX := decode(jwk.X)
+			y, errY := decode(jwk.Y)
+			if jwk.Crv != "P-256" || errX != nil || errY != nil {
+				continue
+			}
+
+			keys[jwk.Kid] = &ecdsa.PublicKey{
+				Curve: elliptic.P256(),
+				X:     new(big.Int).SetBytes(x),
+				Y:     new(big.Int).SetBytes(y),
+			}
+		}
+	}
+
+	p.keys = keys
+	return nil
+}
+
+func (p *Provider) get(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := p.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unable to fetch %s: %s", url, res.Status)
+	}
+
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+func hasAudience(aud interface{}, audience string) bool {
+	switch value := aud.(type) {
+	case string:
+		return value == audience
+	case []interface{}:
+		for _, item := range value {
+			if item == audience {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func stringList(value interface{}) []string {
+	list := []string{}
+
+	switch items := value.(type) {
+	case string:
+		list = append(list, items)
+	case []interface{}:
+		for _, item := range items {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+	}
+
+	return list
+}
+
+func decode(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
@@ -0,0 +1,178 @@
+package oidc_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	oidc "github.com/GameComponent/economy-service/pkg/oidc"
+	signing "github.com/GameComponent/economy-service/pkg/signing"
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+// issuer is a stand-in identity provider
+type issuer struct {
+	server  *httptest.Server
+	keyring *signing.Keyring
+}
+
+func newIssuer(t *testing.T) *issuer {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, _ := x509.MarshalECPrivateKey(key)
+	keyring, err := signing.NewKeyring("", pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i := &issuer{keyring: keyring}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":   i.server.URL,
+			"jwks_uri": i.server.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(i.keyring.JWKS())
+	})
+
+	i.server = httptest.NewServer(mux)
+	return i
+}
+
+func (i *issuer) token(t *testing.T, claims jwt.MapClaims) string {
+	defaults := jwt.MapClaims{
+		"iss":            i.server.URL,
+		"aud":            "economy",
+		"sub":            "user-1",
+		"email":          "admin@example.com",
+		"email_verified": true,
+		"groups":         []string{"economy-admins"},
+		"exp":            time.Now().Add(time.Minute).Unix(),
+	}
+
+	for name, value := range claims {
+		if value == nil {
+			delete(defaults, name)
+			continue
+		}
+
+		defaults[name] = value
+	}
+
+	token, err := i.keyring.Sign(defaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token
+}
+
+func (i *issuer) provider() *oidc.Provider {
+	return oidc.NewProvider(oidc.Config{
+		Issuer:   i.server.URL,
+		Audience: "economy",
+		GroupRoles: map[string][]string{
+			"economy-admins":  {"admin"},
+			"economy-support": {"support"},
+		},
+	}, i.server.Client())
+}
+
+func TestVerify(t *testing.T) {
+	i := newIssuer(t)
+	defer i.server.Close()
+
+	token, err := i.provider().Verify(context.Background(), i.token(t, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if token.Subject != "user-1" || token.Email != "admin@example.com" || !token.EmailVerified {
+		t.Errorf("unexpected token %+v", token)
+	}
+
+	if len(token.Groups) != 1 || token.Groups[0] != "economy-admins" {
+		t.Errorf("unexpected groups %v", token.Groups)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	i := newIssuer(t)
+	defer i.server.Close()
+
+	cases := map[string]jwt.MapClaims{
+		"wrong audience": {"aud": "other"},
+		"wrong issuer":   {"iss": "https://evil.example.com"},
+		"expired":        {"exp": time.Now().Add(-time.Minute).Unix()},
+		"no expiry":      {"exp": nil},
+		"no subject":     {"sub": nil},
+	}
+
+	for name, claims := range cases {
+		if _, err := i.provider().Verify(context.Background(), i.token(t, claims)); err == nil {
+			t.Errorf("%s: token should be rejected", name)
+		}
+	}
+
+	// Tokens signed by someone else
+	other := newIssuer(t)
+	defer other.server.Close()
+
+	if _, err := i.provider().Verify(context.Background(), other.token(t, jwt.MapClaims{"iss": i.server.URL})); err == nil {
+		t.Error("token signed with an unknown key should be rejected")
+	}
+}
+
+func TestVerifyAudienceList(t *testing.T) {
+	i := newIssuer(t)
+	defer i.server.Close()
+
+	_, err := i.provider().Verify(context.Background(), i.token(t, jwt.MapClaims{"aud": []string{"other", "economy"}}))
+	if err != nil {
+		t.Errorf("audience list containing the client should be accepted: %v", err)
+	}
+}
+
+func TestRoles(t *testing.T) {
+	i := newIssuer(t)
+	defer i.server.Close()
+
+	provider := i.provider()
+	granted, managed := provider.Roles(&oidc.IDToken{Groups: []string{"economy-support", "unrelated"}})
+
+	if len(granted) != 1 || granted[0] != "support" {
+		t.Errorf("granted = %v, want [support]", granted)
+	}
+
+	if len(managed) != 2 {
+		t.Errorf("managed = %v, want admin and support", managed)
+	}
+}
+
+func TestParseGroupRoles(t *testing.T) {
+	groupRoles, err := oidc.ParseGroupRoles("admins=admin, staff=support;designer")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(groupRoles["admins"]) != 1 || len(groupRoles["staff"]) != 2 {
+		t.Errorf("unexpected mapping %v", groupRoles)
+	}
+
+	if _, err := oidc.ParseGroupRoles("admins"); err == nil {
+		t.Error("mapping without a role should be rejected")
+	}
+}
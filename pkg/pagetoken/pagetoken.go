@@ -0,0 +1,94 @@
+package pagetoken
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	repository "github.com/GameComponent/economy-service/pkg/repository"
+)
+
+// ErrInvalid is returned for page tokens that were changed, signed with another secret,
+// or handed out for another list
+var ErrInvalid = errors.New("invalid page token")
+
+// Signer turns cursors into opaque page tokens and back, the tokens are signed so clients
+// can not make up cursors, and bound to a scope so a token only continues the list it came from
+type Signer struct {
+	secret []byte
+}
+
+// New creates a signer with a secret, every instance of the service needs the same secret
+// to continue the lists of the others
+func New(secret []byte) *Signer {
+	return &Signer{secret: secret}
+}
+
+// NewRandom creates a signer with a random secret, its tokens stop working on restarts
+func NewRandom() (*Signer, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	return New(secret), nil
+}
+
+// Encode turns a cursor into a page token, an empty cursor gives an empty token
+// which tells clients there is no next page
+func (s *Signer) Encode(scope string, cursor repository.Cursor) string {
+	if len(cursor) == 0 {
+		return ""
+	}
+
+	payload, _ := json.Marshal(cursor)
+
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(s.sign(scope, payload))
+}
+
+// Decode checks a page token and returns its cursor, an empty token is the first page
+func (s *Signer) Decode(scope string, token string) (repository.Cursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return nil, ErrInvalid
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalid
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalid
+	}
+
+	if !hmac.Equal(signature, s.sign(scope, payload)) {
+		return nil, ErrInvalid
+	}
+
+	cursor := repository.Cursor{}
+	if err := json.Unmarshal(payload, &cursor); err != nil || len(cursor) == 0 {
+		return nil, ErrInvalid
+	}
+
+	return cursor, nil
+}
+
+func (s *Signer) sign(scope string, payload []byte) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(scope))
+	mac.Write([]byte{0})
+	mac.Write(payload)
+
+	return mac.Sum(nil)
+}
@@ -0,0 +1,55 @@
+package pagetoken
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	repository "github.com/GameComponent/economy-service/pkg/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+var cursor = repository.Cursor{"2019-10-27T12:00:00.123456789Z", "8b1d4b7c-5c1a-4a2e-9f57-3c1b8b3f0d2a"}
+
+func TestEncodeDecode(t *testing.T) {
+	signer := New([]byte("secret"))
+
+	token := signer.Encode("ListItem", cursor)
+	decoded, err := signer.Decode("ListItem", token)
+
+	assert.NoError(t, err)
+	assert.Equal(t, cursor, decoded)
+}
+
+func TestEmpty(t *testing.T) {
+	signer := New([]byte("secret"))
+
+	assert.Equal(t, "", signer.Encode("ListItem", nil))
+
+	decoded, err := signer.Decode("ListItem", "")
+	assert.NoError(t, err)
+	assert.Nil(t, decoded)
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	signer := New([]byte("secret"))
+	token := signer.Encode("ListItem", cursor)
+
+	// Another list
+	_, err := signer.Decode("ListStorage", token)
+	assert.Equal(t, ErrInvalid, err)
+
+	// Another secret
+	_, err = New([]byte("other")).Decode("ListItem", token)
+	assert.Equal(t, ErrInvalid, err)
+
+	// Changed cursor
+	signature := strings.Split(token, ".")[1]
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`["2019-01-01T00:00:00Z","1"]`)) + "." + signature
+	_, err = signer.Decode("ListItem", forged)
+	assert.Equal(t, ErrInvalid, err)
+
+	// Page numbers of the old tokens
+	_, err = signer.Decode("ListItem", "2")
+	assert.Equal(t, ErrInvalid, err)
+}
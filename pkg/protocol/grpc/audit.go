@@ -0,0 +1,132 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"time"
+
+	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
+	audit "github.com/GameComponent/economy-service/pkg/audit"
+	random "github.com/GameComponent/economy-service/pkg/helper/random"
+	role "github.com/GameComponent/economy-service/pkg/role"
+	v1service "github.com/GameComponent/economy-service/pkg/service/v1"
+	tenant "github.com/GameComponent/economy-service/pkg/tenant"
+	tracing "github.com/GameComponent/economy-service/pkg/tracing"
+	ctxzap "github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	trace "go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// RequestIDHeader carries the id of a request, the REST gateway passes its own on
+const RequestIDHeader = "x-request-id"
+
+// readOnlyMethods are left out of the audit log
+var readOnlyMethods = []string{
+	"Get*",
+	"List*",
+	"Search*",
+	"getAccount",
+}
+
+type auditKey struct{}
+
+// auditScope is filled in by the authorization with who makes the request
+type auditScope struct {
+	claims   *v1service.Claims
+	tenantID string
+}
+
+// auditor records every request of an account or API key that changes something
+type auditor struct {
+	logger *zap.Logger
+}
+
+func newAuditor(logger *zap.Logger) *auditor {
+	return &auditor{
+		logger: logger,
+	}
+}
+
+// intercept runs before the authorization, so requests denied after the token and tenant are resolved,
+// like those missing a permission, are recorded as well. Requests without a valid token or tenant
+// are not, there is no account to record them for
+func (a *auditor) intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	server, ok := info.Server.(*v1service.EconomyServiceServer)
+	if !ok {
+		return nil, fmt.Errorf("unable to cast server")
+	}
+
+	requestID := requestIDOf(ctx)
+	_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDHeader, requestID))
+	tracing.SetRequestID(ctx, requestID)
+	ctxzap.AddFields(ctx, zap.String("request.id", requestID))
+
+	scope := &auditScope{}
+	resp, err := handler(context.WithValue(ctx, auditKey{}, scope), req)
+
+	// Players and unauthenticated requests are not recorded
+	if scope.claims == nil || scope.claims.StandardClaims.Audience == "player" || role.Allowed(readOnlyMethods, info.FullMethod) {
+		return resp, err
+	}
+
+	_, ip := v1service.ClientInfo(ctx)
+	st := status.Convert(err)
+
+	event := &v1.AuditEvent{
+		AccountId: scope.claims.Subject,
+		Method:    path.Base(info.FullMethod),
+		Request:   audit.Sanitize(req),
+		Outcome:   st.Code().String(),
+		Message:   st.Message(),
+		RequestId: requestID,
+		Ip:        ip,
+	}
+
+	if scope.claims.StandardClaims.Audience == "api" {
+		event.ApiKeyId = scope.claims.StandardClaims.Id
+	}
+
+	// The request is done by now, so the event is recorded even when the client went away,
+	// as part of the same trace
+	recordCtx, cancel := context.WithTimeout(trace.ContextWithSpan(context.Background(), trace.SpanFromContext(ctx)), 5*time.Second)
+	defer cancel()
+
+	recordErr := server.AuditRepository.Record(tenant.NewContext(recordCtx, scope.tenantID), event)
+	if recordErr != nil {
+		a.logger.Error(
+			"Unable to record audit event",
+			zap.String("method", event.Method),
+			zap.String("account", event.AccountId),
+			zap.String("request-id", requestID),
+			zap.Error(recordErr),
+		)
+	}
+
+	return resp, err
+}
+
+// setAuditScope tells the audit log who makes the request and in which tenant
+func setAuditScope(ctx context.Context, claims *v1service.Claims) {
+	scope, ok := ctx.Value(auditKey{}).(*auditScope)
+	if !ok {
+		return
+	}
+
+	scope.claims = claims
+	scope.tenantID = tenant.FromContext(ctx)
+}
+
+// requestIDOf returns the id the request came in with, or a new one
+func requestIDOf(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(RequestIDHeader); len(values) > 0 && values[0] != "" {
+		return values[0]
+	}
+
+	requestID, _ := random.GenerateRandomString(12)
+	return requestID
+}
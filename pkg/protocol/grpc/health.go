@@ -0,0 +1,64 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	health "github.com/GameComponent/economy-service/pkg/health"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	grpc_health "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// healthService is called by load balancers, which have no token
+const healthService = "/grpc.health.v1.Health/"
+
+// healthInterval is how often the serving status is checked
+const healthInterval = 5 * time.Second
+
+// watchHealth keeps the serving status of the health service up to date with the readiness check
+func watchHealth(ctx context.Context, logger *zap.Logger, checker *health.Checker, healthServer *grpc_health.Server) {
+	ticker := time.NewTicker(healthInterval)
+	defer ticker.Stop()
+
+	serving := healthpb.HealthCheckResponse_UNKNOWN
+
+	for {
+		status := healthpb.HealthCheckResponse_SERVING
+
+		checkCtx, cancel := context.WithTimeout(ctx, healthInterval)
+		err := checker.Ready(checkCtx)
+		cancel()
+
+		if err != nil {
+			status = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+
+		if status != serving {
+			logger.Info("serving status changed", zap.String("status", status.String()), zap.NamedError("reason", err))
+
+			healthServer.SetServingStatus("", status)
+			healthServer.SetServingStatus("v1.EconomyService", status)
+			serving = status
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// unlessHealth skips an interceptor for the health service
+func unlessHealth(interceptor grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if strings.HasPrefix(info.FullMethod, healthService) {
+			return handler(ctx, req)
+		}
+
+		return interceptor(ctx, req, info, handler)
+	}
+}
@@ -1,9 +1,7 @@
 package middleware
 
 import (
-	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	grpc_zap "github.com/grpc-ecosystem/go-grpc-middleware/logging/zap"
-	grpc_ctxtags "github.com/grpc-ecosystem/go-grpc-middleware/tags"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"google.golang.org/grpc"
@@ -20,26 +18,11 @@ func codeToLevel(code codes.Code) zapcore.Level {
 	return grpc_zap.DefaultCodeToLevel(code)
 }
 
-// AddLogging returns grpc.Server config option that turn on logging.
-func AddLogging(logger *zap.Logger, opts []grpc.ServerOption) []grpc.ServerOption {
-	// Shared options for the logger, with a custom gRPC code to log level function.
-	o := []grpc_zap.Option{
-		grpc_zap.WithLevels(codeToLevel),
-	}
+// AddLogging returns a unary interceptor logging every call with its duration and status code,
+// handlers get a logger for the call from the context with ctxzap.Extract
+func AddLogging(logger *zap.Logger) grpc.UnaryServerInterceptor {
 	// Make sure that log statements internal to gRPC library are logged using the zapLogger as well.
 	grpc_zap.ReplaceGrpcLogger(logger)
 
-	// Add unary interceptor
-	opts = append(opts, grpc_middleware.WithUnaryServerChain(
-		grpc_ctxtags.UnaryServerInterceptor(grpc_ctxtags.WithFieldExtractor(grpc_ctxtags.CodeGenRequestFieldExtractor)),
-		grpc_zap.UnaryServerInterceptor(logger, o...),
-	))
-
-	// Add stream interceptor (added as an example here)
-	opts = append(opts, grpc_middleware.WithStreamServerChain(
-		grpc_ctxtags.StreamServerInterceptor(grpc_ctxtags.WithFieldExtractor(grpc_ctxtags.CodeGenRequestFieldExtractor)),
-		grpc_zap.StreamServerInterceptor(logger, o...),
-	))
-
-	return opts
+	return grpc_zap.UnaryServerInterceptor(logger, grpc_zap.WithLevels(codeToLevel))
 }
@@ -0,0 +1,160 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	ratelimit "github.com/GameComponent/economy-service/pkg/ratelimit"
+	role "github.com/GameComponent/economy-service/pkg/role"
+	v1service "github.com/GameComponent/economy-service/pkg/service/v1"
+	tenant "github.com/GameComponent/economy-service/pkg/tenant"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// quotasTTL is how long the quotas of a tenant are used before they are read again,
+// and with that how long a change through SetConfig takes to apply
+const quotasTTL = 10 * time.Second
+
+type cachedQuotas struct {
+	quotas  *ratelimit.Quotas
+	expires time.Time
+}
+
+// rateLimiter limits the requests of every account, API key and player with token buckets,
+// the quotas come from the rate_limits config of the tenant
+type rateLimiter struct {
+	logger  *zap.Logger
+	buckets *ratelimit.Buckets
+	now     func() time.Time
+
+	mu     sync.Mutex
+	quotas map[string]cachedQuotas
+}
+
+func newRateLimiter(logger *zap.Logger) *rateLimiter {
+	return &rateLimiter{
+		logger:  logger,
+		buckets: ratelimit.NewBuckets(),
+		now:     time.Now,
+		quotas:  map[string]cachedQuotas{},
+	}
+}
+
+// intercept runs after the authorization, so the context holds the tenant and claims
+func (l *rateLimiter) intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	server, ok := info.Server.(*v1service.EconomyServiceServer)
+	if !ok {
+		return nil, fmt.Errorf("unable to cast server")
+	}
+
+	// The public methods have their own limits
+	claims := v1service.ClaimsFromContext(ctx)
+	if claims == nil {
+		return handler(ctx, req)
+	}
+
+	quotas := l.load(ctx, server)
+	tenantID := tenant.FromContext(ctx)
+	principal := principalOf(claims)
+
+	// Quotas of single principals are set by the id of the account, API key or player
+	principalID := claims.Subject
+	if claims.StandardClaims.Audience == "api" {
+		principalID = claims.StandardClaims.Id
+	}
+
+	// Take a token from every bucket that applies, the most restrictive one is reported
+	results := []ratelimit.Result{
+		l.buckets.Take(tenantID+":"+principal, quotas.Rate(principalID)),
+	}
+
+	for _, method := range quotas.Methods {
+		if role.Allowed([]string{method.Pattern}, info.FullMethod) {
+			results = append(results, l.buckets.Take(tenantID+":"+principal+":"+method.Pattern, method.Rate))
+		}
+	}
+
+	reported := ratelimit.Result{Allowed: true}
+	for _, result := range results {
+		switch {
+		case result.Limit == 0:
+		case !result.Allowed:
+			if reported.Allowed || result.RetryAfter > reported.RetryAfter {
+				reported = result
+			}
+		case reported.Allowed && (reported.Limit == 0 || result.Remaining < reported.Remaining):
+			reported = result
+		}
+	}
+
+	if reported.Limit > 0 {
+		_ = grpc.SetHeader(ctx, metadata.Pairs(
+			"x-ratelimit-limit", strconv.FormatInt(reported.Limit, 10),
+			"x-ratelimit-remaining", strconv.FormatInt(reported.Remaining, 10),
+			"x-ratelimit-reset", ceilSeconds(reported.Reset),
+		))
+	}
+
+	if !reported.Allowed {
+		_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", ceilSeconds(reported.RetryAfter)))
+		return nil, status.Error(codes.ResourceExhausted, "Rate limit exceeded")
+	}
+
+	return handler(ctx, req)
+}
+
+// load returns the quotas of the tenant, invalid or missing quotas have no limits
+func (l *rateLimiter) load(ctx context.Context, server *v1service.EconomyServiceServer) *ratelimit.Quotas {
+	tenantID := tenant.FromContext(ctx)
+	now := l.now()
+
+	l.mu.Lock()
+	cached, ok := l.quotas[tenantID]
+	l.mu.Unlock()
+
+	if ok && now.Before(cached.expires) {
+		return cached.quotas
+	}
+
+	value := ""
+	config, err := server.ConfigRepository.Get(ctx, ratelimit.QuotasKey)
+	if err == nil && config != nil {
+		value = config.GetValue()
+	}
+
+	quotas, err := ratelimit.ParseQuotas(value)
+	if err != nil {
+		l.logger.Error("Invalid rate limits", zap.String("tenant", tenantID), zap.Error(err))
+		quotas, _ = ratelimit.ParseQuotas("")
+	}
+
+	l.mu.Lock()
+	l.quotas[tenantID] = cachedQuotas{quotas: quotas, expires: now.Add(quotasTTL)}
+	l.mu.Unlock()
+
+	return quotas
+}
+
+// principalOf returns who makes the request, every API key has its own limits
+func principalOf(claims *v1service.Claims) string {
+	switch claims.StandardClaims.Audience {
+	case "api":
+		return "apikey:" + claims.StandardClaims.Id
+	case "player":
+		return "player:" + claims.Subject
+	}
+
+	return "account:" + claims.Subject
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
@@ -0,0 +1,217 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
+	ratelimit "github.com/GameComponent/economy-service/pkg/ratelimit"
+	repository "github.com/GameComponent/economy-service/pkg/repository"
+	v1service "github.com/GameComponent/economy-service/pkg/service/v1"
+	tenant "github.com/GameComponent/economy-service/pkg/tenant"
+	jwt "github.com/dgrijalva/jwt-go"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// configs holds the rate limits of every tenant and counts how often they are read
+type configs struct {
+	repository.ConfigRepository
+	value string
+	reads int
+}
+
+func (r *configs) Get(ctx context.Context, key string) (*v1.Config, error) {
+	r.reads++
+	if key != ratelimit.QuotasKey || r.value == "" {
+		return nil, repository.NotFound("config", key)
+	}
+
+	return &v1.Config{Key: key, Value: r.value}, nil
+}
+
+// headerStream records the headers a handler sets
+type headerStream struct {
+	grpc.ServerTransportStream
+	header metadata.MD
+}
+
+func (s *headerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func newTestLimiter(value string) (*rateLimiter, *configs, *v1service.EconomyServiceServer) {
+	configRepository := &configs{value: value}
+	server := &v1service.EconomyServiceServer{ConfigRepository: configRepository}
+
+	return newRateLimiter(zap.NewNop()), configRepository, server
+}
+
+// limit makes a request with the claims through the rate limiter
+func limit(l *rateLimiter, server *v1service.EconomyServiceServer, claims *v1service.Claims, method string) (metadata.MD, error) {
+	stream := &headerStream{}
+	ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+	ctx = tenant.NewContext(ctx, tenant.Default)
+	ctx = v1service.NewClaimsContext(ctx, claims)
+
+	info := &grpc.UnaryServerInfo{Server: server, FullMethod: "/v1.EconomyService/" + method}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	}
+
+	_, err := l.intercept(ctx, &v1.GetPlayerRequest{}, info, handler)
+	return stream.header, err
+}
+
+func accountClaims(accountID string) *v1service.Claims {
+	return &v1service.Claims{Subject: accountID, StandardClaims: jwt.StandardClaims{Audience: "account"}}
+}
+
+func apiKeyClaims(accountID string, apiKeyID string) *v1service.Claims {
+	return &v1service.Claims{Subject: accountID, StandardClaims: jwt.StandardClaims{Audience: "api", Id: apiKeyID}}
+}
+
+func TestRateLimiterHeaders(t *testing.T) {
+	l, _, server := newTestLimiter(`{"default": "3/1h"}`)
+
+	for _, remaining := range []string{"2", "1", "0"} {
+		header, err := limit(l, server, accountClaims("account-1"), "GetPlayer")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := header.Get("x-ratelimit-limit"); len(got) != 1 || got[0] != "3" {
+			t.Errorf("x-ratelimit-limit = %v, want 3", got)
+		}
+
+		if got := header.Get("x-ratelimit-remaining"); len(got) != 1 || got[0] != remaining {
+			t.Errorf("x-ratelimit-remaining = %v, want %s", got, remaining)
+		}
+
+		if got := header.Get("x-ratelimit-reset"); len(got) != 1 || got[0] == "0" {
+			t.Errorf("x-ratelimit-reset = %v, want the seconds until the bucket is full", got)
+		}
+	}
+
+	header, err := limit(l, server, accountClaims("account-1"), "GetPlayer")
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("code = %v, want %v", status.Code(err), codes.ResourceExhausted)
+	}
+
+	if got := header.Get("retry-after"); len(got) != 1 || got[0] == "0" {
+		t.Errorf("retry-after = %v, want the seconds until a request is allowed", got)
+	}
+
+	if got := header.Get("x-ratelimit-remaining"); len(got) != 1 || got[0] != "0" {
+		t.Errorf("x-ratelimit-remaining = %v, want 0", got)
+	}
+}
+
+func TestRateLimiterPrincipals(t *testing.T) {
+	l, _, server := newTestLimiter(`{"default": "1/1h", "principals": {"key-2": "2/1h"}}`)
+
+	// Every account has its own bucket
+	if _, err := limit(l, server, accountClaims("account-1"), "GetPlayer"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := limit(l, server, accountClaims("account-2"), "GetPlayer"); err != nil {
+		t.Errorf("account-2 should not share the bucket of account-1: %v", err)
+	}
+	if _, err := limit(l, server, accountClaims("account-1"), "GetPlayer"); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("code = %v, want %v", status.Code(err), codes.ResourceExhausted)
+	}
+
+	// API keys have their own bucket, separate from the account owning them
+	if _, err := limit(l, server, apiKeyClaims("account-1", "key-1"), "GetPlayer"); err != nil {
+		t.Errorf("key-1 should not share the bucket of its account: %v", err)
+	}
+	if _, err := limit(l, server, apiKeyClaims("account-1", "key-1"), "GetPlayer"); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("code = %v, want %v", status.Code(err), codes.ResourceExhausted)
+	}
+
+	// Quotas of single API keys are set by the id of the key
+	for i := 0; i < 2; i++ {
+		if _, err := limit(l, server, apiKeyClaims("account-1", "key-2"), "GetPlayer"); err != nil {
+			t.Errorf("key-2 request %d: %v", i, err)
+		}
+	}
+}
+
+func TestRateLimiterMethods(t *testing.T) {
+	l, _, server := newTestLimiter(`{"default": "10/1h", "methods": {"Give*": "1/1h"}}`)
+
+	if _, err := limit(l, server, accountClaims("account-1"), "GiveItem"); err != nil {
+		t.Fatal(err)
+	}
+
+	header, err := limit(l, server, accountClaims("account-1"), "GiveCurrency")
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("code = %v, want %v", status.Code(err), codes.ResourceExhausted)
+	}
+
+	// The most restrictive bucket is reported
+	if got := header.Get("x-ratelimit-limit"); len(got) != 1 || got[0] != "1" {
+		t.Errorf("x-ratelimit-limit = %v, want 1", got)
+	}
+
+	if _, err := limit(l, server, accountClaims("account-1"), "GetPlayer"); err != nil {
+		t.Errorf("other methods should still be allowed: %v", err)
+	}
+}
+
+func TestRateLimiterReloadsQuotas(t *testing.T) {
+	l, configs, server := newTestLimiter(`{"default": "1/1h"}`)
+
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	if _, err := limit(l, server, accountClaims("account-1"), "GetPlayer"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A change through SetConfig is not seen until the cached quotas expire
+	configs.value = `{"default": "5/1h"}`
+	now = now.Add(quotasTTL - time.Second)
+
+	if _, err := limit(l, server, accountClaims("account-2"), "GetPlayer"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := limit(l, server, accountClaims("account-2"), "GetPlayer"); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("code = %v, want %v with the cached quotas", status.Code(err), codes.ResourceExhausted)
+	}
+	if configs.reads != 1 {
+		t.Errorf("reads = %d, want 1", configs.reads)
+	}
+
+	now = now.Add(time.Second)
+
+	header, err := limit(l, server, accountClaims("account-3"), "GetPlayer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := header.Get("x-ratelimit-limit"); len(got) != 1 || got[0] != "5" {
+		t.Errorf("x-ratelimit-limit = %v, want 5 after the reload", got)
+	}
+	if configs.reads != 2 {
+		t.Errorf("reads = %d, want 2", configs.reads)
+	}
+}
+
+func TestRateLimiterWithoutClaims(t *testing.T) {
+	l, configs, server := newTestLimiter(`{"default": "1/1h"}`)
+
+	for i := 0; i < 3; i++ {
+		if _, err := limit(l, server, nil, "Authenticate"); err != nil {
+			t.Fatalf("public methods have their own limits: %v", err)
+		}
+	}
+
+	if configs.reads != 0 {
+		t.Errorf("reads = %d, want 0", configs.reads)
+	}
+}
@@ -3,27 +3,36 @@ package grpc
 import (
 	"context"
 	"fmt"
-	"log"
 	"net"
 	"os"
 	"os/signal"
-	"path/filepath"
 	"strings"
+	"syscall"
 
 	"google.golang.org/grpc"
 
 	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
+	apikey "github.com/GameComponent/economy-service/pkg/apikey"
+	health "github.com/GameComponent/economy-service/pkg/health"
+	middleware "github.com/GameComponent/economy-service/pkg/protocol/grpc/middleware"
+	role "github.com/GameComponent/economy-service/pkg/role"
 	v1service "github.com/GameComponent/economy-service/pkg/service/v1"
+	tenant "github.com/GameComponent/economy-service/pkg/tenant"
 	jwt "github.com/dgrijalva/jwt-go"
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	ctxzap "github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
+	otelgrpc "go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
+	grpc_health "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
 
 // RunServer runs gRPC service to publish Economy service
-func RunServer(ctx context.Context, v1API v1.EconomyServiceServer, logger *zap.Logger, port string) error {
+func RunServer(ctx context.Context, v1API v1.EconomyServiceServer, checker *health.Checker, logger *zap.Logger, port string) error {
 	listen, err := net.Listen("tcp", ":"+port)
 	if err != nil {
 		return err
@@ -31,18 +40,39 @@ func RunServer(ctx context.Context, v1API v1.EconomyServiceServer, logger *zap.L
 
 	// Register service
 	server := grpc.NewServer(
-		grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(unaryInterceptor)),
+		grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(
+			otelgrpc.UnaryServerInterceptor(),
+			grpc_prometheus.UnaryServerInterceptor,
+			middleware.AddLogging(logger),
+			unlessHealth(newAuditor(logger).intercept),
+			unlessHealth(unaryInterceptor),
+			unlessHealth(newRateLimiter(logger).intercept),
+			validate,
+		)),
 	)
 
 	v1.RegisterEconomyServiceServer(server, v1API)
 
+	// Report the readiness through the standard health checking protocol
+	healthServer := grpc_health.NewServer()
+	healthpb.RegisterHealthServer(server, healthServer)
+	go watchHealth(ctx, logger, checker, healthServer)
+
+	// Expose the latency and status codes of every method
+	grpc_prometheus.EnableHandlingTimeHistogram()
+	grpc_prometheus.Register(server)
+
 	// Graceful shutdown
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	go func() {
 		for range c {
 			// sig is a ^C, handle it
-			log.Println("shutting down gRPC server...")
+			logger.Info("shutting down gRPC server...")
+
+			// Stop being ready first, so load balancers stop sending requests before the server stops
+			healthServer.Shutdown()
+			checker.Drain()
 
 			server.GracefulStop()
 
@@ -55,53 +85,182 @@ func RunServer(ctx context.Context, v1API v1.EconomyServiceServer, logger *zap.L
 	return server.Serve(listen)
 }
 
-func unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-	// Methods that should always work
-	if info.FullMethod == "/v1.EconomyService/Authenticate" {
-		return handler(ctx, req)
-	}
-
-	if info.FullMethod == "/v1.EconomyService/Register" {
-		return handler(ctx, req)
-	}
-
-	if info.FullMethod == "/v1.EconomyService/Refresh" {
-		return handler(ctx, req)
-	}
+// tenantMethods manage the tenants, only accounts of the default tenant can execute them
+var tenantMethods = map[string]bool{
+	"/v1.EconomyService/CreateTenant": true,
+	"/v1.EconomyService/UpdateTenant": true,
+	"/v1.EconomyService/GetTenant":    true,
+	"/v1.EconomyService/ListTenant":   true,
+}
 
+func unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 	// Get the server information
 	server, ok := info.Server.(*v1service.EconomyServiceServer)
 	if !ok {
 		return nil, fmt.Errorf("unable to cast server")
 	}
 
-	// Retrieve the secret from the server's config, cast to byte array
-	secret := []byte(server.Config.JWTSecret)
+	// Methods that should always work, the tenant comes from the header
+	if info.FullMethod == "/v1.EconomyService/Authenticate" ||
+		info.FullMethod == "/v1.EconomyService/AuthenticateOidc" ||
+		info.FullMethod == "/v1.EconomyService/AuthenticateTotp" ||
+		info.FullMethod == "/v1.EconomyService/Register" ||
+		info.FullMethod == "/v1.EconomyService/Refresh" ||
+		info.FullMethod == "/v1.EconomyService/RequestPasswordReset" ||
+		info.FullMethod == "/v1.EconomyService/ResetPassword" ||
+		info.FullMethod == "/v1.EconomyService/VerifyEmail" {
+		tenantCtx, err := resolveTenant(ctx, server, "", true)
+		if err != nil {
+			return nil, err
+		}
+
+		ctxzap.AddFields(tenantCtx, zap.String("tenant", tenant.FromContext(tenantCtx)))
+
+		// These methods can be called by anyone, throttle them against brute-forcing
+		err = server.LimitAuth(tenantCtx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(tenantCtx, req)
+	}
 
 	// Check authorization
-	_, claims, err := authorize(ctx, secret)
+	_, claims, err := authorize(ctx, server)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Tenants are managed by accounts of the default tenant
+	if tenantMethods[info.FullMethod] && claims.Tenant != "" && claims.Tenant != tenant.Default {
+		return nil, status.Error(codes.PermissionDenied, "Not allowed to execute this method")
+	}
 
+	// Scope everything to the tenant of the token, only holders of the switch permission leave it
+	canSwitch, err := canSwitchTenant(ctx, server, claims)
 	if err != nil {
 		return nil, err
 	}
 
-	// Methods that should always work with a valid token
-	if info.FullMethod == "/v1.EconomyService/ChangePassword" {
+	ctx, err = resolveTenant(ctx, server, claims.Tenant, canSwitch)
+	if err != nil {
+		return nil, err
+	}
+
+	// Handlers, the audit log and the log lines of the request can see who makes the request
+	ctx = v1service.NewClaimsContext(ctx, claims)
+	setAuditScope(ctx, claims)
+	addLogFields(ctx, claims)
+
+	// Methods that should always work with a valid account token, they check the credentials
+	// or that the account manages its own sessions themselves
+	if (info.FullMethod == "/v1.EconomyService/ChangePassword" ||
+		info.FullMethod == "/v1.EconomyService/EnrollTotp" ||
+		info.FullMethod == "/v1.EconomyService/ConfirmTotp" ||
+		info.FullMethod == "/v1.EconomyService/DisableTotp" ||
+		info.FullMethod == "/v1.EconomyService/ListSessions" ||
+		info.FullMethod == "/v1.EconomyService/RevokeSession" ||
+		info.FullMethod == "/v1.EconomyService/Logout") && claims.StandardClaims.Audience != "player" {
 		return handler(ctx, req)
 	}
 
-	// Methods that only work if the account has the right permissions
-	for _, permission := range claims.Permissions {
-		matched, _ := filepath.Match("/v1.EconomyService/"+permission, info.FullMethod)
-		if matched {
-			return handler(ctx, req)
+	// Player sessions are handed out by game servers
+	if info.FullMethod == "/v1.EconomyService/CreatePlayerSession" && claims.StandardClaims.Audience != "api" {
+		return nil, status.Error(codes.PermissionDenied, "Player sessions can only be created with an API token")
+	}
+
+	// Methods that only work if the account has the right permissions, directly or through a role,
+	// player tokens are limited to a fixed set of methods
+	permissions := role.PlayerMethods
+	if claims.StandardClaims.Audience != "player" {
+		permissions, err = server.Permissions(ctx, claims)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "Unable to resolve permissions")
 		}
 	}
 
-	return nil, status.Error(codes.PermissionDenied, "Not allowed to execute this method")
+	if !role.Allowed(permissions, info.FullMethod) {
+		return nil, status.Error(codes.PermissionDenied, "Not allowed to execute this method")
+	}
+
+	// Accounts holding the RequireTwoFactor permission, or sensitive permissions once that is enforced,
+	// have to log in with two-factor authentication, until then they can only enroll
+	if claims.StandardClaims.Audience == "account" && !claims.TwoFactor() && role.RequiresTwoFactor(permissions, server.Config.TotpRequireSensitive) {
+		return nil, status.Error(codes.PermissionDenied, "Two-factor authentication is required for this account, enroll with EnrollTotp")
+	}
+
+	// Player tokens only reach the resources of their own player
+	if claims.StandardClaims.Audience == "player" {
+		err = server.AuthorizePlayer(ctx, claims.Subject, req)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return handler(ctx, req)
 }
 
-func authorize(ctx context.Context, secret []byte) (*jwt.Token, *v1service.Claims, error) {
+// resolveTenant scopes the context to the tenant of the token,
+// only the default tenant can select another tenant with the tenant header
+func resolveTenant(ctx context.Context, server *v1service.EconomyServiceServer, tokenTenant string, canSwitch bool) (context.Context, error) {
+	tenantID := tokenTenant
+	if tenantID == "" {
+		tenantID = tenant.Default
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	if requested := md.Get(tenant.Header); len(requested) > 0 && requested[0] != "" && requested[0] != tenantID {
+		if tenantID != tenant.Default || !canSwitch {
+			return nil, status.Error(codes.PermissionDenied, "Not allowed to access this tenant")
+		}
+
+		tenantID = requested[0]
+	}
+
+	// The default tenant always exists, others have to be created first
+	if tenantID != tenant.Default {
+		if _, err := server.TenantRepository.Get(ctx, tenantID); err != nil {
+			return nil, status.Error(codes.NotFound, "Tenant not found")
+		}
+	}
+
+	return tenant.NewContext(ctx, tenantID), nil
+}
+
+// canSwitchTenant checks if the token may select another tenant with the header, the permissions
+// are resolved in the tenant of the token and only when the header is set, player tokens never switch
+func canSwitchTenant(ctx context.Context, server *v1service.EconomyServiceServer, claims *v1service.Claims) (bool, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if claims.StandardClaims.Audience == "player" || len(md.Get(tenant.Header)) == 0 {
+		return false, nil
+	}
+
+	permissions, err := server.Permissions(tenant.NewContext(ctx, claims.Tenant), claims)
+	if err != nil {
+		return false, status.Error(codes.Internal, "Unable to resolve permissions")
+	}
+
+	return role.Allowed(permissions, "/v1.EconomyService/"+tenant.SwitchPermission), nil
+}
+
+// addLogFields adds who makes the request, and in which tenant, to its log lines
+func addLogFields(ctx context.Context, claims *v1service.Claims) {
+	fields := []zap.Field{zap.String("tenant", tenant.FromContext(ctx))}
+
+	switch claims.StandardClaims.Audience {
+	case "player":
+		fields = append(fields, zap.String("player.id", claims.Subject))
+	case "api":
+		fields = append(fields, zap.String("account.id", claims.Subject), zap.String("apikey.id", claims.StandardClaims.Id))
+	default:
+		fields = append(fields, zap.String("account.id", claims.Subject))
+	}
+
+	ctxzap.AddFields(ctx, fields...)
+}
+
+func authorize(ctx context.Context, server *v1service.EconomyServiceServer) (*jwt.Token, *v1service.Claims, error) {
 	// Check if metadata is present
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
@@ -125,6 +284,16 @@ func authorize(ctx context.Context, secret []byte) (*jwt.Token, *v1service.Claim
 	tokenType := strings.ToLower(splits[0])
 	tokenString := splits[1]
 
+	// API keys are looked up instead of parsed
+	if tokenType == "apikey" || (tokenType == "bearer" && apikey.IsKey(tokenString)) {
+		claims, err := server.AuthenticateAPIKey(ctx, tokenString)
+		if err != nil {
+			return nil, nil, status.Errorf(codes.Unauthenticated, "Invalid API key")
+		}
+
+		return nil, claims, nil
+	}
+
 	// Check if we received a Bearer token
 	if tokenType != "bearer" {
 		return nil, nil, status.Errorf(codes.Unauthenticated, "Unable to parse this kind of token")
@@ -132,14 +301,8 @@ func authorize(ctx context.Context, secret []byte) (*jwt.Token, *v1service.Claim
 
 	var claims = &v1service.Claims{}
 
-	// Parse the token and check the encryption method
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
-		}
-
-		return secret, nil
-	})
+	// Parse the token, the keyring checks the signing method and picks the key by kid
+	token, err := jwt.ParseWithClaims(tokenString, claims, server.Keyring.Keyfunc)
 
 	if err != nil {
 		return nil, nil, status.Errorf(codes.Unauthenticated, "Unable to parse this token")
@@ -149,13 +312,13 @@ func authorize(ctx context.Context, secret []byte) (*jwt.Token, *v1service.Claim
 		return nil, nil, status.Errorf(codes.Unauthenticated, "Invalid token")
 	}
 
-	// Long lived JWT API tokens should expire and have the audience set to account
+	// Account tokens should expire and have the audience set to account
 	if claims.StandardClaims.Audience == "account" && claims.ExpiresAt != 0 {
 		return token, claims, nil
 	}
 
-	// Long lived JWT API tokens should not expire and have the audience set to api
-	if claims.StandardClaims.Audience == "api" && claims.ExpiresAt == 0 {
+	// Player tokens should expire and have the player as subject
+	if claims.StandardClaims.Audience == "player" && claims.ExpiresAt != 0 && claims.Subject != "" {
 		return token, claims, nil
 	}
 
@@ -0,0 +1,262 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
+	apikey "github.com/GameComponent/economy-service/pkg/apikey"
+	config "github.com/GameComponent/economy-service/pkg/config"
+	repository "github.com/GameComponent/economy-service/pkg/repository"
+	role "github.com/GameComponent/economy-service/pkg/role"
+	v1service "github.com/GameComponent/economy-service/pkg/service/v1"
+	signing "github.com/GameComponent/economy-service/pkg/signing"
+	tenant "github.com/GameComponent/economy-service/pkg/tenant"
+	jwt "github.com/dgrijalva/jwt-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// tenants only knows the tenants it holds
+type tenants struct {
+	repository.TenantRepository
+	ids map[string]bool
+}
+
+func (r *tenants) Get(ctx context.Context, tenantID string) (*v1.Tenant, error) {
+	if !r.ids[tenantID] {
+		return nil, repository.NotFound("tenant", tenantID)
+	}
+
+	return &v1.Tenant{Id: tenantID}, nil
+}
+
+// apiKeys only knows the key of the game server
+type apiKeys struct {
+	repository.APIKeyRepository
+	hash string
+	key  *v1.ApiKey
+}
+
+func (r *apiKeys) GetByHash(ctx context.Context, hash string) (*v1.ApiKey, error) {
+	if hash != r.hash {
+		return nil, repository.NotFound("api key", "")
+	}
+
+	return r.key, nil
+}
+
+func newTestServer(t *testing.T, requireSensitive bool) (*v1service.EconomyServiceServer, string) {
+	keyring, err := signing.NewKeyring("test-secret", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, _, err := apikey.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &v1service.EconomyServiceServer{
+		Config:           &config.Config{TotpRequireSensitive: requireSensitive},
+		Keyring:          keyring,
+		TenantRepository: &tenants{ids: map[string]bool{"acme": true, "globex": true}},
+		APIKeyRepository: &apiKeys{
+			hash: apikey.Hash(key),
+			key: &v1.ApiKey{
+				Id:        "key-1",
+				AccountId: "account-1",
+				Roles:     []string{role.GameServer},
+			},
+		},
+	}
+
+	return server, key
+}
+
+func signToken(t *testing.T, server *v1service.EconomyServiceServer, claims *v1service.Claims) string {
+	claims.StandardClaims.ExpiresAt = time.Now().Add(time.Hour).Unix()
+
+	token, err := server.Keyring.Sign(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	admin := &v1service.Claims{
+		Subject: "account-1",
+		Roles:   []string{role.Admin},
+		Amr:     []string{"pwd", "otp"},
+		StandardClaims: jwt.StandardClaims{
+			Audience: "account",
+		},
+	}
+	acmeAdmin := &v1service.Claims{
+		Subject: "account-2",
+		Roles:   []string{role.Admin},
+		Tenant:  "acme",
+		Amr:     []string{"pwd", "otp"},
+		StandardClaims: jwt.StandardClaims{
+			Audience: "account",
+		},
+	}
+	passwordAdmin := &v1service.Claims{
+		Subject: "account-3",
+		Roles:   []string{role.Admin},
+		Amr:     []string{"pwd"},
+		StandardClaims: jwt.StandardClaims{
+			Audience: "account",
+		},
+	}
+	requireTwoFactor := &v1service.Claims{
+		Subject:     "account-4",
+		Permissions: []string{"GetPlayer", role.RequireTwoFactor},
+		Amr:         []string{"pwd"},
+		StandardClaims: jwt.StandardClaims{
+			Audience: "account",
+		},
+	}
+	support := &v1service.Claims{
+		Subject: "account-5",
+		Roles:   []string{role.Support},
+		StandardClaims: jwt.StandardClaims{
+			Audience: "account",
+		},
+	}
+	switchTenant := &v1service.Claims{
+		Subject:     "account-6",
+		Permissions: []string{"GetPlayer", tenant.SwitchPermission},
+		StandardClaims: jwt.StandardClaims{
+			Audience: "account",
+		},
+	}
+	player := &v1service.Claims{
+		Subject: "player-1",
+		StandardClaims: jwt.StandardClaims{
+			Audience: "player",
+		},
+	}
+
+	cases := []struct {
+		name             string
+		method           string
+		claims           *v1service.Claims
+		apiKey           bool
+		tenant           string
+		req              interface{}
+		requireSensitive bool
+		code             codes.Code
+		wantTenant       string
+	}{
+		{name: "default tenant", method: "GetPlayer", claims: admin, code: codes.OK, wantTenant: tenant.Default},
+		{name: "switch tenant with the header", method: "GetPlayer", claims: admin, tenant: "acme", code: codes.OK, wantTenant: "acme"},
+		{name: "switch to an unknown tenant", method: "GetPlayer", claims: admin, tenant: "initech", code: codes.NotFound},
+		{name: "switch tenant without the permission", method: "GetPlayer", claims: support, tenant: "acme", code: codes.PermissionDenied},
+		{name: "switch tenant with the permission", method: "GetPlayer", claims: switchTenant, tenant: "acme", code: codes.OK, wantTenant: "acme"},
+		{name: "switch tenant with an api key", method: "GetPlayer", apiKey: true, tenant: "acme", code: codes.PermissionDenied},
+		{name: "stay in the tenant of the token", method: "GetPlayer", claims: acmeAdmin, code: codes.OK, wantTenant: "acme"},
+		{name: "header of the own tenant", method: "GetPlayer", claims: acmeAdmin, tenant: "acme", code: codes.OK, wantTenant: "acme"},
+		{name: "switch away from another tenant", method: "GetPlayer", claims: acmeAdmin, tenant: "globex", code: codes.PermissionDenied},
+		{name: "manage tenants from the default tenant", method: "GetTenant", claims: admin, code: codes.OK, wantTenant: tenant.Default},
+		{name: "manage tenants from another tenant", method: "GetTenant", claims: acmeAdmin, code: codes.PermissionDenied},
+		{name: "player gets itself", method: "GetPlayer", claims: player, req: &v1.GetPlayerRequest{PlayerId: "player-1"}, code: codes.OK, wantTenant: tenant.Default},
+		{name: "player gets another player", method: "GetPlayer", claims: player, req: &v1.GetPlayerRequest{PlayerId: "player-2"}, code: codes.PermissionDenied},
+		{name: "player switches tenant", method: "GetPlayer", claims: player, tenant: "acme", req: &v1.GetPlayerRequest{PlayerId: "player-1"}, code: codes.PermissionDenied},
+		{name: "player gives itself items", method: "GiveItem", claims: player, code: codes.PermissionDenied},
+		{name: "player session with an api key", method: "CreatePlayerSession", apiKey: true, code: codes.OK, wantTenant: tenant.Default},
+		{name: "player session with an account token", method: "CreatePlayerSession", claims: admin, code: codes.PermissionDenied},
+		{name: "api key outside its roles", method: "SetConfig", apiKey: true, code: codes.PermissionDenied},
+		{name: "sensitive permissions without enforcement", method: "GetPlayer", claims: passwordAdmin, code: codes.OK, wantTenant: tenant.Default},
+		{name: "sensitive permissions with enforcement", method: "GetPlayer", claims: passwordAdmin, requireSensitive: true, code: codes.PermissionDenied},
+		{name: "sensitive permissions can enroll", method: "EnrollTotp", claims: passwordAdmin, requireSensitive: true, code: codes.OK, wantTenant: tenant.Default},
+		{name: "sensitive permissions with a second factor", method: "GetPlayer", claims: admin, requireSensitive: true, code: codes.OK, wantTenant: tenant.Default},
+		{name: "require two-factor permission", method: "GetPlayer", claims: requireTwoFactor, code: codes.PermissionDenied},
+		{name: "unauthenticated", method: "GetPlayer", code: codes.Unauthenticated},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			server, key := newTestServer(t, c.requireSensitive)
+
+			md := metadata.MD{}
+			if c.claims != nil {
+				md.Set("authorization", "Bearer "+signToken(t, server, c.claims))
+			}
+			if c.apiKey {
+				md.Set("authorization", "ApiKey "+key)
+			}
+			if c.tenant != "" {
+				md.Set(tenant.Header, c.tenant)
+			}
+
+			req := c.req
+			if req == nil {
+				req = &v1.GetPlayerRequest{}
+			}
+
+			gotTenant := ""
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				gotTenant = tenant.FromContext(ctx)
+				return nil, nil
+			}
+
+			ctx := metadata.NewIncomingContext(context.Background(), md)
+			info := &grpc.UnaryServerInfo{Server: server, FullMethod: "/v1.EconomyService/" + c.method}
+
+			_, err := unaryInterceptor(ctx, req, info, handler)
+			if got := status.Code(err); got != c.code {
+				t.Fatalf("code = %v, want %v (%v)", got, c.code, err)
+			}
+
+			if gotTenant != c.wantTenant {
+				t.Errorf("tenant = %q, want %q", gotTenant, c.wantTenant)
+			}
+		})
+	}
+}
+
+func TestResolveTenant(t *testing.T) {
+	cases := []struct {
+		name        string
+		tokenTenant string
+		header      string
+		canSwitch   bool
+		code        codes.Code
+		want        string
+	}{
+		{name: "no tenant", code: codes.OK, want: tenant.Default},
+		{name: "tenant of the token", tokenTenant: "acme", code: codes.OK, want: "acme"},
+		{name: "default tenant switches", header: "acme", canSwitch: true, code: codes.OK, want: "acme"},
+		{name: "default tenant can't switch", header: "acme", code: codes.PermissionDenied},
+		{name: "other tenant switches", tokenTenant: "acme", header: "globex", canSwitch: true, code: codes.PermissionDenied},
+		{name: "header of the own tenant", tokenTenant: "acme", header: "acme", code: codes.OK, want: "acme"},
+		{name: "unknown tenant", header: "initech", canSwitch: true, code: codes.NotFound},
+		{name: "unknown tenant of the token", tokenTenant: "initech", code: codes.NotFound},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			server, _ := newTestServer(t, false)
+
+			md := metadata.MD{}
+			if c.header != "" {
+				md.Set(tenant.Header, c.header)
+			}
+
+			ctx, err := resolveTenant(metadata.NewIncomingContext(context.Background(), md), server, c.tokenTenant, c.canSwitch)
+			if got := status.Code(err); got != c.code {
+				t.Fatalf("code = %v, want %v (%v)", got, c.code, err)
+			}
+
+			if err == nil && tenant.FromContext(ctx) != c.want {
+				t.Errorf("tenant = %q, want %q", tenant.FromContext(ctx), c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+
+	repository "github.com/GameComponent/economy-service/pkg/repository"
+	v1service "github.com/GameComponent/economy-service/pkg/service/v1"
+	validation "github.com/GameComponent/economy-service/pkg/validation"
+	proto "github.com/golang/protobuf/proto"
+	errdetails "google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// validate refuses requests that break the rules of their message before they reach
+// the handler, the status lists every invalid field in a google.rpc.BadRequest
+func validate(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return handler(ctx, req)
+	}
+
+	violations := validation.Validate(msg)
+	if len(violations) == 0 {
+		return handler(ctx, req)
+	}
+
+	return nil, invalidRequest(violations)
+}
+
+func invalidRequest(violations []*errdetails.BadRequest_FieldViolation) error {
+	descriptions := make([]string, len(violations))
+	for i, violation := range violations {
+		descriptions[i] = violation.Field + " " + violation.Description
+	}
+
+	st := status.New(codes.InvalidArgument, "invalid request: "+strings.Join(descriptions, ", "))
+
+	withDetails, err := st.WithDetails(
+		&errdetails.ErrorInfo{
+			Reason: repository.ReasonInvalidArgument,
+			Domain: v1service.ErrorDomain,
+		},
+		&errdetails.BadRequest{FieldViolations: violations},
+	)
+	if err != nil {
+		return st.Err()
+	}
+
+	return withDetails.Err()
+}
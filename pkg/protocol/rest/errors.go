@@ -0,0 +1,26 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	errdetails "google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorReasonHeader carries the reason of a failed request, like INSUFFICIENT_FUNDS
+const ErrorReasonHeader = "X-Error-Reason"
+
+// errorHandler writes the google.rpc.Status of a failed request with its details, and puts the reason
+// of the google.rpc.ErrorInfo in a header so clients can act on it without decoding the details.
+// The default error body of the gateway can not marshal details.
+func errorHandler(ctx context.Context, mux *runtime.ServeMux, marshaler runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			w.Header().Set(ErrorReasonHeader, info.GetReason())
+		}
+	}
+
+	runtime.DefaultHTTPProtoErrorHandler(ctx, mux, marshaler, w, r, err)
+}
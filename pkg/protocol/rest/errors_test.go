@@ -0,0 +1,55 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	errdetails "google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestErrorHandler(t *testing.T) {
+	st, err := status.New(codes.FailedPrecondition, "not enough of currency gold in the storage").WithDetails(&errdetails.ErrorInfo{
+		Reason:   "INSUFFICIENT_FUNDS",
+		Domain:   "economy-service",
+		Metadata: map[string]string{"currency_id": "gold"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/v1/products/buy", nil)
+	errorHandler(context.Background(), runtime.NewServeMux(), &runtime.JSONPb{}, w, r, st.Err())
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	if got := w.Header().Get(ErrorReasonHeader); got != "INSUFFICIENT_FUNDS" {
+		t.Errorf("%s = %q, want INSUFFICIENT_FUNDS", ErrorReasonHeader, got)
+	}
+
+	if body := w.Body.String(); !strings.Contains(body, `"reason":"INSUFFICIENT_FUNDS"`) || !strings.Contains(body, "google.rpc.ErrorInfo") {
+		t.Errorf("body = %s, want the error info", body)
+	}
+}
+
+func TestErrorHandlerWithoutDetails(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/v1/storages/1", nil)
+	errorHandler(context.Background(), runtime.NewServeMux(), &runtime.JSONPb{}, w, r, status.Error(codes.Internal, "unable to retrieve storage"))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+
+	if got := w.Header().Get(ErrorReasonHeader); got != "" {
+		t.Errorf("%s = %q, want none", ErrorReasonHeader, got)
+	}
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/http"
+
+	tracing "github.com/GameComponent/economy-service/pkg/tracing"
+	otelhttp "go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// AddTracing starts a span for every request, continuing the trace context of the caller,
+// the request id from AddRequestID is attached to it
+func AddTracing(h http.Handler) http.Handler {
+	tagged := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tracing.SetRequestID(r.Context(), GetReqID(r.Context()))
+		h.ServeHTTP(w, r)
+	})
+
+	return otelhttp.NewHandler(
+		tagged,
+		"gateway",
+		otelhttp.WithSpanNameFormatter(func(operation string, r *http.Request) string {
+			return r.Method + " " + r.URL.Path
+		}),
+	)
+}
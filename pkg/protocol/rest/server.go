@@ -2,21 +2,29 @@ package rest
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	otelgrpc "go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 
 	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
+	health "github.com/GameComponent/economy-service/pkg/health"
 	"github.com/GameComponent/economy-service/pkg/protocol/rest/middleware"
+	signing "github.com/GameComponent/economy-service/pkg/signing"
+	tenant "github.com/GameComponent/economy-service/pkg/tenant"
 )
 
 // RunServer runs HTTP/REST gateway
-func RunServer(ctx context.Context, logger *zap.Logger, grpcPort string, httpPort string) error {
+func RunServer(ctx context.Context, logger *zap.Logger, keyring *signing.Keyring, checker *health.Checker, grpcPort string, httpPort string) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
@@ -25,36 +33,135 @@ func RunServer(ctx context.Context, logger *zap.Logger, grpcPort string, httpPor
 			runtime.MIMEWildcard,
 			&runtime.JSONPb{OrigName: false},
 		),
+		runtime.WithIncomingHeaderMatcher(headerMatcher),
+		runtime.WithOutgoingHeaderMatcher(outgoingHeaderMatcher),
+		runtime.WithMetadata(requestIDMetadata),
+		runtime.WithProtoErrorHandler(errorHandler),
 	)
-	opts := []grpc.DialOption{grpc.WithInsecure()}
+	opts := []grpc.DialOption{
+		grpc.WithInsecure(),
+		// Pass the trace context of the request on to the gRPC server
+		grpc.WithUnaryInterceptor(otelgrpc.UnaryClientInterceptor()),
+	}
 	if err := v1.RegisterEconomyServiceHandlerFromEndpoint(ctx, mux, "0.0.0.0:"+grpcPort, opts); err != nil {
 		logger.Fatal("failed to start HTTP gateway", zap.String("reason", err.Error()))
 	}
 
-	srv := &http.Server{
-		Addr: ":" + httpPort,
-		// add handler with middleware
-		Handler: middleware.AddCors(
-			middleware.AddRequestID(
-				middleware.AddLogger(logger, mux),
+	// Other services verify tokens with the published public keys
+	root := http.NewServeMux()
+	root.Handle("/.well-known/jwks.json", jwksHandler(keyring))
+	root.Handle("/", mux)
+
+	// Probes skip the middleware, they would flood the logs and traces
+	probes := http.NewServeMux()
+	probes.Handle("/healthz", livenessHandler(checker))
+	probes.Handle("/readyz", readinessHandler(checker))
+	probes.Handle("/", middleware.AddCors(
+		middleware.AddRequestID(
+			middleware.AddTracing(
+				middleware.AddLogger(logger, root),
 			),
 		),
+	))
+
+	srv := &http.Server{
+		Addr:    ":" + httpPort,
+		Handler: probes,
 	}
 
 	// graceful shutdown
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	go func() {
-		for range c {
-			// sig is a ^C, handle it
-		}
+		<-c
+
+		// Keep serving until load balancers noticed the failing readiness check
+		checker.Drain()
 
-		_, cancel := context.WithTimeout(ctx, 5*time.Second)
+		shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 		defer cancel()
 
-		_ = srv.Shutdown(ctx)
+		_ = srv.Shutdown(shutdownCtx)
 	}()
 
 	logger.Info("starting HTTP/REST gateway", zap.String("port", httpPort))
 	return srv.ListenAndServe()
 }
+
+// jwksHandler serves the public keys tokens are verified with
+func jwksHandler(keyring *signing.Keyring) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		_ = json.NewEncoder(w).Encode(keyring.JWKS())
+	})
+}
+
+// livenessHandler answers as long as the process is able to serve requests
+func livenessHandler(checker *health.Checker) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+
+		if err := checker.Live(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+
+		_, _ = w.Write([]byte("ok\n"))
+	})
+}
+
+// readinessHandler answers when the database is reachable and migrated,
+// and fails as soon as the service is shutting down
+func readinessHandler(checker *health.Checker) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+
+		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+		defer cancel()
+
+		if err := checker.Ready(ctx); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+
+		_, _ = w.Write([]byte("ok\n"))
+	})
+}
+
+// headerMatcher passes the tenant header on to the gRPC server
+func headerMatcher(key string) (string, bool) {
+	if strings.ToLower(key) == tenant.Header {
+		return tenant.Header, true
+	}
+
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+// requestIDMetadata passes the request id on to the gRPC server, which records it in the audit log
+func requestIDMetadata(ctx context.Context, r *http.Request) metadata.MD {
+	return metadata.Pairs("x-request-id", middleware.GetReqID(r.Context()))
+}
+
+// outgoingHeaderMatcher passes the request id and rate limit headers on as is
+func outgoingHeaderMatcher(key string) (string, bool) {
+	switch strings.ToLower(key) {
+	case "x-request-id":
+		return "X-Request-Id", true
+	case "retry-after":
+		return "Retry-After", true
+	case "x-ratelimit-limit":
+		return "X-RateLimit-Limit", true
+	case "x-ratelimit-remaining":
+		return "X-RateLimit-Remaining", true
+	case "x-ratelimit-reset":
+		return "X-RateLimit-Reset", true
+	}
+
+	return runtime.MetadataHeaderPrefix + key, true
+}
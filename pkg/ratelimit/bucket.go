@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// Buckets are token buckets in memory, a bucket holds as many tokens as the limit
+// of its rate and refills at the rate, so bursts up to the limit are allowed
+type Buckets struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+// Result of taking a token from a bucket
+type Result struct {
+	Allowed bool
+	Limit   int64
+	// Remaining tokens after this request
+	Remaining int64
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is the time until the next token when the request is not allowed
+	RetryAfter time.Duration
+}
+
+// NewBuckets creates token buckets
+func NewBuckets() *Buckets {
+	return &Buckets{
+		buckets: map[string]*bucket{},
+		now:     time.Now,
+	}
+}
+
+// Take takes a token from the bucket of a key, a bucket starts full
+func (b *Buckets) Take(key string, rate Rate) Result {
+	if rate.Limit == 0 {
+		return Result{Allowed: true}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	capacity := float64(rate.Limit)
+	perSecond := capacity / rate.Window.Seconds()
+
+	current, ok := b.buckets[key]
+	if !ok {
+		if len(b.buckets) >= maxEntries {
+			b.prune(now)
+		}
+
+		current = &bucket{tokens: capacity, updated: now}
+		b.buckets[key] = current
+	}
+
+	// Refill for the time since the last request
+	current.tokens = math.Min(capacity, current.tokens+now.Sub(current.updated).Seconds()*perSecond)
+	current.updated = now
+
+	result := Result{Limit: rate.Limit}
+	if current.tokens >= 1 {
+		current.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - current.tokens) / perSecond)
+	}
+
+	result.Remaining = int64(current.tokens)
+	result.Reset = seconds((capacity - current.tokens) / perSecond)
+
+	return result
+}
+
+// prune removes buckets that were not used for an hour, these are full again
+// unless their window is longer than that
+func (b *Buckets) prune(now time.Time) {
+	for key, current := range b.buckets {
+		if now.Sub(current.updated) > time.Hour {
+			delete(b.buckets, key)
+		}
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
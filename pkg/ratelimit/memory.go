@@ -0,0 +1,109 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// maxEntries is the number of keys after which expired keys are removed
+const maxEntries = 10000
+
+type entry struct {
+	count        int64
+	expires      time.Time
+	blockedUntil time.Time
+}
+
+// MemoryStore keeps the counters in memory, they are not shared between instances
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+	now     func() time.Time
+}
+
+// NewMemoryStore creates an in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries: map[string]*entry{},
+		now:     time.Now,
+	}
+}
+
+// get returns the entry of a key, creating it when it does not exist or ended
+func (s *MemoryStore) get(key string, now time.Time) *entry {
+	e, ok := s.entries[key]
+	if ok && (now.Before(e.expires) || now.Before(e.blockedUntil)) {
+		return e
+	}
+
+	if len(s.entries) >= maxEntries {
+		s.prune(now)
+	}
+
+	e = &entry{}
+	s.entries[key] = e
+	return e
+}
+
+// prune removes the keys of windows and blocks that ended
+func (s *MemoryStore) prune(now time.Time) {
+	for key, e := range s.entries {
+		if !now.Before(e.expires) && !now.Before(e.blockedUntil) {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// Incr adds one to the counter of a key
+func (s *MemoryStore) Incr(ctx context.Context, key string, window time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	e := s.get(key, now)
+	if !now.Before(e.expires) {
+		e.count = 0
+		e.expires = now.Add(window)
+	}
+
+	e.count++
+	return e.count, nil
+}
+
+// Block blocks a key for a duration
+func (s *MemoryStore) Block(ctx context.Context, key string, duration time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.get(key, now).blockedUntil = now.Add(duration)
+	return nil
+}
+
+// Blocked returns how long a key is still blocked
+func (s *MemoryStore) Blocked(ctx context.Context, key string) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok {
+		return 0, nil
+	}
+
+	remaining := e.blockedUntil.Sub(s.now())
+	if remaining < 0 {
+		return 0, nil
+	}
+
+	return remaining, nil
+}
+
+// Reset removes the counter and block of a key
+func (s *MemoryStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
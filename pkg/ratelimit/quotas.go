@@ -0,0 +1,93 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// QuotasKey is the config key the quotas of the API are read from
+const QuotasKey = "rate_limits"
+
+// Quotas are the request limits of the API per account, API key or player, like
+//
+//	{
+//	  "default": "600/1m",
+//	  "methods": {"GiveItem": "10/1s", "List*": "60/1m"},
+//	  "principals": {"<account, api key or player id>": "6000/1m"}
+//	}
+//
+// The default limits all requests of a principal together, the principals replace the
+// default for a single principal and the methods limit the methods matching a pattern
+type Quotas struct {
+	Default    Rate
+	Methods    []MethodQuota
+	Principals map[string]Rate
+}
+
+// MethodQuota limits the methods matching a pattern
+type MethodQuota struct {
+	Pattern string
+	Rate    Rate
+}
+
+type quotasJSON struct {
+	Default    string            `json:"default"`
+	Methods    map[string]string `json:"methods"`
+	Principals map[string]string `json:"principals"`
+}
+
+// ParseQuotas parses the JSON of the quotas, an empty value has no limits
+func ParseQuotas(value string) (*Quotas, error) {
+	quotas := &Quotas{
+		Methods:    []MethodQuota{},
+		Principals: map[string]Rate{},
+	}
+
+	if value == "" {
+		return quotas, nil
+	}
+
+	var parsed quotasJSON
+	if err := json.Unmarshal([]byte(value), &parsed); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", QuotasKey, err)
+	}
+
+	var err error
+	quotas.Default, err = ParseRate(parsed.Default)
+	if err != nil {
+		return nil, err
+	}
+
+	for pattern, rate := range parsed.Methods {
+		parsedRate, err := ParseRate(rate)
+		if err != nil {
+			return nil, err
+		}
+
+		quotas.Methods = append(quotas.Methods, MethodQuota{Pattern: pattern, Rate: parsedRate})
+	}
+
+	// Keep the order of the methods stable
+	sort.Slice(quotas.Methods, func(i, j int) bool {
+		return quotas.Methods[i].Pattern < quotas.Methods[j].Pattern
+	})
+
+	for principal, rate := range parsed.Principals {
+		quotas.Principals[principal], err = ParseRate(rate)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return quotas, nil
+}
+
+// Rate returns the overall rate of a principal
+func (q *Quotas) Rate(principal string) Rate {
+	if rate, ok := q.Principals[principal]; ok {
+		return rate
+	}
+
+	return q.Default
+}
@@ -0,0 +1,174 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Store keeps the counters of the limiters, the in-memory store only limits a single
+// instance so deployments with several instances can plug in a shared store
+type Store interface {
+	// Incr adds one to the counter of a key and returns the new count, the window
+	// starts with the first increment and the counter is gone once it ends
+	Incr(ctx context.Context, key string, window time.Duration) (int64, error)
+
+	// Block blocks a key for a duration
+	Block(ctx context.Context, key string, duration time.Duration) error
+
+	// Blocked returns how long a key is still blocked, zero when it is not
+	Blocked(ctx context.Context, key string) (time.Duration, error)
+
+	// Reset removes the counter and block of a key
+	Reset(ctx context.Context, key string) error
+}
+
+// Rate is a number of requests per window
+type Rate struct {
+	Limit  int64
+	Window time.Duration
+}
+
+// ParseRate parses a rate like 10/1m, an empty string is no limit
+func ParseRate(s string) (Rate, error) {
+	if s == "" {
+		return Rate{}, nil
+	}
+
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) != 2 {
+		return Rate{}, fmt.Errorf("invalid rate %q, use a limit and window like 10/1m", s)
+	}
+
+	limit, err := strconv.ParseInt(strings.TrimSpace(parts[0]), 10, 64)
+	if err != nil || limit < 0 {
+		return Rate{}, fmt.Errorf("invalid limit in rate %q", s)
+	}
+
+	window, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+	if err != nil || window <= 0 {
+		return Rate{}, fmt.Errorf("invalid window in rate %q", s)
+	}
+
+	return Rate{Limit: limit, Window: window}, nil
+}
+
+// Limiter allows a number of requests per key in a fixed window
+type Limiter struct {
+	store  Store
+	prefix string
+	rate   Rate
+}
+
+// NewLimiter creates a limiter, keys are prefixed so limiters can share a store
+func NewLimiter(store Store, prefix string, rate Rate) *Limiter {
+	return &Limiter{
+		store:  store,
+		prefix: prefix,
+		rate:   rate,
+	}
+}
+
+// Allow counts a request and returns how long to wait when it is over the limit
+func (l *Limiter) Allow(ctx context.Context, key string) (time.Duration, error) {
+	if l == nil || l.rate.Limit == 0 {
+		return 0, nil
+	}
+
+	key = l.prefix + ":" + key
+
+	// Requests over the limit block the key until the window ends
+	blocked, err := l.store.Blocked(ctx, key)
+	if err != nil || blocked > 0 {
+		return blocked, err
+	}
+
+	count, err := l.store.Incr(ctx, key, l.rate.Window)
+	if err != nil {
+		return 0, err
+	}
+
+	if count > l.rate.Limit {
+		err = l.store.Block(ctx, key, l.rate.Window)
+		return l.rate.Window, err
+	}
+
+	return 0, nil
+}
+
+// Lockout locks keys after a number of failures, every following failure doubles the lock
+type Lockout struct {
+	store     Store
+	threshold int64
+	base      time.Duration
+	max       time.Duration
+}
+
+// failureWindow is how long failures are remembered without a success
+const failureWindow = 24 * time.Hour
+
+// NewLockout creates a lockout, a threshold of zero disables it
+func NewLockout(store Store, threshold int64, base time.Duration, max time.Duration) *Lockout {
+	return &Lockout{
+		store:     store,
+		threshold: threshold,
+		base:      base,
+		max:       max,
+	}
+}
+
+// Locked returns how long a key is still locked
+func (l *Lockout) Locked(ctx context.Context, key string) (time.Duration, error) {
+	if l == nil || l.threshold == 0 {
+		return 0, nil
+	}
+
+	return l.store.Blocked(ctx, "lockout:"+key)
+}
+
+// Fail records a failure and returns how long the key is locked because of it
+func (l *Lockout) Fail(ctx context.Context, key string) (time.Duration, error) {
+	if l == nil || l.threshold == 0 {
+		return 0, nil
+	}
+
+	failures, err := l.store.Incr(ctx, "lockout:"+key, failureWindow)
+	if err != nil {
+		return 0, err
+	}
+
+	if failures < l.threshold {
+		return 0, nil
+	}
+
+	duration := Backoff(l.base, l.max, failures-l.threshold)
+	return duration, l.store.Block(ctx, "lockout:"+key, duration)
+}
+
+// Succeed forgets the failures of a key
+func (l *Lockout) Succeed(ctx context.Context, key string) error {
+	if l == nil || l.threshold == 0 {
+		return nil
+	}
+
+	return l.store.Reset(ctx, "lockout:"+key)
+}
+
+// Backoff doubles the base duration for every attempt, up to the max duration
+func Backoff(base time.Duration, max time.Duration, attempt int64) time.Duration {
+	duration := base
+	for i := int64(0); i < attempt; i++ {
+		duration *= 2
+		if duration >= max {
+			return max
+		}
+	}
+
+	if duration > max {
+		return max
+	}
+
+	return duration
+}
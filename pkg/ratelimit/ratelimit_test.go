@@ -0,0 +1,156 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func newTestStore() (*MemoryStore, *clock) {
+	c := &clock{now: time.Unix(1572048000, 0)}
+	store := NewMemoryStore()
+	store.now = c.Now
+	return store, c
+}
+
+func TestParseRate(t *testing.T) {
+	rate, err := ParseRate("10/1m")
+	if err != nil || rate.Limit != 10 || rate.Window != time.Minute {
+		t.Errorf("ParseRate(10/1m) = %v, %v", rate, err)
+	}
+
+	rate, err = ParseRate("")
+	if err != nil || rate.Limit != 0 {
+		t.Errorf("empty rate should be no limit")
+	}
+
+	for _, invalid := range []string{"10", "x/1m", "10/x", "10/0s", "-1/1m"} {
+		if _, err := ParseRate(invalid); err == nil {
+			t.Errorf("ParseRate(%q) should fail", invalid)
+		}
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	ctx := context.Background()
+	store, c := newTestStore()
+	limiter := NewLimiter(store, "ip", Rate{Limit: 2, Window: time.Minute})
+
+	for i := 0; i < 2; i++ {
+		if wait, _ := limiter.Allow(ctx, "127.0.0.1"); wait != 0 {
+			t.Fatalf("request %d should be allowed", i+1)
+		}
+	}
+
+	if wait, _ := limiter.Allow(ctx, "127.0.0.1"); wait != time.Minute {
+		t.Errorf("third request should wait a minute, got %s", wait)
+	}
+
+	if wait, _ := limiter.Allow(ctx, "127.0.0.2"); wait != 0 {
+		t.Errorf("other keys should not be limited")
+	}
+
+	c.now = c.now.Add(30 * time.Second)
+	if wait, _ := limiter.Allow(ctx, "127.0.0.1"); wait != 30*time.Second {
+		t.Errorf("blocked key should wait the rest of the block, got %s", wait)
+	}
+
+	c.now = c.now.Add(31 * time.Second)
+	if wait, _ := limiter.Allow(ctx, "127.0.0.1"); wait != 0 {
+		t.Errorf("request after the block should be allowed, got %s", wait)
+	}
+}
+
+func TestLockout(t *testing.T) {
+	ctx := context.Background()
+	store, c := newTestStore()
+	lockout := NewLockout(store, 3, time.Second, 10*time.Second)
+
+	for i := 0; i < 2; i++ {
+		if locked, _ := lockout.Fail(ctx, "admin@example.com"); locked != 0 {
+			t.Fatalf("failure %d should not lock", i+1)
+		}
+	}
+
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second}
+	for _, want := range expected {
+		if locked, _ := lockout.Fail(ctx, "admin@example.com"); locked != want {
+			t.Errorf("lock = %s, want %s", locked, want)
+		}
+
+		if locked, _ := lockout.Locked(ctx, "admin@example.com"); locked != want {
+			t.Errorf("Locked = %s, want %s", locked, want)
+		}
+
+		c.now = c.now.Add(want)
+	}
+
+	lockout.Succeed(ctx, "admin@example.com")
+	if locked, _ := lockout.Fail(ctx, "admin@example.com"); locked != 0 {
+		t.Errorf("failures should be forgotten after a success")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	if got := Backoff(time.Second, time.Hour, 100); got != time.Hour {
+		t.Errorf("Backoff should be capped, got %s", got)
+	}
+}
+
+func TestBuckets(t *testing.T) {
+	c := &clock{now: time.Unix(1572134400, 0)}
+	buckets := NewBuckets()
+	buckets.now = c.Now
+	rate := Rate{Limit: 2, Window: 2 * time.Second}
+
+	first := buckets.Take("account", rate)
+	if !first.Allowed || first.Remaining != 1 || first.Reset != time.Second {
+		t.Errorf("first request = %+v", first)
+	}
+
+	buckets.Take("account", rate)
+	denied := buckets.Take("account", rate)
+	if denied.Allowed || denied.Remaining != 0 || denied.RetryAfter != time.Second {
+		t.Errorf("request over the limit = %+v", denied)
+	}
+
+	c.now = c.now.Add(time.Second)
+	if result := buckets.Take("account", rate); !result.Allowed {
+		t.Errorf("bucket should refill a token per second")
+	}
+
+	if result := buckets.Take("other", rate); !result.Allowed || result.Remaining != 1 {
+		t.Errorf("other keys should have their own bucket")
+	}
+}
+
+func TestParseQuotas(t *testing.T) {
+	quotas, err := ParseQuotas(`{"default": "600/1m", "methods": {"List*": "60/1m", "GiveItem": "10/1s"}, "principals": {"server": "6000/1m"}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if quotas.Rate("someone").Limit != 600 || quotas.Rate("server").Limit != 6000 {
+		t.Errorf("unexpected principal rates")
+	}
+
+	if len(quotas.Methods) != 2 || quotas.Methods[0].Pattern != "GiveItem" {
+		t.Errorf("methods should be sorted, got %+v", quotas.Methods)
+	}
+
+	if _, err := ParseQuotas(`{"default": "600"}`); err == nil {
+		t.Errorf("invalid rate should fail")
+	}
+
+	if _, err := ParseQuotas(`[]`); err == nil {
+		t.Errorf("invalid JSON should fail")
+	}
+}
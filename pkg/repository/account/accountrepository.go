@@ -4,10 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"time"
-	"fmt"
 
 	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
 	repository "github.com/GameComponent/economy-service/pkg/repository"
+	tenant "github.com/GameComponent/economy-service/pkg/tenant"
 	"go.uber.org/zap"
 )
 
@@ -30,12 +30,14 @@ func (r *AccountRepository) GetByEmail(ctx context.Context, email string) (*v1.A
 	rows, err := r.db.QueryContext(
 		ctx,
 		`
-			SELECT account.id, account.email, account.password, account_permission.permission
+			SELECT account.id, account.tenant_id, account.email, account.password, account.email_verified_at IS NOT NULL, account.totp_enabled_at IS NOT NULL, account_permission.permission
 			FROM account
 			LEFT JOIN account_permission ON (account.id = account_permission.account_id)
 			WHERE email = $1
+			AND account.tenant_id = $2
 		`,
 		email,
+		tenant.FromContext(ctx),
 	)
 
 	if err != nil {
@@ -44,8 +46,11 @@ func (r *AccountRepository) GetByEmail(ctx context.Context, email string) (*v1.A
 
 	type row struct {
 		AccountID    string
+		TenantID     string
 		AccountEmail string
 		AccountHash  string
+		Verified     bool
+		TotpEnabled  bool
 		Permission   string
 	}
 
@@ -55,8 +60,11 @@ func (r *AccountRepository) GetByEmail(ctx context.Context, email string) (*v1.A
 	for rows.Next() {
 		err = rows.Scan(
 			&res.AccountID,
+			&res.TenantID,
 			&res.AccountEmail,
 			&res.AccountHash,
+			&res.Verified,
+			&res.TotpEnabled,
 			&res.Permission,
 		)
 
@@ -67,11 +75,24 @@ func (r *AccountRepository) GetByEmail(ctx context.Context, email string) (*v1.A
 		accountPermissions = append(accountPermissions, res.Permission)
 	}
 
+	if res.AccountID == "" {
+		return nil, repository.NotFound("account", email)
+	}
+
+	roles, err := r.roles(ctx, res.AccountID)
+	if err != nil {
+		return nil, err
+	}
+
 	account := &v1.Account{
-		Id:          res.AccountID,
-		Email:       res.AccountEmail,
-		Hash:        res.AccountHash,
-		Permissions: accountPermissions,
+		Id:            res.AccountID,
+		TenantId:      res.TenantID,
+		Email:         res.AccountEmail,
+		Hash:          res.AccountHash,
+		Permissions:   accountPermissions,
+		Roles:         roles,
+		EmailVerified: res.Verified,
+		TotpEnabled:   res.TotpEnabled,
 	}
 
 	return account, nil
@@ -84,14 +105,19 @@ func (r *AccountRepository) Get(ctx context.Context, accountID string) (*v1.Acco
 		`
 			SELECT
 				account.id,
+				account.tenant_id,
 				account.email,
 				account.password,
+				account.email_verified_at IS NOT NULL,
+				account.totp_enabled_at IS NOT NULL,
 				account_permission.permission
 			FROM account
 			LEFT JOIN account_permission ON (account.id = account_permission.account_id)
 			WHERE account.id = $1
+			AND account.tenant_id = $2
 		`,
 		accountID,
+		tenant.FromContext(ctx),
 	)
 
 	if err != nil {
@@ -100,8 +126,11 @@ func (r *AccountRepository) Get(ctx context.Context, accountID string) (*v1.Acco
 
 	type row struct {
 		AccountID    string
+		TenantID     string
 		AccountEmail string
 		AccountHash  string
+		Verified     bool
+		TotpEnabled  bool
 		Permission   sql.NullString
 	}
 
@@ -111,8 +140,11 @@ func (r *AccountRepository) Get(ctx context.Context, accountID string) (*v1.Acco
 	for rows.Next() {
 		err = rows.Scan(
 			&res.AccountID,
+			&res.TenantID,
 			&res.AccountEmail,
 			&res.AccountHash,
+			&res.Verified,
+			&res.TotpEnabled,
 			&res.Permission,
 		)
 
@@ -125,36 +157,102 @@ func (r *AccountRepository) Get(ctx context.Context, accountID string) (*v1.Acco
 		}
 	}
 
+	if res.AccountID == "" {
+		return nil, repository.NotFound("account", accountID)
+	}
+
+	roles, err := r.roles(ctx, res.AccountID)
+	if err != nil {
+		return nil, err
+	}
+
 	account := &v1.Account{
-		Id:          res.AccountID,
-		Email:       res.AccountEmail,
-		Hash:        res.AccountHash,
-		Permissions: accountPermissions,
+		Id:            res.AccountID,
+		TenantId:      res.TenantID,
+		Email:         res.AccountEmail,
+		Hash:          res.AccountHash,
+		Permissions:   accountPermissions,
+		Roles:         roles,
+		EmailVerified: res.Verified,
+		TotpEnabled:   res.TotpEnabled,
 	}
 
 	return account, nil
 }
 
-// List all accounts
-func (r *AccountRepository) List(ctx context.Context, limit int32, offset int32) ([]*v1.Account, int32, error) {
-	// Query configs from the database
-	rows, err := r.db.QueryContext(
+// GetByIdentity gets an account by the identity of an external identity provider
+func (r *AccountRepository) GetByIdentity(ctx context.Context, issuer string, subject string) (*v1.Account, error) {
+	accountID := ""
+
+	err := r.db.QueryRowContext(
+		ctx,
+		`
+			SELECT account.id
+			FROM account_identity
+			INNER JOIN account ON (account.id = account_identity.account_id)
+			WHERE account_identity.issuer = $1
+			AND account_identity.subject = $2
+			AND account.tenant_id = $3
+		`,
+		issuer,
+		subject,
+		tenant.FromContext(ctx),
+	).Scan(&accountID)
+
+	if err == sql.ErrNoRows {
+		return nil, repository.NotFound("account", subject)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Get(ctx, accountID)
+}
+
+// LinkIdentity links the identity of an external identity provider to an account
+func (r *AccountRepository) LinkIdentity(ctx context.Context, accountID string, issuer string, subject string) error {
+	_, err := r.db.ExecContext(
 		ctx,
+		`
+			INSERT INTO account_identity(account_id, issuer, subject)
+			SELECT id, $2, $3
+			FROM account
+			WHERE id = $1
+			AND tenant_id = $4
+		`,
+		accountID,
+		issuer,
+		subject,
+		tenant.FromContext(ctx),
+	)
+
+	return err
+}
+
+// List all accounts, the newest first
+func (r *AccountRepository) List(ctx context.Context, page repository.Page) ([]*v1.Account, repository.Cursor, int32, error) {
+	keyset, err := repository.NewKeyset(page, repository.Desc("created_at"), repository.Desc("id"))
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	query, args := keyset.Query(
 		`
 			SELECT
 				id,
 				email,
-				(SELECT COUNT(*) FROM account) AS total_size
+				created_at,
+				(SELECT COUNT(*) FROM account WHERE tenant_id = $1) AS total_size
 			FROM account
-			LIMIT $1
-			OFFSET $2
+			WHERE tenant_id = $1
 		`,
-		limit,
-		offset,
+		tenant.FromContext(ctx),
 	)
 
+	// Query configs from the database
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, 0, err
+		return nil, nil, 0, err
 	}
 	defer rows.Close()
 
@@ -164,20 +262,26 @@ func (r *AccountRepository) List(ctx context.Context, limit int32, offset int32)
 
 	for rows.Next() {
 		account := v1.Account{}
+		createdAt := time.Time{}
 
 		err := rows.Scan(
 			&account.Id,
 			&account.Email,
+			&createdAt,
 			&totalSize,
 		)
 		if err != nil {
-			return nil, 0, err
+			return nil, nil, 0, err
+		}
+
+		if !keyset.Add(createdAt, account.Id) {
+			break
 		}
 
 		accounts = append(accounts, &account)
 	}
 
-	return accounts, totalSize, nil
+	return accounts, keyset.Next(), totalSize, nil
 }
 
 // Create an account
@@ -186,9 +290,10 @@ func (r *AccountRepository) Create(ctx context.Context, email string, password s
 
 	err := r.db.QueryRowContext(
 		ctx,
-		`INSERT INTO account(email, password) VALUES($1, $2) RETURNING id`,
+		`INSERT INTO account(email, password, tenant_id) VALUES($1, $2, $3) RETURNING id`,
 		email,
 		password,
+		tenant.FromContext(ctx),
 	).Scan(&id)
 
 	if err != nil {
@@ -196,28 +301,53 @@ func (r *AccountRepository) Create(ctx context.Context, email string, password s
 	}
 
 	account := &v1.Account{
-		Id:    id,
-		Email: email,
-		Hash:  password,
+		Id:       id,
+		TenantId: tenant.FromContext(ctx),
+		Email:    email,
+		Hash:     password,
 	}
 
 	return account, nil
 }
 
 // Update an account
-func (r *AccountRepository) Update(ctx context.Context, email string, password string) (*v1.Account, error) {
+func (r *AccountRepository) Update(ctx context.Context, accountID string, password string) (*v1.Account, error) {
 	_, err := r.db.ExecContext(
 		ctx,
-		`UPDATE account SET password = $1 WHERE email = $2`,
+		`UPDATE account SET password = $1 WHERE id = $2 AND tenant_id = $3`,
 		password,
-		email,
+		accountID,
+		tenant.FromContext(ctx),
 	)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return r.Get(ctx, email)
+	return r.Get(ctx, accountID)
+}
+
+// VerifyEmail marks the email of an account as verified
+func (r *AccountRepository) VerifyEmail(ctx context.Context, accountID string) (*v1.Account, error) {
+	_, err := r.db.ExecContext(
+		ctx,
+		`
+			UPDATE account
+			SET email_verified_at = $1
+			WHERE id = $2
+			AND tenant_id = $3
+			AND email_verified_at IS NULL
+		`,
+		time.Now().UTC(),
+		accountID,
+		tenant.FromContext(ctx),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Get(ctx, accountID)
 }
 
 // AssignPermission assigns a permission to an account
@@ -226,10 +356,14 @@ func (r *AccountRepository) AssignPermission(ctx context.Context, accountID stri
 		ctx,
 		`
 			INSERT INTO account_permission(account_id, permission)
-			VALUES($1, $2)
+			SELECT id, $2
+			FROM account
+			WHERE id = $1
+			AND tenant_id = $3
 		`,
 		accountID,
 		permission,
+		tenant.FromContext(ctx),
 	)
 
 	if err != nil {
@@ -247,9 +381,11 @@ func (r *AccountRepository) RevokePermission(ctx context.Context, accountID stri
 			DELETE FROM account_permission
 			WHERE account_id = $1
 			AND permission = $2
+			AND account_id IN (SELECT id FROM account WHERE tenant_id = $3)
 		`,
 		accountID,
 		permission,
+		tenant.FromContext(ctx),
 	)
 
 	if err != nil {
@@ -259,61 +395,362 @@ func (r *AccountRepository) RevokePermission(ctx context.Context, accountID stri
 	return r.Get(ctx, accountID)
 }
 
-// CreateRefreshToken for an account
-func (r *AccountRepository) CreateRefreshToken(ctx context.Context, token string, accountID string, expires *time.Time) error {
+// AssignRole assigns a role to an account
+func (r *AccountRepository) AssignRole(ctx context.Context, accountID string, role string) (*v1.Account, error) {
 	_, err := r.db.ExecContext(
 		ctx,
-		`INSERT INTO account_token(token, account_id, expires) VALUES($1, $2, $3) RETURNING token`,
-		token,
+		`
+			INSERT INTO account_role(account_id, role)
+			SELECT id, $2
+			FROM account
+			WHERE id = $1
+			AND tenant_id = $3
+			ON CONFLICT (account_id, role) DO NOTHING
+		`,
 		accountID,
-		expires,
+		role,
+		tenant.FromContext(ctx),
 	)
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return r.Get(ctx, accountID)
 }
 
-// InvalidateRefreshTokens for an account
-func (r *AccountRepository) InvalidateRefreshTokens(ctx context.Context, accountID string) error {
+// RevokeRole revokes a role from an account
+func (r *AccountRepository) RevokeRole(ctx context.Context, accountID string, role string) (*v1.Account, error) {
 	_, err := r.db.ExecContext(
 		ctx,
-		`DELETE FROM account_token WHERE account_id = $1`,
+		`
+			DELETE FROM account_role
+			WHERE account_id = $1
+			AND role = $2
+			AND account_id IN (SELECT id FROM account WHERE tenant_id = $3)
+		`,
 		accountID,
+		role,
+		tenant.FromContext(ctx),
 	)
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return r.Get(ctx, accountID)
 }
 
-// GetAccountIDFromRefreshToken for an account
-func (r *AccountRepository) GetAccountIDFromRefreshToken(ctx context.Context, token string) (string, error) {
+// roles gets the names of the roles assigned to an account
+func (r *AccountRepository) roles(ctx context.Context, accountID string) ([]string, error) {
+	roles := []string{}
+	if accountID == "" {
+		return roles, nil
+	}
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT role FROM account_role WHERE account_id = $1 ORDER BY role`,
+		accountID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		role := ""
+		if err := rows.Scan(&role); err != nil {
+			return nil, err
+		}
+
+		roles = append(roles, role)
+	}
+
+	return roles, nil
+}
+
+// CreateVerificationToken stores the hash of a single-use token for an account
+func (r *AccountRepository) CreateVerificationToken(ctx context.Context, accountID string, purpose string, hash string, expires time.Time) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`
+			INSERT INTO account_verification(hash, account_id, purpose, expires)
+			SELECT $1, id, $3, $4
+			FROM account
+			WHERE id = $2
+			AND tenant_id = $5
+		`,
+		hash,
+		accountID,
+		purpose,
+		expires,
+		tenant.FromContext(ctx),
+	)
+
+	return err
+}
+
+// UseVerificationToken marks a token as used and returns the account it belongs to,
+// tokens which are expired or already used are rejected
+func (r *AccountRepository) UseVerificationToken(ctx context.Context, purpose string, hash string) (string, error) {
 	accountID := ""
+	now := time.Now().UTC()
 
 	err := r.db.QueryRowContext(
 		ctx,
 		`
-			SELECT
-				account_id
-			FROM account_token
-			WHERE token = $1
-			AND expires > $2`,
-		token,
-		time.Now().UTC(),
+			UPDATE account_verification
+			SET used_at = $1
+			WHERE hash = $2
+			AND purpose = $3
+			AND used_at IS NULL
+			AND expires > $1
+			AND account_id IN (SELECT id FROM account WHERE tenant_id = $4)
+			RETURNING account_id
+		`,
+		now,
+		hash,
+		purpose,
+		tenant.FromContext(ctx),
 	).Scan(&accountID)
 
+	if err == sql.ErrNoRows {
+		return "", repository.NotFound("verification token", "")
+	}
 	if err != nil {
 		return "", err
 	}
 
-	if accountID == "" {
-		return "", fmt.Errorf("Unable to get a valid token")
+	return accountID, nil
+}
+
+// DeleteExpiredVerificationTokens removes the tokens of all tenants that expired before a moment
+func (r *AccountRepository) DeleteExpiredVerificationTokens(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(
+		ctx,
+		`DELETE FROM account_verification WHERE expires < $1`,
+		before,
+	)
+	if err != nil {
+		return 0, err
 	}
 
-	return accountID, nil
+	return result.RowsAffected()
+}
+
+// SetTotpSecret stores the secret of a two-factor enrollment that still has to be confirmed,
+// the secret of an enabled enrollment is never replaced
+func (r *AccountRepository) SetTotpSecret(ctx context.Context, accountID string, secret string) error {
+	result, err := r.db.ExecContext(
+		ctx,
+		`
+			UPDATE account
+			SET totp_secret = $1, totp_last_step = NULL
+			WHERE id = $2
+			AND tenant_id = $3
+			AND totp_enabled_at IS NULL
+		`,
+		secret,
+		accountID,
+		tenant.FromContext(ctx),
+	)
+
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	// The account is gone or enabled two-factor authentication in the meantime
+	if affected == 0 {
+		return repository.Conflict("account", accountID)
+	}
+
+	return nil
+}
+
+// GetTotpSecret gets the two-factor secret of an account
+func (r *AccountRepository) GetTotpSecret(ctx context.Context, accountID string) (string, error) {
+	var secret sql.NullString
+
+	err := r.db.QueryRowContext(
+		ctx,
+		`SELECT totp_secret FROM account WHERE id = $1 AND tenant_id = $2`,
+		accountID,
+		tenant.FromContext(ctx),
+	).Scan(&secret)
+
+	if err == sql.ErrNoRows {
+		return "", repository.NotFound("account", accountID)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if !secret.Valid {
+		return "", repository.ErrTotpNotEnrolled
+	}
+
+	return secret.String, nil
+}
+
+// EnableTotp enables two-factor authentication and replaces the recovery codes
+func (r *AccountRepository) EnableTotp(ctx context.Context, accountID string, recoveryCodeHashes []string) (*v1.Account, error) {
+	options := sql.TxOptions{
+		ReadOnly: false,
+	}
+
+	// Start a transaction so the recovery codes are never out of sync
+	tx, err := r.db.BeginTx(ctx, &options)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(
+		ctx,
+		`UPDATE account SET totp_enabled_at = $1 WHERE id = $2 AND tenant_id = $3 AND totp_secret IS NOT NULL`,
+		time.Now().UTC(),
+		accountID,
+		tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM account_recovery_code WHERE account_id = $1`, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, hash := range recoveryCodeHashes {
+		_, err = tx.ExecContext(
+			ctx,
+			`INSERT INTO account_recovery_code(account_id, hash) VALUES($1, $2)`,
+			accountID,
+			hash,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Get(ctx, accountID)
+}
+
+// DisableTotp disables two-factor authentication and removes the recovery codes
+func (r *AccountRepository) DisableTotp(ctx context.Context, accountID string) (*v1.Account, error) {
+	options := sql.TxOptions{
+		ReadOnly: false,
+	}
+
+	// Start a transaction
+	tx, err := r.db.BeginTx(ctx, &options)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(
+		ctx,
+		`
+			UPDATE account
+			SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = NULL
+			WHERE id = $1
+			AND tenant_id = $2
+		`,
+		accountID,
+		tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM account_recovery_code WHERE account_id = $1`, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Get(ctx, accountID)
+}
+
+// UseTotpStep records the time step of an accepted code,
+// steps that are not newer than the last one are refused so a code works only once
+func (r *AccountRepository) UseTotpStep(ctx context.Context, accountID string, step int64) error {
+	result, err := r.db.ExecContext(
+		ctx,
+		`
+			UPDATE account
+			SET totp_last_step = $1
+			WHERE id = $2
+			AND tenant_id = $3
+			AND (totp_last_step IS NULL OR totp_last_step < $1)
+		`,
+		step,
+		accountID,
+		tenant.FromContext(ctx),
+	)
+
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return repository.InvalidArgument("code", "is invalid or was already used")
+	}
+
+	return nil
+}
+
+// UseRecoveryCode marks a recovery code as used, used and unknown codes are refused
+func (r *AccountRepository) UseRecoveryCode(ctx context.Context, accountID string, hash string) error {
+	result, err := r.db.ExecContext(
+		ctx,
+		`
+			UPDATE account_recovery_code
+			SET used_at = $1
+			WHERE account_id = $2
+			AND hash = $3
+			AND used_at IS NULL
+			AND account_id IN (SELECT id FROM account WHERE tenant_id = $4)
+		`,
+		time.Now().UTC(),
+		accountID,
+		hash,
+		tenant.FromContext(ctx),
+	)
+
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return repository.InvalidArgument("code", "is invalid or was already used")
+	}
+
+	return nil
 }
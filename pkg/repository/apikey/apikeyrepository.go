@@ -0,0 +1,248 @@
+package apikeyrepository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
+	apikey "github.com/GameComponent/economy-service/pkg/apikey"
+	repository "github.com/GameComponent/economy-service/pkg/repository"
+	tenant "github.com/GameComponent/economy-service/pkg/tenant"
+	ptypes "github.com/golang/protobuf/ptypes"
+	pq "github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// cacheTTL is how long a key is used without checking the database,
+// and with that how long a revoked key keeps working on other instances
+const cacheTTL = 30 * time.Second
+
+// APIKeyRepository struct
+type APIKeyRepository struct {
+	db     *sql.DB
+	logger *zap.Logger
+	cache  *apikey.Cache
+}
+
+// NewAPIKeyRepository constructor
+func NewAPIKeyRepository(db *sql.DB, logger *zap.Logger) repository.APIKeyRepository {
+	return &APIKeyRepository{
+		db:     db,
+		logger: logger,
+		cache:  apikey.NewCache(cacheTTL),
+	}
+}
+
+const columns = `
+	id,
+	account_id,
+	tenant_id,
+	name,
+	prefix,
+	permissions,
+	roles,
+	created_at,
+	expires_at,
+	last_used_at,
+	revoked_at
+`
+
+// Create an API key for an account, only the hash of the key is stored
+func (r *APIKeyRepository) Create(ctx context.Context, accountID string, name string, prefix string, hash string, permissions []string, roles []string, expiresAt *time.Time) (*v1.ApiKey, error) {
+	lastInsertUUID := ""
+	err := r.db.QueryRowContext(
+		ctx,
+		`
+			INSERT INTO api_key(account_id, tenant_id, name, prefix, hash, permissions, roles, expires_at)
+			SELECT id, tenant_id, $2, $3, $4, $5, $6, $7
+			FROM account
+			WHERE id = $1
+			AND tenant_id = $8
+			RETURNING id
+		`,
+		accountID,
+		name,
+		prefix,
+		hash,
+		pq.Array(permissions),
+		pq.Array(roles),
+		pq.NullTime{Time: timeValue(expiresAt), Valid: expiresAt != nil},
+		tenant.FromContext(ctx),
+	).Scan(&lastInsertUUID)
+
+	if err == sql.ErrNoRows {
+		return nil, repository.NotFound("account", accountID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Get(ctx, lastInsertUUID)
+}
+
+// Get an API key
+func (r *APIKeyRepository) Get(ctx context.Context, apiKeyID string) (*v1.ApiKey, error) {
+	key, err := scan(r.db.QueryRowContext(
+		ctx,
+		`SELECT `+columns+` FROM api_key WHERE id = $1 AND tenant_id = $2`,
+		apiKeyID,
+		tenant.FromContext(ctx),
+	))
+	if err == sql.ErrNoRows {
+		return nil, repository.NotFound("api key", apiKeyID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// GetByHash gets an API key of any tenant by the hash of the key, keys are cached for
+// a short while so the last time a key was used is only tracked that precisely
+func (r *APIKeyRepository) GetByHash(ctx context.Context, hash string) (*v1.ApiKey, error) {
+	if key, ok := r.cache.Get(hash); ok {
+		return key, nil
+	}
+
+	key, err := scan(r.db.QueryRowContext(
+		ctx,
+		`
+			UPDATE api_key
+			SET last_used_at = current_timestamp()
+			WHERE hash = $1
+			RETURNING `+columns,
+		hash,
+	))
+	if err == sql.ErrNoRows {
+		return nil, repository.NotFound("api key", "")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r.cache.Set(hash, key)
+
+	return key, nil
+}
+
+// List the API keys of the tenant, optionally of a single account, the newest first
+func (r *APIKeyRepository) List(ctx context.Context, accountID string, page repository.Page) ([]*v1.ApiKey, repository.Cursor, int32, error) {
+	keyset, err := repository.NewKeyset(page, repository.Desc("created_at"), repository.Desc("id"))
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	query, args := keyset.Query(
+		`
+			SELECT
+				`+columns+`,
+				(SELECT COUNT(id) FROM api_key WHERE tenant_id = $1 AND ($2 = '' OR account_id::STRING = $2)) AS total_size
+			FROM api_key
+			WHERE tenant_id = $1
+			AND ($2 = '' OR account_id::STRING = $2)
+		`,
+		tenant.FromContext(ctx),
+		accountID,
+	)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	defer rows.Close()
+
+	// Unwrap rows into keys
+	keys := []*v1.ApiKey{}
+	totalSize := int32(0)
+
+	for rows.Next() {
+		key, err := scan(rows, &totalSize)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+
+		if !keyset.Add(key.CreatedAt, key.Id) {
+			break
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, keyset.Next(), totalSize, nil
+}
+
+// Revoke an API key
+func (r *APIKeyRepository) Revoke(ctx context.Context, apiKeyID string) (*v1.ApiKey, error) {
+	_, err := r.db.ExecContext(
+		ctx,
+		`UPDATE api_key SET revoked_at = current_timestamp() WHERE id = $1 AND tenant_id = $2 AND revoked_at IS NULL`,
+		apiKeyID,
+		tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Stop using the key on this instance right away
+	r.cache.Revoke(apiKeyID)
+
+	return r.Get(ctx, apiKeyID)
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scan reads a key from a row, extra destinations are scanned after the key's columns
+func scan(row scanner, extra ...interface{}) (*v1.ApiKey, error) {
+	key := v1.ApiKey{}
+	createdAt := time.Time{}
+	expiresAt := pq.NullTime{}
+	lastUsedAt := pq.NullTime{}
+	revokedAt := pq.NullTime{}
+
+	dest := []interface{}{
+		&key.Id,
+		&key.AccountId,
+		&key.TenantId,
+		&key.Name,
+		&key.Prefix,
+		pq.Array(&key.Permissions),
+		pq.Array(&key.Roles),
+		&createdAt,
+		&expiresAt,
+		&lastUsedAt,
+		&revokedAt,
+	}
+
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	key.CreatedAt, _ = ptypes.TimestampProto(createdAt)
+
+	if expiresAt.Valid {
+		key.ExpiresAt, _ = ptypes.TimestampProto(expiresAt.Time)
+	}
+
+	if lastUsedAt.Valid {
+		key.LastUsedAt, _ = ptypes.TimestampProto(lastUsedAt.Time)
+	}
+
+	if revokedAt.Valid {
+		key.RevokedAt, _ = ptypes.TimestampProto(revokedAt.Time)
+	}
+
+	return &key, nil
+}
+
+func timeValue(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+
+	return *t
+}
@@ -0,0 +1,175 @@
+package auditrepository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
+	repository "github.com/GameComponent/economy-service/pkg/repository"
+	tenant "github.com/GameComponent/economy-service/pkg/tenant"
+	ptypes "github.com/golang/protobuf/ptypes"
+	pq "github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// AuditRepository struct
+type AuditRepository struct {
+	db     *sql.DB
+	logger *zap.Logger
+}
+
+// NewAuditRepository constructor
+func NewAuditRepository(db *sql.DB, logger *zap.Logger) repository.AuditRepository {
+	return &AuditRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+const columns = `
+	id,
+	created_at,
+	account_id,
+	api_key_id,
+	method,
+	request,
+	outcome,
+	message,
+	request_id,
+	ip
+`
+
+// Record an audit event
+func (r *AuditRepository) Record(ctx context.Context, event *v1.AuditEvent) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`
+			INSERT INTO audit_event(tenant_id, account_id, api_key_id, method, request, outcome, message, request_id, ip)
+			VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		`,
+		tenant.FromContext(ctx),
+		event.GetAccountId(),
+		event.GetApiKeyId(),
+		event.GetMethod(),
+		event.GetRequest(),
+		event.GetOutcome(),
+		event.GetMessage(),
+		event.GetRequestId(),
+		event.GetIp(),
+	)
+
+	return err
+}
+
+// List audit events matching the filter, newest first
+func (r *AuditRepository) List(ctx context.Context, filter repository.AuditEventFilter, page repository.Page) ([]*v1.AuditEvent, repository.Cursor, int32, error) {
+	keyset, err := repository.NewKeyset(page, repository.Desc("created_at"), repository.Desc("id"))
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	startTime := pq.NullTime{}
+	if filter.StartTime != nil {
+		startTime = pq.NullTime{Time: *filter.StartTime, Valid: true}
+	}
+
+	endTime := pq.NullTime{}
+	if filter.EndTime != nil {
+		endTime = pq.NullTime{Time: *filter.EndTime, Valid: true}
+	}
+
+	conditions := `
+		WHERE tenant_id = $1
+		AND ($2 = '' OR account_id = $2)
+		AND ($3 = '' OR method LIKE $3)
+		AND ($4 = '' OR outcome = $4)
+		AND ($5::TIMESTAMPTZ IS NULL OR created_at >= $5)
+		AND ($6::TIMESTAMPTZ IS NULL OR created_at < $6)
+	`
+
+	query, args := keyset.Query(
+		`
+			SELECT
+				`+columns+`,
+				(SELECT COUNT(id) FROM audit_event `+conditions+`) AS total_size
+			FROM audit_event
+			`+conditions,
+		tenant.FromContext(ctx),
+		filter.AccountID,
+		likePattern(filter.Method),
+		filter.Outcome,
+		startTime,
+		endTime,
+	)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	defer rows.Close()
+
+	// Unwrap rows into audit events
+	events := []*v1.AuditEvent{}
+	totalSize := int32(0)
+
+	for rows.Next() {
+		event, err := scan(rows, &totalSize)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+
+		if !keyset.Add(event.CreatedAt, event.Id) {
+			break
+		}
+
+		events = append(events, event)
+	}
+
+	return events, keyset.Next(), totalSize, nil
+}
+
+// likePattern turns a method pattern like the ones of permissions into a LIKE pattern
+func likePattern(pattern string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`,
+		`%`, `\%`,
+		`_`, `\_`,
+		`*`, `%`,
+		`?`, `_`,
+	)
+
+	return replacer.Replace(pattern)
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scan(row scanner, extra ...interface{}) (*v1.AuditEvent, error) {
+	event := v1.AuditEvent{}
+	createdAt := time.Time{}
+
+	dest := []interface{}{
+		&event.Id,
+		&createdAt,
+		&event.AccountId,
+		&event.ApiKeyId,
+		&event.Method,
+		&event.Request,
+		&event.Outcome,
+		&event.Message,
+		&event.RequestId,
+		&event.Ip,
+	}
+
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	event.CreatedAt, _ = ptypes.TimestampProto(createdAt)
+
+	return &event, nil
+}
@@ -0,0 +1,435 @@
+package catalogrepository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	catalog "github.com/GameComponent/economy-service/pkg/catalog"
+	repository "github.com/GameComponent/economy-service/pkg/repository"
+	tenant "github.com/GameComponent/economy-service/pkg/tenant"
+	"go.uber.org/zap"
+)
+
+// CatalogRepository struct
+type CatalogRepository struct {
+	db     *sql.DB
+	logger *zap.Logger
+}
+
+// NewCatalogRepository constructor
+func NewCatalogRepository(db *sql.DB, logger *zap.Logger) repository.CatalogRepository {
+	return &CatalogRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Tables that hold the entries of the catalog
+var tables = map[string]string{
+	catalog.KindItem:     "item",
+	catalog.KindCurrency: "currency",
+	catalog.KindProduct:  "product",
+	catalog.KindPrice:    "price",
+	catalog.KindShop:     "shop",
+}
+
+// Export the catalog, archived entries are left out
+func (r *CatalogRepository) Export(ctx context.Context) (*catalog.Catalog, error) {
+	options := sql.TxOptions{
+		ReadOnly: true,
+	}
+
+	// Start a transaction so the export is consistent
+	tx, err := r.db.BeginTx(ctx, &options)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	return export(ctx, tx)
+}
+
+// Import plans the changes from the current to the desired catalog and applies them unless it is a dry run,
+// the catalog is read and changed in one transaction so the plan can't go stale in between
+func (r *CatalogRepository) Import(ctx context.Context, desired *catalog.Catalog, prune bool, dryRun bool) (*catalog.Plan, error) {
+	options := sql.TxOptions{
+		ReadOnly: dryRun,
+	}
+
+	// Start a transaction
+	tx, err := r.db.BeginTx(ctx, &options)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Imports of the tenant wait for each other, other changes to the catalog made
+	// in the meantime make the serializable transaction fail to commit instead
+	if !dryRun {
+		_, err = tx.ExecContext(
+			ctx,
+			`SELECT id FROM tenant WHERE id = $1 FOR UPDATE`,
+			tenant.FromContext(ctx),
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Plan against the catalog as it is within this transaction
+	current, err := export(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := catalog.Diff(current, desired, prune)
+	if err != nil {
+		return nil, repository.InvalidArgument("catalog", err.Error())
+	}
+
+	if dryRun || plan.Empty() {
+		return plan, nil
+	}
+
+	err = apply(ctx, tx, desired, plan)
+	if err != nil {
+		return nil, err
+	}
+
+	// Commit all changes to the database
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// apply the changes of a plan within a transaction
+func apply(ctx context.Context, tx *sql.Tx, desired *catalog.Catalog, plan *catalog.Plan) error {
+	var err error
+
+	// Resolve the keys of all entries, archived ones included
+	ids := map[string]map[string]string{}
+	for kind, table := range tables {
+		ids[kind], err = loadKeys(ctx, tx, table)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Index the desired entries by their key
+	items := map[string]*catalog.Item{}
+	for _, item := range desired.Items {
+		items[item.Key] = item
+	}
+
+	currencies := map[string]*catalog.Currency{}
+	for _, currency := range desired.Currencies {
+		currencies[currency.Key] = currency
+	}
+
+	products := map[string]*catalog.Product{}
+	prices := map[string]*catalog.Price{}
+	priceProducts := map[string]string{}
+	for _, product := range desired.Products {
+		products[product.Key] = product
+
+		for _, price := range product.Prices {
+			prices[price.Key] = price
+			priceProducts[price.Key] = product.Key
+		}
+	}
+
+	shops := map[string]*catalog.Shop{}
+	for _, shop := range desired.Shops {
+		shops[shop.Key] = shop
+	}
+
+	for _, change := range plan.Changes {
+		if change.Action == catalog.Archive {
+			err = archive(ctx, tx, change.Kind, ids[change.Kind][change.Key])
+			if err != nil {
+				return fmt.Errorf("unable to archive %s %s: %v", change.Kind, change.Key, err)
+			}
+
+			continue
+		}
+
+		switch change.Kind {
+		case catalog.KindItem:
+			err = saveItem(ctx, tx, ids, items[change.Key])
+		case catalog.KindCurrency:
+			err = saveCurrency(ctx, tx, ids, currencies[change.Key])
+		case catalog.KindProduct:
+			err = saveProduct(ctx, tx, ids, products[change.Key])
+		case catalog.KindPrice:
+			err = savePrice(ctx, tx, ids, prices[change.Key], priceProducts[change.Key])
+		case catalog.KindShop:
+			err = saveShop(ctx, tx, ids, shops[change.Key])
+		default:
+			err = fmt.Errorf("unknown kind")
+		}
+
+		if err != nil {
+			return fmt.Errorf("unable to %s %s %s: %v", change.Action, change.Kind, change.Key, err)
+		}
+	}
+
+	return nil
+}
+
+// loadKeys maps the keys of a table within the tenant to ids,
+// rows created through the API have no key yet so their id is used instead
+func loadKeys(ctx context.Context, tx *sql.Tx, table string) (map[string]string, error) {
+	rows, err := tx.QueryContext(
+		ctx,
+		fmt.Sprintf(`SELECT COALESCE(key, id::STRING), id FROM %s WHERE tenant_id = $1`, table),
+		tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := map[string]string{}
+	for rows.Next() {
+		key := ""
+		id := ""
+
+		if err := rows.Scan(&key, &id); err != nil {
+			return nil, err
+		}
+
+		ids[key] = id
+	}
+
+	return ids, nil
+}
+
+func archive(ctx context.Context, tx *sql.Tx, kind string, id string) error {
+	// Prices can not be archived, they are removed together with their contents
+	if kind == catalog.KindPrice {
+		queries := []string{
+			`DELETE FROM price_item WHERE price_id = $1`,
+			`DELETE FROM price_currency WHERE price_id = $1`,
+			`DELETE FROM price WHERE id = $1`,
+		}
+
+		for _, query := range queries {
+			if _, err := tx.ExecContext(ctx, query, id); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	_, err := tx.ExecContext(
+		ctx,
+		fmt.Sprintf(`UPDATE %s SET archived_at = current_timestamp() WHERE id = $1 AND archived_at IS NULL`, tables[kind]),
+		id,
+	)
+
+	return err
+}
+
+// save inserts a new row or updates the existing row with the same key,
+// archived rows are restored when their key returns in the catalog
+func save(ctx context.Context, tx *sql.Tx, ids map[string]map[string]string, kind string, key string, columns []string, values []interface{}) (string, error) {
+	table := tables[kind]
+
+	if id, ok := ids[kind][key]; ok {
+		set := "key = $1"
+		for i, column := range columns {
+			set = fmt.Sprintf("%s, %s = $%v", set, column, i+2)
+		}
+
+		if kind != catalog.KindPrice {
+			set = set + ", archived_at = NULL"
+		}
+
+		arguments := append([]interface{}{key}, values...)
+		arguments = append(arguments, id)
+
+		_, err := tx.ExecContext(
+			ctx,
+			fmt.Sprintf(`UPDATE %s SET %s, updated_at = current_timestamp() WHERE id = $%v`, table, set, len(arguments)),
+			arguments...,
+		)
+
+		return id, err
+	}
+
+	names := "tenant_id, key"
+	placeholders := "$1, $2"
+	for i, column := range columns {
+		names = fmt.Sprintf("%s, %s", names, column)
+		placeholders = fmt.Sprintf("%s, $%v", placeholders, i+3)
+	}
+
+	id := ""
+	err := tx.QueryRowContext(
+		ctx,
+		fmt.Sprintf(`INSERT INTO %s(%s) VALUES (%s) RETURNING id`, table, names, placeholders),
+		append([]interface{}{tenant.FromContext(ctx), key}, values...)...,
+	).Scan(&id)
+	if err != nil {
+		return "", err
+	}
+
+	ids[kind][key] = id
+
+	return id, nil
+}
+
+func saveItem(ctx context.Context, tx *sql.Tx, ids map[string]map[string]string, item *catalog.Item) error {
+	metadata := item.Metadata
+	if metadata == "" {
+		metadata = "{}"
+	}
+
+	_, err := save(
+		ctx,
+		tx,
+		ids,
+		catalog.KindItem,
+		item.Key,
+		[]string{"name", "stackable", "stack_max_amount", "stack_balancing_method", "metadata"},
+		[]interface{}{item.Name, item.Stackable, item.StackMaxAmount, int64(item.StackBalancingMethodValue()), metadata},
+	)
+
+	return err
+}
+
+func saveCurrency(ctx context.Context, tx *sql.Tx, ids map[string]map[string]string, currency *catalog.Currency) error {
+	_, err := save(
+		ctx,
+		tx,
+		ids,
+		catalog.KindCurrency,
+		currency.Key,
+		[]string{"name", "short_name", "symbol"},
+		[]interface{}{currency.Name, currency.ShortName, currency.Symbol},
+	)
+
+	return err
+}
+
+func saveProduct(ctx context.Context, tx *sql.Tx, ids map[string]map[string]string, product *catalog.Product) error {
+	productID, err := save(
+		ctx,
+		tx,
+		ids,
+		catalog.KindProduct,
+		product.Key,
+		[]string{"name"},
+		[]interface{}{product.Name},
+	)
+	if err != nil {
+		return err
+	}
+
+	// Replace the contents of the product
+	return replaceContents(ctx, tx, ids, "product", productID, product.Items, product.Currencies)
+}
+
+func savePrice(ctx context.Context, tx *sql.Tx, ids map[string]map[string]string, price *catalog.Price, productKey string) error {
+	priceID, err := save(
+		ctx,
+		tx,
+		ids,
+		catalog.KindPrice,
+		price.Key,
+		[]string{"product_id"},
+		[]interface{}{ids[catalog.KindProduct][productKey]},
+	)
+	if err != nil {
+		return err
+	}
+
+	// Replace the contents of the price
+	return replaceContents(ctx, tx, ids, "price", priceID, price.Items, price.Currencies)
+}
+
+func saveShop(ctx context.Context, tx *sql.Tx, ids map[string]map[string]string, shop *catalog.Shop) error {
+	metadata := shop.Metadata
+	if metadata == "" {
+		metadata = "{}"
+	}
+
+	shopID, err := save(
+		ctx,
+		tx,
+		ids,
+		catalog.KindShop,
+		shop.Key,
+		[]string{"name", "metadata"},
+		[]interface{}{shop.Name, metadata},
+	)
+	if err != nil {
+		return err
+	}
+
+	// Replace the products of the shop
+	_, err = tx.ExecContext(ctx, `DELETE FROM shop_product WHERE shop_id = $1`, shopID)
+	if err != nil {
+		return err
+	}
+
+	for _, product := range shop.Products {
+		_, err = tx.ExecContext(
+			ctx,
+			`INSERT INTO shop_product(shop_id, product_id) VALUES ($1, $2)`,
+			shopID,
+			ids[catalog.KindProduct][product],
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// replaceContents replaces the items and currencies of a product or price
+func replaceContents(ctx context.Context, tx *sql.Tx, ids map[string]map[string]string, owner string, ownerID string, items []*catalog.ItemAmount, currencies []*catalog.CurrencyAmount) error {
+	queries := []string{
+		fmt.Sprintf(`DELETE FROM %s_item WHERE %s_id = $1`, owner, owner),
+		fmt.Sprintf(`DELETE FROM %s_currency WHERE %s_id = $1`, owner, owner),
+	}
+
+	for _, query := range queries {
+		if _, err := tx.ExecContext(ctx, query, ownerID); err != nil {
+			return err
+		}
+	}
+
+	for _, item := range items {
+		_, err := tx.ExecContext(
+			ctx,
+			fmt.Sprintf(`INSERT INTO %s_item(%s_id, item_id, amount) VALUES ($1, $2, $3)`, owner, owner),
+			ownerID,
+			ids[catalog.KindItem][item.Item],
+			item.Amount,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, currency := range currencies {
+		_, err := tx.ExecContext(
+			ctx,
+			fmt.Sprintf(`INSERT INTO %s_currency(%s_id, currency_id, amount) VALUES ($1, $2, $3)`, owner, owner),
+			ownerID,
+			ids[catalog.KindCurrency][currency.Currency],
+			currency.Amount,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
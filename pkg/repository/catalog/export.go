@@ -0,0 +1,380 @@
+package catalogrepository
+
+import (
+	"context"
+	"database/sql"
+
+	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
+	catalog "github.com/GameComponent/economy-service/pkg/catalog"
+	tenant "github.com/GameComponent/economy-service/pkg/tenant"
+)
+
+// export the catalog within a transaction
+func export(ctx context.Context, tx *sql.Tx) (*catalog.Catalog, error) {
+	var err error
+	result := &catalog.Catalog{}
+
+	result.Items, err = exportItems(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Currencies, err = exportCurrencies(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Products, err = exportProducts(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Shops, err = exportShops(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func exportItems(ctx context.Context, tx *sql.Tx) ([]*catalog.Item, error) {
+	rows, err := tx.QueryContext(
+		ctx,
+		`
+			SELECT
+				COALESCE(key, id::STRING),
+				name,
+				stackable,
+				stack_max_amount,
+				stack_balancing_method,
+				metadata
+			FROM item
+			WHERE archived_at IS NULL
+			AND tenant_id = $1
+			ORDER BY created_at
+		`,
+		tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*catalog.Item{}
+	for rows.Next() {
+		item := catalog.Item{}
+		stackBalancingMethod := int64(0)
+
+		err := rows.Scan(
+			&item.Key,
+			&item.Name,
+			&item.Stackable,
+			&item.StackMaxAmount,
+			&stackBalancingMethod,
+			&item.Metadata,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		// Leave out the defaults to keep the document short
+		if stackBalancingMethod != int64(v1.StackBalancingMethod_DEFAULT) {
+			item.StackBalancingMethod = v1.StackBalancingMethod(stackBalancingMethod).String()
+		}
+
+		if item.Metadata == "{}" {
+			item.Metadata = ""
+		}
+
+		items = append(items, &item)
+	}
+
+	return items, nil
+}
+
+func exportCurrencies(ctx context.Context, tx *sql.Tx) ([]*catalog.Currency, error) {
+	rows, err := tx.QueryContext(
+		ctx,
+		`
+			SELECT
+				COALESCE(key, id::STRING),
+				name,
+				short_name,
+				symbol
+			FROM currency
+			WHERE archived_at IS NULL
+			AND tenant_id = $1
+			ORDER BY created_at
+		`,
+		tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	currencies := []*catalog.Currency{}
+	for rows.Next() {
+		currency := catalog.Currency{}
+
+		err := rows.Scan(
+			&currency.Key,
+			&currency.Name,
+			&currency.ShortName,
+			&currency.Symbol,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		currencies = append(currencies, &currency)
+	}
+
+	return currencies, nil
+}
+
+func exportProducts(ctx context.Context, tx *sql.Tx) ([]*catalog.Product, error) {
+	rows, err := tx.QueryContext(
+		ctx,
+		`
+			SELECT
+				id,
+				COALESCE(key, id::STRING),
+				name
+			FROM product
+			WHERE archived_at IS NULL
+			AND tenant_id = $1
+			ORDER BY created_at
+		`,
+		tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := []*catalog.Product{}
+	productIDs := map[string]*catalog.Product{}
+	for rows.Next() {
+		id := ""
+		product := catalog.Product{}
+
+		err := rows.Scan(
+			&id,
+			&product.Key,
+			&product.Name,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		products = append(products, &product)
+		productIDs[id] = &product
+	}
+
+	// Add the contents of the products
+	err = exportContents(
+		ctx,
+		tx,
+		"product",
+		func(ownerID string, item *catalog.ItemAmount) {
+			if product, ok := productIDs[ownerID]; ok {
+				product.Items = append(product.Items, item)
+			}
+		},
+		func(ownerID string, currency *catalog.CurrencyAmount) {
+			if product, ok := productIDs[ownerID]; ok {
+				product.Currencies = append(product.Currencies, currency)
+			}
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Add the prices of the products
+	priceRows, err := tx.QueryContext(
+		ctx,
+		`
+			SELECT
+				id,
+				COALESCE(key, id::STRING),
+				product_id
+			FROM price
+			WHERE tenant_id = $1
+			ORDER BY created_at
+		`,
+		tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer priceRows.Close()
+
+	priceIDs := map[string]*catalog.Price{}
+	for priceRows.Next() {
+		id := ""
+		productID := ""
+		price := catalog.Price{}
+
+		err := priceRows.Scan(
+			&id,
+			&price.Key,
+			&productID,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if product, ok := productIDs[productID]; ok {
+			product.Prices = append(product.Prices, &price)
+			priceIDs[id] = &price
+		}
+	}
+
+	// Add the contents of the prices
+	err = exportContents(
+		ctx,
+		tx,
+		"price",
+		func(ownerID string, item *catalog.ItemAmount) {
+			if price, ok := priceIDs[ownerID]; ok {
+				price.Items = append(price.Items, item)
+			}
+		},
+		func(ownerID string, currency *catalog.CurrencyAmount) {
+			if price, ok := priceIDs[ownerID]; ok {
+				price.Currencies = append(price.Currencies, currency)
+			}
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return products, nil
+}
+
+func exportShops(ctx context.Context, tx *sql.Tx) ([]*catalog.Shop, error) {
+	rows, err := tx.QueryContext(
+		ctx,
+		`
+			SELECT
+				shop.id,
+				COALESCE(shop.key, shop.id::STRING),
+				shop.name,
+				shop.metadata,
+				COALESCE(product.key, product.id::STRING)
+			FROM shop
+			LEFT JOIN shop_product ON (shop.id = shop_product.shop_id)
+			LEFT JOIN product ON (shop_product.product_id = product.id AND product.archived_at IS NULL)
+			WHERE shop.archived_at IS NULL
+			AND shop.tenant_id = $1
+			ORDER BY shop.created_at
+		`,
+		tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shops := []*catalog.Shop{}
+	shopIDs := map[string]*catalog.Shop{}
+	for rows.Next() {
+		id := ""
+		shop := catalog.Shop{}
+		product := sql.NullString{}
+
+		err := rows.Scan(
+			&id,
+			&shop.Key,
+			&shop.Name,
+			&shop.Metadata,
+			&product,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := shopIDs[id]; !ok {
+			if shop.Metadata == "{}" {
+				shop.Metadata = ""
+			}
+
+			shops = append(shops, &shop)
+			shopIDs[id] = &shop
+		}
+
+		if product.Valid {
+			shopIDs[id].Products = append(shopIDs[id].Products, product.String)
+		}
+	}
+
+	return shops, nil
+}
+
+// exportContents reads the items and currencies of all products or prices
+func exportContents(ctx context.Context, tx *sql.Tx, owner string, addItem func(string, *catalog.ItemAmount), addCurrency func(string, *catalog.CurrencyAmount)) error {
+	itemRows, err := tx.QueryContext(
+		ctx,
+		`
+			SELECT
+				`+owner+`_item.`+owner+`_id,
+				COALESCE(item.key, item.id::STRING),
+				`+owner+`_item.amount
+			FROM `+owner+`_item
+			INNER JOIN `+owner+` ON (`+owner+`_item.`+owner+`_id = `+owner+`.id)
+			INNER JOIN item ON (`+owner+`_item.item_id = item.id)
+			WHERE `+owner+`.tenant_id = $1
+		`,
+		tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return err
+	}
+	defer itemRows.Close()
+
+	for itemRows.Next() {
+		ownerID := ""
+		item := catalog.ItemAmount{}
+
+		if err := itemRows.Scan(&ownerID, &item.Item, &item.Amount); err != nil {
+			return err
+		}
+
+		addItem(ownerID, &item)
+	}
+
+	currencyRows, err := tx.QueryContext(
+		ctx,
+		`
+			SELECT
+				`+owner+`_currency.`+owner+`_id,
+				COALESCE(currency.key, currency.id::STRING),
+				`+owner+`_currency.amount
+			FROM `+owner+`_currency
+			INNER JOIN `+owner+` ON (`+owner+`_currency.`+owner+`_id = `+owner+`.id)
+			INNER JOIN currency ON (`+owner+`_currency.currency_id = currency.id)
+			WHERE `+owner+`.tenant_id = $1
+		`,
+		tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return err
+	}
+	defer currencyRows.Close()
+
+	for currencyRows.Next() {
+		ownerID := ""
+		currency := catalog.CurrencyAmount{}
+
+		if err := currencyRows.Scan(&ownerID, &currency.Currency, &currency.Amount); err != nil {
+			return err
+		}
+
+		addCurrency(ownerID, &currency)
+	}
+
+	return nil
+}
@@ -0,0 +1,341 @@
+package catalogrepository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
+	catalog "github.com/GameComponent/economy-service/pkg/catalog"
+	repository "github.com/GameComponent/economy-service/pkg/repository"
+	tenant "github.com/GameComponent/economy-service/pkg/tenant"
+	ptypes "github.com/golang/protobuf/ptypes"
+	pq "github.com/lib/pq"
+)
+
+// CreateVersion creates a draft catalog version
+func (r *CatalogRepository) CreateVersion(ctx context.Context, name string, document string) (*v1.CatalogVersion, error) {
+	// Add the version to the database and return the generated UUID
+	lastInsertUUID := ""
+	err := r.db.QueryRowContext(
+		ctx,
+		`INSERT INTO catalog_version(name, state, document, tenant_id) VALUES ($1, $2, $3, $4) RETURNING id`,
+		name,
+		int64(v1.CatalogVersionState_CATALOG_VERSION_DRAFT),
+		document,
+		tenant.FromContext(ctx),
+	).Scan(&lastInsertUUID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetVersion(ctx, lastInsertUUID)
+}
+
+// UpdateVersion updates a draft catalog version
+func (r *CatalogRepository) UpdateVersion(ctx context.Context, catalogVersionID string, name string, document string) (*v1.CatalogVersion, error) {
+	index := 1
+	queries := []string{}
+	arguments := []interface{}{}
+
+	// Add name to the query
+	if name != "" {
+		queries = append(queries, fmt.Sprintf("name = $%v", index))
+		arguments = append(arguments, name)
+		index++
+	}
+
+	// Add document to the query
+	if document != "" {
+		queries = append(queries, fmt.Sprintf("document = $%v", index))
+		arguments = append(arguments, document)
+		index++
+	}
+
+	if index <= 1 {
+		return nil, repository.ErrNothingToUpdate
+	}
+
+	// Only drafts can be changed
+	arguments = append(arguments, catalogVersionID, int64(v1.CatalogVersionState_CATALOG_VERSION_DRAFT), tenant.FromContext(ctx))
+	query := fmt.Sprintf(
+		"UPDATE catalog_version SET %v, updated_at = current_timestamp() WHERE id = $%v AND state = $%v AND tenant_id = $%v",
+		strings.Join(queries, ", "),
+		index,
+		index+1,
+		index+2,
+	)
+
+	result, err := r.db.ExecContext(
+		ctx,
+		query,
+		arguments...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Nothing changed when the version is missing or no longer a draft
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		_, err := r.GetVersion(ctx, catalogVersionID)
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, repository.ErrCatalogVersionNotDraft
+	}
+
+	return r.GetVersion(ctx, catalogVersionID)
+}
+
+// GetVersion gets a catalog version
+func (r *CatalogRepository) GetVersion(ctx context.Context, catalogVersionID string) (*v1.CatalogVersion, error) {
+	version, err := scanVersion(r.db.QueryRowContext(
+		ctx,
+		`
+			SELECT id, created_at, updated_at, published_at, name, state, document
+			FROM catalog_version
+			WHERE id = $1
+			AND tenant_id = $2
+		`,
+		catalogVersionID,
+		tenant.FromContext(ctx),
+	))
+
+	if err == sql.ErrNoRows {
+		return nil, repository.NotFound("catalog version", catalogVersionID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return version, nil
+}
+
+// GetPreviousVersion gets the most recently published version that is no longer live
+func (r *CatalogRepository) GetPreviousVersion(ctx context.Context) (*v1.CatalogVersion, error) {
+	version, err := scanVersion(r.db.QueryRowContext(
+		ctx,
+		`
+			SELECT id, created_at, updated_at, published_at, name, state, document
+			FROM catalog_version
+			WHERE state = $1
+			AND tenant_id = $2
+			ORDER BY published_at DESC
+			LIMIT 1
+		`,
+		int64(v1.CatalogVersionState_CATALOG_VERSION_ARCHIVED),
+		tenant.FromContext(ctx),
+	))
+
+	if err == sql.ErrNoRows {
+		return nil, repository.NotFound("previous catalog version", "")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return version, nil
+}
+
+// ListVersions lists all catalog versions, the newest first, the documents are left out
+func (r *CatalogRepository) ListVersions(ctx context.Context, page repository.Page) ([]*v1.CatalogVersion, repository.Cursor, int32, error) {
+	keyset, err := repository.NewKeyset(page, repository.Desc("created_at"), repository.Desc("id"))
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	query, args := keyset.Query(
+		`
+			SELECT
+				id,
+				created_at,
+				updated_at,
+				published_at,
+				name,
+				state,
+				(SELECT COUNT(id) FROM catalog_version WHERE tenant_id = $1) AS total_size
+			FROM catalog_version
+			WHERE tenant_id = $1
+		`,
+		tenant.FromContext(ctx),
+	)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	defer rows.Close()
+
+	// Unwrap rows into versions
+	versions := []*v1.CatalogVersion{}
+	totalSize := int32(0)
+
+	for rows.Next() {
+		version := v1.CatalogVersion{}
+		createdAt := time.Time{}
+		updatedAt := time.Time{}
+		publishedAt := pq.NullTime{}
+		state := int64(0)
+
+		err := rows.Scan(
+			&version.Id,
+			&createdAt,
+			&updatedAt,
+			&publishedAt,
+			&version.Name,
+			&state,
+			&totalSize,
+		)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+
+		if !keyset.Add(createdAt, version.Id) {
+			break
+		}
+
+		version.CreatedAt, _ = ptypes.TimestampProto(createdAt)
+		version.UpdatedAt, _ = ptypes.TimestampProto(updatedAt)
+		version.State = v1.CatalogVersionState(state)
+
+		if publishedAt.Valid {
+			version.PublishedAt, _ = ptypes.TimestampProto(publishedAt.Time)
+		}
+
+		versions = append(versions, &version)
+	}
+
+	return versions, keyset.Next(), totalSize, nil
+}
+
+// PublishVersion makes a catalog version the live catalog,
+// the previously published version is kept for rollbacks
+func (r *CatalogRepository) PublishVersion(ctx context.Context, catalogVersionID string) (*v1.CatalogVersion, *catalog.Plan, error) {
+	options := sql.TxOptions{
+		ReadOnly: false,
+	}
+
+	// Start a transaction
+	tx, err := r.db.BeginTx(ctx, &options)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	version, err := scanVersion(tx.QueryRowContext(
+		ctx,
+		`
+			SELECT id, created_at, updated_at, published_at, name, state, document
+			FROM catalog_version
+			WHERE id = $1
+			AND tenant_id = $2
+			FOR UPDATE
+		`,
+		catalogVersionID,
+		tenant.FromContext(ctx),
+	))
+	if err == sql.ErrNoRows {
+		return nil, nil, repository.NotFound("catalog version", catalogVersionID)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if version.State == v1.CatalogVersionState_CATALOG_VERSION_PUBLISHED {
+		return nil, nil, repository.ErrCatalogVersionPublished
+	}
+
+	desired, err := catalog.Parse([]byte(version.Catalog))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Plan against the catalog as it is within this transaction
+	current, err := export(ctx, tx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	plan, err := catalog.Diff(current, desired, true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = apply(ctx, tx, desired, plan)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Replace the published version
+	_, err = tx.ExecContext(
+		ctx,
+		`UPDATE catalog_version SET state = $1, updated_at = current_timestamp() WHERE state = $2 AND tenant_id = $3`,
+		int64(v1.CatalogVersionState_CATALOG_VERSION_ARCHIVED),
+		int64(v1.CatalogVersionState_CATALOG_VERSION_PUBLISHED),
+		tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`
+			UPDATE catalog_version
+			SET state = $1, published_at = current_timestamp(), updated_at = current_timestamp()
+			WHERE id = $2
+		`,
+		int64(v1.CatalogVersionState_CATALOG_VERSION_PUBLISHED),
+		catalogVersionID,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Commit all changes to the database
+	if err = tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+
+	version, err = r.GetVersion(ctx, catalogVersionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return version, plan, nil
+}
+
+func scanVersion(row *sql.Row) (*v1.CatalogVersion, error) {
+	version := v1.CatalogVersion{}
+	createdAt := time.Time{}
+	updatedAt := time.Time{}
+	publishedAt := pq.NullTime{}
+	state := int64(0)
+
+	err := row.Scan(
+		&version.Id,
+		&createdAt,
+		&updatedAt,
+		&publishedAt,
+		&version.Name,
+		&state,
+		&version.Catalog,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	version.CreatedAt, _ = ptypes.TimestampProto(createdAt)
+	version.UpdatedAt, _ = ptypes.TimestampProto(updatedAt)
+	version.State = v1.CatalogVersionState(state)
+
+	if publishedAt.Valid {
+		version.PublishedAt, _ = ptypes.TimestampProto(publishedAt.Time)
+	}
+
+	return &version, nil
+}
@@ -6,6 +6,7 @@ import (
 
 	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
 	repository "github.com/GameComponent/economy-service/pkg/repository"
+	tenant "github.com/GameComponent/economy-service/pkg/tenant"
 	"go.uber.org/zap"
 )
 
@@ -31,10 +32,14 @@ func (r *ConfigRepository) Get(ctx context.Context, key string) (*v1.Config, err
 
 	err := r.db.QueryRowContext(
 		ctx,
-		`SELECT value FROM config WHERE key = $1`,
+		`SELECT value FROM config WHERE key = $1 AND tenant_id = $2`,
 		key,
+		tenant.FromContext(ctx),
 	).Scan(&config.Value)
 
+	if err == sql.ErrNoRows {
+		return nil, repository.NotFound("config", key)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -47,14 +52,15 @@ func (r *ConfigRepository) Set(ctx context.Context, key string, value string) (*
 	_, err := r.db.ExecContext(
 		ctx,
 		`
-			INSERT INTO config(key, value)
-			VALUES ($1, $2)
-			ON CONFLICT(key)
+			INSERT INTO config(tenant_id, key, value)
+			VALUES ($3, $1, $2)
+			ON CONFLICT(tenant_id, key)
 			DO UPDATE
 			SET value = excluded.value
 		`,
 		key,
 		value,
+		tenant.FromContext(ctx),
 	)
 
 	if err != nil {
@@ -67,26 +73,29 @@ func (r *ConfigRepository) Set(ctx context.Context, key string, value string) (*
 	}, nil
 }
 
-// List all configs
-func (r *ConfigRepository) List(ctx context.Context, limit int32, offset int32) ([]*v1.Config, int32, error) {
-	// Query configs from the database
-	rows, err := r.db.QueryContext(
-		ctx,
+// List all configs, sorted by key
+func (r *ConfigRepository) List(ctx context.Context, page repository.Page) ([]*v1.Config, repository.Cursor, int32, error) {
+	keyset, err := repository.NewKeyset(page, repository.Asc("key"))
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	query, args := keyset.Query(
 		`
 			SELECT 
 				key,
 				value,
-				(SELECT COUNT(*) FROM config) AS total_size
+				(SELECT COUNT(*) FROM config WHERE tenant_id = $1) AS total_size
 			FROM config
-			LIMIT $1
-			OFFSET $2
+			WHERE tenant_id = $1
 		`,
-		limit,
-		offset,
+		tenant.FromContext(ctx),
 	)
 
+	// Query configs from the database
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, 0, err
+		return nil, nil, 0, err
 	}
 	defer rows.Close()
 
@@ -103,11 +112,15 @@ func (r *ConfigRepository) List(ctx context.Context, limit int32, offset int32)
 			&totalSize,
 		)
 		if err != nil {
-			return nil, 0, err
+			return nil, nil, 0, err
+		}
+
+		if !keyset.Add(config.Key) {
+			break
 		}
 
 		configs = append(configs, &config)
 	}
 
-	return configs, totalSize, nil
+	return configs, keyset.Next(), totalSize, nil
 }
@@ -9,7 +9,9 @@ import (
 
 	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
 	repository "github.com/GameComponent/economy-service/pkg/repository"
+	tenant "github.com/GameComponent/economy-service/pkg/tenant"
 	"github.com/golang/protobuf/ptypes"
+	pq "github.com/lib/pq"
 	"go.uber.org/zap"
 )
 
@@ -33,10 +35,11 @@ func (r *CurrencyRepository) Create(ctx context.Context, name string, shortName
 	lastInsertUUID := ""
 	err := r.db.QueryRowContext(
 		ctx,
-		`INSERT INTO currency(name, short_name, symbol) VALUES ($1, $2, $3) RETURNING id`,
+		`INSERT INTO currency(name, short_name, symbol, tenant_id) VALUES ($1, $2, $3, $4) RETURNING id`,
 		name,
 		shortName,
 		symbol,
+		tenant.FromContext(ctx),
 	).Scan(
 		&lastInsertUUID,
 	)
@@ -83,12 +86,12 @@ func (r *CurrencyRepository) Update(ctx context.Context, currencyID string, name
 	}
 
 	if index <= 1 {
-		return nil, fmt.Errorf("no arguments given")
+		return nil, repository.ErrNothingToUpdate
 	}
 
 	// Update the currency
-	arguments = append(arguments, currencyID)
-	query := fmt.Sprintf("UPDATE currency SET %v WHERE id =$%v", strings.Join(queries, ", "), index)
+	arguments = append(arguments, currencyID, tenant.FromContext(ctx))
+	query := fmt.Sprintf("UPDATE currency SET %v WHERE id =$%v AND tenant_id = $%v", strings.Join(queries, ", "), index, index+1)
 	_, err := r.db.ExecContext(
 		ctx,
 		query,
@@ -104,30 +107,61 @@ func (r *CurrencyRepository) Update(ctx context.Context, currencyID string, name
 // Get a currency
 func (r *CurrencyRepository) Get(ctx context.Context, currencyID string) (*v1.Currency, error) {
 	currency := &v1.Currency{}
+	archivedAt := pq.NullTime{}
 
 	err := r.db.QueryRowContext(
 		ctx,
-		`SELECT id, name, short_name, symbol FROM currency WHERE id = $1`,
+		`SELECT id, name, short_name, symbol, archived_at FROM currency WHERE id = $1 AND tenant_id = $2`,
 		currencyID,
+		tenant.FromContext(ctx),
 	).Scan(
 		&currency.Id,
 		&currency.Name,
 		&currency.ShortName,
 		&currency.Symbol,
+		&archivedAt,
 	)
 
+	if err == sql.ErrNoRows {
+		return nil, repository.NotFound("currency", currencyID)
+	}
 	if err != nil {
 		return nil, err
 	}
 
+	// Archived currencies are still resolvable
+	if archivedAt.Valid {
+		currency.ArchivedAt, _ = ptypes.TimestampProto(archivedAt.Time)
+	}
+
 	return currency, nil
 }
 
-// List all currenciees
-func (r *CurrencyRepository) List(ctx context.Context, limit int32, offset int32) ([]*v1.Currency, int32, error) {
-	// Query items from the database
-	rows, err := r.db.QueryContext(
-		ctx,
+// currencyFields are the fields currencies can be filtered and sorted by
+var currencyFields = repository.Fields{
+	"id":         {Column: "id", Type: repository.UUIDField},
+	"name":       {Column: "name", Type: repository.StringField, Sortable: true},
+	"short_name": {Column: "short_name", Type: repository.StringField, Sortable: true},
+	"symbol":     {Column: "symbol", Type: repository.StringField},
+	"created_at": {Column: "created_at", Type: repository.TimestampField, Sortable: true},
+	"updated_at": {Column: "updated_at", Type: repository.TimestampField, Sortable: true},
+	"metadata":   {Column: "metadata", Type: repository.JSONField},
+}
+
+// List the currencies matching the filter of the page, the newest first unless the page has an order
+func (r *CurrencyRepository) List(ctx context.Context, page repository.Page) ([]*v1.Currency, repository.Cursor, int32, error) {
+	filter, keyset, err := repository.NewFilteredKeyset(page, currencyFields, repository.Desc("created_at"), repository.Desc("id"))
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	condition, args := filter.Condition([]interface{}{tenant.FromContext(ctx)})
+	conditions := `
+		WHERE archived_at IS NULL
+		AND tenant_id = $1
+		AND ` + condition
+
+	query, args := keyset.Query(
 		`
 			SELECT 
 				id,
@@ -136,17 +170,16 @@ func (r *CurrencyRepository) List(ctx context.Context, limit int32, offset int32
 				symbol,
 				created_at,
 				updated_at,
-				(SELECT COUNT(*) FROM currency) AS total_size
+				(SELECT COUNT(*) FROM currency `+conditions+`) AS total_size
 			FROM currency
-			LIMIT $1
-			OFFSET $2
-		`,
-		limit,
-		offset,
+		`+conditions,
+		args...,
 	)
 
+	// Query items from the database
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, 0, err
+		return nil, nil, 0, err
 	}
 	defer rows.Close()
 
@@ -169,15 +202,101 @@ func (r *CurrencyRepository) List(ctx context.Context, limit int32, offset int32
 			&totalSize,
 		)
 		if err != nil {
-			return nil, 0, err
+			return nil, nil, 0, err
 		}
 
 		// Convert created_at to timestamp
 		currency.CreatedAt, _ = ptypes.TimestampProto(createdAt)
 		currency.UpdatedAt, _ = ptypes.TimestampProto(updatedAt)
 
+		if !keyset.AddMessage(&currency) {
+			break
+		}
+
 		currencies = append(currencies, &currency)
 	}
 
-	return currencies, totalSize, nil
+	return currencies, keyset.Next(), totalSize, nil
+}
+
+// Archive a currency
+func (r *CurrencyRepository) Archive(ctx context.Context, currencyID string) (*v1.Currency, error) {
+	_, err := r.db.ExecContext(
+		ctx,
+		`UPDATE currency SET archived_at = current_timestamp() WHERE id = $1 AND tenant_id = $2 AND archived_at IS NULL`,
+		currencyID,
+		tenant.FromContext(ctx),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Get(ctx, currencyID)
+}
+
+// Delete a currency, force also deletes the prices, products and storages that reference it
+func (r *CurrencyRepository) Delete(ctx context.Context, currencyID string, force bool) (bool, error) {
+	options := sql.TxOptions{
+		ReadOnly: false,
+	}
+
+	// Start a transaction
+	tx, err := r.db.BeginTx(ctx, &options)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// Make sure the currency belongs to the tenant before touching its references
+	found, err := repository.InTenant(ctx, tx, "currency", currencyID)
+	if err != nil {
+		return false, err
+	}
+
+	if !found {
+		return false, repository.NotFound("currency", currencyID)
+	}
+
+	references := []repository.Reference{
+		{Table: "price_currency", Column: "currency_id"},
+		{Table: "product_currency", Column: "currency_id"},
+		{Table: "storage_currency", Column: "currency_id"},
+	}
+
+	// Refuse to delete currencies that are still in use unless forced
+	referenced, err := repository.IsReferenced(ctx, tx, currencyID, references...)
+	if err != nil {
+		return false, err
+	}
+
+	if referenced && !force {
+		return false, repository.Referenced("currency", currencyID)
+	}
+
+	err = repository.DeleteReferences(ctx, tx, currencyID, references...)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := tx.ExecContext(
+		ctx,
+		`DELETE FROM currency WHERE id = $1`,
+		currencyID,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	// Make sure the currency existed
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return false, repository.NotFound("currency", currencyID)
+	}
+
+	// Commit all changes to the database
+	if err = tx.Commit(); err != nil {
+		return false, err
+	}
+
+	return true, nil
 }
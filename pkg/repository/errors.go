@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	pq "github.com/lib/pq"
+)
+
+// uniqueViolation is the SQL state of an insert or update that breaks a unique constraint
+const uniqueViolation = "23505"
+
+// Reasons of the errors caused by the state of the data, clients receive them as the reason
+// of the google.rpc.ErrorInfo of the failed request
+const (
+	ReasonNotFound                = "NOT_FOUND"
+	ReasonAlreadyExists           = "ALREADY_EXISTS"
+	ReasonConflict                = "CONFLICT"
+	ReasonInvalidArgument         = "INVALID_ARGUMENT"
+	ReasonInsufficientFunds       = "INSUFFICIENT_FUNDS"
+	ReasonInsufficientItems       = "INSUFFICIENT_ITEMS"
+	ReasonStackFull               = "STACK_FULL"
+	ReasonReferenced              = "REFERENCED"
+	ReasonCatalogVersionNotDraft  = "CATALOG_VERSION_NOT_DRAFT"
+	ReasonCatalogVersionPublished = "CATALOG_VERSION_PUBLISHED"
+	ReasonRefreshTokenReused      = "REFRESH_TOKEN_REUSED"
+	ReasonTotpNotEnrolled         = "TOTP_NOT_ENROLLED"
+)
+
+// Error is returned when the state of the data does not allow a change, as opposed to
+// the database failing, the service passes it on to clients
+type Error struct {
+	Reason  string
+	Message string
+
+	// Metadata tells which resource the error is about, like the id of the currency that ran out
+	Metadata map[string]string
+
+	// Field is the request field that was invalid for ReasonInvalidArgument
+	Field string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is makes errors.Is match errors of the same reason, so errors.Is(err, ErrNotFound)
+// holds for every resource that was not found
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Reason == e.Reason
+}
+
+// Errors to compare with errors.Is
+var (
+	ErrNotFound          = &Error{Reason: ReasonNotFound, Message: "not found"}
+	ErrAlreadyExists     = &Error{Reason: ReasonAlreadyExists, Message: "already exists"}
+	ErrConflict          = &Error{Reason: ReasonConflict, Message: "changed concurrently"}
+	ErrInvalidArgument   = &Error{Reason: ReasonInvalidArgument, Message: "invalid argument"}
+	ErrInsufficientFunds = &Error{Reason: ReasonInsufficientFunds, Message: "insufficient funds"}
+	ErrInsufficientItems = &Error{Reason: ReasonInsufficientItems, Message: "insufficient items"}
+	ErrStackFull         = &Error{Reason: ReasonStackFull, Message: "stack is full"}
+)
+
+// ErrNothingToUpdate is returned when an update request has no fields to change
+var ErrNothingToUpdate = &Error{Reason: ReasonInvalidArgument, Message: "no fields given to update"}
+
+// IsUniqueViolation checks if the database refused a row because its unique key is taken
+func IsUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation
+}
+
+// NotFound is returned when a resource does not exist within the tenant, resource is its
+// singular name like storage or item
+func NotFound(resource string, id string) error {
+	return &Error{
+		Reason:   ReasonNotFound,
+		Message:  fmt.Sprintf("%s not found", resource),
+		Metadata: map[string]string{"resource": resource, "id": id},
+	}
+}
+
+// AlreadyExists is returned when a resource with the same unique key exists
+func AlreadyExists(resource string, key string) error {
+	return &Error{
+		Reason:   ReasonAlreadyExists,
+		Message:  fmt.Sprintf("%s already exists", resource),
+		Metadata: map[string]string{"resource": resource, "key": key},
+	}
+}
+
+// Conflict is returned when a resource changed between reading and writing it,
+// retrying the request can succeed
+func Conflict(resource string, id string) error {
+	return &Error{
+		Reason:   ReasonConflict,
+		Message:  fmt.Sprintf("%s changed concurrently, try again", resource),
+		Metadata: map[string]string{"resource": resource, "id": id},
+	}
+}
+
+// InvalidArgument is returned when a field of the request does not fit the data
+func InvalidArgument(field string, description string) error {
+	return &Error{
+		Reason:  ReasonInvalidArgument,
+		Message: fmt.Sprintf("%s: %s", field, description),
+		Field:   field,
+	}
+}
+
+// InsufficientFunds is returned when a storage does not hold enough of a currency
+func InsufficientFunds(storageID string, currencyID string) error {
+	return &Error{
+		Reason:   ReasonInsufficientFunds,
+		Message:  fmt.Sprintf("not enough of currency %s in the storage", currencyID),
+		Metadata: map[string]string{"storage_id": storageID, "currency_id": currencyID},
+	}
+}
+
+// InsufficientItems is returned when a storage does not hold enough of an item
+func InsufficientItems(storageID string, itemID string) error {
+	return &Error{
+		Reason:   ReasonInsufficientItems,
+		Message:  fmt.Sprintf("not enough of item %s in the storage", itemID),
+		Metadata: map[string]string{"storage_id": storageID, "item_id": itemID},
+	}
+}
+
+// StackFull is returned when items do not fit in the stacks of a storage
+func StackFull(storageID string, itemID string) error {
+	return &Error{
+		Reason:   ReasonStackFull,
+		Message:  fmt.Sprintf("the stacks of item %s can not hold the amount", itemID),
+		Metadata: map[string]string{"storage_id": storageID, "item_id": itemID},
+	}
+}
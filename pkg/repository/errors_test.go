@@ -0,0 +1,41 @@
+package repository_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	repository "github.com/GameComponent/economy-service/pkg/repository"
+	pq "github.com/lib/pq"
+)
+
+func TestErrorIs(t *testing.T) {
+	err := fmt.Errorf("buying: %w", repository.NotFound("storage", "wallet"))
+
+	if !errors.Is(err, repository.ErrNotFound) {
+		t.Error("errors.Is(NotFound, ErrNotFound) = false, want true")
+	}
+
+	if errors.Is(err, repository.ErrConflict) {
+		t.Error("errors.Is(NotFound, ErrConflict) = true, want false")
+	}
+
+	var repositoryErr *repository.Error
+	if !errors.As(err, &repositoryErr) || repositoryErr.Metadata["id"] != "wallet" {
+		t.Errorf("errors.As() = %v, want the storage id in the metadata", repositoryErr)
+	}
+}
+
+func TestIsUniqueViolation(t *testing.T) {
+	if !repository.IsUniqueViolation(&pq.Error{Code: "23505"}) {
+		t.Error("IsUniqueViolation(23505) = false, want true")
+	}
+
+	if repository.IsUniqueViolation(&pq.Error{Code: "23503"}) {
+		t.Error("IsUniqueViolation(23503) = true, want false")
+	}
+
+	if repository.IsUniqueViolation(errors.New("connection refused")) {
+		t.Error("IsUniqueViolation(connection refused) = true, want false")
+	}
+}
@@ -0,0 +1,510 @@
+package repository
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// FieldType tells how the values of a filter field are read and compared
+type FieldType int
+
+const (
+	// StringField values are compared as text
+	StringField FieldType = iota
+	// UUIDField values have to be UUIDs and can only be compared for equality
+	UUIDField
+	// BoolField values are true or false and can only be compared for equality
+	BoolField
+	// IntField values are whole numbers
+	IntField
+	// TimestampField values are RFC 3339 timestamps, like "2019-10-27T12:00:00Z"
+	TimestampField
+	// JSONField is a JSONB object, metadata.key compares the text of a key and metadata:key
+	// matches objects that have the key
+	JSONField
+)
+
+// Field is a field clients can filter or sort a list by, only the fields of a list are
+// ever put into SQL, the values always go in as arguments
+type Field struct {
+	Column string
+	Type   FieldType
+
+	// Sortable fields can be used in order_by, their column has to be NOT NULL and named
+	// like the field of the message, the keyset reads the cursor from the message
+	Sortable bool
+
+	// In matches rows whose column is in the rows the query selects, %s is the placeholder
+	// of the value, like products by the shops they are in
+	In string
+}
+
+// Fields are the fields of a list by their name in filters
+type Fields map[string]Field
+
+// maxFilterDepth limits how deep filters nest parentheses and NOTs
+const maxFilterDepth = 32
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// Filter is a parsed filter expression in the syntax of https://google.aip.dev/160, limited to
+// comparisons of fields with values joined by AND, OR, NOT and parentheses, like
+//
+//	stackable = true AND (name = "Sword" OR metadata.rarity != "common")
+//
+// as in AIP-160 OR binds stronger than AND, so a AND b OR c is a AND (b OR c)
+type Filter struct {
+	root condition
+}
+
+// ParseFilter parses a filter expression over the fields of a list, an empty expression matches every row
+func ParseFilter(expression string, fields Fields) (*Filter, error) {
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return nil, InvalidArgument("filter", err.Error())
+	}
+
+	if len(tokens) == 0 {
+		return &Filter{}, nil
+	}
+
+	p := &filterParser{tokens: tokens, fields: fields}
+	root, err := p.expression(0)
+	if err != nil {
+		return nil, InvalidArgument("filter", err.Error())
+	}
+
+	if !p.done() {
+		return nil, InvalidArgument("filter", fmt.Sprintf("expected AND or OR before %s", p.peek()))
+	}
+
+	return &Filter{root: root}, nil
+}
+
+// Condition returns the SQL condition of the filter, its values are appended to the arguments
+// and numbered after them, TRUE when the filter is empty
+func (f *Filter) Condition(args []interface{}) (string, []interface{}) {
+	if f.root == nil {
+		return "TRUE", args
+	}
+
+	return f.root.sql(&args), args
+}
+
+// ParseOrderBy parses a comma separated list of sortable fields, each optionally followed by
+// desc or asc, like "name, created_at desc". The last of the default orders is unique and is
+// added to break ties, without an order_by the list keeps its default orders
+func ParseOrderBy(orderBy string, fields Fields, defaults ...Order) ([]Order, error) {
+	if strings.TrimSpace(orderBy) == "" {
+		return defaults, nil
+	}
+
+	orders := []Order{}
+	seen := map[string]bool{}
+
+	for _, part := range strings.Split(orderBy, ",") {
+		words := strings.Fields(part)
+		if len(words) == 0 || len(words) > 2 {
+			return nil, InvalidArgument("order_by", fmt.Sprintf("%q is not a field optionally followed by asc or desc", strings.TrimSpace(part)))
+		}
+
+		field, ok := fields[words[0]]
+		if !ok || !field.Sortable {
+			return nil, InvalidArgument("order_by", fmt.Sprintf("can not sort by %q", words[0]))
+		}
+
+		if seen[field.Column] {
+			return nil, InvalidArgument("order_by", fmt.Sprintf("sorts by %q more than once", words[0]))
+		}
+		seen[field.Column] = true
+
+		order := Asc(field.Column)
+		if len(words) == 2 {
+			switch strings.ToLower(words[1]) {
+			case "asc":
+			case "desc":
+				order.Descending = true
+			default:
+				return nil, InvalidArgument("order_by", fmt.Sprintf("%q is not asc or desc", words[1]))
+			}
+		}
+
+		orders = append(orders, order)
+	}
+
+	if len(defaults) > 0 {
+		unique := defaults[len(defaults)-1]
+		if !seen[unique.Column] {
+			orders = append(orders, unique)
+		}
+	}
+
+	return orders, nil
+}
+
+// NewFilteredKeyset parses the filter and order_by of a page over the fields of a list,
+// and starts reading the page in that order, the orders are used when there is no order_by
+func NewFilteredKeyset(page Page, fields Fields, orders ...Order) (*Filter, *Keyset, error) {
+	filter, err := ParseFilter(page.Filter, fields)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	orders, err = ParseOrderBy(page.OrderBy, fields, orders...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyset, err := NewKeyset(page, orders...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return filter, keyset, nil
+}
+
+// condition is a node of a parsed filter
+type condition interface {
+	sql(args *[]interface{}) string
+}
+
+// junction joins conditions with AND or OR
+type junction struct {
+	operator   string
+	conditions []condition
+}
+
+func (j junction) sql(args *[]interface{}) string {
+	parts := make([]string, len(j.conditions))
+	for i, c := range j.conditions {
+		parts[i] = c.sql(args)
+	}
+
+	return "(" + strings.Join(parts, " "+j.operator+" ") + ")"
+}
+
+// negation matches the rows a condition does not match
+type negation struct {
+	condition condition
+}
+
+func (n negation) sql(args *[]interface{}) string {
+	return "NOT " + n.condition.sql(args)
+}
+
+// comparison compares a field with a value, key is the key of a JSON field
+type comparison struct {
+	field    Field
+	key      string
+	operator string
+	value    interface{}
+}
+
+func (c comparison) sql(args *[]interface{}) string {
+	placeholder := func(value interface{}) string {
+		*args = append(*args, value)
+		return fmt.Sprintf("$%d", len(*args))
+	}
+
+	switch {
+	case c.field.In != "":
+		return fmt.Sprintf("%s IN (%s)", c.field.Column, fmt.Sprintf(c.field.In, placeholder(c.value)))
+	case c.operator == ":":
+		return fmt.Sprintf("%s ? %s", c.field.Column, placeholder(c.value))
+	case c.field.Type == JSONField:
+		key := placeholder(c.key)
+		return fmt.Sprintf("%s->>%s %s %s", c.field.Column, key, c.operator, placeholder(c.value))
+	default:
+		return fmt.Sprintf("%s %s %s", c.field.Column, c.operator, placeholder(c.value))
+	}
+}
+
+type tokenKind int
+
+const (
+	wordToken tokenKind = iota
+	stringToken
+	operatorToken
+	openToken
+	closeToken
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func (t token) String() string {
+	if t.kind == stringToken {
+		return strconv.Quote(t.text)
+	}
+
+	return fmt.Sprintf("%q", t.text)
+}
+
+// tokenize splits a filter into words, quoted strings, comparators and parentheses
+func tokenize(expression string) ([]token, error) {
+	tokens := []token{}
+	runes := []rune(expression)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: openToken, text: "("})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: closeToken, text: ")"})
+			i++
+		case r == '<' || r == '>' || r == '!':
+			if i+1 < len(runes) && runes[i+1] == '=' {
+				tokens = append(tokens, token{kind: operatorToken, text: string(runes[i : i+2])})
+				i += 2
+				continue
+			}
+			if r == '!' {
+				return nil, fmt.Errorf("expected = after ! at position %d", i+1)
+			}
+			tokens = append(tokens, token{kind: operatorToken, text: string(r)})
+			i++
+		case r == '=' || r == ':':
+			tokens = append(tokens, token{kind: operatorToken, text: string(r)})
+			i++
+		case r == '"' || r == '\'':
+			text := []rune{}
+			j := i + 1
+			for ; j < len(runes) && runes[j] != r; j++ {
+				if runes[j] == '\\' && j+1 < len(runes) {
+					j++
+				}
+				text = append(text, runes[j])
+			}
+			if j == len(runes) {
+				return nil, fmt.Errorf("string at position %d is not closed", i+1)
+			}
+			tokens = append(tokens, token{kind: stringToken, text: string(text)})
+			i = j + 1
+		default:
+			j := i
+			for j < len(runes) && !unicode.IsSpace(runes[j]) && !strings.ContainsRune(`()<>!=:"'`, runes[j]) {
+				j++
+			}
+			tokens = append(tokens, token{kind: wordToken, text: string(runes[i:j])})
+			i = j
+		}
+	}
+
+	return tokens, nil
+}
+
+// filterParser parses the tokens of a filter, a grammar of
+//
+//	expression  = factor {"AND" factor}
+//	factor      = term {"OR" term}
+//	term        = ["NOT"] simple
+//	simple      = "(" expression ")" | restriction
+//	restriction = field comparator value
+type filterParser struct {
+	tokens   []token
+	position int
+	fields   Fields
+}
+
+func (p *filterParser) done() bool {
+	return p.position == len(p.tokens)
+}
+
+func (p *filterParser) peek() token {
+	return p.tokens[p.position]
+}
+
+// keyword reports if the next token is the keyword and takes it
+func (p *filterParser) keyword(keyword string) bool {
+	if p.done() || p.peek().kind != wordToken || p.peek().text != keyword {
+		return false
+	}
+
+	p.position++
+	return true
+}
+
+func (p *filterParser) next(expected string) (token, error) {
+	if p.done() {
+		return token{}, fmt.Errorf("expected %s at the end", expected)
+	}
+
+	t := p.peek()
+	p.position++
+	return t, nil
+}
+
+func (p *filterParser) expression(depth int) (condition, error) {
+	return p.join("AND", depth, p.factor)
+}
+
+func (p *filterParser) factor(depth int) (condition, error) {
+	return p.join("OR", depth, p.term)
+}
+
+// join parses operands separated by an operator
+func (p *filterParser) join(operator string, depth int, operand func(int) (condition, error)) (condition, error) {
+	first, err := operand(depth)
+	if err != nil {
+		return nil, err
+	}
+
+	conditions := []condition{first}
+	for p.keyword(operator) {
+		c, err := operand(depth)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, c)
+	}
+
+	if len(conditions) == 1 {
+		return first, nil
+	}
+
+	return junction{operator: operator, conditions: conditions}, nil
+}
+
+func (p *filterParser) term(depth int) (condition, error) {
+	if depth > maxFilterDepth {
+		return nil, fmt.Errorf("nests more than %d levels deep", maxFilterDepth)
+	}
+
+	if p.keyword("NOT") {
+		c, err := p.term(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return negation{condition: c}, nil
+	}
+
+	if !p.done() && p.peek().kind == openToken {
+		p.position++
+
+		c, err := p.expression(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		t, err := p.next(")")
+		if err != nil {
+			return nil, err
+		}
+		if t.kind != closeToken {
+			return nil, fmt.Errorf("expected ) instead of %s", t)
+		}
+
+		return c, nil
+	}
+
+	return p.restriction()
+}
+
+func (p *filterParser) restriction() (condition, error) {
+	name, err := p.next("a field")
+	if err != nil {
+		return nil, err
+	}
+	if name.kind != wordToken || name.text == "AND" || name.text == "OR" {
+		return nil, fmt.Errorf("expected a field instead of %s", name)
+	}
+
+	field, key, err := p.field(name.text)
+	if err != nil {
+		return nil, err
+	}
+
+	operator, err := p.next("a comparator")
+	if err != nil {
+		return nil, err
+	}
+	if operator.kind != operatorToken {
+		return nil, fmt.Errorf("expected a comparator after %s instead of %s", name, operator)
+	}
+
+	value, err := p.next("a value")
+	if err != nil {
+		return nil, err
+	}
+	if value.kind != wordToken && value.kind != stringToken {
+		return nil, fmt.Errorf("expected a value after %s instead of %s", operator, value)
+	}
+
+	c := comparison{field: field, key: key, operator: operator.text}
+	if c.value, err = fieldValue(name.text, c, value.text); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// field finds a field by its name, names like metadata.rarity are keys of JSON fields
+func (p *filterParser) field(name string) (Field, string, error) {
+	if field, ok := p.fields[name]; ok {
+		return field, "", nil
+	}
+
+	parts := strings.SplitN(name, ".", 2)
+	if field, ok := p.fields[parts[0]]; ok && len(parts) == 2 && field.Type == JSONField && parts[1] != "" {
+		return field, parts[1], nil
+	}
+
+	return Field{}, "", fmt.Errorf("can not filter by %q", name)
+}
+
+// fieldValue checks the comparator of a comparison and reads its value for the type of the field
+func fieldValue(name string, c comparison, text string) (interface{}, error) {
+	equality := c.operator == "=" || c.operator == "!="
+
+	switch {
+	case c.operator == ":":
+		if c.field.Type != JSONField || c.key != "" {
+			return nil, fmt.Errorf("%s does not support :, only objects like metadata:key do", name)
+		}
+		return text, nil
+	case c.field.Type == JSONField && c.key == "":
+		return nil, fmt.Errorf("%s can only be filtered by its keys, like %s.key or %s:key", name, name, name)
+	case c.field.In != "" && c.operator != "=":
+		return nil, fmt.Errorf("%s only supports =", name)
+	case !equality && (c.field.Type == UUIDField || c.field.Type == BoolField || c.field.Type == JSONField):
+		return nil, fmt.Errorf("%s only supports = and !=", name)
+	}
+
+	switch c.field.Type {
+	case UUIDField:
+		if !uuidPattern.MatchString(text) {
+			return nil, fmt.Errorf("%s has to be compared with a UUID", name)
+		}
+		return text, nil
+	case BoolField:
+		if text != "true" && text != "false" {
+			return nil, fmt.Errorf("%s has to be compared with true or false", name)
+		}
+		return text == "true", nil
+	case IntField:
+		value, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s has to be compared with a whole number", name)
+		}
+		return value, nil
+	case TimestampField:
+		value, err := time.Parse(time.RFC3339Nano, text)
+		if err != nil {
+			return nil, fmt.Errorf(`%s has to be compared with a quoted RFC 3339 timestamp, like "2019-10-27T12:00:00Z"`, name)
+		}
+		return value, nil
+	default:
+		return text, nil
+	}
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testFields = Fields{
+	"id":               {Column: "id", Type: UUIDField},
+	"name":             {Column: "name", Type: StringField, Sortable: true},
+	"stackable":        {Column: "stackable", Type: BoolField},
+	"stack_max_amount": {Column: "stack_max_amount", Type: IntField, Sortable: true},
+	"created_at":       {Column: "created_at", Type: TimestampField, Sortable: true},
+	"metadata":         {Column: "metadata", Type: JSONField},
+	"shop_id":          {Column: "id", Type: UUIDField, In: "SELECT product_id FROM shop_product WHERE shop_id = %s"},
+}
+
+func TestFilterEmpty(t *testing.T) {
+	filter, err := ParseFilter("  ", testFields)
+	assert.NoError(t, err)
+
+	condition, args := filter.Condition([]interface{}{"default"})
+
+	assert.Equal(t, "TRUE", condition)
+	assert.Equal(t, []interface{}{"default"}, args)
+}
+
+func TestFilterComparisons(t *testing.T) {
+	filter, err := ParseFilter(`stackable = true AND stack_max_amount >= 10 AND name != "Iron Sword"`, testFields)
+	assert.NoError(t, err)
+
+	condition, args := filter.Condition([]interface{}{"default"})
+
+	assert.Equal(t, "(stackable = $2 AND stack_max_amount >= $3 AND name != $4)", condition)
+	assert.Equal(t, []interface{}{"default", true, int64(10), "Iron Sword"}, args)
+}
+
+func TestFilterPrecedence(t *testing.T) {
+	// OR binds stronger than AND
+	filter, err := ParseFilter(`name = a AND name = b OR NOT (name = 'c')`, testFields)
+	assert.NoError(t, err)
+
+	condition, args := filter.Condition(nil)
+
+	assert.Equal(t, "(name = $1 AND (name = $2 OR NOT name = $3))", condition)
+	assert.Equal(t, []interface{}{"a", "b", "c"}, args)
+}
+
+func TestFilterTimestamp(t *testing.T) {
+	filter, err := ParseFilter(`created_at > "2019-10-27T12:00:00Z"`, testFields)
+	assert.NoError(t, err)
+
+	condition, args := filter.Condition(nil)
+
+	assert.Equal(t, "created_at > $1", condition)
+	assert.Equal(t, []interface{}{time.Date(2019, 10, 27, 12, 0, 0, 0, time.UTC)}, args)
+}
+
+func TestFilterMetadata(t *testing.T) {
+	filter, err := ParseFilter(`metadata.rarity = "epic" AND metadata:color`, testFields)
+	assert.NoError(t, err)
+
+	condition, args := filter.Condition(nil)
+
+	assert.Equal(t, "(metadata->>$1 = $2 AND metadata ? $3)", condition)
+	assert.Equal(t, []interface{}{"rarity", "epic", "color"}, args)
+}
+
+func TestFilterIn(t *testing.T) {
+	filter, err := ParseFilter(`shop_id = "8b1d4b7c-5c1a-4a2e-9f57-3c1b8b3f0d2a"`, testFields)
+	assert.NoError(t, err)
+
+	condition, args := filter.Condition([]interface{}{"default"})
+
+	assert.Equal(t, "id IN (SELECT product_id FROM shop_product WHERE shop_id = $2)", condition)
+	assert.Equal(t, []interface{}{"default", "8b1d4b7c-5c1a-4a2e-9f57-3c1b8b3f0d2a"}, args)
+}
+
+func TestFilterInvalid(t *testing.T) {
+	filters := []string{
+		`unknown = 1`,
+		`name`,
+		`name =`,
+		`name = a name = b`,
+		`(name = a`,
+		`name = a)`,
+		`name = "a`,
+		`name ! a`,
+		`name = a AND`,
+		`stackable = yes`,
+		`stackable > true`,
+		`stack_max_amount = many`,
+		`created_at > yesterday`,
+		`id = 1`,
+		`metadata = a`,
+		`name:a`,
+		`shop_id != "8b1d4b7c-5c1a-4a2e-9f57-3c1b8b3f0d2a"`,
+		`name = "x"; DROP TABLE item`,
+	}
+
+	for _, filter := range filters {
+		_, err := ParseFilter(filter, testFields)
+		assert.True(t, errors.Is(err, ErrInvalidArgument), filter)
+	}
+}
+
+func TestOrderBy(t *testing.T) {
+	orders, err := ParseOrderBy("", testFields, Desc("created_at"), Desc("id"))
+	assert.NoError(t, err)
+	assert.Equal(t, []Order{Desc("created_at"), Desc("id")}, orders)
+
+	// The unique order of the list breaks ties
+	orders, err = ParseOrderBy("name, stack_max_amount DESC", testFields, Desc("created_at"), Desc("id"))
+	assert.NoError(t, err)
+	assert.Equal(t, []Order{Asc("name"), Desc("stack_max_amount"), Desc("id")}, orders)
+}
+
+func TestOrderByInvalid(t *testing.T) {
+	orderBys := []string{
+		"stackable",
+		"unknown",
+		"name sideways",
+		"name desc extra",
+		"name,",
+		"name, name desc",
+	}
+
+	for _, orderBy := range orderBys {
+		_, err := ParseOrderBy(orderBy, testFields, Desc("created_at"), Desc("id"))
+		assert.True(t, errors.Is(err, ErrInvalidArgument), orderBy)
+	}
+}
@@ -9,7 +9,9 @@ import (
 
 	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
 	repository "github.com/GameComponent/economy-service/pkg/repository"
+	tenant "github.com/GameComponent/economy-service/pkg/tenant"
 	ptypes "github.com/golang/protobuf/ptypes"
+	pq "github.com/lib/pq"
 	"go.uber.org/zap"
 )
 
@@ -43,14 +45,16 @@ func (r *ItemRepository) Create(ctx context.Context, name string, stackable bool
 				stackable,
 				stack_max_amount,
 				stack_balancing_method,
-				metadata
+				metadata,
+				tenant_id
 			)
 			VALUES (
 				$1,
 				$2,
 				$3,
 				$4,
-				$5
+				$5,
+				$6
 			)
 			RETURNING id
 		`,
@@ -59,6 +63,7 @@ func (r *ItemRepository) Create(ctx context.Context, name string, stackable bool
 		stackMaxAmount,
 		stackBalancingMethod,
 		metadata,
+		tenant.FromContext(ctx),
 	).Scan(&lastInsertUUID)
 
 	if err != nil {
@@ -89,12 +94,12 @@ func (r *ItemRepository) Update(ctx context.Context, itemID string, name string,
 	}
 
 	if index <= 1 {
-		return nil, fmt.Errorf("no arguments given")
+		return nil, repository.ErrNothingToUpdate
 	}
 
 	// Update the item
-	arguments = append(arguments, itemID)
-	query := fmt.Sprintf("UPDATE item SET %v WHERE id =$%v", strings.Join(queries, ", "), index)
+	arguments = append(arguments, itemID, tenant.FromContext(ctx))
+	query := fmt.Sprintf("UPDATE item SET %v WHERE id =$%v AND tenant_id = $%v", strings.Join(queries, ", "), index, index+1)
 	_, err := r.db.ExecContext(
 		ctx,
 		query,
@@ -108,29 +113,49 @@ func (r *ItemRepository) Update(ctx context.Context, itemID string, name string,
 	return r.Get(ctx, itemID)
 }
 
-// List all items
-func (r *ItemRepository) List(ctx context.Context, limit int32, offset int32) ([]*v1.Item, int32, error) {
-	// Query items from the database
-	rows, err := r.db.QueryContext(
-		ctx,
+// itemFields are the fields items can be filtered and sorted by
+var itemFields = repository.Fields{
+	"id":               {Column: "id", Type: repository.UUIDField},
+	"name":             {Column: "name", Type: repository.StringField, Sortable: true},
+	"stackable":        {Column: "stackable", Type: repository.BoolField},
+	"stack_max_amount": {Column: "stack_max_amount", Type: repository.IntField, Sortable: true},
+	"created_at":       {Column: "created_at", Type: repository.TimestampField, Sortable: true},
+	"updated_at":       {Column: "updated_at", Type: repository.TimestampField, Sortable: true},
+	"metadata":         {Column: "metadata", Type: repository.JSONField},
+}
+
+// List the items matching the filter of the page, the newest first unless the page has an order
+func (r *ItemRepository) List(ctx context.Context, page repository.Page) ([]*v1.Item, repository.Cursor, int32, error) {
+	filter, keyset, err := repository.NewFilteredKeyset(page, itemFields, repository.Desc("created_at"), repository.Desc("id"))
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	condition, args := filter.Condition([]interface{}{tenant.FromContext(ctx)})
+	conditions := `
+		WHERE archived_at IS NULL
+		AND tenant_id = $1
+		AND ` + condition
+
+	query, args := keyset.Query(
 		`
 			SELECT 
 				id,
 				name,
+				stackable,
+				stack_max_amount,
 				created_at,
 				updated_at,
-				(SELECT COUNT(*) FROM item) AS total_size
+				(SELECT COUNT(*) FROM item `+conditions+`) AS total_size
 			FROM item
-			ORDER BY created_at DESC 
-			LIMIT $1
-			OFFSET $2
-		`,
-		limit,
-		offset,
+		`+conditions,
+		args...,
 	)
 
+	// Query items from the database
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, 0, err
+		return nil, nil, 0, err
 	}
 	defer rows.Close()
 
@@ -146,22 +171,28 @@ func (r *ItemRepository) List(ctx context.Context, limit int32, offset int32) ([
 		err := rows.Scan(
 			&item.Id,
 			&item.Name,
+			&item.Stackable,
+			&item.StackMaxAmount,
 			&createdAt,
 			&updatedAt,
 			&totalSize,
 		)
 		if err != nil {
-			return nil, 0, err
+			return nil, nil, 0, err
 		}
 
 		// Convert created_at to timestamp
 		item.CreatedAt, _ = ptypes.TimestampProto(createdAt)
 		item.UpdatedAt, _ = ptypes.TimestampProto(updatedAt)
 
+		if !keyset.AddMessage(&item) {
+			break
+		}
+
 		items = append(items, &item)
 	}
 
-	return items, totalSize, nil
+	return items, keyset.Next(), totalSize, nil
 }
 
 // Get an item
@@ -169,6 +200,7 @@ func (r *ItemRepository) Get(ctx context.Context, itemID string) (*v1.Item, erro
 	item := &v1.Item{}
 	createdAt := time.Time{}
 	updatedAt := time.Time{}
+	archivedAt := pq.NullTime{}
 
 	err := r.db.QueryRowContext(
 		ctx,
@@ -181,11 +213,14 @@ func (r *ItemRepository) Get(ctx context.Context, itemID string) (*v1.Item, erro
 				stack_balancing_method,
 				created_at,
 				updated_at,
+				archived_at,
 				metadata
 			FROM item
 			WHERE id = $1
+			AND tenant_id = $2
 		`,
 		itemID,
+		tenant.FromContext(ctx),
 	).Scan(
 		&item.Id,
 		&item.Name,
@@ -194,8 +229,12 @@ func (r *ItemRepository) Get(ctx context.Context, itemID string) (*v1.Item, erro
 		&item.StackBalancingMethod,
 		&createdAt,
 		&updatedAt,
+		&archivedAt,
 		&item.Metadata,
 	)
+	if err == sql.ErrNoRows {
+		return nil, repository.NotFound("item", itemID)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -204,33 +243,42 @@ func (r *ItemRepository) Get(ctx context.Context, itemID string) (*v1.Item, erro
 	item.CreatedAt, _ = ptypes.TimestampProto(createdAt)
 	item.UpdatedAt, _ = ptypes.TimestampProto(updatedAt)
 
+	// Archived items are still resolvable
+	if archivedAt.Valid {
+		item.ArchivedAt, _ = ptypes.TimestampProto(archivedAt.Time)
+	}
+
 	return item, nil
 }
 
-// Search item
-func (r *ItemRepository) Search(ctx context.Context, query string, limit int32, offset int32) ([]*v1.Item, int32, error) {
-	// Query items from the database
-	rows, err := r.db.QueryContext(
-		ctx,
+// Search item, the newest first
+func (r *ItemRepository) Search(ctx context.Context, search string, page repository.Page) ([]*v1.Item, repository.Cursor, int32, error) {
+	keyset, err := repository.NewKeyset(page, repository.Desc("created_at"), repository.Desc("id"))
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	query, args := keyset.Query(
 		`
 			SELECT
 				id,
 				name,
 				created_at,
 				updated_at,
-				(SELECT COUNT(id) FROM item WHERE name ~* $1) AS total_size
+				(SELECT COUNT(id) FROM item WHERE name ~* $1 AND archived_at IS NULL AND tenant_id = $2) AS total_size
 			FROM item
 			WHERE name ~* $1
-			LIMIT $2
-			OFFSET $3
+			AND archived_at IS NULL
+			AND tenant_id = $2
 		`,
-		query,
-		limit,
-		offset,
+		search,
+		tenant.FromContext(ctx),
 	)
 
+	// Query items from the database
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, 0, err
+		return nil, nil, 0, err
 	}
 	defer rows.Close()
 
@@ -251,7 +299,11 @@ func (r *ItemRepository) Search(ctx context.Context, query string, limit int32,
 			&totalSize,
 		)
 		if err != nil {
-			return nil, 0, err
+			return nil, nil, 0, err
+		}
+
+		if !keyset.Add(createdAt, item.Id) {
+			break
 		}
 
 		// Convert created_at to timestamp
@@ -261,5 +313,87 @@ func (r *ItemRepository) Search(ctx context.Context, query string, limit int32,
 		items = append(items, &item)
 	}
 
-	return items, totalSize, nil
+	return items, keyset.Next(), totalSize, nil
+}
+
+// Archive an item
+func (r *ItemRepository) Archive(ctx context.Context, itemID string) (*v1.Item, error) {
+	_, err := r.db.ExecContext(
+		ctx,
+		`UPDATE item SET archived_at = current_timestamp() WHERE id = $1 AND tenant_id = $2 AND archived_at IS NULL`,
+		itemID,
+		tenant.FromContext(ctx),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Get(ctx, itemID)
+}
+
+// Delete an item, force also deletes the prices, products and storages that reference it
+func (r *ItemRepository) Delete(ctx context.Context, itemID string, force bool) (bool, error) {
+	options := sql.TxOptions{
+		ReadOnly: false,
+	}
+
+	// Start a transaction
+	tx, err := r.db.BeginTx(ctx, &options)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// Make sure the item belongs to the tenant before touching its references
+	found, err := repository.InTenant(ctx, tx, "item", itemID)
+	if err != nil {
+		return false, err
+	}
+
+	if !found {
+		return false, repository.NotFound("item", itemID)
+	}
+
+	references := []repository.Reference{
+		{Table: "price_item", Column: "item_id"},
+		{Table: "product_item", Column: "item_id"},
+		{Table: "storage_item", Column: "item_id"},
+	}
+
+	// Refuse to delete items that are still in use unless forced
+	referenced, err := repository.IsReferenced(ctx, tx, itemID, references...)
+	if err != nil {
+		return false, err
+	}
+
+	if referenced && !force {
+		return false, repository.Referenced("item", itemID)
+	}
+
+	err = repository.DeleteReferences(ctx, tx, itemID, references...)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := tx.ExecContext(
+		ctx,
+		`DELETE FROM item WHERE id = $1`,
+		itemID,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	// Make sure the item existed
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return false, repository.NotFound("item", itemID)
+	}
+
+	// Commit all changes to the database
+	if err = tx.Commit(); err != nil {
+		return false, err
+	}
+
+	return true, nil
 }
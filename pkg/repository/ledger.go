@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
+	tenant "github.com/GameComponent/economy-service/pkg/tenant"
+)
+
+// Reasons stored with a ledger entry
+const (
+	LedgerReasonGiveItem     = "give_item"
+	LedgerReasonGiveCurrency = "give_currency"
+	LedgerReasonPurchase     = "purchase"
+)
+
+// RecordPurchase stores a purchase and returns its id
+func RecordPurchase(ctx context.Context, tx *sql.Tx, productID string, priceID string, receivingStorageID string, payingStorageID string) (string, error) {
+	// The player and tenant are taken from the paying storage and the purchase
+	// is tied to the catalog version that is live at the time of buying
+	purchaseID := ""
+	err := tx.QueryRowContext(
+		ctx,
+		`
+			INSERT INTO purchase(tenant_id, player_id, product_id, price_id, receiving_storage_id, paying_storage_id, catalog_version_id)
+			SELECT
+				tenant_id,
+				player_id,
+				$2,
+				$3,
+				$4,
+				id,
+				(SELECT catalog_version.id FROM catalog_version WHERE catalog_version.state = 1 AND catalog_version.tenant_id = storage.tenant_id LIMIT 1)
+			FROM storage
+			WHERE id = $1
+			AND tenant_id = $5
+			RETURNING id
+		`,
+		payingStorageID,
+		productID,
+		priceID,
+		receivingStorageID,
+		tenant.FromContext(ctx),
+	).Scan(&purchaseID)
+
+	if err != nil {
+		return "", err
+	}
+
+	return purchaseID, nil
+}
+
+// RecordLedgerEntry stores a change to the contents of a storage
+func RecordLedgerEntry(ctx context.Context, tx *sql.Tx, entry *v1.LedgerEntry) error {
+	// The player and tenant are copied from the storage so the entry survives the storage
+	_, err := tx.ExecContext(
+		ctx,
+		`
+			INSERT INTO ledger_entry(tenant_id, player_id, storage_id, item_id, currency_id, amount, reason, purchase_id)
+			SELECT tenant_id, player_id, id, $2, $3, $4, $5, $6
+			FROM storage
+			WHERE id = $1
+			AND tenant_id = $7
+		`,
+		entry.StorageId,
+		nullString(entry.ItemId),
+		nullString(entry.CurrencyId),
+		entry.Amount,
+		entry.Reason,
+		nullString(entry.PurchaseId),
+		tenant.FromContext(ctx),
+	)
+
+	return err
+}
+
+func nullString(value string) sql.NullString {
+	return sql.NullString{
+		String: value,
+		Valid:  value != "",
+	}
+}
@@ -0,0 +1,151 @@
+package ledgerrepository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
+	repository "github.com/GameComponent/economy-service/pkg/repository"
+	tenant "github.com/GameComponent/economy-service/pkg/tenant"
+	ptypes "github.com/golang/protobuf/ptypes"
+	"go.uber.org/zap"
+)
+
+// LedgerRepository struct
+type LedgerRepository struct {
+	db     *sql.DB
+	logger *zap.Logger
+}
+
+// NewLedgerRepository constructor
+func NewLedgerRepository(db *sql.DB, logger *zap.Logger) repository.LedgerRepository {
+	return &LedgerRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// ListByPlayer lists all ledger entries of a player
+func (r *LedgerRepository) ListByPlayer(ctx context.Context, playerID string) ([]*v1.LedgerEntry, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`
+			SELECT
+				id,
+				created_at,
+				player_id,
+				storage_id,
+				item_id,
+				currency_id,
+				amount,
+				reason,
+				purchase_id
+			FROM ledger_entry
+			WHERE player_id = $1
+			AND tenant_id = $2
+			ORDER BY created_at
+		`,
+		playerID,
+		tenant.FromContext(ctx),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Unwrap rows into ledger entries
+	entries := []*v1.LedgerEntry{}
+
+	for rows.Next() {
+		entry := v1.LedgerEntry{}
+		createdAt := time.Time{}
+		itemID := sql.NullString{}
+		currencyID := sql.NullString{}
+		purchaseID := sql.NullString{}
+
+		err := rows.Scan(
+			&entry.Id,
+			&createdAt,
+			&entry.PlayerId,
+			&entry.StorageId,
+			&itemID,
+			&currencyID,
+			&entry.Amount,
+			&entry.Reason,
+			&purchaseID,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		entry.CreatedAt, _ = ptypes.TimestampProto(createdAt)
+		entry.ItemId = itemID.String
+		entry.CurrencyId = currencyID.String
+		entry.PurchaseId = purchaseID.String
+
+		entries = append(entries, &entry)
+	}
+
+	return entries, nil
+}
+
+// ListPurchasesByPlayer lists all purchases of a player
+func (r *LedgerRepository) ListPurchasesByPlayer(ctx context.Context, playerID string) ([]*v1.Purchase, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`
+			SELECT
+				id,
+				created_at,
+				player_id,
+				product_id,
+				price_id,
+				receiving_storage_id,
+				paying_storage_id,
+				catalog_version_id
+			FROM purchase
+			WHERE player_id = $1
+			AND tenant_id = $2
+			ORDER BY created_at
+		`,
+		playerID,
+		tenant.FromContext(ctx),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Unwrap rows into purchases
+	purchases := []*v1.Purchase{}
+
+	for rows.Next() {
+		purchase := v1.Purchase{}
+		createdAt := time.Time{}
+		catalogVersionID := sql.NullString{}
+
+		err := rows.Scan(
+			&purchase.Id,
+			&createdAt,
+			&purchase.PlayerId,
+			&purchase.ProductId,
+			&purchase.PriceId,
+			&purchase.ReceivingStorageId,
+			&purchase.PayingStorageId,
+			&catalogVersionID,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		purchase.CreatedAt, _ = ptypes.TimestampProto(createdAt)
+		purchase.CatalogVersionId = catalogVersionID.String
+
+		purchases = append(purchases, &purchase)
+	}
+
+	return purchases, nil
+}
@@ -0,0 +1,186 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	proto "github.com/golang/protobuf/proto"
+	ptypes "github.com/golang/protobuf/ptypes"
+	timestamp "github.com/golang/protobuf/ptypes/timestamp"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// DefaultPageSize is the number of rows of a page when the request does not set a size
+const DefaultPageSize = 100
+
+// Cursor holds the values of the order columns of the last row of a page,
+// the next page starts right after that row
+type Cursor []string
+
+// Page asks for the rows after the cursor, the first page has no cursor. Lists that
+// support it only return the rows matching the filter, sorted by the order_by
+type Page struct {
+	Size    int32
+	After   Cursor
+	Filter  string
+	OrderBy string
+}
+
+// Order is a column the rows of a list are sorted by, the last order of a list
+// has to be unique, like the id, so every row has a fixed position
+type Order struct {
+	Column     string
+	Descending bool
+}
+
+// Asc sorts rows by a column from low to high
+func Asc(column string) Order {
+	return Order{Column: column}
+}
+
+// Desc sorts rows by a column from high to low
+func Desc(column string) Order {
+	return Order{Column: column, Descending: true}
+}
+
+// Keyset reads a list one page at a time, instead of skipping the rows of previous pages with
+// OFFSET it continues after the last row, so inserted rows do not shift pages and deep pages stay fast
+type Keyset struct {
+	page   Page
+	orders []Order
+	rows   int32
+	next   Cursor
+}
+
+// NewKeyset starts reading a page of rows sorted by the orders
+func NewKeyset(page Page, orders ...Order) (*Keyset, error) {
+	if page.Size <= 0 {
+		page.Size = DefaultPageSize
+	}
+
+	if page.After != nil && len(page.After) != len(orders) {
+		return nil, InvalidArgument("page_token", "does not belong to this list")
+	}
+
+	return &Keyset{
+		page:   page,
+		orders: orders,
+	}, nil
+}
+
+// Query adds the condition that skips the rows up to the cursor, the ORDER BY and the LIMIT to a query
+// that ends with its WHERE conditions, the values of the cursor are appended to the arguments
+func (k *Keyset) Query(query string, args ...interface{}) (string, []interface{}) {
+	if k.page.After != nil {
+		var condition string
+		condition, args = k.after(args)
+		query += " AND " + condition
+	}
+
+	// One row more than the page is read to know if there is a next page
+	columns := make([]string, len(k.orders))
+	for i, order := range k.orders {
+		columns[i] = order.Column
+		if order.Descending {
+			columns[i] += " DESC"
+		}
+	}
+
+	query += fmt.Sprintf(" ORDER BY %s LIMIT %d", strings.Join(columns, ", "), k.page.Size+1)
+
+	return query, args
+}
+
+// after returns the condition matching the rows after the cursor, rows come after it when they equal it
+// on the first columns and come after it on the next, like (a > $1) OR (a = $1 AND b > $2)
+func (k *Keyset) after(args []interface{}) (string, []interface{}) {
+	placeholders := make([]string, len(k.orders))
+	for i, value := range k.page.After {
+		args = append(args, value)
+		placeholders[i] = fmt.Sprintf("$%d", len(args))
+	}
+
+	alternatives := make([]string, len(k.orders))
+	for i, order := range k.orders {
+		conditions := []string{}
+		for j := 0; j < i; j++ {
+			conditions = append(conditions, fmt.Sprintf("%s = %s", k.orders[j].Column, placeholders[j]))
+		}
+
+		operator := ">"
+		if order.Descending {
+			operator = "<"
+		}
+		conditions = append(conditions, fmt.Sprintf("%s %s %s", order.Column, operator, placeholders[i]))
+
+		alternatives[i] = "(" + strings.Join(conditions, " AND ") + ")"
+	}
+
+	return "(" + strings.Join(alternatives, " OR ") + ")", args
+}
+
+// Add takes the values of the order columns of a row, in the order of the columns, and reports
+// if the row is on the page, the extra row that only tells there is a next page is not
+func (k *Keyset) Add(values ...interface{}) bool {
+	if k.rows == k.page.Size {
+		k.rows++
+		return false
+	}
+
+	k.rows++
+
+	// The last row on the page is where the next page starts
+	if k.rows == k.page.Size {
+		k.next = make(Cursor, len(values))
+		for i, value := range values {
+			k.next[i] = cursorValue(value)
+		}
+	}
+
+	return true
+}
+
+// AddMessage is Add with the values of the order columns read from the fields of the
+// same name of the message a row was scanned into
+func (k *Keyset) AddMessage(message proto.Message) bool {
+	reflected := proto.MessageReflect(message)
+	fields := reflected.Descriptor().Fields()
+
+	values := make([]interface{}, len(k.orders))
+	for i, order := range k.orders {
+		value := reflected.Get(fields.ByName(protoreflect.Name(order.Column)))
+		if m, ok := value.Interface().(protoreflect.Message); ok {
+			values[i] = proto.MessageV1(m.Interface())
+			continue
+		}
+		values[i] = value.Interface()
+	}
+
+	return k.Add(values...)
+}
+
+// Next returns the cursor of the next page, nil when this is the last page
+func (k *Keyset) Next() Cursor {
+	if k.rows <= k.page.Size {
+		return nil
+	}
+
+	return k.next
+}
+
+// cursorValue formats a value so the database reads it back exactly,
+// timestamps keep their nanoseconds
+func cursorValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case *timestamp.Timestamp:
+		t, _ := ptypes.Timestamp(v)
+		return t.UTC().Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
+	ptypes "github.com/golang/protobuf/ptypes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeysetFirstPage(t *testing.T) {
+	keyset, err := NewKeyset(Page{Size: 2}, Desc("created_at"), Desc("id"))
+	assert.NoError(t, err)
+
+	query, args := keyset.Query("SELECT id FROM item WHERE tenant_id = $1", "default")
+
+	assert.Equal(t, "SELECT id FROM item WHERE tenant_id = $1 ORDER BY created_at DESC, id DESC LIMIT 3", query)
+	assert.Equal(t, []interface{}{"default"}, args)
+}
+
+func TestKeysetAfterCursor(t *testing.T) {
+	keyset, err := NewKeyset(Page{Size: 2, After: Cursor{"2019-10-27T12:00:00Z", "b"}}, Desc("created_at"), Asc("id"))
+	assert.NoError(t, err)
+
+	query, args := keyset.Query("SELECT id FROM item WHERE tenant_id = $1", "default")
+
+	assert.Equal(
+		t,
+		"SELECT id FROM item WHERE tenant_id = $1"+
+			" AND ((created_at < $2) OR (created_at = $2 AND id > $3))"+
+			" ORDER BY created_at DESC, id LIMIT 3",
+		query,
+	)
+	assert.Equal(t, []interface{}{"default", "2019-10-27T12:00:00Z", "b"}, args)
+}
+
+func TestKeysetDefaultSize(t *testing.T) {
+	keyset, err := NewKeyset(Page{}, Asc("key"))
+	assert.NoError(t, err)
+
+	query, _ := keyset.Query("SELECT key FROM config WHERE tenant_id = $1")
+
+	assert.Equal(t, "SELECT key FROM config WHERE tenant_id = $1 ORDER BY key LIMIT 101", query)
+}
+
+func TestKeysetCursorOfOtherList(t *testing.T) {
+	_, err := NewKeyset(Page{Size: 2, After: Cursor{"a"}}, Desc("created_at"), Desc("id"))
+
+	assert.True(t, errors.Is(err, ErrInvalidArgument))
+}
+
+func TestKeysetNext(t *testing.T) {
+	createdAt := time.Date(2019, 10, 27, 12, 0, 0, 123456789, time.UTC)
+
+	// A full page followed by the extra row has a next page
+	keyset, _ := NewKeyset(Page{Size: 2}, Desc("created_at"), Desc("id"))
+	assert.True(t, keyset.Add(createdAt, "a"))
+	assert.True(t, keyset.Add(createdAt, "b"))
+	assert.False(t, keyset.Add(createdAt, "c"))
+	assert.Equal(t, Cursor{"2019-10-27T12:00:00.123456789Z", "b"}, keyset.Next())
+
+	// A full page without the extra row is the last page
+	keyset, _ = NewKeyset(Page{Size: 2}, Desc("created_at"), Desc("id"))
+	assert.True(t, keyset.Add(createdAt, "a"))
+	assert.True(t, keyset.Add(createdAt, "b"))
+	assert.Nil(t, keyset.Next())
+
+	// So is an empty page
+	keyset, _ = NewKeyset(Page{Size: 2}, Desc("created_at"), Desc("id"))
+	assert.Nil(t, keyset.Next())
+}
+
+func TestKeysetAddMessage(t *testing.T) {
+	createdAt, _ := ptypes.TimestampProto(time.Date(2019, 10, 27, 12, 0, 0, 0, time.UTC))
+
+	keyset, _ := NewKeyset(Page{Size: 1}, Desc("stack_max_amount"), Desc("created_at"), Desc("id"))
+	assert.True(t, keyset.AddMessage(&v1.Item{Id: "a", StackMaxAmount: 10, CreatedAt: createdAt}))
+	assert.False(t, keyset.AddMessage(&v1.Item{Id: "b", StackMaxAmount: 5, CreatedAt: createdAt}))
+	assert.Equal(t, Cursor{"10", "2019-10-27T12:00:00Z", "a"}, keyset.Next())
+}
@@ -8,6 +8,9 @@ import (
 
 	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
 	repository "github.com/GameComponent/economy-service/pkg/repository"
+	tenant "github.com/GameComponent/economy-service/pkg/tenant"
+	ptypes "github.com/golang/protobuf/ptypes"
+	pq "github.com/lib/pq"
 	"go.uber.org/zap"
 )
 
@@ -33,12 +36,16 @@ func (r *PlayerRepository) Create(ctx context.Context, playerID string, name str
 	}
 	_, err := r.db.ExecContext(
 		ctx,
-		`INSERT INTO player(id, name, metadata) VALUES ($1, $2, $3)`,
+		`INSERT INTO player(id, name, metadata, tenant_id) VALUES ($1, $2, $3, $4)`,
 		playerID,
 		name,
 		metadata,
+		tenant.FromContext(ctx),
 	)
 
+	if repository.IsUniqueViolation(err) {
+		return nil, repository.AlreadyExists("player", playerID)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -67,12 +74,12 @@ func (r *PlayerRepository) Update(ctx context.Context, playerID string, name str
 	}
 
 	if index <= 1 {
-		return nil, fmt.Errorf("no arguments given")
+		return nil, repository.ErrNothingToUpdate
 	}
 
 	// Update the player
-	arguments = append(arguments, playerID)
-	query := fmt.Sprintf("UPDATE player SET %v WHERE id =$%v", strings.Join(queries, ", "), index)
+	arguments = append(arguments, playerID, tenant.FromContext(ctx))
+	query := fmt.Sprintf("UPDATE player SET %v WHERE id =$%v AND tenant_id = $%v", strings.Join(queries, ", "), index, index+1)
 	_, err := r.db.ExecContext(
 		ctx,
 		query,
@@ -95,13 +102,17 @@ func (r *PlayerRepository) Get(ctx context.Context, playerID string) (*v1.Player
 				player.id AS playerId,
 				player.name AS playerName,
 				player.metadata AS playerMetadata,
+				player.archived_at AS playerArchivedAt,
+				player.erased_at AS playerErasedAt,
 				storage.id as storageId,
 				storage.name as storageName
 			FROM player
-			LEFT JOIN storage ON (player.id = storage.player_id)
+			LEFT JOIN storage ON (player.id = storage.player_id AND player.tenant_id = storage.tenant_id AND storage.archived_at IS NULL)
 			WHERE player.id = $1
+			AND player.tenant_id = $2
 		`,
 		playerID,
+		tenant.FromContext(ctx),
 	)
 	if err != nil {
 		return nil, err
@@ -111,6 +122,8 @@ func (r *PlayerRepository) Get(ctx context.Context, playerID string) (*v1.Player
 		PlayerID       string
 		PlayerName     string
 		PlayerMetadata string
+		PlayerArchived pq.NullTime
+		PlayerErased   pq.NullTime
 		StorageID      sql.NullString
 		StorageName    sql.NullString
 	}
@@ -123,6 +136,8 @@ func (r *PlayerRepository) Get(ctx context.Context, playerID string) (*v1.Player
 			&res.PlayerID,
 			&res.PlayerName,
 			&res.PlayerMetadata,
+			&res.PlayerArchived,
+			&res.PlayerErased,
 			&res.StorageID,
 			&res.StorageName,
 		)
@@ -141,7 +156,7 @@ func (r *PlayerRepository) Get(ctx context.Context, playerID string) (*v1.Player
 
 	// Check if there is atleast 1 row found
 	if res.PlayerID == "" {
-		return nil, fmt.Errorf("Player not found")
+		return nil, repository.NotFound("player", playerID)
 	}
 
 	// Create the player struct
@@ -152,35 +167,59 @@ func (r *PlayerRepository) Get(ctx context.Context, playerID string) (*v1.Player
 		Storages: storages,
 	}
 
+	// Archived players are still resolvable
+	if res.PlayerArchived.Valid {
+		player.ArchivedAt, _ = ptypes.TimestampProto(res.PlayerArchived.Time)
+	}
+
+	if res.PlayerErased.Valid {
+		player.ErasedAt, _ = ptypes.TimestampProto(res.PlayerErased.Time)
+	}
+
 	return player, nil
 }
 
-// List all player
-func (r *PlayerRepository) List(ctx context.Context, limit int32, offset int32) ([]*v1.Player, int32, error) {
-	// Query items from the database
-	rows, err := r.db.QueryContext(
-		ctx,
+// playerFields are the fields players can be filtered and sorted by
+var playerFields = repository.Fields{
+	"id":       {Column: "id", Type: repository.StringField, Sortable: true},
+	"name":     {Column: "name", Type: repository.StringField, Sortable: true},
+	"metadata": {Column: "metadata", Type: repository.JSONField},
+}
+
+// List the players matching the filter of the page, sorted by id unless the page has an order
+func (r *PlayerRepository) List(ctx context.Context, page repository.Page) ([]*v1.Player, repository.Cursor, int32, error) {
+	filter, keyset, err := repository.NewFilteredKeyset(page, playerFields, repository.Asc("id"))
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	condition, args := filter.Condition([]interface{}{tenant.FromContext(ctx)})
+	conditions := `
+		WHERE archived_at IS NULL
+		AND tenant_id = $1
+		AND ` + condition
+
+	query, args := keyset.Query(
 		`
 			SELECT
 				id,
 				name,
-				(SELECT COUNT(id) FROM player) AS total_size
+				(SELECT COUNT(id) FROM player `+conditions+`) AS total_size
 			FROM player
-			LIMIT $1
-			OFFSET $2
-		`,
-		limit,
-		offset,
+		`+conditions,
+		args...,
 	)
 
+	// Query items from the database
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, 0, err
+		return nil, nil, 0, err
 	}
 	defer rows.Close()
 
 	// Unwrap rows into items
 	players := []*v1.Player{}
-	totalSize := int32(1)
+	totalSize := int32(0)
 
 	for rows.Next() {
 		player := v1.Player{}
@@ -191,37 +230,45 @@ func (r *PlayerRepository) List(ctx context.Context, limit int32, offset int32)
 			&totalSize,
 		)
 		if err != nil {
-			return nil, 0, err
+			return nil, nil, 0, err
+		}
+
+		if !keyset.AddMessage(&player) {
+			break
 		}
 
 		players = append(players, &player)
 	}
 
-	return players, totalSize, nil
+	return players, keyset.Next(), totalSize, nil
 }
 
-// Search player
-func (r *PlayerRepository) Search(ctx context.Context, query string, limit int32, offset int32) ([]*v1.Player, int32, error) {
-	// Query items from the database
-	rows, err := r.db.QueryContext(
-		ctx,
+// Search player, sorted by id
+func (r *PlayerRepository) Search(ctx context.Context, search string, page repository.Page) ([]*v1.Player, repository.Cursor, int32, error) {
+	keyset, err := repository.NewKeyset(page, repository.Asc("id"))
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	query, args := keyset.Query(
 		`
 			SELECT
 				id,
 				name,
-				(SELECT COUNT(id) FROM player WHERE name ~* $1) AS total_size
+				(SELECT COUNT(id) FROM player WHERE name ~* $1 AND archived_at IS NULL AND tenant_id = $2) AS total_size
 			FROM player
 			WHERE name ~* $1
-			LIMIT $2
-			OFFSET $3
+			AND archived_at IS NULL
+			AND tenant_id = $2
 		`,
-		query,
-		limit,
-		offset,
+		search,
+		tenant.FromContext(ctx),
 	)
 
+	// Query items from the database
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, 0, err
+		return nil, nil, 0, err
 	}
 	defer rows.Close()
 
@@ -238,11 +285,159 @@ func (r *PlayerRepository) Search(ctx context.Context, query string, limit int32
 			&totalSize,
 		)
 		if err != nil {
-			return nil, 0, err
+			return nil, nil, 0, err
+		}
+
+		if !keyset.Add(player.Id) {
+			break
 		}
 
 		players = append(players, &player)
 	}
 
-	return players, totalSize, nil
+	return players, keyset.Next(), totalSize, nil
+}
+
+// Archive a player
+func (r *PlayerRepository) Archive(ctx context.Context, playerID string) (*v1.Player, error) {
+	_, err := r.db.ExecContext(
+		ctx,
+		`UPDATE player SET archived_at = current_timestamp() WHERE id = $1 AND tenant_id = $2 AND archived_at IS NULL`,
+		playerID,
+		tenant.FromContext(ctx),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Get(ctx, playerID)
+}
+
+// Delete a player, force also deletes the storages of the player and their contents
+func (r *PlayerRepository) Delete(ctx context.Context, playerID string, force bool) (bool, error) {
+	options := sql.TxOptions{
+		ReadOnly: false,
+	}
+
+	// Start a transaction
+	tx, err := r.db.BeginTx(ctx, &options)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	tenantID := tenant.FromContext(ctx)
+
+	// Refuse to delete players that still own storages unless forced,
+	// player ids are only unique within a tenant
+	referenced := false
+	err = tx.QueryRowContext(
+		ctx,
+		`SELECT EXISTS(SELECT 1 FROM storage WHERE player_id = $1 AND tenant_id = $2)`,
+		playerID,
+		tenantID,
+	).Scan(&referenced)
+	if err != nil {
+		return false, err
+	}
+
+	if referenced && !force {
+		return false, repository.Referenced("player", playerID)
+	}
+
+	// Delete the contents of the storages before the storages themselves
+	queries := []string{
+		`DELETE FROM storage_item WHERE storage_id IN (SELECT id FROM storage WHERE player_id = $1 AND tenant_id = $2)`,
+		`DELETE FROM storage_currency WHERE storage_id IN (SELECT id FROM storage WHERE player_id = $1 AND tenant_id = $2)`,
+		`DELETE FROM storage WHERE player_id = $1 AND tenant_id = $2`,
+	}
+
+	for _, query := range queries {
+		_, err = tx.ExecContext(ctx, query, playerID, tenantID)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	result, err := tx.ExecContext(
+		ctx,
+		`DELETE FROM player WHERE id = $1 AND tenant_id = $2`,
+		playerID,
+		tenantID,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	// Make sure the player existed
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return false, repository.NotFound("player", playerID)
+	}
+
+	// Commit all changes to the database
+	if err = tx.Commit(); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Erase the personal data of a player, the id is kept so purchases and ledger entries stay consistent
+func (r *PlayerRepository) Erase(ctx context.Context, playerID string) (*v1.Player, error) {
+	options := sql.TxOptions{
+		ReadOnly: false,
+	}
+
+	// Start a transaction
+	tx, err := r.db.BeginTx(ctx, &options)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	tenantID := tenant.FromContext(ctx)
+
+	// Anonymize the player and hide it from lists
+	result, err := tx.ExecContext(
+		ctx,
+		`
+			UPDATE player
+			SET
+				name = '',
+				metadata = '{}',
+				erased_at = current_timestamp(),
+				archived_at = COALESCE(archived_at, current_timestamp())
+			WHERE id = $1
+			AND tenant_id = $2
+		`,
+		playerID,
+		tenantID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Make sure the player existed
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return nil, repository.NotFound("player", playerID)
+	}
+
+	// Storage metadata can contain personal data as well
+	_, err = tx.ExecContext(
+		ctx,
+		`UPDATE storage SET metadata = '{}' WHERE player_id = $1 AND tenant_id = $2`,
+		playerID,
+		tenantID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Commit all changes to the database
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return r.Get(ctx, playerID)
 }
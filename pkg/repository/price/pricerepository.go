@@ -7,6 +7,7 @@ import (
 
 	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
 	repository "github.com/GameComponent/economy-service/pkg/repository"
+	tenant "github.com/GameComponent/economy-service/pkg/tenant"
 	"github.com/golang/protobuf/ptypes"
 	"go.uber.org/zap"
 )
@@ -57,8 +58,10 @@ func (r *PriceRepository) Get(ctx context.Context, priceID string) (*v1.Price, e
 			LEFT JOIN price_currency ON (price.id = price_currency.price_id)
 			LEFT JOIN currency ON (currency.id = price_currency.currency_id)
 			WHERE price.id = $1
+			AND price.tenant_id = $2
 		`,
 		priceID,
+		tenant.FromContext(ctx),
 	)
 	if err != nil {
 		return nil, err
@@ -163,6 +166,10 @@ func (r *PriceRepository) Get(ctx context.Context, priceID string) (*v1.Price, e
 		}
 	}
 
+	if res.PriceID == "" {
+		return nil, repository.NotFound("price", priceID)
+	}
+
 	// Convert item map into item slice
 	currencies := []*v1.PriceCurrency{}
 	for _, value := range priceCurrencies {
@@ -192,10 +199,14 @@ func (r *PriceRepository) Create(ctx context.Context, productID string) (*v1.Pri
 	lastInsertUUID := ""
 	err := r.db.QueryRowContext(
 		ctx,
-		`INSERT INTO price(product_id) VALUES($1) RETURNING id`,
+		`INSERT INTO price(product_id, tenant_id) SELECT id, tenant_id FROM product WHERE id = $1 AND tenant_id = $2 RETURNING id`,
 		productID,
+		tenant.FromContext(ctx),
 	).Scan(&lastInsertUUID)
 
+	if err == sql.ErrNoRows {
+		return nil, repository.NotFound("product", productID)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -207,33 +218,61 @@ func (r *PriceRepository) Create(ctx context.Context, productID string) (*v1.Pri
 
 // Delete a Price
 func (r *PriceRepository) Delete(ctx context.Context, priceID string) (bool, error) {
-	_, err := r.db.ExecContext(
+	result, err := r.db.ExecContext(
 		ctx,
-		`DELETE FROM price WHERE id = $1`,
+		`DELETE FROM price WHERE id = $1 AND tenant_id = $2`,
 		priceID,
+		tenant.FromContext(ctx),
 	)
 
 	if err != nil {
 		return false, err
 	}
 
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	if affected == 0 {
+		return false, repository.NotFound("price", priceID)
+	}
+
 	return true, nil
 }
 
 // AttachPriceCurrency attaches a currency to a price
 func (r *PriceRepository) AttachPriceCurrency(ctx context.Context, priceID string, currencyID string, amount int64) (*v1.Price, error) {
-	_, err := r.db.ExecContext(
+	// Both the price and the currency have to belong to the tenant
+	result, err := r.db.ExecContext(
 		ctx,
-		`INSERT INTO price_currency(price_id, currency_id, amount) VALUES ($1, $2, $3)`,
+		`
+			INSERT INTO price_currency(price_id, currency_id, amount)
+			SELECT price.id, currency.id, $3
+			FROM price, currency
+			WHERE price.id = $1
+			AND currency.id = $2
+			AND price.tenant_id = $4
+			AND currency.tenant_id = $4
+		`,
 		priceID,
 		currencyID,
 		amount,
+		tenant.FromContext(ctx),
 	)
 
 	if err != nil {
 		return nil, err
 	}
 
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return nil, &repository.Error{
+			Reason:   repository.ReasonNotFound,
+			Message:  "price or currency not found",
+			Metadata: map[string]string{"price_id": priceID, "currency_id": currencyID},
+		}
+	}
+
 	return r.Get(ctx, priceID)
 }
 
@@ -242,8 +281,9 @@ func (r *PriceRepository) DetachPriceCurrency(ctx context.Context, priceCurrency
 	priceID := ""
 	err := r.db.QueryRowContext(
 		ctx,
-		`DELETE FROM price_currency WHERE id = $1 returning price_id`,
+		`DELETE FROM price_currency WHERE id = $1 AND price_id IN (SELECT id FROM price WHERE tenant_id = $2) returning price_id`,
 		priceCurrencyID,
+		tenant.FromContext(ctx),
 	).Scan(&priceID)
 
 	if err != nil {
@@ -255,18 +295,36 @@ func (r *PriceRepository) DetachPriceCurrency(ctx context.Context, priceCurrency
 
 // AttachPriceItem attaches an item to a price
 func (r *PriceRepository) AttachPriceItem(ctx context.Context, priceID string, itemID string, amount int64) (*v1.Price, error) {
-	_, err := r.db.ExecContext(
+	// Both the price and the item have to belong to the tenant
+	result, err := r.db.ExecContext(
 		ctx,
-		`INSERT INTO price_item(price_id, item_id, amount) VALUES ($1, $2, $3)`,
+		`
+			INSERT INTO price_item(price_id, item_id, amount)
+			SELECT price.id, item.id, $3
+			FROM price, item
+			WHERE price.id = $1
+			AND item.id = $2
+			AND price.tenant_id = $4
+			AND item.tenant_id = $4
+		`,
 		priceID,
 		itemID,
 		amount,
+		tenant.FromContext(ctx),
 	)
 
 	if err != nil {
 		return nil, err
 	}
 
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return nil, &repository.Error{
+			Reason:   repository.ReasonNotFound,
+			Message:  "price or item not found",
+			Metadata: map[string]string{"price_id": priceID, "item_id": itemID},
+		}
+	}
+
 	return r.Get(ctx, priceID)
 }
 
@@ -275,8 +333,9 @@ func (r *PriceRepository) DetachPriceItem(ctx context.Context, priceItemID strin
 	priceID := ""
 	err := r.db.QueryRowContext(
 		ctx,
-		`DELETE FROM price_item WHERE id = $1 returning price_id`,
+		`DELETE FROM price_item WHERE id = $1 AND price_id IN (SELECT id FROM price WHERE tenant_id = $2) returning price_id`,
 		priceItemID,
+		tenant.FromContext(ctx),
 	).Scan(&priceID)
 
 	if err != nil {
@@ -3,10 +3,10 @@ package productrepository
 import (
 	"context"
 	"database/sql"
-	"fmt"
 	"math"
 
 	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
+	repository "github.com/GameComponent/economy-service/pkg/repository"
 )
 
 // BuyProduct buys a product
@@ -46,6 +46,12 @@ func (r *ProductRepository) BuyProduct(ctx context.Context, product *v1.Product,
 		return nil, err
 	}
 
+	// Keep a record of the purchase
+	err = recordPurchase(ctx, tx, product, price, receivingStorage, payingStorage)
+	if err != nil {
+		return nil, err
+	}
+
 	// Commit all changes to the database
 	if err = tx.Commit(); err != nil {
 		return nil, err
@@ -54,6 +60,68 @@ func (r *ProductRepository) BuyProduct(ctx context.Context, product *v1.Product,
 	return product, nil
 }
 
+func recordPurchase(ctx context.Context, tx *sql.Tx, product *v1.Product, price *v1.Price, receivingStorage *v1.Storage, payingStorage *v1.Storage) error {
+	purchaseID, err := repository.RecordPurchase(
+		ctx,
+		tx,
+		product.Id,
+		price.Id,
+		receivingStorage.Id,
+		payingStorage.Id,
+	)
+	if err != nil {
+		return err
+	}
+
+	entries := []*v1.LedgerEntry{}
+
+	// Everything that was paid is taken from the paying Storage
+	for _, priceCurrency := range price.Currencies {
+		entries = append(entries, &v1.LedgerEntry{
+			StorageId:  payingStorage.Id,
+			CurrencyId: priceCurrency.Currency.Id,
+			Amount:     -priceCurrency.Amount,
+		})
+	}
+
+	for _, priceItem := range price.Items {
+		entries = append(entries, &v1.LedgerEntry{
+			StorageId: payingStorage.Id,
+			ItemId:    priceItem.Item.Id,
+			Amount:    -priceItem.Amount,
+		})
+	}
+
+	// Everything in the Product is given to the receiving Storage
+	for _, productCurrency := range product.Currencies {
+		entries = append(entries, &v1.LedgerEntry{
+			StorageId:  receivingStorage.Id,
+			CurrencyId: productCurrency.Currency.Id,
+			Amount:     productCurrency.Amount,
+		})
+	}
+
+	for _, productItem := range product.Items {
+		entries = append(entries, &v1.LedgerEntry{
+			StorageId: receivingStorage.Id,
+			ItemId:    productItem.Item.Id,
+			Amount:    productItem.Amount,
+		})
+	}
+
+	for _, entry := range entries {
+		entry.Reason = repository.LedgerReasonPurchase
+		entry.PurchaseId = purchaseID
+
+		err = repository.RecordLedgerEntry(ctx, tx, entry)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func takeCurrenciesFromStorage(ctx context.Context, tx *sql.Tx, priceCurrencies []*v1.PriceCurrency, storage *v1.Storage) error {
 	// Get a map of paying StorageCurrencies
 	payingStorageCurrencies := storage.Currencies
@@ -66,8 +134,11 @@ func takeCurrenciesFromStorage(ctx context.Context, tx *sql.Tx, priceCurrencies
 	for _, priceCurrency := range priceCurrencies {
 		// Get the StorageCurrency Id
 		storageCurrency := payingStorageCurrenciesMap[priceCurrency.Currency.Id]
+		if storageCurrency == nil || storageCurrency.Amount < priceCurrency.Amount {
+			return repository.InsufficientFunds(storage.Id, priceCurrency.Currency.Id)
+		}
 
-		_, err := tx.ExecContext(
+		result, err := tx.ExecContext(
 			ctx,
 			`
 				UPDATE storage_currency
@@ -82,6 +153,11 @@ func takeCurrenciesFromStorage(ctx context.Context, tx *sql.Tx, priceCurrencies
 		if err != nil {
 			return err
 		}
+
+		// The amount changed since the storage was read
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			return repository.Conflict("storage currency", storageCurrency.Id)
+		}
 	}
 
 	return nil
@@ -148,7 +224,7 @@ func takeStackableItemFromStorage(ctx context.Context, tx *sql.Tx, priceItem *v1
 		total = total + amount.Amount
 	}
 	if total < priceItem.Amount {
-		return fmt.Errorf("not enough items in storage")
+		return repository.InsufficientItems(storage.Id, priceItem.Item.Id)
 	}
 
 	remainder := priceItem.Amount
@@ -223,7 +299,7 @@ func takeUnstackableItemFromStorage(ctx context.Context, tx *sql.Tx, priceItem *
 
 	// Check if there enough items in the Storage
 	if itemAmount < priceItem.Amount {
-		return fmt.Errorf("not enough items in storage")
+		return repository.InsufficientItems(storage.Id, priceItem.Item.Id)
 	}
 
 	// Delete the items from the storage
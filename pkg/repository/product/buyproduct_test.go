@@ -2,11 +2,14 @@ package productrepository_test
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	sqlmock "github.com/DATA-DOG/go-sqlmock"
 	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
+	repository "github.com/GameComponent/economy-service/pkg/repository"
 	productrepository "github.com/GameComponent/economy-service/pkg/repository/product"
+	"go.uber.org/zap"
 )
 
 func TestBuyProductShouldStartTransaction(t *testing.T) {
@@ -17,10 +20,10 @@ func TestBuyProductShouldStartTransaction(t *testing.T) {
 	defer db.Close()
 
 	mock.ExpectBegin()
-	mock.ExpectQuery("SELECT *")
+	mock.ExpectQuery("INSERT INTO purchase").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("1"))
 	mock.ExpectCommit()
 
-	productRepository := productrepository.NewProductRepository(db)
+	productRepository := productrepository.NewProductRepository(db, zap.NewNop())
 	product := v1.Product{}
 	price := v1.Price{}
 	receivingStorage := v1.Storage{}
@@ -37,3 +40,35 @@ func TestBuyProductShouldStartTransaction(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestBuyProductInsufficientFunds(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	productRepository := productrepository.NewProductRepository(db, zap.NewNop())
+	price := v1.Price{
+		Currencies: []*v1.PriceCurrency{{Currency: &v1.Currency{Id: "gold"}, Amount: 10}},
+	}
+	payingStorage := v1.Storage{Id: "wallet"}
+
+	_, err = productRepository.BuyProduct(
+		context.Background(),
+		&v1.Product{},
+		&price,
+		&v1.Storage{},
+		&payingStorage,
+	)
+	if !errors.Is(err, repository.ErrInsufficientFunds) {
+		t.Fatalf("BuyProduct() = %v, want insufficient funds", err)
+	}
+
+	if got := err.(*repository.Error).Metadata["currency_id"]; got != "gold" {
+		t.Errorf("currency_id = %q, want gold", got)
+	}
+}
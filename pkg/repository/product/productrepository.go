@@ -11,6 +11,7 @@ import (
 
 	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
 	repository "github.com/GameComponent/economy-service/pkg/repository"
+	tenant "github.com/GameComponent/economy-service/pkg/tenant"
 	"go.uber.org/zap"
 )
 
@@ -53,8 +54,9 @@ func (r *ProductRepository) Create(ctx context.Context, name string) (*v1.Produc
 	lastInsertUUID := ""
 	err := r.db.QueryRowContext(
 		ctx,
-		`INSERT INTO product(name) VALUES ($1) RETURNING id`,
+		`INSERT INTO product(name, tenant_id) VALUES ($1, $2) RETURNING id`,
 		name,
+		tenant.FromContext(ctx),
 	).Scan(&lastInsertUUID)
 
 	if err != nil {
@@ -71,9 +73,10 @@ func (r *ProductRepository) Create(ctx context.Context, name string) (*v1.Produc
 func (r *ProductRepository) Update(ctx context.Context, id string, name string) (*v1.Product, error) {
 	_, err := r.db.ExecContext(
 		ctx,
-		`UPDATE product SET name = $1 WHERE id = $2`,
+		`UPDATE product SET name = $1 WHERE id = $2 AND tenant_id = $3`,
 		name,
 		id,
+		tenant.FromContext(ctx),
 	)
 
 	if err != nil {
@@ -88,29 +91,46 @@ func (r *ProductRepository) Update(ctx context.Context, id string, name string)
 	return product, nil
 }
 
-// List all products
-func (r *ProductRepository) List(ctx context.Context, limit int32, offset int32) ([]*v1.Product, int32, error) {
-	// Query products from the database
-	rows, err := r.db.QueryContext(
-		ctx,
+// productFields are the fields products can be filtered and sorted by, shop_id matches
+// the products of a shop
+var productFields = repository.Fields{
+	"id":         {Column: "id", Type: repository.UUIDField},
+	"name":       {Column: "name", Type: repository.StringField, Sortable: true},
+	"created_at": {Column: "created_at", Type: repository.TimestampField, Sortable: true},
+	"updated_at": {Column: "updated_at", Type: repository.TimestampField, Sortable: true},
+	"shop_id":    {Column: "id", Type: repository.UUIDField, In: "SELECT product_id FROM shop_product WHERE shop_id = %s"},
+}
+
+// List the products matching the filter of the page, the newest first unless the page has an order
+func (r *ProductRepository) List(ctx context.Context, page repository.Page) ([]*v1.Product, repository.Cursor, int32, error) {
+	filter, keyset, err := repository.NewFilteredKeyset(page, productFields, repository.Desc("created_at"), repository.Desc("id"))
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	condition, args := filter.Condition([]interface{}{tenant.FromContext(ctx)})
+	conditions := `
+		WHERE archived_at IS NULL
+		AND tenant_id = $1
+		AND ` + condition
+
+	query, args := keyset.Query(
 		`
 			SELECT 
 				id,
 				name,
 				created_at,
 				updated_at,
-				(SELECT COUNT(*) FROM product) AS total_size
+				(SELECT COUNT(*) FROM product `+conditions+`) AS total_size
 			FROM product
-			ORDER BY created_at DESC 
-			LIMIT $1
-			OFFSET $2
-		`,
-		limit,
-		offset,
+		`+conditions,
+		args...,
 	)
 
+	// Query products from the database
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, 0, err
+		return nil, nil, 0, err
 	}
 	defer rows.Close()
 
@@ -131,17 +151,21 @@ func (r *ProductRepository) List(ctx context.Context, limit int32, offset int32)
 			&totalSize,
 		)
 		if err != nil {
-			return nil, 0, err
+			return nil, nil, 0, err
 		}
 
 		// Convert created_at to timestamp
 		product.CreatedAt, _ = ptypes.TimestampProto(createdAt)
 		product.UpdatedAt, _ = ptypes.TimestampProto(updatedAt)
 
+		if !keyset.AddMessage(&product) {
+			break
+		}
+
 		products = append(products, &product)
 	}
 
-	return products, totalSize, nil
+	return products, keyset.Next(), totalSize, nil
 }
 
 // Get an product
@@ -154,6 +178,7 @@ func (r *ProductRepository) Get(ctx context.Context, productID string) (*v1.Prod
 				product.name AS productName,
 				product.created_at AS productCreatedAt,
 				product.updated_at AS productUpdatedAt,
+				product.archived_at AS productArchivedAt,
 				product_item.id AS productItemId,
 				product_item.amount AS productItemAmount,
 				product_currency.id AS productCurrencyId,
@@ -196,8 +221,10 @@ func (r *ProductRepository) Get(ctx context.Context, productID string) (*v1.Prod
 			LEFT JOIN currency price_currency_currency ON (price_currency_currency.id = price_currency.currency_id)
 			LEFT JOIN item price_item_item ON (price_item_item.id = price_item.item_id)
 			WHERE product.id = $1
+			AND product.tenant_id = $2
 		`,
 		productID,
+		tenant.FromContext(ctx),
 	)
 
 	if err != nil {
@@ -209,6 +236,7 @@ func (r *ProductRepository) Get(ctx context.Context, productID string) (*v1.Prod
 		ProductName                       string
 		ProductCreatedAt                  time.Time
 		ProductUpdatedAt                  time.Time
+		ProductArchivedAt                 NullTime
 		ProductItemID                     sql.NullString
 		ProductItemAmount                 sql.NullInt64
 		ProductCurrencyID                 sql.NullString
@@ -255,6 +283,7 @@ func (r *ProductRepository) Get(ctx context.Context, productID string) (*v1.Prod
 			&res.ProductName,
 			&res.ProductCreatedAt,
 			&res.ProductUpdatedAt,
+			&res.ProductArchivedAt,
 			&res.ProductItemID,
 			&res.ProductItemAmount,
 			&res.ProductCurrencyID,
@@ -396,6 +425,10 @@ func (r *ProductRepository) Get(ctx context.Context, productID string) (*v1.Prod
 		}
 	}
 
+	if res.ProductID == "" {
+		return nil, repository.NotFound("product", productID)
+	}
+
 	// Convert item map into item slice
 	items := []*v1.ProductItem{}
 	for _, value := range productItems {
@@ -445,33 +478,42 @@ func (r *ProductRepository) Get(ctx context.Context, productID string) (*v1.Prod
 	product.CreatedAt, _ = ptypes.TimestampProto(res.ProductCreatedAt)
 	product.UpdatedAt, _ = ptypes.TimestampProto(res.ProductUpdatedAt)
 
+	// Archived products are still resolvable
+	if res.ProductArchivedAt.Valid {
+		product.ArchivedAt, _ = ptypes.TimestampProto(res.ProductArchivedAt.Time)
+	}
+
 	return product, nil
 }
 
-// Search product
-func (r *ProductRepository) Search(ctx context.Context, query string, limit int32, offset int32) ([]*v1.Product, int32, error) {
-	// Query products from the database
-	rows, err := r.db.QueryContext(
-		ctx,
+// Search product, the newest first
+func (r *ProductRepository) Search(ctx context.Context, search string, page repository.Page) ([]*v1.Product, repository.Cursor, int32, error) {
+	keyset, err := repository.NewKeyset(page, repository.Desc("created_at"), repository.Desc("id"))
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	query, args := keyset.Query(
 		`
 			SELECT
 				id,
 				name,
 				created_at,
 				updated_at,
-				(SELECT COUNT(id) FROM product WHERE name ~* $1) AS total_size
+				(SELECT COUNT(id) FROM product WHERE name ~* $1 AND archived_at IS NULL AND tenant_id = $2) AS total_size
 			FROM product
 			WHERE name ~* $1
-			LIMIT $2
-			OFFSET $3
+			AND archived_at IS NULL
+			AND tenant_id = $2
 		`,
-		query,
-		limit,
-		offset,
+		search,
+		tenant.FromContext(ctx),
 	)
 
+	// Query products from the database
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, 0, err
+		return nil, nil, 0, err
 	}
 	defer rows.Close()
 
@@ -492,7 +534,11 @@ func (r *ProductRepository) Search(ctx context.Context, query string, limit int3
 			&totalSize,
 		)
 		if err != nil {
-			return nil, 0, err
+			return nil, nil, 0, err
+		}
+
+		if !keyset.Add(createdAt, product.Id) {
+			break
 		}
 
 		// Convert created_at to timestamp
@@ -502,23 +548,41 @@ func (r *ProductRepository) Search(ctx context.Context, query string, limit int3
 		products = append(products, &product)
 	}
 
-	return products, totalSize, nil
+	return products, keyset.Next(), totalSize, nil
 }
 
 // AttachItem to a product
 func (r *ProductRepository) AttachItem(ctx context.Context, productID string, itemID string, amount int64) (*v1.Product, error) {
-	_, err := r.db.ExecContext(
+	// Both the product and the item have to belong to the tenant
+	result, err := r.db.ExecContext(
 		ctx,
-		`INSERT INTO product_item(product_id, item_id, amount) VALUES ($1, $2, $3)`,
+		`
+			INSERT INTO product_item(product_id, item_id, amount)
+			SELECT product.id, item.id, $3
+			FROM product, item
+			WHERE product.id = $1
+			AND item.id = $2
+			AND product.tenant_id = $4
+			AND item.tenant_id = $4
+		`,
 		productID,
 		itemID,
 		amount,
+		tenant.FromContext(ctx),
 	)
 
 	if err != nil {
 		return nil, err
 	}
 
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return nil, &repository.Error{
+			Reason:   repository.ReasonNotFound,
+			Message:  "product or item not found",
+			Metadata: map[string]string{"product_id": productID, "item_id": itemID},
+		}
+	}
+
 	return r.Get(ctx, productID)
 }
 
@@ -527,10 +591,14 @@ func (r *ProductRepository) DetachItem(ctx context.Context, productItemID string
 	productID := ""
 	err := r.db.QueryRowContext(
 		ctx,
-		`DELETE FROM product_item WHERE id = $1 RETURNING product_id`,
+		`DELETE FROM product_item WHERE id = $1 AND product_id IN (SELECT id FROM product WHERE tenant_id = $2) RETURNING product_id`,
 		productItemID,
+		tenant.FromContext(ctx),
 	).Scan(&productID)
 
+	if err == sql.ErrNoRows {
+		return nil, repository.NotFound("product item", productItemID)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -544,18 +612,36 @@ func (r *ProductRepository) DetachItem(ctx context.Context, productItemID string
 
 // AttachCurrency to a product
 func (r *ProductRepository) AttachCurrency(ctx context.Context, productID string, currencyID string, amount int64) (*v1.Product, error) {
-	_, err := r.db.ExecContext(
+	// Both the product and the currency have to belong to the tenant
+	result, err := r.db.ExecContext(
 		ctx,
-		`INSERT INTO product_currency(product_id, currency_id, amount) VALUES ($1, $2, $3)`,
+		`
+			INSERT INTO product_currency(product_id, currency_id, amount)
+			SELECT product.id, currency.id, $3
+			FROM product, currency
+			WHERE product.id = $1
+			AND currency.id = $2
+			AND product.tenant_id = $4
+			AND currency.tenant_id = $4
+		`,
 		productID,
 		currencyID,
 		amount,
+		tenant.FromContext(ctx),
 	)
 
 	if err != nil {
 		return nil, err
 	}
 
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return nil, &repository.Error{
+			Reason:   repository.ReasonNotFound,
+			Message:  "product or currency not found",
+			Metadata: map[string]string{"product_id": productID, "currency_id": currencyID},
+		}
+	}
+
 	return r.Get(ctx, productID)
 }
 
@@ -564,10 +650,14 @@ func (r *ProductRepository) DetachCurrency(ctx context.Context, productCurrencyI
 	productID := ""
 	err := r.db.QueryRowContext(
 		ctx,
-		`DELETE FROM product_currency WHERE id = $1 RETURNING product_id`,
+		`DELETE FROM product_currency WHERE id = $1 AND product_id IN (SELECT id FROM product WHERE tenant_id = $2) RETURNING product_id`,
 		productCurrencyID,
+		tenant.FromContext(ctx),
 	).Scan(&productID)
 
+	if err == sql.ErrNoRows {
+		return nil, repository.NotFound("product currency", productCurrencyID)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -590,8 +680,12 @@ func (r *ProductRepository) ListPrice(ctx context.Context, productID string) ([]
 				created_at,
 				updated_at
 			FROM price
+			WHERE product_id = $1
+			AND tenant_id = $2
 			ORDER BY created_at DESC 
 		`,
+		productID,
+		tenant.FromContext(ctx),
 	)
 
 	if err != nil {
@@ -625,3 +719,93 @@ func (r *ProductRepository) ListPrice(ctx context.Context, productID string) ([]
 
 	return prices, nil
 }
+
+// Archive a product
+func (r *ProductRepository) Archive(ctx context.Context, productID string) (*v1.Product, error) {
+	_, err := r.db.ExecContext(
+		ctx,
+		`UPDATE product SET archived_at = current_timestamp() WHERE id = $1 AND tenant_id = $2 AND archived_at IS NULL`,
+		productID,
+		tenant.FromContext(ctx),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Get(ctx, productID)
+}
+
+// Delete a product together with its prices, force also detaches it from shops
+func (r *ProductRepository) Delete(ctx context.Context, productID string, force bool) (bool, error) {
+	options := sql.TxOptions{
+		ReadOnly: false,
+	}
+
+	// Start a transaction
+	tx, err := r.db.BeginTx(ctx, &options)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// Make sure the product belongs to the tenant before touching its prices
+	found, err := repository.InTenant(ctx, tx, "product", productID)
+	if err != nil {
+		return false, err
+	}
+
+	if !found {
+		return false, repository.NotFound("product", productID)
+	}
+
+	reference := repository.Reference{Table: "shop_product", Column: "product_id"}
+
+	// Refuse to delete products that are still sold in a shop unless forced
+	referenced, err := repository.IsReferenced(ctx, tx, productID, reference)
+	if err != nil {
+		return false, err
+	}
+
+	if referenced && !force {
+		return false, repository.Referenced("product", productID)
+	}
+
+	// The prices, items and currencies of a product belong to the product
+	queries := []string{
+		`DELETE FROM shop_product WHERE product_id = $1`,
+		`DELETE FROM price_item WHERE price_id IN (SELECT id FROM price WHERE product_id = $1)`,
+		`DELETE FROM price_currency WHERE price_id IN (SELECT id FROM price WHERE product_id = $1)`,
+		`DELETE FROM price WHERE product_id = $1`,
+		`DELETE FROM product_item WHERE product_id = $1`,
+		`DELETE FROM product_currency WHERE product_id = $1`,
+	}
+
+	for _, query := range queries {
+		_, err = tx.ExecContext(ctx, query, productID)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	result, err := tx.ExecContext(
+		ctx,
+		`DELETE FROM product WHERE id = $1`,
+		productID,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	// Make sure the product existed
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return false, repository.NotFound("product", productID)
+	}
+
+	// Commit all changes to the database
+	if err = tx.Commit(); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// ErrReferenced is returned when a row can not be deleted because other rows still reference it
+var ErrReferenced = &Error{Reason: ReasonReferenced, Message: "row is still referenced"}
+
+// Referenced is returned when a resource can not be deleted because other rows still reference it,
+// resource is its singular name like currency or item
+func Referenced(resource string, id string) error {
+	return &Error{
+		Reason:   ReasonReferenced,
+		Message:  fmt.Sprintf("%s is still in use, set force to delete it anyway", resource),
+		Metadata: map[string]string{"resource": resource, "id": id},
+	}
+}
+
+// Reference is a column in a table that references another row
+type Reference struct {
+	Table  string
+	Column string
+}
+
+// IsReferenced checks if any of the references point to the given id
+func IsReferenced(ctx context.Context, tx *sql.Tx, id string, references ...Reference) (bool, error) {
+	for _, reference := range references {
+		count := int64(0)
+		err := tx.QueryRowContext(
+			ctx,
+			fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE %s = $1`, reference.Table, reference.Column),
+			id,
+		).Scan(&count)
+
+		if err != nil {
+			return false, err
+		}
+
+		if count > 0 {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// DeleteReferences deletes all rows that point to the given id
+func DeleteReferences(ctx context.Context, tx *sql.Tx, id string, references ...Reference) error {
+	for _, reference := range references {
+		_, err := tx.ExecContext(
+			ctx,
+			fmt.Sprintf(`DELETE FROM %s WHERE %s = $1`, reference.Table, reference.Column),
+			id,
+		)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
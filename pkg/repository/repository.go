@@ -5,27 +5,82 @@ import (
 	"time"
 
 	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
+	catalog "github.com/GameComponent/economy-service/pkg/catalog"
 )
 
 // AccountRepository interface
 type AccountRepository interface {
 	Create(ctx context.Context, email string, password string) (*v1.Account, error)
 	Update(ctx context.Context, accountID string, password string) (*v1.Account, error)
-	List(ctx context.Context, limit int32, offset int32) ([]*v1.Account, int32, error)
+	List(ctx context.Context, page Page) ([]*v1.Account, Cursor, int32, error)
 	Get(ctx context.Context, accountID string) (*v1.Account, error)
 	GetByEmail(ctx context.Context, email string) (*v1.Account, error)
+	GetByIdentity(ctx context.Context, issuer string, subject string) (*v1.Account, error)
+	LinkIdentity(ctx context.Context, accountID string, issuer string, subject string) error
 	AssignPermission(ctx context.Context, accountID string, permission string) (*v1.Account, error)
 	RevokePermission(ctx context.Context, accountID string, permission string) (*v1.Account, error)
-	CreateRefreshToken(ctx context.Context, token string, accountID string, expires *time.Time) error
-	InvalidateRefreshTokens(ctx context.Context, accountID string) error
-	GetAccountIDFromRefreshToken(ctx context.Context, token string) (string, error)
+	AssignRole(ctx context.Context, accountID string, role string) (*v1.Account, error)
+	RevokeRole(ctx context.Context, accountID string, role string) (*v1.Account, error)
+	VerifyEmail(ctx context.Context, accountID string) (*v1.Account, error)
+	CreateVerificationToken(ctx context.Context, accountID string, purpose string, hash string, expires time.Time) error
+	UseVerificationToken(ctx context.Context, purpose string, hash string) (string, error)
+	DeleteExpiredVerificationTokens(ctx context.Context, before time.Time) (int64, error)
+	SetTotpSecret(ctx context.Context, accountID string, secret string) error
+	GetTotpSecret(ctx context.Context, accountID string) (string, error)
+	EnableTotp(ctx context.Context, accountID string, recoveryCodeHashes []string) (*v1.Account, error)
+	DisableTotp(ctx context.Context, accountID string) (*v1.Account, error)
+	UseTotpStep(ctx context.Context, accountID string, step int64) error
+	UseRecoveryCode(ctx context.Context, accountID string, hash string) error
 }
 
+// APIKeyRepository interface
+type APIKeyRepository interface {
+	Create(ctx context.Context, accountID string, name string, prefix string, hash string, permissions []string, roles []string, expiresAt *time.Time) (*v1.ApiKey, error)
+	Get(ctx context.Context, apiKeyID string) (*v1.ApiKey, error)
+	GetByHash(ctx context.Context, hash string) (*v1.ApiKey, error)
+	List(ctx context.Context, accountID string, page Page) ([]*v1.ApiKey, Cursor, int32, error)
+	Revoke(ctx context.Context, apiKeyID string) (*v1.ApiKey, error)
+}
+
+// AuditRepository interface, audit events can only be recorded and never changed
+type AuditRepository interface {
+	Record(ctx context.Context, event *v1.AuditEvent) error
+	List(ctx context.Context, filter AuditEventFilter, page Page) ([]*v1.AuditEvent, Cursor, int32, error)
+}
+
+// AuditEventFilter narrows down the listed audit events, empty fields match every event
+type AuditEventFilter struct {
+	AccountID string
+	// Method is a pattern like the ones of permissions
+	Method    string
+	Outcome   string
+	StartTime *time.Time
+	EndTime   *time.Time
+}
+
+// CatalogRepository interface
+type CatalogRepository interface {
+	Export(ctx context.Context) (*catalog.Catalog, error)
+	Import(ctx context.Context, desired *catalog.Catalog, prune bool, dryRun bool) (*catalog.Plan, error)
+	CreateVersion(ctx context.Context, name string, document string) (*v1.CatalogVersion, error)
+	UpdateVersion(ctx context.Context, catalogVersionID string, name string, document string) (*v1.CatalogVersion, error)
+	GetVersion(ctx context.Context, catalogVersionID string) (*v1.CatalogVersion, error)
+	GetPreviousVersion(ctx context.Context) (*v1.CatalogVersion, error)
+	ListVersions(ctx context.Context, page Page) ([]*v1.CatalogVersion, Cursor, int32, error)
+	PublishVersion(ctx context.Context, catalogVersionID string) (*v1.CatalogVersion, *catalog.Plan, error)
+}
+
+// Errors returned when changing catalog versions
+var (
+	ErrCatalogVersionNotDraft  = &Error{Reason: ReasonCatalogVersionNotDraft, Message: "only draft catalog versions can be changed"}
+	ErrCatalogVersionPublished = &Error{Reason: ReasonCatalogVersionPublished, Message: "catalog version is already published"}
+)
+
 // ConfigRepository interface
 type ConfigRepository interface {
 	Get(ctx context.Context, key string) (*v1.Config, error)
 	Set(ctx context.Context, key string, value string) (*v1.Config, error)
-	List(ctx context.Context, limit int32, offset int32) ([]*v1.Config, int32, error)
+	List(ctx context.Context, page Page) ([]*v1.Config, Cursor, int32, error)
 }
 
 // CurrencyRepository interface
@@ -33,7 +88,9 @@ type CurrencyRepository interface {
 	Create(ctx context.Context, name string, shortName string, symbol string) (*v1.Currency, error)
 	Update(ctx context.Context, currencyID string, name string, shortName string, symbol string) (*v1.Currency, error)
 	Get(ctx context.Context, currencyID string) (*v1.Currency, error)
-	List(ctx context.Context, limit int32, offset int32) ([]*v1.Currency, int32, error)
+	List(ctx context.Context, page Page) ([]*v1.Currency, Cursor, int32, error)
+	Archive(ctx context.Context, currencyID string) (*v1.Currency, error)
+	Delete(ctx context.Context, currencyID string, force bool) (bool, error)
 }
 
 // ItemRepository interface
@@ -41,8 +98,16 @@ type ItemRepository interface {
 	Create(ctx context.Context, name string, stackable bool, stackMaxAmount int64, stackBalancingMethod int64, metadata string) (*v1.Item, error)
 	Get(ctx context.Context, itemID string) (*v1.Item, error)
 	Update(ctx context.Context, itemID string, name string, metadata string) (*v1.Item, error)
-	List(ctx context.Context, limit int32, offset int32) ([]*v1.Item, int32, error)
-	Search(ctx context.Context, query string, limit int32, offset int32) ([]*v1.Item, int32, error)
+	List(ctx context.Context, page Page) ([]*v1.Item, Cursor, int32, error)
+	Search(ctx context.Context, query string, page Page) ([]*v1.Item, Cursor, int32, error)
+	Archive(ctx context.Context, itemID string) (*v1.Item, error)
+	Delete(ctx context.Context, itemID string, force bool) (bool, error)
+}
+
+// LedgerRepository interface
+type LedgerRepository interface {
+	ListByPlayer(ctx context.Context, playerID string) ([]*v1.LedgerEntry, error)
+	ListPurchasesByPlayer(ctx context.Context, playerID string) ([]*v1.Purchase, error)
 }
 
 // PlayerRepository interface
@@ -50,8 +115,11 @@ type PlayerRepository interface {
 	Create(ctx context.Context, playerID string, name string, metadata string) (*v1.Player, error)
 	Update(ctx context.Context, playerID string, name string, metadata string) (*v1.Player, error)
 	Get(ctx context.Context, playerID string) (*v1.Player, error)
-	List(ctx context.Context, limit int32, offset int32) ([]*v1.Player, int32, error)
-	Search(ctx context.Context, query string, limit int32, offset int32) ([]*v1.Player, int32, error)
+	List(ctx context.Context, page Page) ([]*v1.Player, Cursor, int32, error)
+	Search(ctx context.Context, query string, page Page) ([]*v1.Player, Cursor, int32, error)
+	Archive(ctx context.Context, playerID string) (*v1.Player, error)
+	Delete(ctx context.Context, playerID string, force bool) (bool, error)
+	Erase(ctx context.Context, playerID string) (*v1.Player, error)
 }
 
 // PriceRepository interface
@@ -70,24 +138,52 @@ type ProductRepository interface {
 	Create(ctx context.Context, name string) (*v1.Product, error)
 	Get(ctx context.Context, productID string) (*v1.Product, error)
 	Update(ctx context.Context, productID string, name string) (*v1.Product, error)
-	List(ctx context.Context, limit int32, offset int32) ([]*v1.Product, int32, error)
-	Search(ctx context.Context, query string, limit int32, offset int32) ([]*v1.Product, int32, error)
+	List(ctx context.Context, page Page) ([]*v1.Product, Cursor, int32, error)
+	Search(ctx context.Context, query string, page Page) ([]*v1.Product, Cursor, int32, error)
 	AttachItem(ctx context.Context, productID string, itemID string, amount int64) (*v1.Product, error)
 	DetachItem(ctx context.Context, productItemID string) (*v1.Product, error)
 	AttachCurrency(ctx context.Context, productID string, currencyID string, amount int64) (*v1.Product, error)
 	DetachCurrency(ctx context.Context, productCurrencyID string) (*v1.Product, error)
 	BuyProduct(ctx context.Context, product *v1.Product, price *v1.Price, receivingStorage *v1.Storage, payingStorage *v1.Storage) (*v1.Product, error)
 	ListPrice(ctx context.Context, productID string) ([]*v1.Price, error)
+	Archive(ctx context.Context, productID string) (*v1.Product, error)
+	Delete(ctx context.Context, productID string, force bool) (bool, error)
+}
+
+// RoleRepository interface
+type RoleRepository interface {
+	Create(ctx context.Context, name string, permissions []string) (*v1.Role, error)
+	Get(ctx context.Context, name string) (*v1.Role, error)
+	List(ctx context.Context) ([]*v1.Role, error)
 }
 
+// SessionRepository interface
+type SessionRepository interface {
+	Create(ctx context.Context, accountID string, amr []string, userAgent string, ip string, tokenHash string, expiresAt time.Time) (*v1.Session, error)
+	Rotate(ctx context.Context, tokenHash string, newTokenHash string) (*v1.Session, error)
+	Get(ctx context.Context, sessionID string) (*v1.Session, error)
+	List(ctx context.Context, accountID string, page Page) ([]*v1.Session, Cursor, int32, error)
+	Revoke(ctx context.Context, sessionID string) (*v1.Session, error)
+	RevokeAll(ctx context.Context, accountID string) error
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+// ErrRefreshTokenReused is returned when a refresh token is exchanged a second time
+var ErrRefreshTokenReused = &Error{Reason: ReasonRefreshTokenReused, Message: "refresh_token was already used, the session is revoked"}
+
+// ErrTotpNotEnrolled is returned when two-factor authentication is confirmed before enrolling
+var ErrTotpNotEnrolled = &Error{Reason: ReasonTotpNotEnrolled, Message: "please enroll first"}
+
 // ShopRepository interface
 type ShopRepository interface {
 	Get(ctx context.Context, shopID string) (*v1.Shop, error)
 	Create(ctx context.Context, name string, metadata string) (*v1.Shop, error)
 	Update(ctx context.Context, shopID string, name string, metadata string) (*v1.Shop, error)
-	List(ctx context.Context, limit int32, offset int32) ([]*v1.Shop, int32, error)
+	List(ctx context.Context, page Page) ([]*v1.Shop, Cursor, int32, error)
 	AttachProduct(ctx context.Context, shopID string, productID string) (*v1.Shop, error)
 	DetachProduct(ctx context.Context, shopProductID string) (*v1.Shop, error)
+	Archive(ctx context.Context, shopID string) (*v1.Shop, error)
+	Delete(ctx context.Context, shopID string, force bool) (bool, error)
 }
 
 // StorageRepository interface
@@ -95,10 +191,47 @@ type StorageRepository interface {
 	Create(ctx context.Context, playerID string, name string, metadata string) (*v1.Storage, error)
 	Update(ctx context.Context, storageID string, name string, metadata string) (*v1.Storage, error)
 	Get(ctx context.Context, storageID string) (*v1.Storage, error)
-	GiveItem(ctx context.Context, storageID string, itemID string, amount int64) (*string, error)
-	IncreaseItemAmount(ctx context.Context, storageItemID string, amount int64) error
+	GiveItem(ctx context.Context, storageID string, itemID string, grant ItemGrant) error
 	GiveCurrency(ctx context.Context, storageID string, currencyID string, amount int64) (*v1.StorageCurrency, error)
-	List(ctx context.Context, limit int32, offset int32) ([]*v1.Storage, int32, error)
+	List(ctx context.Context, page Page) ([]*v1.Storage, Cursor, int32, error)
+	ListByPlayer(ctx context.Context, playerID string) ([]*v1.Storage, error)
 	SplitStack(ctx context.Context, storageItemID string, amounts []int64) (*v1.Storage, error)
 	MergeStack(ctx context.Context, toStorageItemID string, fromStorageItemID string) (*v1.Storage, error)
+	Archive(ctx context.Context, storageID string) (*v1.Storage, error)
+	Delete(ctx context.Context, storageID string, force bool) (bool, error)
+}
+
+// ItemGrant is how items given to a storage are spread over its stacks
+type ItemGrant struct {
+	// Increases are added to existing stacks
+	Increases []StackIncrease
+	// Stacks are the amounts of the new stacks
+	Stacks []int64
+}
+
+// StackIncrease is an amount added to an existing stack
+type StackIncrease struct {
+	StorageItemID string
+	Amount        int64
+}
+
+// Amount is the total amount of items of the grant
+func (g ItemGrant) Amount() int64 {
+	amount := int64(0)
+	for _, increase := range g.Increases {
+		amount += increase.Amount
+	}
+	for _, stack := range g.Stacks {
+		amount += stack
+	}
+
+	return amount
+}
+
+// TenantRepository interface
+type TenantRepository interface {
+	Create(ctx context.Context, tenantID string, name string) (*v1.Tenant, error)
+	Update(ctx context.Context, tenantID string, name string) (*v1.Tenant, error)
+	Get(ctx context.Context, tenantID string) (*v1.Tenant, error)
+	List(ctx context.Context, page Page) ([]*v1.Tenant, Cursor, int32, error)
 }
@@ -0,0 +1,119 @@
+package rolerepository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
+	repository "github.com/GameComponent/economy-service/pkg/repository"
+	tenant "github.com/GameComponent/economy-service/pkg/tenant"
+	ptypes "github.com/golang/protobuf/ptypes"
+	pq "github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// RoleRepository struct
+type RoleRepository struct {
+	db     *sql.DB
+	logger *zap.Logger
+}
+
+// NewRoleRepository constructor
+func NewRoleRepository(db *sql.DB, logger *zap.Logger) repository.RoleRepository {
+	return &RoleRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Create a role, an existing role with the same name gets its permissions replaced
+func (r *RoleRepository) Create(ctx context.Context, name string, permissions []string) (*v1.Role, error) {
+	_, err := r.db.ExecContext(
+		ctx,
+		`
+			INSERT INTO role(tenant_id, name, permissions)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (tenant_id, name)
+			DO UPDATE
+			SET permissions = excluded.permissions, updated_at = current_timestamp()
+		`,
+		tenant.FromContext(ctx),
+		name,
+		pq.Array(permissions),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Get(ctx, name)
+}
+
+// Get a role
+func (r *RoleRepository) Get(ctx context.Context, name string) (*v1.Role, error) {
+	role := &v1.Role{}
+	createdAt := time.Time{}
+	updatedAt := time.Time{}
+
+	err := r.db.QueryRowContext(
+		ctx,
+		`SELECT name, permissions, created_at, updated_at FROM role WHERE name = $1 AND tenant_id = $2`,
+		name,
+		tenant.FromContext(ctx),
+	).Scan(
+		&role.Name,
+		pq.Array(&role.Permissions),
+		&createdAt,
+		&updatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, repository.NotFound("role", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	role.CreatedAt, _ = ptypes.TimestampProto(createdAt)
+	role.UpdatedAt, _ = ptypes.TimestampProto(updatedAt)
+
+	return role, nil
+}
+
+// List all roles of the tenant
+func (r *RoleRepository) List(ctx context.Context) ([]*v1.Role, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT name, permissions, created_at, updated_at FROM role WHERE tenant_id = $1 ORDER BY name`,
+		tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Unwrap rows into roles
+	roles := []*v1.Role{}
+
+	for rows.Next() {
+		role := v1.Role{}
+		createdAt := time.Time{}
+		updatedAt := time.Time{}
+
+		err := rows.Scan(
+			&role.Name,
+			pq.Array(&role.Permissions),
+			&createdAt,
+			&updatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		role.CreatedAt, _ = ptypes.TimestampProto(createdAt)
+		role.UpdatedAt, _ = ptypes.TimestampProto(updatedAt)
+
+		roles = append(roles, &role)
+	}
+
+	return roles, nil
+}
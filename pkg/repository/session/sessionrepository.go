@@ -0,0 +1,390 @@
+package sessionrepository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
+	repository "github.com/GameComponent/economy-service/pkg/repository"
+	tenant "github.com/GameComponent/economy-service/pkg/tenant"
+	ptypes "github.com/golang/protobuf/ptypes"
+	pq "github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// SessionRepository struct
+type SessionRepository struct {
+	db     *sql.DB
+	logger *zap.Logger
+}
+
+// NewSessionRepository constructor
+func NewSessionRepository(db *sql.DB, logger *zap.Logger) repository.SessionRepository {
+	return &SessionRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+const columns = `
+	id,
+	account_id,
+	amr,
+	user_agent,
+	ip,
+	created_at,
+	last_used_at,
+	expires_at,
+	revoked_at
+`
+
+// Create a session with its first refresh token, only the hash of the token is stored
+func (r *SessionRepository) Create(ctx context.Context, accountID string, amr []string, userAgent string, ip string, tokenHash string, expiresAt time.Time) (*v1.Session, error) {
+	options := sql.TxOptions{
+		ReadOnly: false,
+	}
+
+	// Start a transaction
+	tx, err := r.db.BeginTx(ctx, &options)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	session, err := scan(tx.QueryRowContext(
+		ctx,
+		`
+			INSERT INTO account_session(account_id, tenant_id, amr, user_agent, ip, expires_at)
+			SELECT id, tenant_id, $2, $3, $4, $5
+			FROM account
+			WHERE id = $1
+			AND tenant_id = $6
+			RETURNING `+columns,
+		accountID,
+		pq.Array(amr),
+		userAgent,
+		ip,
+		expiresAt,
+		tenant.FromContext(ctx),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO account_refresh_token(hash, session_id) VALUES($1, $2)`,
+		tokenHash,
+		session.Id,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// Rotate exchanges a refresh token for a new one of the same session. A token that was
+// exchanged before means it leaked, the whole session is revoked and ErrRefreshTokenReused returned
+func (r *SessionRepository) Rotate(ctx context.Context, tokenHash string, newTokenHash string) (*v1.Session, error) {
+	options := sql.TxOptions{
+		ReadOnly: false,
+	}
+
+	// Start a transaction
+	tx, err := r.db.BeginTx(ctx, &options)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	sessionID := ""
+	err = tx.QueryRowContext(
+		ctx,
+		`
+			UPDATE account_refresh_token
+			SET used_at = current_timestamp()
+			WHERE hash = $1
+			AND used_at IS NULL
+			RETURNING session_id
+		`,
+		tokenHash,
+	).Scan(&sessionID)
+
+	if err == sql.ErrNoRows {
+		return nil, r.revokeReused(ctx, tx, tokenHash)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := scan(tx.QueryRowContext(
+		ctx,
+		`
+			UPDATE account_session
+			SET last_used_at = current_timestamp()
+			WHERE id = $1
+			AND tenant_id = $2
+			AND revoked_at IS NULL
+			AND expires_at > current_timestamp()
+			RETURNING `+columns,
+		sessionID,
+		tenant.FromContext(ctx),
+	))
+	if err == sql.ErrNoRows {
+		return nil, repository.NotFound("session", sessionID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO account_refresh_token(hash, session_id) VALUES($1, $2)`,
+		newTokenHash,
+		session.Id,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// revokeReused revokes the session of a token that was already exchanged,
+// unknown tokens are not found
+func (r *SessionRepository) revokeReused(ctx context.Context, tx *sql.Tx, tokenHash string) error {
+	sessionID := ""
+	err := tx.QueryRowContext(
+		ctx,
+		`SELECT session_id FROM account_refresh_token WHERE hash = $1`,
+		tokenHash,
+	).Scan(&sessionID)
+	if err == sql.ErrNoRows {
+		return repository.NotFound("refresh token", "")
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`
+			UPDATE account_session
+			SET revoked_at = current_timestamp()
+			WHERE id = $1
+			AND tenant_id = $2
+			AND revoked_at IS NULL
+		`,
+		sessionID,
+		tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	r.logger.Warn("refresh token reused, session revoked", zap.String("session_id", sessionID))
+
+	return repository.ErrRefreshTokenReused
+}
+
+// Get a session
+func (r *SessionRepository) Get(ctx context.Context, sessionID string) (*v1.Session, error) {
+	session, err := scan(r.db.QueryRowContext(
+		ctx,
+		`SELECT `+columns+` FROM account_session WHERE id = $1 AND tenant_id = $2`,
+		sessionID,
+		tenant.FromContext(ctx),
+	))
+	if err == sql.ErrNoRows {
+		return nil, repository.NotFound("session", sessionID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// List the active sessions of an account, the most recently used first
+func (r *SessionRepository) List(ctx context.Context, accountID string, page repository.Page) ([]*v1.Session, repository.Cursor, int32, error) {
+	keyset, err := repository.NewKeyset(page, repository.Desc("last_used_at"), repository.Desc("id"))
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	query, args := keyset.Query(
+		`
+			SELECT
+				`+columns+`,
+				(
+					SELECT COUNT(id)
+					FROM account_session
+					WHERE tenant_id = $1
+					AND account_id = $2
+					AND revoked_at IS NULL
+					AND expires_at > current_timestamp()
+				) AS total_size
+			FROM account_session
+			WHERE tenant_id = $1
+			AND account_id = $2
+			AND revoked_at IS NULL
+			AND expires_at > current_timestamp()
+		`,
+		tenant.FromContext(ctx),
+		accountID,
+	)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	defer rows.Close()
+
+	// Unwrap rows into sessions
+	sessions := []*v1.Session{}
+	totalSize := int32(0)
+
+	for rows.Next() {
+		session, err := scan(rows, &totalSize)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+
+		if !keyset.Add(session.LastUsedAt, session.Id) {
+			break
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	return sessions, keyset.Next(), totalSize, nil
+}
+
+// Revoke a session, its refresh tokens stop working right away
+func (r *SessionRepository) Revoke(ctx context.Context, sessionID string) (*v1.Session, error) {
+	_, err := r.db.ExecContext(
+		ctx,
+		`UPDATE account_session SET revoked_at = current_timestamp() WHERE id = $1 AND tenant_id = $2 AND revoked_at IS NULL`,
+		sessionID,
+		tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Get(ctx, sessionID)
+}
+
+// RevokeAll revokes every session of an account
+func (r *SessionRepository) RevokeAll(ctx context.Context, accountID string) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`UPDATE account_session SET revoked_at = current_timestamp() WHERE account_id = $1 AND tenant_id = $2 AND revoked_at IS NULL`,
+		accountID,
+		tenant.FromContext(ctx),
+	)
+
+	return err
+}
+
+// DeleteExpired removes the sessions of all tenants that expired or were revoked before a moment,
+// together with their refresh tokens
+func (r *SessionRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	options := sql.TxOptions{
+		ReadOnly: false,
+	}
+
+	// Start a transaction
+	tx, err := r.db.BeginTx(ctx, &options)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(
+		ctx,
+		`
+			DELETE FROM account_refresh_token
+			WHERE session_id IN (
+				SELECT id FROM account_session WHERE expires_at < $1 OR revoked_at < $1
+			)
+		`,
+		before,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := tx.ExecContext(
+		ctx,
+		`DELETE FROM account_session WHERE expires_at < $1 OR revoked_at < $1`,
+		before,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return deleted, tx.Commit()
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scan reads a session from a row, extra destinations are scanned after the session's columns
+func scan(row scanner, extra ...interface{}) (*v1.Session, error) {
+	session := v1.Session{}
+	createdAt := time.Time{}
+	lastUsedAt := time.Time{}
+	expiresAt := time.Time{}
+	revokedAt := pq.NullTime{}
+
+	dest := []interface{}{
+		&session.Id,
+		&session.AccountId,
+		pq.Array(&session.Amr),
+		&session.UserAgent,
+		&session.Ip,
+		&createdAt,
+		&lastUsedAt,
+		&expiresAt,
+		&revokedAt,
+	}
+
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	session.CreatedAt, _ = ptypes.TimestampProto(createdAt)
+	session.LastUsedAt, _ = ptypes.TimestampProto(lastUsedAt)
+	session.ExpiresAt, _ = ptypes.TimestampProto(expiresAt)
+
+	if revokedAt.Valid {
+		session.RevokedAt, _ = ptypes.TimestampProto(revokedAt.Time)
+	}
+
+	return &session, nil
+}
@@ -10,6 +10,7 @@ import (
 
 	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
 	repository "github.com/GameComponent/economy-service/pkg/repository"
+	tenant "github.com/GameComponent/economy-service/pkg/tenant"
 	ptypes "github.com/golang/protobuf/ptypes"
 	"go.uber.org/zap"
 )
@@ -58,6 +59,7 @@ func (r *ShopRepository) Get(ctx context.Context, shopID string) (*v1.Shop, erro
 				shop.name as shopName,
 				shop.created_at as shopCreatedAt,
 				shop.updated_at as shopUpdatedAt,
+				shop.archived_at as shopArchivedAt,
 				product.id AS productId,
 				product.name AS productName,
 				product.created_at AS productCreatedAt,
@@ -95,7 +97,7 @@ func (r *ShopRepository) Get(ctx context.Context, shopID string) (*v1.Shop, erro
 				price_item_item.updated_at AS priceItemItemUpdatedAt
 			FROM shop
       LEFT JOIN shop_product ON (shop_product.shop_id = shop.id)
-      LEFT JOIN product ON (product.id = shop_product.product_id)
+      LEFT JOIN product ON (product.id = shop_product.product_id AND product.archived_at IS NULL)
       LEFT JOIN product_item ON (product_item.product_id = product.id)
       LEFT JOIN item ON (item.id = product_item.item_id)
       LEFT JOIN product_currency ON (product_currency.product_id = product.id)
@@ -106,8 +108,10 @@ func (r *ShopRepository) Get(ctx context.Context, shopID string) (*v1.Shop, erro
       LEFT JOIN currency price_currency_currency ON (price_currency_currency.id = price_currency.currency_id)
       LEFT JOIN item price_item_item ON (price_item_item.id = price_item.item_id)
 			WHERE shop.id = $1
+			AND shop.tenant_id = $2
 		`,
 		shopID,
+		tenant.FromContext(ctx),
 	)
 
 	if err != nil {
@@ -119,6 +123,7 @@ func (r *ShopRepository) Get(ctx context.Context, shopID string) (*v1.Shop, erro
 		ShopName                          string
 		ShopCreatedAt                     time.Time
 		ShopUpdatedAt                     time.Time
+		ShopArchivedAt                    NullTime
 		ProductID                         sql.NullString
 		ProductName                       sql.NullString
 		ProductCreatedAt                  NullTime
@@ -171,6 +176,7 @@ func (r *ShopRepository) Get(ctx context.Context, shopID string) (*v1.Shop, erro
 			&res.ShopName,
 			&res.ShopCreatedAt,
 			&res.ShopUpdatedAt,
+			&res.ShopArchivedAt,
 			&res.ProductID,
 			&res.ProductName,
 			&res.ProductCreatedAt,
@@ -343,6 +349,10 @@ func (r *ShopRepository) Get(ctx context.Context, shopID string) (*v1.Shop, erro
 		}
 	}
 
+	if res.ShopID == "" {
+		return nil, repository.NotFound("shop", shopID)
+	}
+
 	// Convert item map into item slice
 	products := []*v1.Product{}
 	for shopProductKey, shopProductValue := range shopProducts {
@@ -394,6 +404,11 @@ func (r *ShopRepository) Get(ctx context.Context, shopID string) (*v1.Shop, erro
 	shop.CreatedAt, _ = ptypes.TimestampProto(res.ShopCreatedAt)
 	shop.UpdatedAt, _ = ptypes.TimestampProto(res.ShopUpdatedAt)
 
+	// Archived shops are still resolvable
+	if res.ShopArchivedAt.Valid {
+		shop.ArchivedAt, _ = ptypes.TimestampProto(res.ShopArchivedAt.Time)
+	}
+
 	return shop, nil
 }
 
@@ -407,9 +422,10 @@ func (r *ShopRepository) Create(ctx context.Context, name string, metadata strin
 	shopID := ""
 	err := r.db.QueryRowContext(
 		ctx,
-		`INSERT INTO shop(name, metadata) VALUES ($1, $2) RETURNING id`,
+		`INSERT INTO shop(name, metadata, tenant_id) VALUES ($1, $2, $3) RETURNING id`,
 		name,
 		metadata,
+		tenant.FromContext(ctx),
 	).Scan(&shopID)
 
 	if err != nil {
@@ -440,12 +456,12 @@ func (r *ShopRepository) Update(ctx context.Context, shopID string, name string,
 	}
 
 	if index <= 1 {
-		return nil, fmt.Errorf("no arguments given")
+		return nil, repository.ErrNothingToUpdate
 	}
 
 	// Update the shop
-	arguments = append(arguments, shopID)
-	query := fmt.Sprintf("UPDATE shop SET %v WHERE id =$%v", strings.Join(queries, ", "), index)
+	arguments = append(arguments, shopID, tenant.FromContext(ctx))
+	query := fmt.Sprintf("UPDATE shop SET %v WHERE id =$%v AND tenant_id = $%v", strings.Join(queries, ", "), index, index+1)
 	_, err := r.db.ExecContext(
 		ctx,
 		query,
@@ -458,64 +474,112 @@ func (r *ShopRepository) Update(ctx context.Context, shopID string, name string,
 	return r.Get(ctx, shopID)
 }
 
-// List all shops
-func (r *ShopRepository) List(ctx context.Context, limit int32, offset int32) ([]*v1.Shop, int32, error) {
-	// Query shops from the database
-	rows, err := r.db.QueryContext(
-		ctx,
+// shopFields are the fields shops can be filtered and sorted by
+var shopFields = repository.Fields{
+	"id":         {Column: "id", Type: repository.UUIDField},
+	"name":       {Column: "name", Type: repository.StringField, Sortable: true},
+	"created_at": {Column: "created_at", Type: repository.TimestampField, Sortable: true},
+	"updated_at": {Column: "updated_at", Type: repository.TimestampField, Sortable: true},
+	"metadata":   {Column: "metadata", Type: repository.JSONField},
+}
+
+// List the shops matching the filter of the page, the newest first unless the page has an order
+func (r *ShopRepository) List(ctx context.Context, page repository.Page) ([]*v1.Shop, repository.Cursor, int32, error) {
+	filter, keyset, err := repository.NewFilteredKeyset(page, shopFields, repository.Desc("created_at"), repository.Desc("id"))
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	condition, args := filter.Condition([]interface{}{tenant.FromContext(ctx)})
+	conditions := `
+		WHERE archived_at IS NULL
+		AND tenant_id = $1
+		AND ` + condition
+
+	query, args := keyset.Query(
 		`
 			SELECT
 				id,
 				name,
-				(SELECT COUNT(id) FROM shop) AS total_size
+				created_at,
+				updated_at,
+				(SELECT COUNT(id) FROM shop `+conditions+`) AS total_size
 			FROM shop
-			LIMIT $1
-			OFFSET $2
-		`,
-		limit,
-		offset,
+		`+conditions,
+		args...,
 	)
 
+	// Query shops from the database
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, 0, err
+		return nil, nil, 0, err
 	}
 	defer rows.Close()
 
 	// Unwrap rows into shops
 	shops := []*v1.Shop{}
-	totalSize := int32(1)
+	totalSize := int32(0)
 
 	for rows.Next() {
 		shop := v1.Shop{}
+		createdAt := time.Time{}
+		updatedAt := time.Time{}
 
 		err := rows.Scan(
 			&shop.Id,
 			&shop.Name,
+			&createdAt,
+			&updatedAt,
 			&totalSize,
 		)
 		if err != nil {
-			return nil, 0, err
+			return nil, nil, 0, err
+		}
+
+		shop.CreatedAt, _ = ptypes.TimestampProto(createdAt)
+		shop.UpdatedAt, _ = ptypes.TimestampProto(updatedAt)
+
+		if !keyset.AddMessage(&shop) {
+			break
 		}
 
 		shops = append(shops, &shop)
 	}
 
-	return shops, totalSize, nil
+	return shops, keyset.Next(), totalSize, nil
 }
 
 // AttachProduct to a shop
 func (r *ShopRepository) AttachProduct(ctx context.Context, shopID string, productID string) (*v1.Shop, error) {
-	_, err := r.db.ExecContext(
+	// Both the shop and the product have to belong to the tenant
+	result, err := r.db.ExecContext(
 		ctx,
-		`INSERT INTO shop_product(shop_id, product_id) VALUES ($1, $2)`,
+		`
+			INSERT INTO shop_product(shop_id, product_id)
+			SELECT shop.id, product.id
+			FROM shop, product
+			WHERE shop.id = $1
+			AND product.id = $2
+			AND shop.tenant_id = $3
+			AND product.tenant_id = $3
+		`,
 		shopID,
 		productID,
+		tenant.FromContext(ctx),
 	)
 
 	if err != nil {
 		return nil, err
 	}
 
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return nil, &repository.Error{
+			Reason:   repository.ReasonNotFound,
+			Message:  "shop or product not found",
+			Metadata: map[string]string{"shop_id": shopID, "product_id": productID},
+		}
+	}
+
 	return r.Get(ctx, shopID)
 }
 
@@ -524,10 +588,14 @@ func (r *ShopRepository) DetachProduct(ctx context.Context, shopProductID string
 	shopID := ""
 	err := r.db.QueryRowContext(
 		ctx,
-		`DELETE FROM shop_product WHERE product_id = $1 RETURNING shop_id`,
+		`DELETE FROM shop_product WHERE product_id = $1 AND shop_id IN (SELECT id FROM shop WHERE tenant_id = $2) RETURNING shop_id`,
 		shopProductID,
+		tenant.FromContext(ctx),
 	).Scan(&shopID)
 
+	if err == sql.ErrNoRows {
+		return nil, repository.NotFound("shop product", shopProductID)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -538,3 +606,81 @@ func (r *ShopRepository) DetachProduct(ctx context.Context, shopProductID string
 
 	return r.Get(ctx, shopID)
 }
+
+// Archive a shop
+func (r *ShopRepository) Archive(ctx context.Context, shopID string) (*v1.Shop, error) {
+	_, err := r.db.ExecContext(
+		ctx,
+		`UPDATE shop SET archived_at = current_timestamp() WHERE id = $1 AND tenant_id = $2 AND archived_at IS NULL`,
+		shopID,
+		tenant.FromContext(ctx),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Get(ctx, shopID)
+}
+
+// Delete a shop, force also detaches the products of the shop
+func (r *ShopRepository) Delete(ctx context.Context, shopID string, force bool) (bool, error) {
+	options := sql.TxOptions{
+		ReadOnly: false,
+	}
+
+	// Start a transaction
+	tx, err := r.db.BeginTx(ctx, &options)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// Make sure the shop belongs to the tenant before touching its products
+	found, err := repository.InTenant(ctx, tx, "shop", shopID)
+	if err != nil {
+		return false, err
+	}
+
+	if !found {
+		return false, repository.NotFound("shop", shopID)
+	}
+
+	reference := repository.Reference{Table: "shop_product", Column: "shop_id"}
+
+	// Refuse to delete shops that still have products unless forced
+	referenced, err := repository.IsReferenced(ctx, tx, shopID, reference)
+	if err != nil {
+		return false, err
+	}
+
+	if referenced && !force {
+		return false, repository.Referenced("shop", shopID)
+	}
+
+	err = repository.DeleteReferences(ctx, tx, shopID, reference)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := tx.ExecContext(
+		ctx,
+		`DELETE FROM shop WHERE id = $1`,
+		shopID,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	// Make sure the shop existed
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return false, repository.NotFound("shop", shopID)
+	}
+
+	// Commit all changes to the database
+	if err = tx.Commit(); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
@@ -2,13 +2,74 @@ package shoprepository_test
 
 import (
 	"context"
+	"database/sql/driver"
 	"testing"
 	"time"
 
 	sqlmock "github.com/DATA-DOG/go-sqlmock"
 	shoprepository "github.com/GameComponent/economy-service/pkg/repository/shop"
+	"go.uber.org/zap"
 )
 
+// columns are the columns Get scans, in order
+var columns = []string{
+	"shopId",
+	"shopName",
+	"shopCreatedAt",
+	"shopUpdatedAt",
+	"shopArchivedAt",
+	"productId",
+	"productName",
+	"productCreatedAt",
+	"productUpdatedAt",
+	"productItemId",
+	"productItemAmount",
+	"productCurrencyId",
+	"productCurrencyAmount",
+	"itemId",
+	"itemName",
+	"itemStackable",
+	"itemStackMaxAmount",
+	"itemStackBalancingMethod",
+	"itemCreatedAt",
+	"itemUpdatedAt",
+	"currencyId",
+	"currencyName",
+	"currencyShortName",
+	"currencySymbol",
+	"priceId",
+	"priceCurrencyId",
+	"priceCurrencyAmount",
+	"priceItemId",
+	"priceItemAmount",
+	"priceCurrencyCurrencyId",
+	"priceCurrencyCurrencyName",
+	"priceCurrencyCurrencyShortName",
+	"priceCurrencyCurrencySymbol",
+	"priceItemItemId",
+	"priceItemItemName",
+	"priceItemItemStackable",
+	"priceItemItemStackMaxAmount",
+	"priceItemItemStackBalancingMethod",
+	"priceItemItemCreatedAt",
+	"priceItemItemUpdatedAt",
+}
+
+// shopRow returns a row of the shop "shop.id", values sets columns by name and the others are null
+func shopRow(values map[string]driver.Value) []driver.Value {
+	row := make([]driver.Value, len(columns))
+	for i, column := range columns {
+		row[i] = values[column]
+	}
+
+	row[0] = "shop.id"
+	row[1] = "shop.name"
+	row[2] = time.Now()
+	row[3] = time.Now()
+
+	return row
+}
+
 func TestGet(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -16,38 +77,23 @@ func TestGet(t *testing.T) {
 	}
 	defer db.Close()
 
-	columns := sqlmock.NewRows([]string{
-		"shop.id",
-		"shop.name",
-		"shop.created_at",
-		"shop.updated_at",
-		"product.id",
-		"product.name",
-		"product.created_at",
-		"product.updated_at",
-		"item.id",
-		"item.name",
-		"item.metadata",
-		"product_item.id",
-		"product_item.amount",
-	}).AddRow(
-		"shop.id",
-		"shop.name",
-		time.Now(),
-		time.Now(),
-		"product.id",
-		"product.name",
-		time.Now(),
-		time.Now(),
-		"item.id",
-		"item.name",
-		"{}",
-		"product_item.id",
-		2,
-	)
-	mock.ExpectQuery("SELECT (.+)").WillReturnRows(columns)
-
-	shopRepository := shoprepository.NewShopRepository(db)
+	rows := sqlmock.NewRows(columns).
+		AddRow(shopRow(map[string]driver.Value{
+			"productId":         "product.id",
+			"productName":       "product.name",
+			"productCreatedAt":  time.Now(),
+			"productUpdatedAt":  time.Now(),
+			"productItemId":     "product_item.id",
+			"productItemAmount": 2,
+			"itemId":            "item.id",
+			"itemName":          "item.name",
+			"itemStackable":     false,
+			"itemCreatedAt":     time.Now(),
+			"itemUpdatedAt":     time.Now(),
+		})...)
+	mock.ExpectQuery("SELECT (.+)").WillReturnRows(rows)
+
+	shopRepository := shoprepository.NewShopRepository(db, zap.NewNop())
 	result, err := shopRepository.Get(context.Background(), "shop.id")
 	if err != nil {
 		t.Error(err)
@@ -122,52 +168,36 @@ func TestGet2Products(t *testing.T) {
 	}
 	defer db.Close()
 
-	columns := sqlmock.NewRows([]string{
-		"shop.id",
-		"shop.name",
-		"shop.created_at",
-		"shop.updated_at",
-		"product.id",
-		"product.name",
-		"product.created_at",
-		"product.updated_at",
-		"item.id",
-		"item.name",
-		"item.metadata",
-		"product_item.id",
-		"product_item.amount",
-	}).AddRow(
-		"shop.id",
-		"shop.name",
-		time.Now(),
-		time.Now(),
-		"product.id",
-		"product.name",
-		time.Now(),
-		time.Now(),
-		"item.id",
-		"item.name",
-		"{}",
-		"product_item.id",
-		2,
-	).AddRow(
-		"shop.id",
-		"shop.name",
-		time.Now(),
-		time.Now(),
-		"product.idb",
-		"product.nameb",
-		time.Now(),
-		time.Now(),
-		"item.idb",
-		"item.nameb",
-		"{}",
-		"product_item.idb",
-		2,
-	)
-	mock.ExpectQuery("SELECT (.+)").WillReturnRows(columns)
-
-	shopRepository := shoprepository.NewShopRepository(db)
+	rows := sqlmock.NewRows(columns).
+		AddRow(shopRow(map[string]driver.Value{
+			"productId":         "product.id",
+			"productName":       "product.name",
+			"productCreatedAt":  time.Now(),
+			"productUpdatedAt":  time.Now(),
+			"productItemId":     "product_item.id",
+			"productItemAmount": 2,
+			"itemId":            "item.id",
+			"itemName":          "item.name",
+			"itemStackable":     false,
+			"itemCreatedAt":     time.Now(),
+			"itemUpdatedAt":     time.Now(),
+		})...).
+		AddRow(shopRow(map[string]driver.Value{
+			"productId":         "product.idb",
+			"productName":       "product.nameb",
+			"productCreatedAt":  time.Now(),
+			"productUpdatedAt":  time.Now(),
+			"productItemId":     "product_item.idb",
+			"productItemAmount": 2,
+			"itemId":            "item.idb",
+			"itemName":          "item.nameb",
+			"itemStackable":     false,
+			"itemCreatedAt":     time.Now(),
+			"itemUpdatedAt":     time.Now(),
+		})...)
+	mock.ExpectQuery("SELECT (.+)").WillReturnRows(rows)
+
+	shopRepository := shoprepository.NewShopRepository(db, zap.NewNop())
 	result, err := shopRepository.Get(context.Background(), "shop.id")
 	if err != nil {
 		t.Error(err)
@@ -187,14 +217,14 @@ func TestGet2Products(t *testing.T) {
 
 	products := result.GetProducts()
 	if len(products) != 2 {
-		t.Errorf("result.GetProducts() should return 2 products")
+		t.Fatalf("result.GetProducts() should return 2 products")
 	}
 
+	// The products are not in a fixed order
 	product := products[0]
 	productB := products[1]
-
-	if product == nil {
-		t.Errorf("product.GetProducts() first item should not be nil")
+	if product.GetId() == "product.idb" {
+		product, productB = productB, product
 	}
 
 	if product.GetId() != "product.id" {
@@ -221,52 +251,36 @@ func TestGet2Items(t *testing.T) {
 	}
 	defer db.Close()
 
-	columns := sqlmock.NewRows([]string{
-		"shop.id",
-		"shop.name",
-		"shop.created_at",
-		"shop.updated_at",
-		"product.id",
-		"product.name",
-		"product.created_at",
-		"product.updated_at",
-		"item.id",
-		"item.name",
-		"item.metadata",
-		"product_item.id",
-		"product_item.amount",
-	}).AddRow(
-		"shop.id",
-		"shop.name",
-		time.Now(),
-		time.Now(),
-		"product.id",
-		"product.name",
-		time.Now(),
-		time.Now(),
-		"item.id",
-		"item.name",
-		"{}",
-		"product_item.id",
-		2,
-	).AddRow(
-		"shop.id",
-		"shop.name",
-		time.Now(),
-		time.Now(),
-		"product.id",
-		"product.name",
-		time.Now(),
-		time.Now(),
-		"item.idb",
-		"item.nameb",
-		"{}",
-		"product_item.idb",
-		4,
-	)
-	mock.ExpectQuery("SELECT (.+)").WillReturnRows(columns)
-
-	shopRepository := shoprepository.NewShopRepository(db)
+	rows := sqlmock.NewRows(columns).
+		AddRow(shopRow(map[string]driver.Value{
+			"productId":         "product.id",
+			"productName":       "product.name",
+			"productCreatedAt":  time.Now(),
+			"productUpdatedAt":  time.Now(),
+			"productItemId":     "product_item.id",
+			"productItemAmount": 2,
+			"itemId":            "item.id",
+			"itemName":          "item.name",
+			"itemStackable":     false,
+			"itemCreatedAt":     time.Now(),
+			"itemUpdatedAt":     time.Now(),
+		})...).
+		AddRow(shopRow(map[string]driver.Value{
+			"productId":         "product.id",
+			"productName":       "product.name",
+			"productCreatedAt":  time.Now(),
+			"productUpdatedAt":  time.Now(),
+			"productItemId":     "product_item.idb",
+			"productItemAmount": 4,
+			"itemId":            "item.idb",
+			"itemName":          "item.nameb",
+			"itemStackable":     false,
+			"itemCreatedAt":     time.Now(),
+			"itemUpdatedAt":     time.Now(),
+		})...)
+	mock.ExpectQuery("SELECT (.+)").WillReturnRows(rows)
+
+	shopRepository := shoprepository.NewShopRepository(db, zap.NewNop())
 	result, err := shopRepository.Get(context.Background(), "shop.id")
 	if err != nil {
 		t.Error(err)
@@ -304,12 +318,14 @@ func TestGet2Items(t *testing.T) {
 
 	productItems := product.Items
 	if len(productItems) != 2 {
-		t.Errorf("product.GetProducts() should return 2 items")
+		t.Fatalf("product.GetProducts() should return 2 items")
 	}
 
+	// The items are not in a fixed order
 	productItem := productItems[0]
-	if productItem == nil {
-		t.Errorf("product.Items first item should not be nil")
+	productItemb := productItems[1]
+	if productItem.GetId() == "product_item.idb" {
+		productItem, productItemb = productItemb, productItem
 	}
 
 	if productItem.GetId() != "product_item.id" {
@@ -333,11 +349,6 @@ func TestGet2Items(t *testing.T) {
 		t.Errorf("item.GetName() does not match")
 	}
 
-	productItemb := productItems[1]
-	if productItemb == nil {
-		t.Errorf("product.Items second item should not be nil")
-	}
-
 	if productItemb.GetId() != "product_item.idb" {
 		t.Errorf("productItemb.GetId() does not match")
 	}
@@ -366,38 +377,11 @@ func TestGetNoProductShouldReturnShop(t *testing.T) {
 	}
 	defer db.Close()
 
-	columns := sqlmock.NewRows([]string{
-		"shop.id",
-		"shop.name",
-		"shop.created_at",
-		"shop.updated_at",
-		"product.id",
-		"product.name",
-		"product.created_at",
-		"product.updated_at",
-		"item.id",
-		"item.name",
-		"item.metadata",
-		"product_item.id",
-		"product_item.amount",
-	}).AddRow(
-		"shop.id",
-		"shop.name",
-		time.Now(),
-		time.Now(),
-		nil,
-		nil,
-		nil,
-		nil,
-		nil,
-		nil,
-		nil,
-		nil,
-		nil,
-	)
-	mock.ExpectQuery("SELECT (.+)").WillReturnRows(columns)
-
-	shopRepository := shoprepository.NewShopRepository(db)
+	rows := sqlmock.NewRows(columns).
+		AddRow(shopRow(nil)...)
+	mock.ExpectQuery("SELECT (.+)").WillReturnRows(rows)
+
+	shopRepository := shoprepository.NewShopRepository(db, zap.NewNop())
 	result, err := shopRepository.Get(context.Background(), "shop.id")
 	if err != nil {
 		t.Error(err)
@@ -9,7 +9,9 @@ import (
 
 	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
 	repository "github.com/GameComponent/economy-service/pkg/repository"
+	tenant "github.com/GameComponent/economy-service/pkg/tenant"
 	ptypes "github.com/golang/protobuf/ptypes"
+	pq "github.com/lib/pq"
 	"go.uber.org/zap"
 )
 
@@ -34,14 +36,23 @@ func (r *StorageRepository) Create(ctx context.Context, playerID string, name st
 		metadata = "{}"
 	}
 
-	// Add item to the databased return the generated UUID
+	// Add item to the databased return the generated UUID,
+	// the player has to exist within the tenant
 	lastInsertUUID := ""
 	err := r.db.QueryRowContext(
 		ctx,
-		`INSERT INTO storage(player_id, name, metadata) VALUES ($1, $2, $3) RETURNING id`,
+		`
+			INSERT INTO storage(player_id, name, metadata, tenant_id)
+			SELECT id, $2, $3, tenant_id
+			FROM player
+			WHERE id = $1
+			AND tenant_id = $4
+			RETURNING id
+		`,
 		playerID,
 		name,
 		metadata,
+		tenant.FromContext(ctx),
 	).Scan(&lastInsertUUID)
 
 	if err != nil {
@@ -72,12 +83,12 @@ func (r *StorageRepository) Update(ctx context.Context, storageID string, name s
 	}
 
 	if index <= 1 {
-		return nil, fmt.Errorf("no arguments given")
+		return nil, repository.ErrNothingToUpdate
 	}
 
 	// Update the storage
-	arguments = append(arguments, storageID)
-	query := fmt.Sprintf("UPDATE storage SET %v WHERE id =$%v", strings.Join(queries, ", "), index)
+	arguments = append(arguments, storageID, tenant.FromContext(ctx))
+	query := fmt.Sprintf("UPDATE storage SET %v WHERE id =$%v AND tenant_id = $%v", strings.Join(queries, ", "), index, index+1)
 	_, err := r.db.ExecContext(
 		ctx,
 		query,
@@ -99,6 +110,7 @@ func (r *StorageRepository) Get(ctx context.Context, storageID string) (*v1.Stor
       storage.name as storageName,
       storage.metadata as storageData,
       storage.player_id as playerId,
+      storage.archived_at as storageArchivedAt,
       storage_item.id as storageItemId,
       storage_item.amount as storageItemAmount,
 			storage_item.metadata as storageItemData,
@@ -119,8 +131,10 @@ func (r *StorageRepository) Get(ctx context.Context, storageID string) (*v1.Stor
 		LEFT JOIN item ON (storage_item.item_id = item.id)
 		LEFT JOIN storage_currency ON (storage.id = storage_currency.storage_id)
     LEFT JOIN currency ON (storage_currency.currency_id = currency.id)
-    WHERE storage.id = $1`,
+    WHERE storage.id = $1
+    AND storage.tenant_id = $2`,
 		storageID,
+		tenant.FromContext(ctx),
 	)
 
 	if err != nil {
@@ -132,6 +146,7 @@ func (r *StorageRepository) Get(ctx context.Context, storageID string) (*v1.Stor
 		StorageName              string
 		StorageData              string
 		PlayerID                 string
+		StorageArchivedAt        pq.NullTime
 		StorageItemID            sql.NullString
 		StorageItemAmount        sql.NullInt64
 		StorageItemItemData      sql.NullString
@@ -159,6 +174,7 @@ func (r *StorageRepository) Get(ctx context.Context, storageID string) (*v1.Stor
 			&res.StorageName,
 			&res.StorageData,
 			&res.PlayerID,
+			&res.StorageArchivedAt,
 			&res.StorageItemID,
 			&res.StorageItemAmount,
 			&res.StorageItemItemData,
@@ -232,7 +248,7 @@ func (r *StorageRepository) Get(ctx context.Context, storageID string) (*v1.Stor
 	}
 
 	if res.StorageID == "" {
-		return nil, fmt.Errorf("Unable to retrieve storage")
+		return nil, repository.NotFound("storage", storageID)
 	}
 
 	// Convert item map into item slice
@@ -256,55 +272,136 @@ func (r *StorageRepository) Get(ctx context.Context, storageID string) (*v1.Stor
 		Metadata:   res.StorageData,
 	}
 
+	// Archived storages are still resolvable
+	if res.StorageArchivedAt.Valid {
+		storage.ArchivedAt, _ = ptypes.TimestampProto(res.StorageArchivedAt.Time)
+	}
+
 	return storage, nil
 }
 
-// GiveItem to a storage
-func (r *StorageRepository) GiveItem(ctx context.Context, storageID string, itemID string, amount int64) (*string, error) {
-	// Add item to the databased return the generated UUID
-	lastInsertUUID := ""
-	err := r.db.QueryRowContext(
-		ctx,
-		`INSERT INTO storage_item(item_id, storage_id, amount) VALUES ($1, $2, $3) RETURNING id`,
-		itemID,
-		storageID,
-		amount,
-	).Scan(&lastInsertUUID)
+// GiveItem to a storage, the stacks change and the ledger entry is recorded in one transaction
+func (r *StorageRepository) GiveItem(ctx context.Context, storageID string, itemID string, grant repository.ItemGrant) error {
+	options := sql.TxOptions{
+		ReadOnly: false,
+	}
 
+	// Start a transaction
+	tx, err := r.db.BeginTx(ctx, &options)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	defer tx.Rollback()
 
-	return &lastInsertUUID, nil
-}
+	// Add to the existing stacks of the storage
+	for _, increase := range grant.Increases {
+		result, err := tx.ExecContext(
+			ctx,
+			`
+				UPDATE storage_item
+				SET amount = amount + $1
+				WHERE id = $2
+				AND storage_id IN (SELECT id FROM storage WHERE id = $3 AND tenant_id = $4)
+			`,
+			increase.Amount,
+			increase.StorageItemID,
+			storageID,
+			tenant.FromContext(ctx),
+		)
+		if err != nil {
+			return err
+		}
 
-// IncreaseItemAmount to a storage
-func (r *StorageRepository) IncreaseItemAmount(ctx context.Context, storageItemID string, amount int64) error {
-	_, err := r.db.ExecContext(
-		ctx,
-		`UPDATE storage_item SET amount = amount + $1 WHERE id = $2`,
-		amount,
-		storageItemID,
-	)
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return repository.Conflict("storage item", increase.StorageItemID)
+		}
+	}
 
+	// Create the new stacks
+	for _, amount := range grant.Stacks {
+		storageItemID := ""
+		err := tx.QueryRowContext(
+			ctx,
+			`
+				INSERT INTO storage_item(item_id, storage_id, amount)
+				SELECT $1, id, $3
+				FROM storage
+				WHERE id = $2
+				AND tenant_id = $4
+				RETURNING id
+			`,
+			itemID,
+			storageID,
+			amount,
+			tenant.FromContext(ctx),
+		).Scan(&storageItemID)
+
+		if err == sql.ErrNoRows {
+			return repository.NotFound("storage", storageID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// Keep a record of the given items
+	err = repository.RecordLedgerEntry(ctx, tx, &v1.LedgerEntry{
+		StorageId: storageID,
+		ItemId:    itemID,
+		Amount:    grant.Amount(),
+		Reason:    repository.LedgerReasonGiveItem,
+	})
 	if err != nil {
 		return err
 	}
 
-	return nil
+	// Commit all changes to the database
+	return tx.Commit()
 }
 
-// GiveCurrency to a storage
+// GiveCurrency to a storage, the balance changes and the ledger entry is recorded in one transaction
 func (r *StorageRepository) GiveCurrency(ctx context.Context, storageID string, currencyID string, amount int64) (*v1.StorageCurrency, error) {
-	// Add item to the databased return the generated UUID
+	options := sql.TxOptions{
+		ReadOnly: false,
+	}
+
+	// Start a transaction
+	tx, err := r.db.BeginTx(ctx, &options)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Only currencies of the tenant can be given
+	err = tx.QueryRowContext(
+		ctx,
+		`SELECT id FROM currency WHERE id = $1 AND tenant_id = $2`,
+		currencyID,
+		tenant.FromContext(ctx),
+	).Scan(&currencyID)
+
+	if err == sql.ErrNoRows {
+		return nil, repository.NotFound("currency", currencyID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
 	storageCurrencyUUID := ""
 	storageCurrencyAmount := int64(0)
 
-	err := r.db.QueryRowContext(
+	err = tx.QueryRowContext(
 		ctx,
 		`
       INSERT INTO storage_currency(currency_id, storage_id, amount)
-      VALUES($1, $2, $3)
+      SELECT $1, id, $3
+      FROM storage
+      WHERE id = $2
+      AND tenant_id = $4
       ON CONFLICT(currency_id,storage_id) DO UPDATE
       SET amount = storage_currency.amount + EXCLUDED.amount
       RETURNING id, amount
@@ -312,8 +409,29 @@ func (r *StorageRepository) GiveCurrency(ctx context.Context, storageID string,
 		currencyID,
 		storageID,
 		amount,
+		tenant.FromContext(ctx),
 	).Scan(&storageCurrencyUUID, &storageCurrencyAmount)
 
+	if err == sql.ErrNoRows {
+		return nil, repository.NotFound("storage", storageID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep a record of the given currency
+	err = repository.RecordLedgerEntry(ctx, tx, &v1.LedgerEntry{
+		StorageId:  storageID,
+		CurrencyId: currencyID,
+		Amount:     amount,
+		Reason:     repository.LedgerReasonGiveCurrency,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Commit all changes to the database
+	err = tx.Commit()
 	if err != nil {
 		return nil, err
 	}
@@ -326,11 +444,30 @@ func (r *StorageRepository) GiveCurrency(ctx context.Context, storageID string,
 	return storageCurrency, nil
 }
 
-// List all storages
-func (r *StorageRepository) List(ctx context.Context, limit int32, offset int32) ([]*v1.Storage, int32, error) {
-	// Query items from the database
-	rows, err := r.db.QueryContext(
-		ctx,
+// storageFields are the fields storages can be filtered and sorted by
+var storageFields = repository.Fields{
+	"id":         {Column: "id", Type: repository.UUIDField},
+	"name":       {Column: "name", Type: repository.StringField, Sortable: true},
+	"player_id":  {Column: "player_id", Type: repository.StringField, Sortable: true},
+	"created_at": {Column: "created_at", Type: repository.TimestampField, Sortable: true},
+	"updated_at": {Column: "updated_at", Type: repository.TimestampField, Sortable: true},
+	"metadata":   {Column: "metadata", Type: repository.JSONField},
+}
+
+// List the storages matching the filter of the page, the newest first unless the page has an order
+func (r *StorageRepository) List(ctx context.Context, page repository.Page) ([]*v1.Storage, repository.Cursor, int32, error) {
+	filter, keyset, err := repository.NewFilteredKeyset(page, storageFields, repository.Desc("created_at"), repository.Desc("id"))
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	condition, args := filter.Condition([]interface{}{tenant.FromContext(ctx)})
+	conditions := `
+		WHERE archived_at IS NULL
+		AND tenant_id = $1
+		AND ` + condition
+
+	query, args := keyset.Query(
 		`
 			SELECT 
 				id,
@@ -338,17 +475,16 @@ func (r *StorageRepository) List(ctx context.Context, limit int32, offset int32)
 				player_id,
 				created_at,
 				updated_at,
-				(SELECT COUNT(DISTINCT id) FROM storage) AS total_size
+				(SELECT COUNT(DISTINCT id) FROM storage `+conditions+`) AS total_size
 			FROM storage
-			LIMIT $1
-			OFFSET $2
-		`,
-		limit,
-		offset,
+		`+conditions,
+		args...,
 	)
 
+	// Query items from the database
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, 0, err
+		return nil, nil, 0, err
 	}
 	defer rows.Close()
 
@@ -370,7 +506,64 @@ func (r *StorageRepository) List(ctx context.Context, limit int32, offset int32)
 			&totalSize,
 		)
 		if err != nil {
-			return nil, 0, err
+			return nil, nil, 0, err
+		}
+
+		// Convert created_at to timestamp
+		storage.CreatedAt, _ = ptypes.TimestampProto(createdAt)
+		storage.UpdatedAt, _ = ptypes.TimestampProto(updatedAt)
+
+		if !keyset.AddMessage(&storage) {
+			break
+		}
+
+		storages = append(storages, &storage)
+	}
+
+	return storages, keyset.Next(), totalSize, nil
+}
+
+// ListByPlayer lists all storages of a player, including the archived ones
+func (r *StorageRepository) ListByPlayer(ctx context.Context, playerID string) ([]*v1.Storage, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`
+			SELECT
+				id,
+				name,
+				player_id,
+				created_at,
+				updated_at
+			FROM storage
+			WHERE player_id = $1
+			AND tenant_id = $2
+		`,
+		playerID,
+		tenant.FromContext(ctx),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Unwrap rows into storages
+	storages := []*v1.Storage{}
+
+	for rows.Next() {
+		storage := v1.Storage{}
+		createdAt := time.Time{}
+		updatedAt := time.Time{}
+
+		err := rows.Scan(
+			&storage.Id,
+			&storage.Name,
+			&storage.PlayerId,
+			&createdAt,
+			&updatedAt,
+		)
+		if err != nil {
+			return nil, err
 		}
 
 		// Convert created_at to timestamp
@@ -380,7 +573,7 @@ func (r *StorageRepository) List(ctx context.Context, limit int32, offset int32)
 		storages = append(storages, &storage)
 	}
 
-	return storages, totalSize, nil
+	return storages, nil
 }
 
 // SplitStack splits a stack in a storage into multiple stacks
@@ -410,14 +603,16 @@ func (r *StorageRepository) SplitStack(ctx context.Context, storageItemID string
 			FROM storage_item
 			WHERE id = $1
 			AND amount = $2
+			AND storage_id IN (SELECT id FROM storage WHERE tenant_id = $3)
 		`,
 		storageItemID,
 		totalAmount,
+		tenant.FromContext(ctx),
 	).Scan(&storageID)
 
 	// Either the item does not exist anymore or its amount has changed
 	if storageID == "" {
-		return nil, fmt.Errorf("Unable to update storage_item")
+		return nil, repository.Conflict("storage item", storageItemID)
 	}
 
 	// Update the existing stack
@@ -483,23 +678,32 @@ func (r *StorageRepository) MergeStack(ctx context.Context, toStorageItemID stri
 				WHERE storage_item.id = $1
 			)
 			WHERE id = $2
+			AND storage_id IN (SELECT id FROM storage WHERE tenant_id = $3)
 			RETURNING storage_id
 		`,
 		fromStorageItemID,
 		toStorageItemID,
+		tenant.FromContext(ctx),
 	).Scan(&storageID)
 
+	// The stack to merge into does not exist within the tenant
+	if storageID == "" {
+		return nil, repository.NotFound("storage item", toStorageItemID)
+	}
+
 	_, err = tx.ExecContext(
 		ctx,
 		`
 			DELETE FROM storage_item
 			WHERE id = $1
+			AND storage_id IN (SELECT id FROM storage WHERE tenant_id = $2)
 		`,
 		fromStorageItemID,
+		tenant.FromContext(ctx),
 	)
 
 	if err != nil {
-		return nil, fmt.Errorf("unable to merge stacks")
+		return nil, err
 	}
 
 	// Commit all changes to the database
@@ -509,3 +713,84 @@ func (r *StorageRepository) MergeStack(ctx context.Context, toStorageItemID stri
 
 	return r.Get(ctx, storageID)
 }
+
+// Archive a storage
+func (r *StorageRepository) Archive(ctx context.Context, storageID string) (*v1.Storage, error) {
+	_, err := r.db.ExecContext(
+		ctx,
+		`UPDATE storage SET archived_at = current_timestamp() WHERE id = $1 AND tenant_id = $2 AND archived_at IS NULL`,
+		storageID,
+		tenant.FromContext(ctx),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Get(ctx, storageID)
+}
+
+// Delete a storage, force also deletes the items and currencies in the storage
+func (r *StorageRepository) Delete(ctx context.Context, storageID string, force bool) (bool, error) {
+	options := sql.TxOptions{
+		ReadOnly: false,
+	}
+
+	// Start a transaction
+	tx, err := r.db.BeginTx(ctx, &options)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// Make sure the storage belongs to the tenant before touching its contents
+	found, err := repository.InTenant(ctx, tx, "storage", storageID)
+	if err != nil {
+		return false, err
+	}
+
+	if !found {
+		return false, repository.NotFound("storage", storageID)
+	}
+
+	references := []repository.Reference{
+		{Table: "storage_item", Column: "storage_id"},
+		{Table: "storage_currency", Column: "storage_id"},
+	}
+
+	// Refuse to delete storages that are not empty unless forced
+	referenced, err := repository.IsReferenced(ctx, tx, storageID, references...)
+	if err != nil {
+		return false, err
+	}
+
+	if referenced && !force {
+		return false, repository.Referenced("storage", storageID)
+	}
+
+	err = repository.DeleteReferences(ctx, tx, storageID, references...)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := tx.ExecContext(
+		ctx,
+		`DELETE FROM storage WHERE id = $1`,
+		storageID,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	// Make sure the storage existed
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return false, repository.NotFound("storage", storageID)
+	}
+
+	// Commit all changes to the database
+	if err = tx.Commit(); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	tenant "github.com/GameComponent/economy-service/pkg/tenant"
+)
+
+// InTenant checks if a row of a table belongs to the tenant of the request
+func InTenant(ctx context.Context, tx *sql.Tx, table string, id string) (bool, error) {
+	exists := false
+	err := tx.QueryRowContext(
+		ctx,
+		fmt.Sprintf(`SELECT EXISTS(SELECT 1 FROM %s WHERE id = $1 AND tenant_id = $2)`, table),
+		id,
+		tenant.FromContext(ctx),
+	).Scan(&exists)
+
+	if err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
@@ -0,0 +1,142 @@
+package tenantrepository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
+	repository "github.com/GameComponent/economy-service/pkg/repository"
+	ptypes "github.com/golang/protobuf/ptypes"
+	"go.uber.org/zap"
+)
+
+// TenantRepository struct
+type TenantRepository struct {
+	db     *sql.DB
+	logger *zap.Logger
+}
+
+// NewTenantRepository constructor
+func NewTenantRepository(db *sql.DB, logger *zap.Logger) repository.TenantRepository {
+	return &TenantRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Create a tenant
+func (r *TenantRepository) Create(ctx context.Context, tenantID string, name string) (*v1.Tenant, error) {
+	_, err := r.db.ExecContext(
+		ctx,
+		`INSERT INTO tenant(id, name) VALUES ($1, $2)`,
+		tenantID,
+		name,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Get(ctx, tenantID)
+}
+
+// Update a tenant
+func (r *TenantRepository) Update(ctx context.Context, tenantID string, name string) (*v1.Tenant, error) {
+	_, err := r.db.ExecContext(
+		ctx,
+		`UPDATE tenant SET name = $1, updated_at = current_timestamp() WHERE id = $2`,
+		name,
+		tenantID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Get(ctx, tenantID)
+}
+
+// Get a tenant
+func (r *TenantRepository) Get(ctx context.Context, tenantID string) (*v1.Tenant, error) {
+	tenant := &v1.Tenant{}
+	createdAt := time.Time{}
+	updatedAt := time.Time{}
+
+	err := r.db.QueryRowContext(
+		ctx,
+		`SELECT id, name, created_at, updated_at FROM tenant WHERE id = $1`,
+		tenantID,
+	).Scan(
+		&tenant.Id,
+		&tenant.Name,
+		&createdAt,
+		&updatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, repository.NotFound("tenant", tenantID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tenant.CreatedAt, _ = ptypes.TimestampProto(createdAt)
+	tenant.UpdatedAt, _ = ptypes.TimestampProto(updatedAt)
+
+	return tenant, nil
+}
+
+// List all tenants, sorted by id
+func (r *TenantRepository) List(ctx context.Context, page repository.Page) ([]*v1.Tenant, repository.Cursor, int32, error) {
+	keyset, err := repository.NewKeyset(page, repository.Asc("id"))
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	query, args := keyset.Query(`
+		SELECT
+			id,
+			name,
+			created_at,
+			updated_at,
+			(SELECT COUNT(id) FROM tenant) AS total_size
+		FROM tenant
+		WHERE TRUE
+	`)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	defer rows.Close()
+
+	// Unwrap rows into tenants
+	tenants := []*v1.Tenant{}
+	totalSize := int32(0)
+
+	for rows.Next() {
+		tenant := v1.Tenant{}
+		createdAt := time.Time{}
+		updatedAt := time.Time{}
+
+		err := rows.Scan(
+			&tenant.Id,
+			&tenant.Name,
+			&createdAt,
+			&updatedAt,
+			&totalSize,
+		)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+
+		if !keyset.Add(tenant.Id) {
+			break
+		}
+
+		tenant.CreatedAt, _ = ptypes.TimestampProto(createdAt)
+		tenant.UpdatedAt, _ = ptypes.TimestampProto(updatedAt)
+
+		tenants = append(tenants, &tenant)
+	}
+
+	return tenants, keyset.Next(), totalSize, nil
+}
@@ -0,0 +1,205 @@
+package role
+
+import (
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+// Built-in roles, these exist in every tenant and can not be changed
+const (
+	Admin      = "admin"
+	Support    = "support"
+	Designer   = "designer"
+	GameServer = "game-server"
+)
+
+// methodPrefix is prepended to a permission before it is matched against a method
+const methodPrefix = "/v1.EconomyService/"
+
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// BuiltIn holds the permissions of the built-in roles
+var BuiltIn = map[string][]string{
+	Admin: {
+		"*",
+	},
+	// Support reads players and the catalog to help players, the audit trail,
+	// API keys and tenants are left to admins
+	Support: {
+		"GetPlayer",
+		"ListPlayer",
+		"SearchPlayer",
+		"GetStorage",
+		"ListStorage",
+		"GetItem",
+		"ListItem",
+		"SearchItem",
+		"GetCurrency",
+		"ListCurrency",
+		"GetShop",
+		"ListShop",
+		"GetProduct",
+		"ListProduct",
+		"GetPrice",
+		"ListProductPrice",
+		"GetCatalogVersion",
+		"ListCatalogVersion",
+		"GetConfig",
+		"ListConfig",
+		"ListRole",
+		"ListAccount",
+		"getAccount",
+		"UpdatePlayer",
+		"ArchivePlayer",
+		"GiveItem",
+		"GiveCurrency",
+		"ExportPlayerData",
+	},
+	// Designers change the catalog through drafts, the live catalog is only
+	// changed by publishing a catalog version
+	Designer: {
+		"GetItem",
+		"ListItem",
+		"SearchItem",
+		"GetCurrency",
+		"ListCurrency",
+		"GetShop",
+		"ListShop",
+		"GetProduct",
+		"ListProduct",
+		"GetPrice",
+		"ListProductPrice",
+		"ExportCatalog",
+		"*CatalogVersion",
+		"GetConfig",
+		"ListConfig",
+		"SetConfig",
+	},
+	GameServer: {
+		"GetPlayer",
+		"CreatePlayer",
+		"UpdatePlayer",
+		"CreatePlayerSession",
+		"GetStorage",
+		"CreateStorage",
+		"UpdateStorage",
+		"GiveItem",
+		"GiveCurrency",
+		"SplitStack",
+		"MergeStack",
+		"BuyProduct",
+		"GetItem",
+		"ListItem",
+		"GetCurrency",
+		"ListCurrency",
+		"GetShop",
+		"ListShop",
+		"GetProduct",
+		"ListProduct",
+		"GetPrice",
+		"ListProductPrice",
+		"GetConfig",
+		"ListConfig",
+	},
+}
+
+// PlayerMethods are the only methods player tokens can execute,
+// the player has to own the players and storages it references
+var PlayerMethods = []string{
+	"GetPlayer",
+	"GetStorage",
+	"SplitStack",
+	"MergeStack",
+	"BuyProduct",
+	"GetShop",
+	"ListShop",
+	"GetProduct",
+	"ListProduct",
+	"ListProductPrice",
+	"GetPrice",
+	"GetItem",
+	"GetCurrency",
+}
+
+// RequireTwoFactor is a permission that forces two-factor authentication on
+// the accounts holding it, without granting access to any method
+const RequireTwoFactor = "RequireTwoFactor"
+
+// Sensitive are the methods that can create value or hand out access,
+// when enforced accounts allowed to execute one of them have to use two-factor authentication
+var Sensitive = []string{
+	"GiveCurrency",
+	"GiveItem",
+	"CreateApiKey",
+	"AssignPermission",
+	"AssignRole",
+	"CreateRole",
+	"CreateTenant",
+}
+
+// RequiresTwoFactor checks if the permissions force two-factor authentication, RequireTwoFactor
+// has to be held as is so wildcards don't match it, the sensitive methods only count when enforceSensitive is set
+func RequiresTwoFactor(permissions []string, enforceSensitive bool) bool {
+	for _, permission := range permissions {
+		if permission == RequireTwoFactor {
+			return true
+		}
+	}
+
+	if !enforceSensitive {
+		return false
+	}
+
+	for _, method := range Sensitive {
+		if Allowed(permissions, methodPrefix+method) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsBuiltIn checks if a role is one of the built-in roles
+func IsBuiltIn(name string) bool {
+	_, ok := BuiltIn[name]
+	return ok
+}
+
+// BuiltInNames returns the names of the built-in roles in alphabetical order
+func BuiltInNames() []string {
+	names := []string{}
+	for name := range BuiltIn {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// ValidName checks if a name can be used for a role
+func ValidName(name string) bool {
+	return validName.MatchString(name)
+}
+
+// ValidPermission checks if a permission is a valid method pattern
+func ValidPermission(permission string) bool {
+	if permission == "" {
+		return false
+	}
+
+	_, err := filepath.Match(methodPrefix+permission, methodPrefix)
+	return err == nil
+}
+
+// Allowed checks if one of the permissions grants access to the full method name
+func Allowed(permissions []string, fullMethod string) bool {
+	for _, permission := range permissions {
+		matched, _ := filepath.Match(methodPrefix+permission, fullMethod)
+		if matched {
+			return true
+		}
+	}
+
+	return false
+}
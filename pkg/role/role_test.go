@@ -0,0 +1,98 @@
+package role_test
+
+import (
+	"testing"
+
+	role "github.com/GameComponent/economy-service/pkg/role"
+)
+
+func TestBuiltInPermissionsAreValid(t *testing.T) {
+	for name, permissions := range role.BuiltIn {
+		for _, permission := range permissions {
+			if !role.ValidPermission(permission) {
+				t.Errorf("role %s has an invalid permission %q", name, permission)
+			}
+		}
+	}
+}
+
+func TestPlayerMethodsAreValid(t *testing.T) {
+	for _, method := range role.PlayerMethods {
+		if !role.ValidPermission(method) {
+			t.Errorf("invalid player method %q", method)
+		}
+	}
+
+	if role.Allowed(role.PlayerMethods, "/v1.EconomyService/GiveItem") {
+		t.Error("players should not be able to give themselves items")
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	cases := []struct {
+		role   string
+		method string
+		want   bool
+	}{
+		{role.Admin, "/v1.EconomyService/DeletePlayer", true},
+		{role.Support, "/v1.EconomyService/GetPlayer", true},
+		{role.Support, "/v1.EconomyService/SearchPlayer", true},
+		{role.Support, "/v1.EconomyService/DeletePlayer", false},
+		{role.Support, "/v1.EconomyService/ListAuditEvents", false},
+		{role.Support, "/v1.EconomyService/ListApiKeys", false},
+		{role.Support, "/v1.EconomyService/GetTenant", false},
+		{role.Support, "/v1.EconomyService/ListTenant", false},
+		{role.Designer, "/v1.EconomyService/PublishCatalogVersion", true},
+		{role.Designer, "/v1.EconomyService/GiveItem", false},
+		{role.Designer, "/v1.EconomyService/UpdateProduct", false},
+		{role.Designer, "/v1.EconomyService/UpdatePrice", false},
+		{role.Designer, "/v1.EconomyService/CreatePrice", false},
+		{role.Designer, "/v1.EconomyService/ImportCatalog", false},
+		{role.GameServer, "/v1.EconomyService/BuyProduct", true},
+		{role.GameServer, "/v1.EconomyService/SetConfig", false},
+	}
+
+	for _, c := range cases {
+		if got := role.Allowed(role.BuiltIn[c.role], c.method); got != c.want {
+			t.Errorf("Allowed(%s, %s) = %v, want %v", c.role, c.method, got, c.want)
+		}
+	}
+}
+
+func TestRequiresTwoFactor(t *testing.T) {
+	cases := []struct {
+		permissions      []string
+		enforceSensitive bool
+		want             bool
+	}{
+		{role.BuiltIn[role.Admin], true, true},
+		{role.BuiltIn[role.Support], true, true},
+		{role.BuiltIn[role.Designer], true, false},
+		{[]string{"Give*"}, true, true},
+		{[]string{"GetPlayer", role.RequireTwoFactor}, true, true},
+		{[]string{"GetPlayer"}, true, false},
+		{role.BuiltIn[role.Admin], false, false},
+		{[]string{"Give*"}, false, false},
+		{[]string{"GetPlayer", role.RequireTwoFactor}, false, true},
+	}
+
+	for _, c := range cases {
+		if got := role.RequiresTwoFactor(c.permissions, c.enforceSensitive); got != c.want {
+			t.Errorf("RequiresTwoFactor(%v, %v) = %v, want %v", c.permissions, c.enforceSensitive, got, c.want)
+		}
+	}
+}
+
+func TestValidPermission(t *testing.T) {
+	if role.ValidPermission("") {
+		t.Error("empty permission should be invalid")
+	}
+
+	if role.ValidPermission("Get[") {
+		t.Error("malformed pattern should be invalid")
+	}
+
+	if !role.ValidPermission("Get*") {
+		t.Error("Get* should be valid")
+	}
+}
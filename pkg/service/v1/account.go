@@ -2,13 +2,16 @@ package v1
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"strconv"
+	"net/mail"
 	"time"
 
 	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
 	"github.com/GameComponent/economy-service/pkg/helper/random"
+	repository "github.com/GameComponent/economy-service/pkg/repository"
 	jwt "github.com/dgrijalva/jwt-go"
+	"go.uber.org/zap"
 	bcrypt "golang.org/x/crypto/bcrypt"
 	codes "google.golang.org/grpc/codes"
 	status "google.golang.org/grpc/status"
@@ -19,9 +22,32 @@ type Claims struct {
 	Subject     string   `json:"sub"`
 	Email       string   `json:"email"`
 	Permissions []string `json:"permissions"`
+	Roles       []string `json:"roles,omitempty"`
+	Tenant      string   `json:"tenant,omitempty"`
+	Amr         []string `json:"amr,omitempty"`
+	SessionID   string   `json:"sid,omitempty"`
 	jwt.StandardClaims
 }
 
+// Authentication methods of a token, as in the amr claim of OpenID Connect
+const (
+	amrPassword  = "pwd"
+	amrOTP       = "otp"
+	amrFederated = "fed"
+)
+
+// TwoFactor checks if the token was handed out after a second factor, logins through
+// the OpenID Connect provider count as well since the provider enforces its own policy
+func (c *Claims) TwoFactor() bool {
+	for _, method := range c.Amr {
+		if method == amrOTP || method == amrFederated {
+			return true
+		}
+	}
+
+	return false
+}
+
 func hashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	return string(bytes), err
@@ -32,40 +58,63 @@ func checkPasswordHash(password, hash string) bool {
 	return err == nil
 }
 
-// Authenticate an account
-func (s *EconomyServiceServer) Authenticate(ctx context.Context, req *v1.AuthenticateRequest) (*v1.AuthenticateResponse, error) {
-	fmt.Println("Authenticate")
-
-	// Check if the user entered to correct credentials
-	account, err := s.AccountRepository.GetByEmail(ctx, req.GetEmail())
-
+// checkCredentials gets the account of an email and password, failures count towards
+// the lockout of the email which is only reset after a complete login
+func (s *EconomyServiceServer) checkCredentials(ctx context.Context, email string, password string) (*v1.Account, error) {
+	err := s.checkLockout(ctx, email)
 	if err != nil {
-		return nil, status.Error(codes.Unauthenticated, "invalid credentials")
+		return nil, err
 	}
 
-	if !checkPasswordHash(req.GetPassword(), account.Hash) {
+	account, err := s.AccountRepository.GetByEmail(ctx, email)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, s.errorStatus(ctx, err, "unable to find account")
+	}
+	if err != nil || !checkPasswordHash(password, account.Hash) {
+		s.failLogin(ctx, email)
 		return nil, status.Error(codes.Unauthenticated, "invalid credentials")
 	}
 
-	// Generate a JWT token
-	token, err := s.generateToken(account)
-	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to generate access_token")
+	if !account.TotpEnabled {
+		s.succeedLogin(ctx, email)
 	}
 
-	// Generate a save token
-	refreshToken, err := random.GenerateRandomString(128)
+	return account, nil
+}
+
+// Authenticate an account
+func (s *EconomyServiceServer) Authenticate(ctx context.Context, req *v1.AuthenticateRequest) (*v1.AuthenticateResponse, error) {
+	// Check if the user entered to correct credentials
+	account, err := s.checkCredentials(ctx, req.GetEmail(), req.GetPassword())
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to generate refresh_token")
+		return nil, err
+	}
+
+	// Accounts with two-factor authentication continue with AuthenticateTotp
+	if account.TotpEnabled {
+		challengeToken, err := s.generateChallengeToken(account)
+		if err != nil {
+			return nil, s.errorStatus(ctx, err, "unable to generate challenge_token")
+		}
+
+		return &v1.AuthenticateResponse{
+			TotpRequired:   true,
+			ChallengeToken: challengeToken,
+		}, nil
 	}
 
-	// Calculate the expiration
-	refreshExpiration := time.Now().UTC().Add(time.Duration(s.Config.JWTRefreshExpiration) * time.Second)
+	amr := []string{amrPassword}
+
+	// Start a session with a refresh token
+	session, refreshToken, err := s.startSession(ctx, account, amr)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to generate refresh_token")
+	}
 
-	// Add the refresh token to the database
-	err = s.AccountRepository.CreateRefreshToken(ctx, refreshToken, account.Id, &refreshExpiration)
+	// Generate a JWT token
+	token, err := s.generateToken(account, amr, session.Id)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to generate refresh_token")
+		return nil, s.errorStatus(ctx, err, "unable to generate access_token")
 	}
 
 	return &v1.AuthenticateResponse{
@@ -78,30 +127,54 @@ func (s *EconomyServiceServer) Authenticate(ctx context.Context, req *v1.Authent
 
 // Register an account
 func (s *EconomyServiceServer) Register(ctx context.Context, req *v1.RegisterRequest) (*v1.RegisterResponse, error) {
-	fmt.Println("Register")
+	// Check if the email is a plain address, names and comments are not accepted
+	address, err := mail.ParseAddress(req.GetEmail())
+	if err != nil || address.Address != req.GetEmail() {
+		return nil, status.Error(codes.InvalidArgument, "please enter a valid email")
+	}
+
+	if req.GetPassword() == "" {
+		return nil, status.Error(codes.InvalidArgument, "please enter password")
+	}
 
 	// Hash the password
 	hash, err := hashPassword(req.GetPassword())
 	if err != nil {
-		return nil, err
+		return nil, s.errorStatus(ctx, err, "unable to hash password")
 	}
 
 	// Check if user already exists
-	exstingAccount, _ := s.AccountRepository.GetByEmail(ctx, req.GetEmail())
-	if exstingAccount != nil && exstingAccount.Id != "" {
+	_, err = s.AccountRepository.GetByEmail(ctx, req.GetEmail())
+	if err == nil {
 		return nil, status.Error(codes.AlreadyExists, "user with email already exists")
 	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return nil, s.errorStatus(ctx, err, "unable to find account")
+	}
 
 	// Create the user
 	account, err := s.AccountRepository.Create(ctx, req.GetEmail(), hash)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to create account")
+		return nil, s.errorStatus(ctx, err, "unable to create account")
+	}
+
+	// Send the email verification, the account can be used before it is verified
+	err = s.sendVerificationToken(
+		ctx,
+		account,
+		purposeVerifyEmail,
+		verifyEmailExpiration,
+		"Verify your email",
+		"verify-email",
+	)
+	if err != nil {
+		s.log(ctx).Error("unable to send verification email", zap.String("account_id", account.Id), zap.Error(err))
 	}
 
 	// Generate a JWT token
-	token, err := s.generateToken(account)
+	token, err := s.generateToken(account, []string{amrPassword}, "")
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to generate token")
+		return nil, s.errorStatus(ctx, err, "unable to generate token")
 	}
 
 	return &v1.RegisterResponse{
@@ -109,54 +182,38 @@ func (s *EconomyServiceServer) Register(ctx context.Context, req *v1.RegisterReq
 	}, nil
 }
 
-// GenerateSecret for an account
-func (s *EconomyServiceServer) GenerateSecret(ctx context.Context, req *v1.GenerateSecretRequest) (*v1.GenerateSecretResponse, error) {
-	fmt.Println("GenerateSecret")
-
-	if req.GetAccountId() == "" {
-		return nil, status.Error(codes.InvalidArgument, "please enter an account_id")
-	}
-
-	account, err := s.AccountRepository.Get(ctx, req.GetAccountId())
+// Refresh tokens for an account
+func (s *EconomyServiceServer) Refresh(ctx context.Context, req *v1.RefreshRequest) (*v1.RefreshResponse, error) {
+	// Every refresh token is exchanged for a new one
+	refreshToken, err := random.GenerateRandomString(32)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unbable to generate token")
+		return nil, s.errorStatus(ctx, err, "unable to generate refresh_token")
 	}
 
-	token, err := s.generateLongLivedToken(account)
-	if err != nil {
-		return nil, status.Error(codes.Internal, "unbable to generate token")
+	session, err := s.SessionRepository.Rotate(ctx, hashToken(req.GetToken()), hashToken(refreshToken))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, status.Error(codes.Unauthenticated, "unable to find valid refresh_token")
 	}
-
-	return &v1.GenerateSecretResponse{
-		Token: token,
-	}, nil
-}
-
-// Refresh tokens for an account
-func (s *EconomyServiceServer) Refresh(ctx context.Context, req *v1.RefreshRequest) (*v1.RefreshResponse, error) {
-	fmt.Println("Refresh")
-
-	accountID, err := s.AccountRepository.GetAccountIDFromRefreshToken(ctx, req.Token)
 	if err != nil {
-		return nil, status.Error(codes.Unauthenticated, "unable to find valid refresh_token")
+		return nil, s.errorStatus(ctx, err, "unable to rotate refresh_token")
 	}
 
-	account, err := s.AccountRepository.Get(ctx, accountID)
-
+	account, err := s.AccountRepository.Get(ctx, session.GetAccountId())
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to find account")
+		return nil, s.errorStatus(ctx, err, "unable to find account")
 	}
 
 	// Generate a JWT token
-	token, err := s.generateToken(account)
+	token, err := s.generateToken(account, session.GetAmr(), session.GetId())
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to generate access_token")
+		return nil, s.errorStatus(ctx, err, "unable to generate access_token")
 	}
 
 	return &v1.RefreshResponse{
-		AccessToken: token,
-		TokenType:   "Bearer",
-		ExpiresIn:   int32(s.Config.JWTExpiration),
+		AccessToken:  token,
+		TokenType:    "Bearer",
+		ExpiresIn:    int32(s.Config.JWTExpiration),
+		RefreshToken: refreshToken,
 	}, nil
 }
 
@@ -164,7 +221,7 @@ func (s *EconomyServiceServer) Refresh(ctx context.Context, req *v1.RefreshReque
 func (s *EconomyServiceServer) GetAccount(ctx context.Context, req *v1.GetAccountRequest) (*v1.GetAccountResponse, error) {
 	account, err := s.AccountRepository.Get(ctx, req.GetAccountId())
 	if err != nil {
-		return nil, err
+		return nil, s.errorStatus(ctx, err, "unable to find account")
 	}
 
 	// Filter out the account's hash
@@ -179,78 +236,66 @@ func (s *EconomyServiceServer) GetAccount(ctx context.Context, req *v1.GetAccoun
 
 // ListAccount lists accounts
 func (s *EconomyServiceServer) ListAccount(ctx context.Context, req *v1.ListAccountRequest) (*v1.ListAccountResponse, error) {
-	fmt.Println("ListAccount")
-
-	// Parse the page token
-	var parsedToken int64
-	parsedToken, _ = strconv.ParseInt(req.GetPageToken(), 10, 32)
-
-	// Get the limit
-	limit := req.GetPageSize()
-	if limit == 0 {
-		limit = 100
-	}
-
-	// Get the offset
-	offset := int32(0)
-	if len(req.GetPageToken()) > 0 {
-		offset = int32(parsedToken) * limit
+	// Continue after the last row of the previous page
+	page, err := s.listPage(req)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "invalid page token")
 	}
 
 	// Get the accounts from the repository
-	accounts, totalSize, err := s.AccountRepository.List(ctx, limit, offset)
+	accounts, next, totalSize, err := s.AccountRepository.List(ctx, page.Page)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to retrieve account list")
-	}
-
-	// Determine if there is a next page
-	var nextPageToken string
-	if totalSize > (offset + limit) {
-		nextPage := int32(parsedToken) + 1
-		nextPageToken = strconv.Itoa(int(nextPage))
+		return nil, s.errorStatus(ctx, err, "unable to retrieve account list")
 	}
 
 	return &v1.ListAccountResponse{
 		Accounts:      accounts,
 		TotalSize:     totalSize,
-		NextPageToken: nextPageToken,
+		NextPageToken: page.nextPageToken(next),
 	}, nil
 }
 
 // ChangePassword for an account
 func (s *EconomyServiceServer) ChangePassword(ctx context.Context, req *v1.ChangePasswordRequest) (*v1.ChangePasswordResponse, error) {
-	fmt.Println("ChangePassword")
-
 	// Check if the user entered to correct credentials
-	account, err := s.AccountRepository.GetByEmail(ctx, req.GetEmail())
-
+	account, err := s.checkCredentials(ctx, req.GetEmail(), req.GetPassword())
 	if err != nil {
-		return nil, status.Error(codes.Unauthenticated, "invalid credentials")
+		return nil, err
 	}
 
-	if !checkPasswordHash(req.GetPassword(), account.Hash) {
-		return nil, status.Error(codes.Unauthenticated, "invalid credentials")
+	// The password alone is not enough when two-factor authentication is enabled
+	amr := []string{amrPassword}
+	if account.TotpEnabled {
+		err = s.checkSecondFactor(ctx, account, req.GetCode())
+		if err != nil {
+			return nil, err
+		}
+
+		amr = append(amr, amrOTP)
 	}
 
-	// Invalidate all existing refresh tokens
-	s.AccountRepository.InvalidateRefreshTokens(ctx, account.Id)
+	// Revoke all existing sessions
+	err = s.SessionRepository.RevokeAll(ctx, account.Id)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to revoke sessions")
+	}
 
 	// Hash the password
 	hash, err := hashPassword(req.GetNewPassword())
 	if err != nil {
-		return nil, err
+		return nil, s.errorStatus(ctx, err, "unable to hash password")
 	}
 
 	// Update the account
 	updatedAccount, err := s.AccountRepository.Update(ctx, account.Id, hash)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to update account")
+		return nil, s.errorStatus(ctx, err, "unable to update account")
 	}
 
 	// Generate a JWT token
-	token, err := s.generateToken(updatedAccount)
+	token, err := s.generateToken(updatedAccount, amr, "")
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to generate token")
+		return nil, s.errorStatus(ctx, err, "unable to generate token")
 	}
 
 	return &v1.ChangePasswordResponse{
@@ -260,8 +305,6 @@ func (s *EconomyServiceServer) ChangePassword(ctx context.Context, req *v1.Chang
 
 // AssignPermission to an account
 func (s *EconomyServiceServer) AssignPermission(ctx context.Context, req *v1.AssignPermissionRequest) (*v1.AssignPermissionResponse, error) {
-	fmt.Println("AssignPermission")
-
 	if req.GetAccountId() == "" {
 		return nil, status.Error(codes.InvalidArgument, "please enter account_id")
 	}
@@ -272,7 +315,7 @@ func (s *EconomyServiceServer) AssignPermission(ctx context.Context, req *v1.Ass
 
 	account, err := s.AccountRepository.AssignPermission(ctx, req.GetAccountId(), req.GetPermission())
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to assign permission")
+		return nil, s.errorStatus(ctx, err, "unable to assign permission")
 	}
 
 	// Filter out the account's hash
@@ -287,11 +330,9 @@ func (s *EconomyServiceServer) AssignPermission(ctx context.Context, req *v1.Ass
 
 // RevokePermission from an account
 func (s *EconomyServiceServer) RevokePermission(ctx context.Context, req *v1.RevokePermissionRequest) (*v1.RevokePermissionResponse, error) {
-	fmt.Println("RevokePermission")
-
 	account, err := s.AccountRepository.RevokePermission(ctx, req.GetAccountId(), req.GetPermission())
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to revoke permission")
+		return nil, s.errorStatus(ctx, err, "unable to revoke permission")
 	}
 
 	// Filter out the account's hash
@@ -304,51 +345,25 @@ func (s *EconomyServiceServer) RevokePermission(ctx context.Context, req *v1.Rev
 	}, nil
 }
 
-func (s *EconomyServiceServer) generateToken(account *v1.Account) (string, error) {
+func (s *EconomyServiceServer) generateToken(account *v1.Account, amr []string, sessionID string) (string, error) {
 	expirationTime := time.Now().Add(time.Duration(s.Config.JWTExpiration) * time.Second)
-	secret := []byte(s.Config.JWTSecret)
 
 	claims := &Claims{
 		Subject:     account.Id,
 		Email:       account.Email,
 		Permissions: account.Permissions,
+		Roles:       account.Roles,
+		Tenant:      account.TenantId,
+		Amr:         amr,
+		SessionID:   sessionID,
 		StandardClaims: jwt.StandardClaims{
 			Audience:  "account",
 			ExpiresAt: expirationTime.Unix(),
 		},
 	}
 
-	// Create a new token object, specifying signing method and the claims
-	// you would like it to contain.
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	// Sign and get the complete encoded token as a string using the secret
-	tokenString, err := token.SignedString(secret)
-	if err != nil {
-		return "", fmt.Errorf("unable to sign token")
-	}
-
-	return tokenString, nil
-}
-
-func (s *EconomyServiceServer) generateLongLivedToken(account *v1.Account) (string, error) {
-	secret := []byte(s.Config.JWTSecret)
-
-	claims := &Claims{
-		Subject:     account.Id,
-		Email:       account.Email,
-		Permissions: account.Permissions,
-		StandardClaims: jwt.StandardClaims{
-			Audience: "api",
-		},
-	}
-
-	// Create a new token object, specifying signing method and the claims
-	// you would like it to contain.
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	// Sign and get the complete encoded token as a string using the secret
-	tokenString, err := token.SignedString(secret)
+	// Sign and get the complete encoded token as a string using the current key
+	tokenString, err := s.Keyring.Sign(claims)
 	if err != nil {
 		return "", fmt.Errorf("unable to sign token")
 	}
@@ -0,0 +1,137 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
+	apikey "github.com/GameComponent/economy-service/pkg/apikey"
+	role "github.com/GameComponent/economy-service/pkg/role"
+	jwt "github.com/dgrijalva/jwt-go"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CreateApiKey creates an API key for an account
+func (s *EconomyServiceServer) CreateApiKey(ctx context.Context, req *v1.CreateApiKeyRequest) (*v1.CreateApiKeyResponse, error) {
+	if req.GetAccountId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "please enter an account_id")
+	}
+
+	if len(req.GetPermissions()) == 0 && len(req.GetRoles()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "please enter permissions or roles")
+	}
+
+	for _, permission := range req.GetPermissions() {
+		if !role.ValidPermission(permission) {
+			return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("invalid permission %q", permission))
+		}
+	}
+
+	// Only existing roles can be given to a key
+	for _, name := range req.GetRoles() {
+		if role.IsBuiltIn(name) {
+			continue
+		}
+
+		_, err := s.RoleRepository.Get(ctx, name)
+		if err != nil {
+			return nil, s.errorStatus(ctx, err, "unable to retrieve role")
+		}
+	}
+
+	if req.GetExpiresIn() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "expires_in should not be negative")
+	}
+
+	var expiresAt *time.Time
+	if req.GetExpiresIn() > 0 {
+		expiration := time.Now().UTC().Add(time.Duration(req.GetExpiresIn()) * time.Second)
+		expiresAt = &expiration
+	}
+
+	key, prefix, err := apikey.Generate()
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to generate key")
+	}
+
+	apiKey, err := s.APIKeyRepository.Create(
+		ctx,
+		req.GetAccountId(),
+		req.GetName(),
+		prefix,
+		apikey.Hash(key),
+		req.GetPermissions(),
+		req.GetRoles(),
+		expiresAt,
+	)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to create api key")
+	}
+
+	return &v1.CreateApiKeyResponse{
+		ApiKey: apiKey,
+		Key:    key,
+	}, nil
+}
+
+// ListApiKeys lists API keys
+func (s *EconomyServiceServer) ListApiKeys(ctx context.Context, req *v1.ListApiKeysRequest) (*v1.ListApiKeysResponse, error) {
+	// Continue after the last row of the previous page
+	page, err := s.listPage(req)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "invalid page token")
+	}
+
+	// Get the keys
+	apiKeys, next, totalSize, err := s.APIKeyRepository.List(ctx, req.GetAccountId(), page.Page)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to retrieve api key list")
+	}
+
+	return &v1.ListApiKeysResponse{
+		ApiKeys:       apiKeys,
+		TotalSize:     totalSize,
+		NextPageToken: page.nextPageToken(next),
+	}, nil
+}
+
+// RevokeApiKey revokes an API key
+func (s *EconomyServiceServer) RevokeApiKey(ctx context.Context, req *v1.RevokeApiKeyRequest) (*v1.RevokeApiKeyResponse, error) {
+	if req.GetApiKeyId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "please enter an api_key_id")
+	}
+
+	apiKey, err := s.APIKeyRepository.Revoke(ctx, req.GetApiKeyId())
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to revoke api key")
+	}
+
+	return &v1.RevokeApiKeyResponse{
+		ApiKey: apiKey,
+	}, nil
+}
+
+// AuthenticateAPIKey turns a valid API key into the claims of a token with the api audience
+func (s *EconomyServiceServer) AuthenticateAPIKey(ctx context.Context, key string) (*Claims, error) {
+	apiKey, err := s.APIKeyRepository.GetByHash(ctx, apikey.Hash(key))
+	if err != nil {
+		return nil, fmt.Errorf("unknown api key")
+	}
+
+	if !apikey.Valid(apiKey, time.Now()) {
+		return nil, fmt.Errorf("api key is revoked or expired")
+	}
+
+	return &Claims{
+		Subject:     apiKey.GetAccountId(),
+		Permissions: apiKey.GetPermissions(),
+		Roles:       apiKey.GetRoles(),
+		Tenant:      apiKey.GetTenantId(),
+		StandardClaims: jwt.StandardClaims{
+			Audience: "api",
+			Id:       apiKey.GetId(),
+		},
+	}, nil
+}
@@ -0,0 +1,63 @@
+package v1
+
+import (
+	"context"
+
+	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
+	repository "github.com/GameComponent/economy-service/pkg/repository"
+	role "github.com/GameComponent/economy-service/pkg/role"
+	ptypes "github.com/golang/protobuf/ptypes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ListAuditEvents lists the audit events of the tenant
+func (s *EconomyServiceServer) ListAuditEvents(ctx context.Context, req *v1.ListAuditEventsRequest) (*v1.ListAuditEventsResponse, error) {
+	if req.GetMethod() != "" && !role.ValidPermission(req.GetMethod()) {
+		return nil, status.Error(codes.InvalidArgument, "method should be a method name or pattern")
+	}
+
+	filter := repository.AuditEventFilter{
+		AccountID: req.GetAccountId(),
+		Method:    req.GetMethod(),
+		Outcome:   req.GetOutcome(),
+	}
+
+	if req.GetStartTime() != nil {
+		startTime, err := ptypes.Timestamp(req.GetStartTime())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid start_time")
+		}
+		filter.StartTime = &startTime
+	}
+
+	if req.GetEndTime() != nil {
+		endTime, err := ptypes.Timestamp(req.GetEndTime())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid end_time")
+		}
+		filter.EndTime = &endTime
+	}
+
+	if filter.StartTime != nil && filter.EndTime != nil && !filter.EndTime.After(*filter.StartTime) {
+		return nil, status.Error(codes.InvalidArgument, "end_time should be after start_time")
+	}
+
+	// Continue after the last row of the previous page
+	page, err := s.listPage(req)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "invalid page token")
+	}
+
+	// Get the audit events
+	events, next, totalSize, err := s.AuditRepository.List(ctx, filter, page.Page)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to retrieve audit event list")
+	}
+
+	return &v1.ListAuditEventsResponse{
+		AuditEvents:   events,
+		TotalSize:     totalSize,
+		NextPageToken: page.nextPageToken(next),
+	}, nil
+}
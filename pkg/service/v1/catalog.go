@@ -0,0 +1,78 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
+	catalog "github.com/GameComponent/economy-service/pkg/catalog"
+	repository "github.com/GameComponent/economy-service/pkg/repository"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ExportCatalog exports the catalog
+func (s *EconomyServiceServer) ExportCatalog(ctx context.Context, req *v1.ExportCatalogRequest) (*v1.ExportCatalogResponse, error) {
+	current, err := s.CatalogRepository.Export(ctx)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to export catalog")
+	}
+
+	format := catalog.FormatYAML
+	if req.GetFormat() == v1.CatalogFormat_CATALOG_JSON {
+		format = catalog.FormatJSON
+	}
+
+	document, err := catalog.Marshal(current, format)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to encode catalog")
+	}
+
+	return &v1.ExportCatalogResponse{
+		Catalog: string(document),
+	}, nil
+}
+
+// ImportCatalog imports a catalog
+func (s *EconomyServiceServer) ImportCatalog(ctx context.Context, req *v1.ImportCatalogRequest) (*v1.ImportCatalogResponse, error) {
+	if req.GetCatalog() == "" {
+		return nil, status.Error(codes.InvalidArgument, "no catalog given")
+	}
+
+	desired, err := catalog.Parse([]byte(req.GetCatalog()))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("unable to parse catalog: %v", err))
+	}
+
+	// Plan and apply the changes at once
+	plan, err := s.CatalogRepository.Import(ctx, desired, req.GetPrune(), req.GetDryRun())
+	var repositoryErr *repository.Error
+	if errors.As(err, &repositoryErr) {
+		return nil, errorDetails(repositoryErr)
+	}
+	if err != nil {
+		s.log(ctx).Error("unable to apply catalog", zap.Error(err))
+		return nil, status.Error(codes.Aborted, "unable to apply catalog, no changes were made")
+	}
+
+	return &v1.ImportCatalogResponse{
+		Changes: catalogChanges(plan),
+		Applied: !req.GetDryRun() && !plan.Empty(),
+	}, nil
+}
+
+// catalogChanges converts the changes of a plan to their API representation
+func catalogChanges(plan *catalog.Plan) []*v1.CatalogChange {
+	changes := []*v1.CatalogChange{}
+	for _, change := range plan.Changes {
+		changes = append(changes, &v1.CatalogChange{
+			Action: string(change.Action),
+			Kind:   change.Kind,
+			Key:    change.Key,
+		})
+	}
+
+	return changes
+}
@@ -0,0 +1,205 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
+	catalog "github.com/GameComponent/economy-service/pkg/catalog"
+	repository "github.com/GameComponent/economy-service/pkg/repository"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CreateCatalogVersion creates a draft of the catalog
+func (s *EconomyServiceServer) CreateCatalogVersion(ctx context.Context, req *v1.CreateCatalogVersionRequest) (*v1.CreateCatalogVersionResponse, error) {
+	// Start from the live catalog when no catalog is given
+	var desired *catalog.Catalog
+	var err error
+	if req.GetCatalog() == "" {
+		desired, err = s.CatalogRepository.Export(ctx)
+		if err != nil {
+			return nil, s.errorStatus(ctx, err, "unable to retrieve the current catalog")
+		}
+	} else {
+		desired, err = parseCatalog(req.GetCatalog())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	document, err := catalog.Marshal(desired, catalog.FormatYAML)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to encode catalog")
+	}
+
+	version, err := s.CatalogRepository.CreateVersion(ctx, req.GetName(), string(document))
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to create catalog version")
+	}
+
+	return &v1.CreateCatalogVersionResponse{
+		CatalogVersion: version,
+	}, nil
+}
+
+// UpdateCatalogVersion updates a draft of the catalog
+func (s *EconomyServiceServer) UpdateCatalogVersion(ctx context.Context, req *v1.UpdateCatalogVersionRequest) (*v1.UpdateCatalogVersionResponse, error) {
+	if req.GetCatalogVersionId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "no catalog_version_id given")
+	}
+
+	if req.GetName() == "" && req.GetCatalog() == "" {
+		return nil, status.Error(codes.InvalidArgument, "no name or catalog given")
+	}
+
+	document := ""
+	if req.GetCatalog() != "" {
+		desired, err := parseCatalog(req.GetCatalog())
+		if err != nil {
+			return nil, err
+		}
+
+		encoded, err := catalog.Marshal(desired, catalog.FormatYAML)
+		if err != nil {
+			return nil, s.errorStatus(ctx, err, "unable to encode catalog")
+		}
+
+		document = string(encoded)
+	}
+
+	version, err := s.CatalogRepository.UpdateVersion(ctx, req.GetCatalogVersionId(), req.GetName(), document)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to update catalog version")
+	}
+
+	return &v1.UpdateCatalogVersionResponse{
+		CatalogVersion: version,
+	}, nil
+}
+
+// GetCatalogVersion gets a version of the catalog
+func (s *EconomyServiceServer) GetCatalogVersion(ctx context.Context, req *v1.GetCatalogVersionRequest) (*v1.GetCatalogVersionResponse, error) {
+	version, err := s.CatalogRepository.GetVersion(ctx, req.GetCatalogVersionId())
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to retrieve catalog version")
+	}
+
+	return &v1.GetCatalogVersionResponse{
+		CatalogVersion: version,
+	}, nil
+}
+
+// ListCatalogVersion lists the versions of the catalog
+func (s *EconomyServiceServer) ListCatalogVersion(ctx context.Context, req *v1.ListCatalogVersionRequest) (*v1.ListCatalogVersionResponse, error) {
+	// Continue after the last row of the previous page
+	page, err := s.listPage(req)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "invalid page token")
+	}
+
+	// Get the versions
+	versions, next, totalSize, err := s.CatalogRepository.ListVersions(ctx, page.Page)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to retrieve catalog version list")
+	}
+
+	return &v1.ListCatalogVersionResponse{
+		CatalogVersions: versions,
+		TotalSize:       totalSize,
+		NextPageToken:   page.nextPageToken(next),
+	}, nil
+}
+
+// PreviewCatalogVersion shows the changes publishing a version would make
+func (s *EconomyServiceServer) PreviewCatalogVersion(ctx context.Context, req *v1.PreviewCatalogVersionRequest) (*v1.PreviewCatalogVersionResponse, error) {
+	version, err := s.CatalogRepository.GetVersion(ctx, req.GetCatalogVersionId())
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to retrieve catalog version")
+	}
+
+	desired, err := catalog.Parse([]byte(version.GetCatalog()))
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to parse catalog version")
+	}
+
+	current, err := s.CatalogRepository.Export(ctx)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to retrieve the current catalog")
+	}
+
+	// A version replaces the whole catalog
+	plan, err := catalog.Diff(current, desired, true)
+	if err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	return &v1.PreviewCatalogVersionResponse{
+		Changes: catalogChanges(plan),
+	}, nil
+}
+
+// PublishCatalogVersion makes a version the live catalog
+func (s *EconomyServiceServer) PublishCatalogVersion(ctx context.Context, req *v1.PublishCatalogVersionRequest) (*v1.PublishCatalogVersionResponse, error) {
+	if req.GetCatalogVersionId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "no catalog_version_id given")
+	}
+
+	version, plan, err := s.publishCatalogVersion(ctx, req.GetCatalogVersionId())
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.PublishCatalogVersionResponse{
+		CatalogVersion: version,
+		Changes:        catalogChanges(plan),
+	}, nil
+}
+
+// RollbackCatalogVersion publishes the previously published version again
+func (s *EconomyServiceServer) RollbackCatalogVersion(ctx context.Context, req *v1.RollbackCatalogVersionRequest) (*v1.RollbackCatalogVersionResponse, error) {
+	previous, err := s.CatalogRepository.GetPreviousVersion(ctx)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to retrieve the previous catalog version")
+	}
+
+	version, plan, err := s.publishCatalogVersion(ctx, previous.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.RollbackCatalogVersionResponse{
+		CatalogVersion: version,
+		Changes:        catalogChanges(plan),
+	}, nil
+}
+
+func (s *EconomyServiceServer) publishCatalogVersion(ctx context.Context, catalogVersionID string) (*v1.CatalogVersion, *catalog.Plan, error) {
+	version, plan, err := s.CatalogRepository.PublishVersion(ctx, catalogVersionID)
+	var repositoryErr *repository.Error
+	if errors.As(err, &repositoryErr) {
+		return nil, nil, errorDetails(repositoryErr)
+	}
+	if err != nil {
+		s.log(ctx).Error("unable to publish catalog version", zap.Error(err))
+		return nil, nil, status.Error(codes.Aborted, "unable to publish catalog version, no changes were made")
+	}
+
+	return version, plan, nil
+}
+
+// parseCatalog parses and validates a catalog document from a request
+func parseCatalog(document string) (*catalog.Catalog, error) {
+	desired, err := catalog.Parse([]byte(document))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("unable to parse catalog: %v", err))
+	}
+
+	if err := desired.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return desired, nil
+}
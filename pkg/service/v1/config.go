@@ -2,11 +2,9 @@ package v1
 
 import (
 	"context"
-	"fmt"
-	"strconv"
 
 	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
-	"go.uber.org/zap"
+	ratelimit "github.com/GameComponent/economy-service/pkg/ratelimit"
 	codes "google.golang.org/grpc/codes"
 	status "google.golang.org/grpc/status"
 )
@@ -15,7 +13,7 @@ import (
 func (s *EconomyServiceServer) GetConfig(ctx context.Context, req *v1.GetConfigRequest) (*v1.GetConfigResponse, error) {
 	config, err := s.ConfigRepository.Get(ctx, req.GetKey())
 	if err != nil {
-		return nil, status.Error(codes.NotFound, "config not found")
+		return nil, s.errorStatus(ctx, err, "unable to retrieve config")
 	}
 
 	return &v1.GetConfigResponse{
@@ -25,10 +23,16 @@ func (s *EconomyServiceServer) GetConfig(ctx context.Context, req *v1.GetConfigR
 
 // SetConfig sets a config
 func (s *EconomyServiceServer) SetConfig(ctx context.Context, req *v1.SetConfigRequest) (*v1.SetConfigResponse, error) {
+	// Invalid rate limits would be ignored, refuse them instead
+	if req.GetKey() == ratelimit.QuotasKey {
+		if _, err := ratelimit.ParseQuotas(req.GetValue()); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
 	config, err := s.ConfigRepository.Set(ctx, req.GetKey(), req.GetValue())
 	if err != nil {
-		s.Logger.Error("unable to set config", zap.Error(err))
-		return nil, status.Error(codes.Internal, "unable to set config")
+		return nil, s.errorStatus(ctx, err, "unable to set config")
 	}
 
 	return &v1.SetConfigResponse{
@@ -38,40 +42,21 @@ func (s *EconomyServiceServer) SetConfig(ctx context.Context, req *v1.SetConfigR
 
 // ListConfig lists configs
 func (s *EconomyServiceServer) ListConfig(ctx context.Context, req *v1.ListConfigRequest) (*v1.ListConfigResponse, error) {
-	fmt.Println("ListConfig")
-
-	// Parse the page token
-	var parsedToken int64
-	parsedToken, _ = strconv.ParseInt(req.GetPageToken(), 10, 32)
-
-	// Get the limit
-	limit := req.GetPageSize()
-	if limit == 0 {
-		limit = 100
-	}
-
-	// Get the offset
-	offset := int32(0)
-	if len(req.GetPageToken()) > 0 {
-		offset = int32(parsedToken) * limit
+	// Continue after the last row of the previous page
+	page, err := s.listPage(req)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "invalid page token")
 	}
 
 	// Get the items from the repository
-	configs, totalSize, err := s.ConfigRepository.List(ctx, limit, offset)
+	configs, next, totalSize, err := s.ConfigRepository.List(ctx, page.Page)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to retrieve config list")
-	}
-
-	// Determine if there is a next page
-	var nextPageToken string
-	if totalSize > (offset + limit) {
-		nextPage := int32(parsedToken) + 1
-		nextPageToken = strconv.Itoa(int(nextPage))
+		return nil, s.errorStatus(ctx, err, "unable to retrieve config list")
 	}
 
 	return &v1.ListConfigResponse{
 		Configs:       configs,
 		TotalSize:     totalSize,
-		NextPageToken: nextPageToken,
+		NextPageToken: page.nextPageToken(next),
 	}, nil
 }
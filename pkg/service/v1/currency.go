@@ -2,8 +2,6 @@ package v1
 
 import (
 	"context"
-	"fmt"
-	"strconv"
 
 	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
 	codes "google.golang.org/grpc/codes"
@@ -12,48 +10,27 @@ import (
 
 // ListCurrency lists currencies
 func (s *EconomyServiceServer) ListCurrency(ctx context.Context, req *v1.ListCurrencyRequest) (*v1.ListCurrencyResponse, error) {
-	fmt.Println("ListCurrency")
-
-	// Parse the page token
-	var parsedToken int64
-	parsedToken, _ = strconv.ParseInt(req.GetPageToken(), 10, 32)
-
-	// Get the limit
-	limit := req.GetPageSize()
-	if limit == 0 {
-		limit = 100
-	}
-
-	// Get the offset
-	offset := int32(0)
-	if len(req.GetPageToken()) > 0 {
-		offset = int32(parsedToken) * limit
+	// Continue after the last row of the previous page
+	page, err := s.listPage(req)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "invalid page token")
 	}
 
 	// Get the currencies from the repository
-	currencies, totalSize, err := s.CurrencyRepository.List(ctx, limit, offset)
+	currencies, next, totalSize, err := s.CurrencyRepository.List(ctx, page.Page)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to retrieve currency list")
-	}
-
-	// Determine if there is a next page
-	var nextPageToken string
-	if totalSize > (offset + limit) {
-		nextPage := int32(parsedToken) + 1
-		nextPageToken = strconv.Itoa(int(nextPage))
+		return nil, s.errorStatus(ctx, err, "unable to retrieve currency list")
 	}
 
 	return &v1.ListCurrencyResponse{
 		Currencies:    currencies,
 		TotalSize:     totalSize,
-		NextPageToken: nextPageToken,
+		NextPageToken: page.nextPageToken(next),
 	}, nil
 }
 
 // CreateCurrency creates a new currency
 func (s *EconomyServiceServer) CreateCurrency(ctx context.Context, req *v1.CreateCurrencyRequest) (*v1.CreateCurrencyResponse, error) {
-	fmt.Println("CreateCurrency")
-
 	// Check the name
 	if req.GetName() == "" {
 		return nil, status.Error(codes.InvalidArgument, "no name given")
@@ -76,7 +53,7 @@ func (s *EconomyServiceServer) CreateCurrency(ctx context.Context, req *v1.Creat
 		req.GetSymbol(),
 	)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to create currency")
+		return nil, s.errorStatus(ctx, err, "unable to create currency")
 	}
 
 	return &v1.CreateCurrencyResponse{
@@ -86,8 +63,6 @@ func (s *EconomyServiceServer) CreateCurrency(ctx context.Context, req *v1.Creat
 
 // UpdateCurrency updates an existing currency
 func (s *EconomyServiceServer) UpdateCurrency(ctx context.Context, req *v1.UpdateCurrencyRequest) (*v1.UpdateCurrencyResponse, error) {
-	fmt.Println("UpdateCurrency")
-
 	// Check the id
 	if req.GetCurrencyId() == "" {
 		return nil, status.Error(codes.InvalidArgument, "no currency_id given")
@@ -101,7 +76,7 @@ func (s *EconomyServiceServer) UpdateCurrency(ctx context.Context, req *v1.Updat
 		req.GetSymbol(),
 	)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to update currency")
+		return nil, s.errorStatus(ctx, err, "unable to update currency")
 	}
 
 	return &v1.UpdateCurrencyResponse{
@@ -111,14 +86,44 @@ func (s *EconomyServiceServer) UpdateCurrency(ctx context.Context, req *v1.Updat
 
 // GetCurrency gets a currency
 func (s *EconomyServiceServer) GetCurrency(ctx context.Context, req *v1.GetCurrencyRequest) (*v1.GetCurrencyResponse, error) {
-	fmt.Println("GetCurrency")
-
 	currency, err := s.CurrencyRepository.Get(ctx, req.GetCurrencyId())
 	if err != nil {
-		return nil, status.Error(codes.NotFound, "currency not found")
+		return nil, s.errorStatus(ctx, err, "unable to retrieve currency")
 	}
 
 	return &v1.GetCurrencyResponse{
 		Currency: currency,
 	}, nil
 }
+
+// ArchiveCurrency archives a currency
+func (s *EconomyServiceServer) ArchiveCurrency(ctx context.Context, req *v1.ArchiveCurrencyRequest) (*v1.ArchiveCurrencyResponse, error) {
+	if req.GetCurrencyId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "no currency_id given")
+	}
+
+	currency, err := s.CurrencyRepository.Archive(ctx, req.GetCurrencyId())
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to archive currency")
+	}
+
+	return &v1.ArchiveCurrencyResponse{
+		Currency: currency,
+	}, nil
+}
+
+// DeleteCurrency deletes a currency
+func (s *EconomyServiceServer) DeleteCurrency(ctx context.Context, req *v1.DeleteCurrencyRequest) (*v1.DeleteCurrencyResponse, error) {
+	if req.GetCurrencyId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "no currency_id given")
+	}
+
+	success, err := s.CurrencyRepository.Delete(ctx, req.GetCurrencyId(), req.GetForce())
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to delete currency")
+	}
+
+	return &v1.DeleteCurrencyResponse{
+		Success: success,
+	}, nil
+}
@@ -1,6 +1,7 @@
 package v1
 
 import (
+	"context"
 	"database/sql"
 
 	"google.golang.org/grpc/codes"
@@ -8,8 +9,15 @@ import (
 
 	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
 	config "github.com/GameComponent/economy-service/pkg/config"
+	mail "github.com/GameComponent/economy-service/pkg/mail"
+	oidc "github.com/GameComponent/economy-service/pkg/oidc"
+	pagetoken "github.com/GameComponent/economy-service/pkg/pagetoken"
+	ratelimit "github.com/GameComponent/economy-service/pkg/ratelimit"
 	repository "github.com/GameComponent/economy-service/pkg/repository"
+	signing "github.com/GameComponent/economy-service/pkg/signing"
+	ctxzap "github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 const (
@@ -21,15 +29,29 @@ type Config struct {
 	DB                 *sql.DB
 	Logger             *zap.Logger
 	Config             *config.Config
+	Keyring            *signing.Keyring
+	PageTokens         *pagetoken.Signer
+	OIDCProvider       *oidc.Provider
+	Mailer             mail.Sender
+	AuthIPLimiter      *ratelimit.Limiter
+	AuthEmailLimiter   *ratelimit.Limiter
+	Lockout            *ratelimit.Lockout
 	AccountRepository  repository.AccountRepository
+	APIKeyRepository   repository.APIKeyRepository
+	AuditRepository    repository.AuditRepository
+	CatalogRepository  repository.CatalogRepository
 	ConfigRepository   repository.ConfigRepository
 	CurrencyRepository repository.CurrencyRepository
 	ItemRepository     repository.ItemRepository
+	LedgerRepository   repository.LedgerRepository
 	PlayerRepository   repository.PlayerRepository
 	PriceRepository    repository.PriceRepository
 	ProductRepository  repository.ProductRepository
+	RoleRepository     repository.RoleRepository
+	SessionRepository  repository.SessionRepository
 	ShopRepository     repository.ShopRepository
 	StorageRepository  repository.StorageRepository
+	TenantRepository   repository.TenantRepository
 }
 
 // EconomyServiceServer is implementation of v1.EconomyServiceServer proto interface
@@ -37,15 +59,29 @@ type EconomyServiceServer struct {
 	DB                 *sql.DB
 	Logger             *zap.Logger
 	Config             *config.Config
+	Keyring            *signing.Keyring
+	PageTokens         *pagetoken.Signer
+	OIDCProvider       *oidc.Provider
+	Mailer             mail.Sender
+	AuthIPLimiter      *ratelimit.Limiter
+	AuthEmailLimiter   *ratelimit.Limiter
+	Lockout            *ratelimit.Lockout
 	AccountRepository  repository.AccountRepository
+	APIKeyRepository   repository.APIKeyRepository
+	AuditRepository    repository.AuditRepository
+	CatalogRepository  repository.CatalogRepository
 	ConfigRepository   repository.ConfigRepository
 	CurrencyRepository repository.CurrencyRepository
 	ItemRepository     repository.ItemRepository
+	LedgerRepository   repository.LedgerRepository
 	PlayerRepository   repository.PlayerRepository
 	PriceRepository    repository.PriceRepository
 	ProductRepository  repository.ProductRepository
+	RoleRepository     repository.RoleRepository
+	SessionRepository  repository.SessionRepository
 	ShopRepository     repository.ShopRepository
 	StorageRepository  repository.StorageRepository
+	TenantRepository   repository.TenantRepository
 }
 
 // NewEconomyServiceServer creates economy service
@@ -54,15 +90,29 @@ func NewEconomyServiceServer(config Config) v1.EconomyServiceServer {
 		config.DB,
 		config.Logger,
 		config.Config,
+		config.Keyring,
+		config.PageTokens,
+		config.OIDCProvider,
+		config.Mailer,
+		config.AuthIPLimiter,
+		config.AuthEmailLimiter,
+		config.Lockout,
 		config.AccountRepository,
+		config.APIKeyRepository,
+		config.AuditRepository,
+		config.CatalogRepository,
 		config.ConfigRepository,
 		config.CurrencyRepository,
 		config.ItemRepository,
+		config.LedgerRepository,
 		config.PlayerRepository,
 		config.PriceRepository,
 		config.ProductRepository,
+		config.RoleRepository,
+		config.SessionRepository,
 		config.ShopRepository,
 		config.StorageRepository,
+		config.TenantRepository,
 	}
 }
 
@@ -77,3 +127,14 @@ func (s *EconomyServiceServer) checkAPI(api string) error {
 	}
 	return nil
 }
+
+// log returns the logger of the request, carrying its id, method and who makes it,
+// outside of a gRPC call it falls back to the logger of the server
+func (s *EconomyServiceServer) log(ctx context.Context) *zap.Logger {
+	// ctxzap hands out a logger that drops everything when the context has none
+	if logger := ctxzap.Extract(ctx); logger.Core().Enabled(zapcore.FatalLevel) {
+		return logger
+	}
+
+	return s.Logger
+}
@@ -0,0 +1,76 @@
+package v1
+
+import (
+	"context"
+	"errors"
+
+	repository "github.com/GameComponent/economy-service/pkg/repository"
+	proto "github.com/golang/protobuf/proto"
+	"go.uber.org/zap"
+	errdetails "google.golang.org/genproto/googleapis/rpc/errdetails"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// ErrorDomain is the domain of the google.rpc.ErrorInfo details of failed requests
+const ErrorDomain = "economy-service"
+
+// reasonCodes maps the reasons of repository errors to the code clients receive
+var reasonCodes = map[string]codes.Code{
+	repository.ReasonNotFound:                codes.NotFound,
+	repository.ReasonAlreadyExists:           codes.AlreadyExists,
+	repository.ReasonConflict:                codes.Aborted,
+	repository.ReasonInvalidArgument:         codes.InvalidArgument,
+	repository.ReasonInsufficientFunds:       codes.FailedPrecondition,
+	repository.ReasonInsufficientItems:       codes.FailedPrecondition,
+	repository.ReasonStackFull:               codes.FailedPrecondition,
+	repository.ReasonReferenced:              codes.FailedPrecondition,
+	repository.ReasonCatalogVersionNotDraft:  codes.FailedPrecondition,
+	repository.ReasonCatalogVersionPublished: codes.FailedPrecondition,
+	repository.ReasonRefreshTokenReused:      codes.Unauthenticated,
+	repository.ReasonTotpNotEnrolled:         codes.FailedPrecondition,
+}
+
+// errorStatus turns an error into the status the client receives, repository errors keep their
+// reason and metadata, anything else is logged and hidden behind an Internal status with message
+func (s *EconomyServiceServer) errorStatus(ctx context.Context, err error, message string) error {
+	var repositoryErr *repository.Error
+	if !errors.As(err, &repositoryErr) {
+		s.log(ctx).Error(message, zap.Error(err))
+		return status.Error(codes.Internal, message)
+	}
+
+	return errorDetails(repositoryErr)
+}
+
+// errorDetails converts a repository error to a status with a google.rpc.ErrorInfo,
+// and a google.rpc.BadRequest for invalid fields
+func errorDetails(err *repository.Error) error {
+	code, ok := reasonCodes[err.Reason]
+	if !ok {
+		code = codes.FailedPrecondition
+	}
+
+	st := status.New(code, err.Message)
+
+	details := []proto.Message{&errdetails.ErrorInfo{
+		Reason:   err.Reason,
+		Domain:   ErrorDomain,
+		Metadata: err.Metadata,
+	}}
+
+	if err.Field != "" {
+		details = append(details, &errdetails.BadRequest{
+			FieldViolations: []*errdetails.BadRequest_FieldViolation{
+				{Field: err.Field, Description: err.Message},
+			},
+		})
+	}
+
+	withDetails, detailsErr := st.WithDetails(details...)
+	if detailsErr != nil {
+		return st.Err()
+	}
+
+	return withDetails.Err()
+}
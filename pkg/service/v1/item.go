@@ -2,8 +2,6 @@ package v1
 
 import (
 	"context"
-	"fmt"
-	"strconv"
 
 	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
 	codes "google.golang.org/grpc/codes"
@@ -12,8 +10,6 @@ import (
 
 // CreateItem creates a new item
 func (s *EconomyServiceServer) CreateItem(ctx context.Context, req *v1.CreateItemRequest) (*v1.CreateItemResponse, error) {
-	fmt.Println("CreateItem")
-
 	// Add item to the databased return the generated UUID
 	item, err := s.ItemRepository.Create(
 		ctx,
@@ -24,7 +20,7 @@ func (s *EconomyServiceServer) CreateItem(ctx context.Context, req *v1.CreateIte
 		req.GetMetadata(),
 	)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to create item")
+		return nil, s.errorStatus(ctx, err, "unable to create item")
 	}
 
 	return &v1.CreateItemResponse{
@@ -34,8 +30,6 @@ func (s *EconomyServiceServer) CreateItem(ctx context.Context, req *v1.CreateIte
 
 // UpdateItem updates an item
 func (s *EconomyServiceServer) UpdateItem(ctx context.Context, req *v1.UpdateItemRequest) (*v1.UpdateItemResponse, error) {
-	fmt.Println("UpdateItem")
-
 	item, err := s.ItemRepository.Update(
 		ctx,
 		req.GetItemId(),
@@ -44,7 +38,7 @@ func (s *EconomyServiceServer) UpdateItem(ctx context.Context, req *v1.UpdateIte
 	)
 
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to update item")
+		return nil, s.errorStatus(ctx, err, "unable to update item")
 	}
 
 	return &v1.UpdateItemResponse{
@@ -56,7 +50,7 @@ func (s *EconomyServiceServer) UpdateItem(ctx context.Context, req *v1.UpdateIte
 func (s *EconomyServiceServer) GetItem(ctx context.Context, req *v1.GetItemRequest) (*v1.GetItemResponse, error) {
 	item, err := s.ItemRepository.Get(ctx, req.GetItemId())
 	if err != nil {
-		return nil, err
+		return nil, s.errorStatus(ctx, err, "unable to retrieve item")
 	}
 
 	return &v1.GetItemResponse{
@@ -66,85 +60,79 @@ func (s *EconomyServiceServer) GetItem(ctx context.Context, req *v1.GetItemReque
 
 // ListItem lists items
 func (s *EconomyServiceServer) ListItem(ctx context.Context, req *v1.ListItemRequest) (*v1.ListItemResponse, error) {
-	fmt.Println("ListItem")
-
-	// Parse the page token
-	var parsedToken int64
-	parsedToken, _ = strconv.ParseInt(req.GetPageToken(), 10, 32)
-
-	// Get the limit
-	limit := req.GetPageSize()
-	if limit == 0 {
-		limit = 100
-	}
-
-	// Get the offset
-	offset := int32(0)
-	if len(req.GetPageToken()) > 0 {
-		offset = int32(parsedToken) * limit
+	// Continue after the last row of the previous page
+	page, err := s.listPage(req)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "invalid page token")
 	}
 
 	// Get the items from the repository
-	items, totalSize, err := s.ItemRepository.List(ctx, limit, offset)
+	items, next, totalSize, err := s.ItemRepository.List(ctx, page.Page)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to retrieve item list")
-	}
-
-	// Determine if there is a next page
-	var nextPageToken string
-	if totalSize > (offset + limit) {
-		nextPage := int32(parsedToken) + 1
-		nextPageToken = strconv.Itoa(int(nextPage))
+		return nil, s.errorStatus(ctx, err, "unable to retrieve item list")
 	}
 
 	return &v1.ListItemResponse{
 		Items:         items,
 		TotalSize:     totalSize,
-		NextPageToken: nextPageToken,
+		NextPageToken: page.nextPageToken(next),
 	}, nil
 }
 
 // SearchItem search for an item
 func (s *EconomyServiceServer) SearchItem(ctx context.Context, req *v1.SearchItemRequest) (*v1.SearchItemResponse, error) {
-	fmt.Println("SearchItem")
-
 	// Check if query is empty
 	if req.GetQuery() == "" {
 		return nil, status.Error(codes.InvalidArgument, "no query given")
 	}
 
-	// Parse the page token
-	var parsedToken int64
-	parsedToken, _ = strconv.ParseInt(req.GetPageToken(), 10, 32)
+	// Continue after the last row of the previous page
+	page, err := s.listPage(req)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "invalid page token")
+	}
 
-	// Get the limit
-	limit := req.GetPageSize()
-	if limit == 0 {
-		limit = 100
+	// Search the items
+	items, next, totalSize, err := s.ItemRepository.Search(ctx, req.GetQuery(), page.Page)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to retrieve item search results")
 	}
 
-	// Get the offset
-	offset := int32(0)
-	if len(req.GetPageToken()) > 0 {
-		offset = int32(parsedToken) * limit
+	return &v1.SearchItemResponse{
+		Items:         items,
+		TotalSize:     totalSize,
+		NextPageToken: page.nextPageToken(next),
+	}, nil
+}
+
+// ArchiveItem archives an item
+func (s *EconomyServiceServer) ArchiveItem(ctx context.Context, req *v1.ArchiveItemRequest) (*v1.ArchiveItemResponse, error) {
+	if req.GetItemId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "no item_id given")
 	}
 
-	// Search the items
-	items, totalSize, err := s.ItemRepository.Search(ctx, req.GetQuery(), limit, offset)
+	item, err := s.ItemRepository.Archive(ctx, req.GetItemId())
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to retrieve item search results")
+		return nil, s.errorStatus(ctx, err, "unable to archive item")
 	}
 
-	// Determine if there is a next page
-	var nextPageToken string
-	if totalSize > (offset + limit) {
-		nextPage := int32(parsedToken) + 1
-		nextPageToken = strconv.Itoa(int(nextPage))
+	return &v1.ArchiveItemResponse{
+		Item: item,
+	}, nil
+}
+
+// DeleteItem deletes an item
+func (s *EconomyServiceServer) DeleteItem(ctx context.Context, req *v1.DeleteItemRequest) (*v1.DeleteItemResponse, error) {
+	if req.GetItemId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "no item_id given")
 	}
 
-	return &v1.SearchItemResponse{
-		Items:         items,
-		TotalSize:     totalSize,
-		NextPageToken: nextPageToken,
+	success, err := s.ItemRepository.Delete(ctx, req.GetItemId(), req.GetForce())
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to delete item")
+	}
+
+	return &v1.DeleteItemResponse{
+		Success: success,
 	}, nil
 }
@@ -0,0 +1,120 @@
+package v1
+
+import (
+	"context"
+	"errors"
+
+	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
+	oidc "github.com/GameComponent/economy-service/pkg/oidc"
+	repository "github.com/GameComponent/economy-service/pkg/repository"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// AuthenticateOidc authenticates with an ID token of the configured identity provider,
+// accounts are created on first login and their roles follow the groups of the user
+func (s *EconomyServiceServer) AuthenticateOidc(ctx context.Context, req *v1.AuthenticateOidcRequest) (*v1.AuthenticateOidcResponse, error) {
+	if s.OIDCProvider == nil {
+		return nil, status.Error(codes.Unimplemented, "oidc login is not configured")
+	}
+
+	idToken, err := s.OIDCProvider.Verify(ctx, req.GetIdToken())
+	if err != nil {
+		s.log(ctx).Info("invalid oidc id token", zap.Error(err))
+		return nil, status.Error(codes.Unauthenticated, "invalid id_token")
+	}
+
+	// Only users in a mapped group get access
+	granted, managed := s.OIDCProvider.Roles(idToken)
+	if len(granted) == 0 {
+		return nil, status.Error(codes.PermissionDenied, "not a member of a group with access")
+	}
+
+	account, err := s.oidcAccount(ctx, idToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// The identity provider decides about the roles it manages
+	hasRole := map[string]bool{}
+	for _, role := range granted {
+		hasRole[role] = true
+	}
+
+	for _, role := range managed {
+		if hasRole[role] {
+			account, err = s.AccountRepository.AssignRole(ctx, account.Id, role)
+		} else {
+			account, err = s.AccountRepository.RevokeRole(ctx, account.Id, role)
+		}
+
+		if err != nil {
+			return nil, s.errorStatus(ctx, err, "unable to update roles")
+		}
+	}
+
+	// The identity provider is responsible for the second factor
+	amr := []string{amrFederated}
+
+	// Start a session with a refresh token
+	session, refreshToken, err := s.startSession(ctx, account, amr)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to generate refresh_token")
+	}
+
+	// Generate a JWT token
+	token, err := s.generateToken(account, amr, session.Id)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to generate access_token")
+	}
+
+	return &v1.AuthenticateOidcResponse{
+		AccessToken:  token,
+		TokenType:    "Bearer",
+		ExpiresIn:    int32(s.Config.JWTExpiration),
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// oidcAccount finds the account linked to the identity, an account with the same
+// verified email is linked and otherwise a new account is created
+func (s *EconomyServiceServer) oidcAccount(ctx context.Context, idToken *oidc.IDToken) (*v1.Account, error) {
+	account, err := s.AccountRepository.GetByIdentity(ctx, idToken.Issuer, idToken.Subject)
+	if err == nil {
+		return account, nil
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return nil, s.errorStatus(ctx, err, "unable to find account")
+	}
+
+	if idToken.Email == "" {
+		return nil, status.Error(codes.InvalidArgument, "id_token has no email")
+	}
+
+	existingAccount, err := s.AccountRepository.GetByEmail(ctx, idToken.Email)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, s.errorStatus(ctx, err, "unable to find account")
+	}
+	if err == nil {
+		// Never hand over an account based on an email the provider did not verify
+		if !idToken.EmailVerified {
+			return nil, status.Error(codes.AlreadyExists, "user with email already exists")
+		}
+
+		account = existingAccount
+	} else {
+		// Accounts without a password can only log in through the identity provider
+		account, err = s.AccountRepository.Create(ctx, idToken.Email, "")
+		if err != nil {
+			return nil, s.errorStatus(ctx, err, "unable to create account")
+		}
+	}
+
+	err = s.AccountRepository.LinkIdentity(ctx, account.Id, idToken.Issuer, idToken.Subject)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to link account")
+	}
+
+	return account, nil
+}
@@ -0,0 +1,77 @@
+package v1
+
+import (
+	pagetoken "github.com/GameComponent/economy-service/pkg/pagetoken"
+	repository "github.com/GameComponent/economy-service/pkg/repository"
+	proto "github.com/golang/protobuf/proto"
+	protov2 "google.golang.org/protobuf/proto"
+)
+
+// listRequest is a request of a List or Search method
+type listRequest interface {
+	proto.Message
+	GetPageSize() int32
+	GetPageToken() string
+}
+
+// filteredRequest is a list request that can filter and sort its list
+type filteredRequest interface {
+	GetFilter() string
+	GetOrderBy() string
+}
+
+// listPage is the page a list request asks for
+type listPage struct {
+	repository.Page
+
+	scope  string
+	tokens *pagetoken.Signer
+}
+
+// listPage reads the page token of a request, tokens are bound to the request they came from
+// apart from the page fields, so a token can not continue a list with another query or filter
+func (s *EconomyServiceServer) listPage(req listRequest) (listPage, error) {
+	scope, err := pageScope(req)
+	if err != nil {
+		return listPage{}, err
+	}
+
+	cursor, err := s.PageTokens.Decode(scope, req.GetPageToken())
+	if err != nil {
+		return listPage{}, repository.InvalidArgument("page_token", "is not a page token of this list, start again without one")
+	}
+
+	page := repository.Page{Size: req.GetPageSize(), After: cursor}
+	if filtered, ok := req.(filteredRequest); ok {
+		page.Filter = filtered.GetFilter()
+		page.OrderBy = filtered.GetOrderBy()
+	}
+
+	return listPage{
+		Page:   page,
+		scope:  scope,
+		tokens: s.PageTokens,
+	}, nil
+}
+
+// nextPageToken is the token clients send for the page after the cursor, empty on the last page
+func (p listPage) nextPageToken(cursor repository.Cursor) string {
+	return p.tokens.Encode(p.scope, cursor)
+}
+
+// pageScope is the request without its page fields, prefixed with the name of its message
+func pageScope(req listRequest) (string, error) {
+	scoped := proto.Clone(req)
+	reflected := proto.MessageReflect(scoped)
+
+	fields := reflected.Descriptor().Fields()
+	reflected.Clear(fields.ByName("page_size"))
+	reflected.Clear(fields.ByName("page_token"))
+
+	data, err := protov2.MarshalOptions{Deterministic: true}.Marshal(proto.MessageV2(scoped))
+	if err != nil {
+		return "", err
+	}
+
+	return string(reflected.Descriptor().FullName()) + ":" + string(data), nil
+}
@@ -2,10 +2,8 @@ package v1
 
 import (
 	"context"
-	"strconv"
 
 	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
-	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -15,8 +13,7 @@ func (s *EconomyServiceServer) GetPlayer(ctx context.Context, req *v1.GetPlayerR
 	player, err := s.PlayerRepository.Get(ctx, req.GetPlayerId())
 
 	if err != nil {
-		s.Logger.Error("player not found", zap.Error(err))
-		return nil, status.Error(codes.NotFound, "player not found")
+		return nil, s.errorStatus(ctx, err, "unable to retrieve player")
 	}
 
 	return &v1.GetPlayerResponse{
@@ -41,7 +38,7 @@ func (s *EconomyServiceServer) CreatePlayer(ctx context.Context, req *v1.CreateP
 		req.GetMetadata(),
 	)
 	if err != nil {
-		return nil, status.Error(codes.Aborted, "unable to create player, make sure the player_id is unique")
+		return nil, s.errorStatus(ctx, err, "unable to create player")
 	}
 
 	return &v1.CreatePlayerResponse{
@@ -62,8 +59,7 @@ func (s *EconomyServiceServer) UpdatePlayer(ctx context.Context, req *v1.UpdateP
 		req.GetMetadata(),
 	)
 	if err != nil {
-		s.Logger.Error("unable to update player", zap.Error(err))
-		return nil, status.Error(codes.NotFound, "unable to update player")
+		return nil, s.errorStatus(ctx, err, "unable to update player")
 	}
 
 	return &v1.UpdatePlayerResponse{
@@ -73,40 +69,22 @@ func (s *EconomyServiceServer) UpdatePlayer(ctx context.Context, req *v1.UpdateP
 
 // ListPlayer lists players
 func (s *EconomyServiceServer) ListPlayer(ctx context.Context, req *v1.ListPlayerRequest) (*v1.ListPlayerResponse, error) {
-	// Parse the page token
-	var parsedToken int64
-	parsedToken, _ = strconv.ParseInt(req.GetPageToken(), 10, 32)
-
-	// Get the limit
-	limit := req.GetPageSize()
-	if limit == 0 {
-		limit = 100
-	}
-
-	// Get the offset
-	offset := int32(0)
-	if len(req.GetPageToken()) > 0 {
-		offset = int32(parsedToken) * limit
+	// Continue after the last row of the previous page
+	page, err := s.listPage(req)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "invalid page token")
 	}
 
 	// Get the players
-	players, totalSize, err := s.PlayerRepository.List(ctx, limit, offset)
+	players, next, totalSize, err := s.PlayerRepository.List(ctx, page.Page)
 	if err != nil {
-		s.Logger.Error("unable to list players", zap.Error(err))
-		return nil, status.Error(codes.Aborted, "unable to list players")
-	}
-
-	// Determine if there is a next page
-	var nextPageToken string
-	if totalSize > (offset + limit) {
-		nextPage := int32(parsedToken) + 1
-		nextPageToken = strconv.Itoa(int(nextPage))
+		return nil, s.errorStatus(ctx, err, "unable to list players")
 	}
 
 	return &v1.ListPlayerResponse{
 		Players:       players,
 		TotalSize:     totalSize,
-		NextPageToken: nextPageToken,
+		NextPageToken: page.nextPageToken(next),
 	}, nil
 }
 
@@ -117,38 +95,53 @@ func (s *EconomyServiceServer) SearchPlayer(ctx context.Context, req *v1.SearchP
 		return nil, status.Error(codes.InvalidArgument, "no query given")
 	}
 
-	// Parse the page token
-	var parsedToken int64
-	parsedToken, _ = strconv.ParseInt(req.GetPageToken(), 10, 32)
-
-	// Get the limit
-	limit := req.GetPageSize()
-	if limit == 0 {
-		limit = 100
-	}
-
-	// Get the offset
-	offset := int32(0)
-	if len(req.GetPageToken()) > 0 {
-		offset = int32(parsedToken) * limit
+	// Continue after the last row of the previous page
+	page, err := s.listPage(req)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "invalid page token")
 	}
 
 	// Search the players
-	players, totalSize, err := s.PlayerRepository.Search(ctx, req.GetQuery(), limit, offset)
+	players, next, totalSize, err := s.PlayerRepository.Search(ctx, req.GetQuery(), page.Page)
 	if err != nil {
 		return nil, err
 	}
 
-	// Determine if there is a next page
-	var nextPageToken string
-	if totalSize > (offset + limit) {
-		nextPage := int32(parsedToken) + 1
-		nextPageToken = strconv.Itoa(int(nextPage))
-	}
-
 	return &v1.SearchPlayerResponse{
 		Players:       players,
 		TotalSize:     totalSize,
-		NextPageToken: nextPageToken,
+		NextPageToken: page.nextPageToken(next),
+	}, nil
+}
+
+// ArchivePlayer archives a player
+func (s *EconomyServiceServer) ArchivePlayer(ctx context.Context, req *v1.ArchivePlayerRequest) (*v1.ArchivePlayerResponse, error) {
+	if req.GetPlayerId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "no player_id given")
+	}
+
+	player, err := s.PlayerRepository.Archive(ctx, req.GetPlayerId())
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to archive player")
+	}
+
+	return &v1.ArchivePlayerResponse{
+		Player: player,
+	}, nil
+}
+
+// DeletePlayer deletes a player
+func (s *EconomyServiceServer) DeletePlayer(ctx context.Context, req *v1.DeletePlayerRequest) (*v1.DeletePlayerResponse, error) {
+	if req.GetPlayerId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "no player_id given")
+	}
+
+	success, err := s.PlayerRepository.Delete(ctx, req.GetPlayerId(), req.GetForce())
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to delete player")
+	}
+
+	return &v1.DeletePlayerResponse{
+		Success: success,
 	}, nil
 }
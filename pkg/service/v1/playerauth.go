@@ -0,0 +1,59 @@
+package v1
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// AuthorizePlayer makes sure every player and storage referenced by a request
+// belongs to the player, used for tokens with the player audience
+func (s *EconomyServiceServer) AuthorizePlayer(ctx context.Context, playerID string, req interface{}) error {
+	if playerID == "" {
+		return status.Error(codes.PermissionDenied, "token is not bound to a player")
+	}
+
+	// Requests that reference a player directly
+	if r, ok := req.(interface{ GetPlayerId() string }); ok && r.GetPlayerId() != "" {
+		if r.GetPlayerId() != playerID {
+			return status.Error(codes.PermissionDenied, "not allowed to access another player")
+		}
+	}
+
+	// Requests that reference one or more storages, storage items are
+	// checked against their storage by the methods themselves
+	storageIDs := []string{}
+	if r, ok := req.(interface{ GetStorageId() string }); ok {
+		storageIDs = append(storageIDs, r.GetStorageId())
+	}
+	if r, ok := req.(interface{ GetToStorageId() string }); ok {
+		storageIDs = append(storageIDs, r.GetToStorageId())
+	}
+	if r, ok := req.(interface{ GetFromStorageId() string }); ok {
+		storageIDs = append(storageIDs, r.GetFromStorageId())
+	}
+	if r, ok := req.(interface{ GetReceivingStorageId() string }); ok {
+		storageIDs = append(storageIDs, r.GetReceivingStorageId())
+	}
+	if r, ok := req.(interface{ GetPayingStorageId() string }); ok {
+		storageIDs = append(storageIDs, r.GetPayingStorageId())
+	}
+
+	for _, storageID := range storageIDs {
+		if storageID == "" {
+			continue
+		}
+
+		storage, err := s.StorageRepository.Get(ctx, storageID)
+		if err != nil {
+			return s.errorStatus(ctx, err, "unable to retrieve storage")
+		}
+
+		if storage.GetPlayerId() != playerID {
+			return status.Error(codes.PermissionDenied, "not allowed to access the storage of another player")
+		}
+	}
+
+	return nil
+}
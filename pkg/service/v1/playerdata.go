@@ -0,0 +1,131 @@
+package v1
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+
+	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
+	jsonpb "github.com/golang/protobuf/jsonpb"
+	ptypes "github.com/golang/protobuf/ptypes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ExportPlayerData exports everything that is stored about a player
+func (s *EconomyServiceServer) ExportPlayerData(ctx context.Context, req *v1.ExportPlayerDataRequest) (*v1.ExportPlayerDataResponse, error) {
+	if req.GetPlayerId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "no player_id given")
+	}
+
+	playerData, err := s.getPlayerData(ctx, req.GetPlayerId())
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to collect player data")
+	}
+
+	// Encode the player data as JSON
+	marshaler := jsonpb.Marshaler{
+		OrigName:     true,
+		EmitDefaults: true,
+		Indent:       "  ",
+	}
+
+	document, err := marshaler.MarshalToString(playerData)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to encode player data")
+	}
+
+	filename := fmt.Sprintf("player-%s", req.GetPlayerId())
+
+	if req.GetFormat() == v1.ExportFormat_JSON {
+		return &v1.ExportPlayerDataResponse{
+			Data:        []byte(document),
+			ContentType: "application/json",
+			Filename:    filename + ".json",
+		}, nil
+	}
+
+	// Wrap the JSON document in a zip archive
+	buffer := new(bytes.Buffer)
+	archive := zip.NewWriter(buffer)
+
+	file, err := archive.Create(filename + ".json")
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to create archive")
+	}
+
+	_, err = file.Write([]byte(document))
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to create archive")
+	}
+
+	if err = archive.Close(); err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to create archive")
+	}
+
+	return &v1.ExportPlayerDataResponse{
+		Data:        buffer.Bytes(),
+		ContentType: "application/zip",
+		Filename:    filename + ".zip",
+	}, nil
+}
+
+// ErasePlayer anonymizes the personal data of a player
+func (s *EconomyServiceServer) ErasePlayer(ctx context.Context, req *v1.ErasePlayerRequest) (*v1.ErasePlayerResponse, error) {
+	if req.GetPlayerId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "no player_id given")
+	}
+
+	player, err := s.PlayerRepository.Erase(ctx, req.GetPlayerId())
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to erase player")
+	}
+
+	return &v1.ErasePlayerResponse{
+		Player: player,
+	}, nil
+}
+
+func (s *EconomyServiceServer) getPlayerData(ctx context.Context, playerID string) (*v1.PlayerData, error) {
+	player, err := s.PlayerRepository.Get(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Include the archived storages as well
+	playerStorages, err := s.StorageRepository.ListByPlayer(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+
+	storages := []*v1.Storage{}
+	for _, playerStorage := range playerStorages {
+		storage, err := s.StorageRepository.Get(ctx, playerStorage.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		storage.CreatedAt = playerStorage.CreatedAt
+		storage.UpdatedAt = playerStorage.UpdatedAt
+		storages = append(storages, storage)
+	}
+
+	purchases, err := s.LedgerRepository.ListPurchasesByPlayer(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+
+	ledgerEntries, err := s.LedgerRepository.ListByPlayer(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.PlayerData{
+		Player:        player,
+		Storages:      storages,
+		Purchases:     purchases,
+		LedgerEntries: ledgerEntries,
+		ExportedAt:    ptypes.TimestampNow(),
+	}, nil
+}
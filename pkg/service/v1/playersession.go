@@ -0,0 +1,67 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
+	tenant "github.com/GameComponent/economy-service/pkg/tenant"
+	jwt "github.com/dgrijalva/jwt-go"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CreatePlayerSession creates a short lived token for a game client of the player
+func (s *EconomyServiceServer) CreatePlayerSession(ctx context.Context, req *v1.CreatePlayerSessionRequest) (*v1.CreatePlayerSessionResponse, error) {
+	if req.GetPlayerId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "please enter a player_id")
+	}
+
+	player, err := s.PlayerRepository.Get(ctx, req.GetPlayerId())
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to retrieve player")
+	}
+
+	if player.GetArchivedAt() != nil || player.GetErasedAt() != nil {
+		return nil, status.Error(codes.FailedPrecondition, "player is archived")
+	}
+
+	// Use the configured expiration unless a shorter one is requested
+	expiresIn := int32(s.Config.PlayerExpiration)
+	if req.GetExpiresIn() > 0 && req.GetExpiresIn() < expiresIn {
+		expiresIn = req.GetExpiresIn()
+	}
+
+	token, err := s.generatePlayerToken(ctx, player.GetId(), expiresIn)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to generate access_token")
+	}
+
+	return &v1.CreatePlayerSessionResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   expiresIn,
+	}, nil
+}
+
+func (s *EconomyServiceServer) generatePlayerToken(ctx context.Context, playerID string, expiresIn int32) (string, error) {
+	expirationTime := time.Now().Add(time.Duration(expiresIn) * time.Second)
+
+	claims := &Claims{
+		Subject: playerID,
+		Tenant:  tenant.FromContext(ctx),
+		StandardClaims: jwt.StandardClaims{
+			Audience:  "player",
+			ExpiresAt: expirationTime.Unix(),
+		},
+	}
+
+	// Sign and get the complete encoded token as a string using the current key
+	tokenString, err := s.Keyring.Sign(claims)
+	if err != nil {
+		return "", fmt.Errorf("unable to sign token")
+	}
+
+	return tokenString, nil
+}
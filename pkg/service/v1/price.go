@@ -2,7 +2,6 @@ package v1
 
 import (
 	"context"
-	"fmt"
 
 	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
 	codes "google.golang.org/grpc/codes"
@@ -11,11 +10,9 @@ import (
 
 // GetPrice get a price
 func (s *EconomyServiceServer) GetPrice(ctx context.Context, req *v1.GetPriceRequest) (*v1.GetPriceResponse, error) {
-	fmt.Println("GetPrice")
-
 	price, err := s.PriceRepository.Get(ctx, req.GetPriceId())
 	if err != nil {
-		return nil, status.Error(codes.NotFound, "price not found")
+		return nil, s.errorStatus(ctx, err, "unable to retrieve price")
 	}
 
 	return &v1.GetPriceResponse{
@@ -25,8 +22,6 @@ func (s *EconomyServiceServer) GetPrice(ctx context.Context, req *v1.GetPriceReq
 
 // CreatePrice creates a new price
 func (s *EconomyServiceServer) CreatePrice(ctx context.Context, req *v1.CreatePriceRequest) (*v1.CreatePriceResponse, error) {
-	fmt.Println("CreatePrice")
-
 	if req.GetProductId() == "" {
 		return nil, status.Error(codes.InvalidArgument, "no product_id given")
 	}
@@ -34,7 +29,7 @@ func (s *EconomyServiceServer) CreatePrice(ctx context.Context, req *v1.CreatePr
 	// Add the price to the databased return the generated UUID
 	price, err := s.PriceRepository.Create(ctx, req.GetProductId())
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to create price")
+		return nil, s.errorStatus(ctx, err, "unable to create price")
 	}
 
 	return &v1.CreatePriceResponse{
@@ -44,8 +39,6 @@ func (s *EconomyServiceServer) CreatePrice(ctx context.Context, req *v1.CreatePr
 
 // AttachPriceCurrency attaches a currency to a price
 func (s *EconomyServiceServer) AttachPriceCurrency(ctx context.Context, req *v1.AttachPriceCurrencyRequest) (*v1.AttachPriceCurrencyResponse, error) {
-	fmt.Println("AttachPriceCurrency")
-
 	price, err := s.PriceRepository.AttachPriceCurrency(
 		ctx,
 		req.GetPriceId(),
@@ -54,7 +47,7 @@ func (s *EconomyServiceServer) AttachPriceCurrency(ctx context.Context, req *v1.
 	)
 
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to attach currency to price")
+		return nil, s.errorStatus(ctx, err, "unable to attach currency to price")
 	}
 
 	return &v1.AttachPriceCurrencyResponse{
@@ -64,15 +57,13 @@ func (s *EconomyServiceServer) AttachPriceCurrency(ctx context.Context, req *v1.
 
 // DetachPriceCurrency detaches a currency from a price
 func (s *EconomyServiceServer) DetachPriceCurrency(ctx context.Context, req *v1.DetachPriceCurrencyRequest) (*v1.DetachPriceCurrencyResponse, error) {
-	fmt.Println("DetachPriceCurrency")
-
 	price, err := s.PriceRepository.DetachPriceCurrency(
 		ctx,
 		req.GetPriceCurrencyId(),
 	)
 
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to detach currency from price")
+		return nil, s.errorStatus(ctx, err, "unable to detach currency from price")
 	}
 
 	return &v1.DetachPriceCurrencyResponse{
@@ -82,8 +73,6 @@ func (s *EconomyServiceServer) DetachPriceCurrency(ctx context.Context, req *v1.
 
 // AttachPriceItem attaches an item to a price
 func (s *EconomyServiceServer) AttachPriceItem(ctx context.Context, req *v1.AttachPriceItemRequest) (*v1.AttachPriceItemResponse, error) {
-	fmt.Println("AttachPriceItem")
-
 	price, err := s.PriceRepository.AttachPriceItem(
 		ctx,
 		req.GetPriceId(),
@@ -92,7 +81,7 @@ func (s *EconomyServiceServer) AttachPriceItem(ctx context.Context, req *v1.Atta
 	)
 
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to attach item to price")
+		return nil, s.errorStatus(ctx, err, "unable to attach item to price")
 	}
 
 	return &v1.AttachPriceItemResponse{
@@ -102,15 +91,13 @@ func (s *EconomyServiceServer) AttachPriceItem(ctx context.Context, req *v1.Atta
 
 // DetachPriceItem detaches an item from a price
 func (s *EconomyServiceServer) DetachPriceItem(ctx context.Context, req *v1.DetachPriceItemRequest) (*v1.DetachPriceItemResponse, error) {
-	fmt.Println("DetachPriceItem")
-
 	price, err := s.PriceRepository.DetachPriceCurrency(
 		ctx,
 		req.GetPriceItemId(),
 	)
 
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to detach item from price")
+		return nil, s.errorStatus(ctx, err, "unable to detach item from price")
 	}
 
 	return &v1.DetachPriceItemResponse{
@@ -120,15 +107,13 @@ func (s *EconomyServiceServer) DetachPriceItem(ctx context.Context, req *v1.Deta
 
 // DeletePrice deletes a price
 func (s *EconomyServiceServer) DeletePrice(ctx context.Context, req *v1.DeletePriceRequest) (*v1.DeletePriceResponse, error) {
-	fmt.Println("DeletePrice")
-
 	success, err := s.PriceRepository.Delete(
 		ctx,
 		req.GetPriceId(),
 	)
 
 	if err != nil {
-		return nil, status.Error(codes.NotFound, "price not found")
+		return nil, s.errorStatus(ctx, err, "unable to delete price")
 	}
 
 	return &v1.DeletePriceResponse{
@@ -2,22 +2,21 @@ package v1
 
 import (
 	"context"
-	"fmt"
-	"strconv"
 
 	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
+	metrics "github.com/GameComponent/economy-service/pkg/metrics"
+	repository "github.com/GameComponent/economy-service/pkg/repository"
+	tenant "github.com/GameComponent/economy-service/pkg/tenant"
 	codes "google.golang.org/grpc/codes"
 	status "google.golang.org/grpc/status"
 )
 
 // CreateProduct creates a new product
 func (s *EconomyServiceServer) CreateProduct(ctx context.Context, req *v1.CreateProductRequest) (*v1.CreateProductResponse, error) {
-	fmt.Println("CreateProduct")
-
 	// Add product to the databased return the generated UUID
 	product, err := s.ProductRepository.Create(ctx, req.GetName())
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to create product")
+		return nil, s.errorStatus(ctx, err, "unable to create product")
 	}
 
 	return &v1.CreateProductResponse{
@@ -27,8 +26,6 @@ func (s *EconomyServiceServer) CreateProduct(ctx context.Context, req *v1.Create
 
 // UpdateProduct updates a product
 func (s *EconomyServiceServer) UpdateProduct(ctx context.Context, req *v1.UpdateProductRequest) (*v1.UpdateProductResponse, error) {
-	fmt.Println("UpdateProduct")
-
 	if req.GetProductId() == "" {
 		return nil, status.Error(codes.InvalidArgument, "no product_id given")
 	}
@@ -40,7 +37,7 @@ func (s *EconomyServiceServer) UpdateProduct(ctx context.Context, req *v1.Update
 	// Add product to the databased return the generated UUID
 	product, err := s.ProductRepository.Update(ctx, req.GetProductId(), req.GetName())
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to update product")
+		return nil, s.errorStatus(ctx, err, "unable to update product")
 	}
 
 	return &v1.UpdateProductResponse{
@@ -50,41 +47,22 @@ func (s *EconomyServiceServer) UpdateProduct(ctx context.Context, req *v1.Update
 
 // ListProduct lists products
 func (s *EconomyServiceServer) ListProduct(ctx context.Context, req *v1.ListProductRequest) (*v1.ListProductResponse, error) {
-	fmt.Println("ListProduct")
-
-	// Parse the page token
-	var parsedToken int64
-	parsedToken, _ = strconv.ParseInt(req.GetPageToken(), 10, 32)
-
-	// Get the limit
-	limit := req.GetPageSize()
-	if limit == 0 {
-		limit = 100
-	}
-
-	// Get the offset
-	offset := int32(0)
-	if len(req.GetPageToken()) > 0 {
-		offset = int32(parsedToken) * limit
+	// Continue after the last row of the previous page
+	page, err := s.listPage(req)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "invalid page token")
 	}
 
 	// Get the products
-	products, totalSize, err := s.ProductRepository.List(ctx, limit, offset)
+	products, next, totalSize, err := s.ProductRepository.List(ctx, page.Page)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to retrieve product list")
-	}
-
-	// Determine if there is a next page
-	var nextPageToken string
-	if totalSize > (offset + limit) {
-		nextPage := int32(parsedToken) + 1
-		nextPageToken = strconv.Itoa(int(nextPage))
+		return nil, s.errorStatus(ctx, err, "unable to retrieve product list")
 	}
 
 	return &v1.ListProductResponse{
 		Products:      products,
 		TotalSize:     totalSize,
-		NextPageToken: nextPageToken,
+		NextPageToken: page.nextPageToken(next),
 	}, nil
 }
 
@@ -92,7 +70,7 @@ func (s *EconomyServiceServer) ListProduct(ctx context.Context, req *v1.ListProd
 func (s *EconomyServiceServer) GetProduct(ctx context.Context, req *v1.GetProductRequest) (*v1.GetProductResponse, error) {
 	product, err := s.ProductRepository.Get(ctx, req.GetProductId())
 	if err != nil {
-		return nil, status.Error(codes.NotFound, "product not found")
+		return nil, s.errorStatus(ctx, err, "unable to retrieve product")
 	}
 
 	return &v1.GetProductResponse{
@@ -102,8 +80,6 @@ func (s *EconomyServiceServer) GetProduct(ctx context.Context, req *v1.GetProduc
 
 // AttachItem attaches an item to a product
 func (s *EconomyServiceServer) AttachItem(ctx context.Context, req *v1.AttachItemRequest) (*v1.AttachItemResponse, error) {
-	fmt.Println("AttachItem")
-
 	// Add product to the databased return the generated UUID
 	product, err := s.ProductRepository.AttachItem(
 		ctx,
@@ -113,7 +89,7 @@ func (s *EconomyServiceServer) AttachItem(ctx context.Context, req *v1.AttachIte
 	)
 
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to attach item to product")
+		return nil, s.errorStatus(ctx, err, "unable to attach item to product")
 	}
 
 	return &v1.AttachItemResponse{
@@ -123,8 +99,6 @@ func (s *EconomyServiceServer) AttachItem(ctx context.Context, req *v1.AttachIte
 
 // DetachItem detaches an item from a product
 func (s *EconomyServiceServer) DetachItem(ctx context.Context, req *v1.DetachItemRequest) (*v1.DetachItemResponse, error) {
-	fmt.Println("DetachItem")
-
 	// Add product to the databased return the generated UUID
 	product, err := s.ProductRepository.DetachItem(
 		ctx,
@@ -132,7 +106,7 @@ func (s *EconomyServiceServer) DetachItem(ctx context.Context, req *v1.DetachIte
 	)
 
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to detach item from product")
+		return nil, s.errorStatus(ctx, err, "unable to detach item from product")
 	}
 
 	return &v1.DetachItemResponse{
@@ -142,8 +116,6 @@ func (s *EconomyServiceServer) DetachItem(ctx context.Context, req *v1.DetachIte
 
 // AttachCurrency attaches a currency to a product
 func (s *EconomyServiceServer) AttachCurrency(ctx context.Context, req *v1.AttachCurrencyRequest) (*v1.AttachCurrencyResponse, error) {
-	fmt.Println("AttachCurrency")
-
 	// Add product to the databased return the generated UUID
 	product, err := s.ProductRepository.AttachCurrency(
 		ctx,
@@ -153,7 +125,7 @@ func (s *EconomyServiceServer) AttachCurrency(ctx context.Context, req *v1.Attac
 	)
 
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to attach currency to product")
+		return nil, s.errorStatus(ctx, err, "unable to attach currency to product")
 	}
 
 	return &v1.AttachCurrencyResponse{
@@ -163,8 +135,6 @@ func (s *EconomyServiceServer) AttachCurrency(ctx context.Context, req *v1.Attac
 
 // DetachCurrency detaches a currency from a product
 func (s *EconomyServiceServer) DetachCurrency(ctx context.Context, req *v1.DetachCurrencyRequest) (*v1.DetachCurrencyResponse, error) {
-	fmt.Println("DetachCurrency")
-
 	// Add product to the databased return the generated UUID
 	product, err := s.ProductRepository.DetachCurrency(
 		ctx,
@@ -172,7 +142,7 @@ func (s *EconomyServiceServer) DetachCurrency(ctx context.Context, req *v1.Detac
 	)
 
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to detach item from product")
+		return nil, s.errorStatus(ctx, err, "unable to detach item from product")
 	}
 
 	return &v1.DetachCurrencyResponse{
@@ -182,8 +152,6 @@ func (s *EconomyServiceServer) DetachCurrency(ctx context.Context, req *v1.Detac
 
 // ListProductPrice lists all prices for the product
 func (s *EconomyServiceServer) ListProductPrice(ctx context.Context, req *v1.ListProductPriceRequest) (*v1.ListProductPriceResponse, error) {
-	fmt.Println("ListProductPrice")
-
 	if req.GetProductId() == "" {
 		return nil, status.Error(codes.InvalidArgument, "no product_id given")
 	}
@@ -200,8 +168,6 @@ func (s *EconomyServiceServer) ListProductPrice(ctx context.Context, req *v1.Lis
 
 // BuyProduct buys the product
 func (s *EconomyServiceServer) BuyProduct(ctx context.Context, req *v1.BuyProductRequest) (*v1.BuyProductResponse, error) {
-	fmt.Println("BuyProduct")
-
 	if req.GetProductId() == "" {
 		return nil, status.Error(codes.InvalidArgument, "no product_id given")
 	}
@@ -221,7 +187,12 @@ func (s *EconomyServiceServer) BuyProduct(ctx context.Context, req *v1.BuyProduc
 	// Get the Product
 	product, err := s.ProductRepository.Get(ctx, req.GetProductId())
 	if err != nil {
-		return nil, status.Error(codes.NotFound, "product not found")
+		return nil, s.errorStatus(ctx, err, "unable to retrieve product")
+	}
+
+	// Archived products can no longer be bought
+	if product.ArchivedAt != nil {
+		return nil, status.Error(codes.FailedPrecondition, "product is archived")
 	}
 
 	// Turn the Price slice into a map
@@ -234,13 +205,13 @@ func (s *EconomyServiceServer) BuyProduct(ctx context.Context, req *v1.BuyProduc
 	// Check if the price is part of the Product
 	price := productPricesMap[req.GetPriceId()]
 	if price == nil || price.Id == "" {
-		return nil, status.Error(codes.NotFound, "price not found in product")
+		return nil, s.errorStatus(ctx, repository.NotFound("price", req.GetPriceId()), "price not found in product")
 	}
 
 	// Get the paying Storage
 	payingStorage, err := s.StorageRepository.Get(ctx, req.GetPayingStorageId())
-	if payingStorage == nil || payingStorage.Id == "" {
-		return nil, status.Error(codes.NotFound, "paying_storage_id not found")
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to retrieve paying storage")
 	}
 
 	// Get the receiving Storage
@@ -255,14 +226,10 @@ func (s *EconomyServiceServer) BuyProduct(ctx context.Context, req *v1.BuyProduc
 	if req.GetPayingStorageId() != req.GetReceivingStorageId() {
 		receivingStorage, err = s.StorageRepository.Get(ctx, req.GetReceivingStorageId())
 		if err != nil {
-			return nil, err
+			return nil, s.errorStatus(ctx, err, "unable to retrieve receiving storage")
 		}
 	}
 
-	if receivingStorage.Id == "" {
-		return nil, status.Error(codes.NotFound, "receiving_storage_id not found")
-	}
-
 	// Determine if there is enough of the Currency in the paying Storage
 	for _, priceCurrency := range price.Currencies {
 		hasEnoughOfCurrency := false
@@ -278,10 +245,7 @@ func (s *EconomyServiceServer) BuyProduct(ctx context.Context, req *v1.BuyProduc
 		}
 
 		if !hasEnoughOfCurrency {
-			return nil, status.Error(
-				codes.Aborted,
-				fmt.Sprintf("not enough of currency %s in the storage", priceCurrency.Currency.Id),
-			)
+			return nil, s.errorStatus(ctx, repository.InsufficientFunds(payingStorage.Id, priceCurrency.Currency.Id), "unable to buy product")
 		}
 	}
 
@@ -305,10 +269,7 @@ func (s *EconomyServiceServer) BuyProduct(ctx context.Context, req *v1.BuyProduc
 		}
 
 		if remainingItems > 0 {
-			return nil, status.Error(
-				codes.Aborted,
-				fmt.Sprintf("not enough of items %s in the storage", priceItem.Item.Id),
-			)
+			return nil, s.errorStatus(ctx, repository.InsufficientItems(payingStorage.Id, priceItem.Item.Id), "unable to buy product")
 		}
 	}
 
@@ -320,10 +281,44 @@ func (s *EconomyServiceServer) BuyProduct(ctx context.Context, req *v1.BuyProduc
 		payingStorage,
 	)
 	if err != nil {
-		return nil, err
+		return nil, s.errorStatus(ctx, err, "unable to buy product")
 	}
 
+	metrics.Purchase(tenant.FromContext(ctx), product, price, repository.LedgerReasonPurchase)
+
 	return &v1.BuyProductResponse{
 		Product: product,
 	}, nil
 }
+
+// ArchiveProduct archives a product
+func (s *EconomyServiceServer) ArchiveProduct(ctx context.Context, req *v1.ArchiveProductRequest) (*v1.ArchiveProductResponse, error) {
+	if req.GetProductId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "no product_id given")
+	}
+
+	product, err := s.ProductRepository.Archive(ctx, req.GetProductId())
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to archive product")
+	}
+
+	return &v1.ArchiveProductResponse{
+		Product: product,
+	}, nil
+}
+
+// DeleteProduct deletes a product
+func (s *EconomyServiceServer) DeleteProduct(ctx context.Context, req *v1.DeleteProductRequest) (*v1.DeleteProductResponse, error) {
+	if req.GetProductId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "no product_id given")
+	}
+
+	success, err := s.ProductRepository.Delete(ctx, req.GetProductId(), req.GetForce())
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to delete product")
+	}
+
+	return &v1.DeleteProductResponse{
+		Success: success,
+	}, nil
+}
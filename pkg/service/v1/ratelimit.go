@@ -0,0 +1,92 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	tenant "github.com/GameComponent/economy-service/pkg/tenant"
+	"go.uber.org/zap"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	metadata "google.golang.org/grpc/metadata"
+	status "google.golang.org/grpc/status"
+)
+
+// LimitAuth throttles the public authentication methods per client address and per email,
+// the limiters fail open so an unavailable store does not lock everyone out
+func (s *EconomyServiceServer) LimitAuth(ctx context.Context, req interface{}) error {
+	_, ip := ClientInfo(ctx)
+
+	wait, err := s.AuthIPLimiter.Allow(ctx, ip)
+	if err != nil {
+		s.log(ctx).Error("unable to check the rate limit", zap.Error(err))
+	}
+	if wait > 0 {
+		return tooManyRequests(ctx, wait)
+	}
+
+	r, ok := req.(interface{ GetEmail() string })
+	if !ok || r.GetEmail() == "" {
+		return nil
+	}
+
+	wait, err = s.AuthEmailLimiter.Allow(ctx, emailKey(ctx, r.GetEmail()))
+	if err != nil {
+		s.log(ctx).Error("unable to check the rate limit", zap.Error(err))
+	}
+	if wait > 0 {
+		return tooManyRequests(ctx, wait)
+	}
+
+	return nil
+}
+
+// checkLockout refuses accounts that are locked after too many failed logins
+func (s *EconomyServiceServer) checkLockout(ctx context.Context, email string) error {
+	locked, err := s.Lockout.Locked(ctx, emailKey(ctx, email))
+	if err != nil {
+		s.log(ctx).Error("unable to check the lockout", zap.Error(err))
+	}
+	if locked > 0 {
+		return tooManyRequests(ctx, locked)
+	}
+
+	return nil
+}
+
+// failLogin records a failed login, the lock starts with the next attempt
+func (s *EconomyServiceServer) failLogin(ctx context.Context, email string) {
+	locked, err := s.Lockout.Fail(ctx, emailKey(ctx, email))
+	if err != nil {
+		s.log(ctx).Error("unable to record the failed login", zap.Error(err))
+	}
+	if locked > 0 {
+		s.log(ctx).Warn("account locked after failed logins", zap.String("email", email), zap.Duration("duration", locked))
+	}
+}
+
+// succeedLogin forgets the failed logins of an account
+func (s *EconomyServiceServer) succeedLogin(ctx context.Context, email string) {
+	err := s.Lockout.Succeed(ctx, emailKey(ctx, email))
+	if err != nil {
+		s.log(ctx).Error("unable to reset the failed logins", zap.Error(err))
+	}
+}
+
+// emailKey is the key of an email in the limiters, emails are unique per tenant
+func emailKey(ctx context.Context, email string) string {
+	return tenant.FromContext(ctx) + ":" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// tooManyRequests returns the error for a limited request, the REST gateway
+// turns it into a 429 with the Retry-After header
+func tooManyRequests(ctx context.Context, wait time.Duration) error {
+	seconds := int64(math.Ceil(wait.Seconds()))
+	_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.FormatInt(seconds, 10)))
+
+	return status.Error(codes.ResourceExhausted, fmt.Sprintf("too many attempts, retry in %d seconds", seconds))
+}
@@ -0,0 +1,135 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
+	repository "github.com/GameComponent/economy-service/pkg/repository"
+	role "github.com/GameComponent/economy-service/pkg/role"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CreateRole creates or replaces a custom role
+func (s *EconomyServiceServer) CreateRole(ctx context.Context, req *v1.CreateRoleRequest) (*v1.CreateRoleResponse, error) {
+	if !role.ValidName(req.GetName()) {
+		return nil, status.Error(codes.InvalidArgument, "name should be lowercase letters, digits, dashes or underscores")
+	}
+
+	if role.IsBuiltIn(req.GetName()) {
+		return nil, status.Error(codes.AlreadyExists, "built-in roles can not be changed")
+	}
+
+	if len(req.GetPermissions()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "please enter permissions")
+	}
+
+	for _, permission := range req.GetPermissions() {
+		if !role.ValidPermission(permission) {
+			return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("invalid permission %q", permission))
+		}
+	}
+
+	createdRole, err := s.RoleRepository.Create(ctx, req.GetName(), req.GetPermissions())
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to create role")
+	}
+
+	return &v1.CreateRoleResponse{
+		Role: createdRole,
+	}, nil
+}
+
+// ListRole lists the built-in roles followed by the custom roles
+func (s *EconomyServiceServer) ListRole(ctx context.Context, req *v1.ListRoleRequest) (*v1.ListRoleResponse, error) {
+	roles := []*v1.Role{}
+	for _, name := range role.BuiltInNames() {
+		roles = append(roles, &v1.Role{
+			Name:        name,
+			Permissions: role.BuiltIn[name],
+			BuiltIn:     true,
+		})
+	}
+
+	customRoles, err := s.RoleRepository.List(ctx)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to retrieve role list")
+	}
+
+	return &v1.ListRoleResponse{
+		Roles: append(roles, customRoles...),
+	}, nil
+}
+
+// AssignRole to an account
+func (s *EconomyServiceServer) AssignRole(ctx context.Context, req *v1.AssignRoleRequest) (*v1.AssignRoleResponse, error) {
+	if req.GetAccountId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "please enter account_id")
+	}
+
+	// Only existing roles can be assigned
+	if !role.IsBuiltIn(req.GetRole()) {
+		_, err := s.RoleRepository.Get(ctx, req.GetRole())
+		if err != nil {
+			return nil, s.errorStatus(ctx, err, "unable to retrieve role")
+		}
+	}
+
+	account, err := s.AccountRepository.AssignRole(ctx, req.GetAccountId(), req.GetRole())
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to assign role")
+	}
+
+	// Filter out the account's hash
+	if account.Hash != "" {
+		account.Hash = ""
+	}
+
+	return &v1.AssignRoleResponse{
+		Account: account,
+	}, nil
+}
+
+// RevokeRole from an account
+func (s *EconomyServiceServer) RevokeRole(ctx context.Context, req *v1.RevokeRoleRequest) (*v1.RevokeRoleResponse, error) {
+	account, err := s.AccountRepository.RevokeRole(ctx, req.GetAccountId(), req.GetRole())
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to revoke role")
+	}
+
+	// Filter out the account's hash
+	if account.Hash != "" {
+		account.Hash = ""
+	}
+
+	return &v1.RevokeRoleResponse{
+		Account: account,
+	}, nil
+}
+
+// Permissions resolves the permissions of the token, roles are resolved
+// on every request so changes to a role apply to tokens already handed out
+func (s *EconomyServiceServer) Permissions(ctx context.Context, claims *Claims) ([]string, error) {
+	permissions := append([]string{}, claims.Permissions...)
+
+	for _, name := range claims.Roles {
+		if role.IsBuiltIn(name) {
+			permissions = append(permissions, role.BuiltIn[name]...)
+			continue
+		}
+
+		customRole, err := s.RoleRepository.Get(ctx, name)
+		if errors.Is(err, repository.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		permissions = append(permissions, customRole.GetPermissions()...)
+	}
+
+	return permissions, nil
+}
@@ -0,0 +1,180 @@
+package v1
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
+	random "github.com/GameComponent/economy-service/pkg/helper/random"
+	role "github.com/GameComponent/economy-service/pkg/role"
+	codes "google.golang.org/grpc/codes"
+	metadata "google.golang.org/grpc/metadata"
+	peer "google.golang.org/grpc/peer"
+	status "google.golang.org/grpc/status"
+)
+
+type claimsKey struct{}
+
+// NewClaimsContext returns a context carrying the claims of the token making the request
+func NewClaimsContext(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims of the token making the request, nil without a token
+func ClaimsFromContext(ctx context.Context) *Claims {
+	claims, _ := ctx.Value(claimsKey{}).(*Claims)
+	return claims
+}
+
+// ListSessions lists the active sessions of an account
+func (s *EconomyServiceServer) ListSessions(ctx context.Context, req *v1.ListSessionsRequest) (*v1.ListSessionsResponse, error) {
+	accountID, err := s.sessionAccount(ctx, req.GetAccountId(), "/v1.EconomyService/ListSessions")
+	if err != nil {
+		return nil, err
+	}
+
+	// Continue after the last row of the previous page
+	page, err := s.listPage(req)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "invalid page token")
+	}
+
+	// Get the sessions
+	sessions, next, totalSize, err := s.SessionRepository.List(ctx, accountID, page.Page)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to retrieve session list")
+	}
+
+	claims := ClaimsFromContext(ctx)
+	for _, session := range sessions {
+		session.Current = claims != nil && claims.SessionID == session.Id
+	}
+
+	return &v1.ListSessionsResponse{
+		Sessions:      sessions,
+		TotalSize:     totalSize,
+		NextPageToken: page.nextPageToken(next),
+	}, nil
+}
+
+// RevokeSession revokes a session
+func (s *EconomyServiceServer) RevokeSession(ctx context.Context, req *v1.RevokeSessionRequest) (*v1.RevokeSessionResponse, error) {
+	if req.GetSessionId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "please enter a session_id")
+	}
+
+	session, err := s.SessionRepository.Get(ctx, req.GetSessionId())
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to retrieve session")
+	}
+
+	_, err = s.sessionAccount(ctx, session.GetAccountId(), "/v1.EconomyService/RevokeSession")
+	if err != nil {
+		return nil, err
+	}
+
+	session, err = s.SessionRepository.Revoke(ctx, session.GetId())
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to revoke session")
+	}
+
+	return &v1.RevokeSessionResponse{
+		Session: session,
+	}, nil
+}
+
+// Logout revokes the session of the token making the request,
+// the access token itself keeps working until it expires
+func (s *EconomyServiceServer) Logout(ctx context.Context, req *v1.LogoutRequest) (*v1.LogoutResponse, error) {
+	claims := ClaimsFromContext(ctx)
+	if claims == nil || claims.SessionID == "" {
+		return nil, status.Error(codes.FailedPrecondition, "token does not belong to a session")
+	}
+
+	_, err := s.SessionRepository.Revoke(ctx, claims.SessionID)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to revoke session")
+	}
+
+	return &v1.LogoutResponse{}, nil
+}
+
+// sessionAccount checks if the token making the request can manage the sessions of an account,
+// accounts manage their own sessions and need the permission of the method for other accounts
+func (s *EconomyServiceServer) sessionAccount(ctx context.Context, accountID string, fullMethod string) (string, error) {
+	claims := ClaimsFromContext(ctx)
+	if claims == nil {
+		return "", status.Error(codes.Unauthenticated, "no token")
+	}
+
+	if accountID == "" {
+		accountID = claims.Subject
+	}
+
+	if claims.StandardClaims.Audience == "account" && accountID == claims.Subject {
+		return accountID, nil
+	}
+
+	permissions, err := s.Permissions(ctx, claims)
+	if err != nil {
+		return "", s.errorStatus(ctx, err, "unable to resolve permissions")
+	}
+
+	if !role.Allowed(permissions, fullMethod) {
+		return "", status.Error(codes.PermissionDenied, "not allowed to manage the sessions of this account")
+	}
+
+	return accountID, nil
+}
+
+// startSession starts a session for an account and returns it together with its refresh token
+func (s *EconomyServiceServer) startSession(ctx context.Context, account *v1.Account, amr []string) (*v1.Session, string, error) {
+	refreshToken, err := random.GenerateRandomString(32)
+	if err != nil {
+		return nil, "", err
+	}
+
+	userAgent, ip := ClientInfo(ctx)
+	expiresAt := time.Now().UTC().Add(time.Duration(s.Config.JWTRefreshExpiration) * time.Second)
+
+	session, err := s.SessionRepository.Create(ctx, account.Id, amr, userAgent, ip, hashToken(refreshToken), expiresAt)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return session, refreshToken, nil
+}
+
+// ClientInfo returns the user agent and address of the client, requests through the
+// REST gateway carry the ones of the original client
+func ClientInfo(ctx context.Context) (string, string) {
+	userAgent := ""
+	ip := ""
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get("grpcgateway-user-agent"); len(values) > 0 {
+		userAgent = values[0]
+	} else if values := md.Get("user-agent"); len(values) > 0 {
+		userAgent = values[0]
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		ip = p.Addr.String()
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+	}
+
+	// The forwarded address is only trusted from the gateway, which runs on the same host
+	// and appends the address it received the request from
+	if parsed := net.ParseIP(ip); parsed != nil && parsed.IsLoopback() {
+		if values := md.Get("x-forwarded-for"); len(values) > 0 {
+			addresses := strings.Split(values[len(values)-1], ",")
+			ip = strings.TrimSpace(addresses[len(addresses)-1])
+		}
+	}
+
+	return userAgent, ip
+}
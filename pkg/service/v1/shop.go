@@ -2,8 +2,6 @@ package v1
 
 import (
 	"context"
-	"fmt"
-	"strconv"
 
 	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
 	"google.golang.org/grpc/codes"
@@ -12,12 +10,10 @@ import (
 
 // GetShop gets a shop
 func (s *EconomyServiceServer) GetShop(ctx context.Context, req *v1.GetShopRequest) (*v1.GetShopResponse, error) {
-	fmt.Println("GetShop")
-
 	shop, err := s.ShopRepository.Get(ctx, req.GetShopId())
 
 	if err != nil {
-		return nil, status.Error(codes.NotFound, "shop not found")
+		return nil, s.errorStatus(ctx, err, "unable to retrieve shop")
 	}
 
 	return &v1.GetShopResponse{
@@ -27,8 +23,6 @@ func (s *EconomyServiceServer) GetShop(ctx context.Context, req *v1.GetShopReque
 
 // CreateShop creates a new shop
 func (s *EconomyServiceServer) CreateShop(ctx context.Context, req *v1.CreateShopRequest) (*v1.CreateShopResponse, error) {
-	fmt.Println("CreateShop")
-
 	shop, err := s.ShopRepository.Create(
 		ctx,
 		req.GetName(),
@@ -36,7 +30,7 @@ func (s *EconomyServiceServer) CreateShop(ctx context.Context, req *v1.CreateSho
 	)
 
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to create shop")
+		return nil, s.errorStatus(ctx, err, "unable to create shop")
 	}
 
 	return &v1.CreateShopResponse{
@@ -46,8 +40,6 @@ func (s *EconomyServiceServer) CreateShop(ctx context.Context, req *v1.CreateSho
 
 // UpdateShop update an existing shop
 func (s *EconomyServiceServer) UpdateShop(ctx context.Context, req *v1.UpdateShopRequest) (*v1.UpdateShopResponse, error) {
-	fmt.Println("UpdateShop")
-
 	shop, err := s.ShopRepository.Update(
 		ctx,
 		req.GetShopId(),
@@ -56,7 +48,7 @@ func (s *EconomyServiceServer) UpdateShop(ctx context.Context, req *v1.UpdateSho
 	)
 
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to update shop")
+		return nil, s.errorStatus(ctx, err, "unable to update shop")
 	}
 
 	return &v1.UpdateShopResponse{
@@ -66,48 +58,27 @@ func (s *EconomyServiceServer) UpdateShop(ctx context.Context, req *v1.UpdateSho
 
 // ListShop lists shops
 func (s *EconomyServiceServer) ListShop(ctx context.Context, req *v1.ListShopRequest) (*v1.ListShopResponse, error) {
-	fmt.Println("ListShop")
-
-	// Parse the page token
-	var parsedToken int64
-	parsedToken, _ = strconv.ParseInt(req.GetPageToken(), 10, 32)
-
-	// Get the limit
-	limit := req.GetPageSize()
-	if limit == 0 {
-		limit = 100
-	}
-
-	// Get the offset
-	offset := int32(0)
-	if len(req.GetPageToken()) > 0 {
-		offset = int32(parsedToken) * limit
+	// Continue after the last row of the previous page
+	page, err := s.listPage(req)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "invalid page token")
 	}
 
 	// Get the shops
-	shops, totalSize, err := s.ShopRepository.List(ctx, limit, offset)
+	shops, next, totalSize, err := s.ShopRepository.List(ctx, page.Page)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to retrieve shop list")
-	}
-
-	// Determine if there is a next page
-	var nextPageToken string
-	if totalSize > (offset + limit) {
-		nextPage := int32(parsedToken) + 1
-		nextPageToken = strconv.Itoa(int(nextPage))
+		return nil, s.errorStatus(ctx, err, "unable to retrieve shop list")
 	}
 
 	return &v1.ListShopResponse{
 		Shops:         shops,
 		TotalSize:     totalSize,
-		NextPageToken: nextPageToken,
+		NextPageToken: page.nextPageToken(next),
 	}, nil
 }
 
 // AttachProduct attaches a product to a shop
 func (s *EconomyServiceServer) AttachProduct(ctx context.Context, req *v1.AttachProductRequest) (*v1.AttachProductResponse, error) {
-	fmt.Println("AttachProduct")
-
 	// Add product to the database return the generated UUID
 	shop, err := s.ShopRepository.AttachProduct(
 		ctx,
@@ -116,7 +87,7 @@ func (s *EconomyServiceServer) AttachProduct(ctx context.Context, req *v1.Attach
 	)
 
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to attach product to shop")
+		return nil, s.errorStatus(ctx, err, "unable to attach product to shop")
 	}
 
 	return &v1.AttachProductResponse{
@@ -126,8 +97,6 @@ func (s *EconomyServiceServer) AttachProduct(ctx context.Context, req *v1.Attach
 
 // DetachProduct detaches a product from a shop
 func (s *EconomyServiceServer) DetachProduct(ctx context.Context, req *v1.DetachProductRequest) (*v1.DetachProductResponse, error) {
-	fmt.Println("DetachProduct")
-
 	// Add product to the databased return the generated UUID
 	shop, err := s.ShopRepository.DetachProduct(
 		ctx,
@@ -135,10 +104,42 @@ func (s *EconomyServiceServer) DetachProduct(ctx context.Context, req *v1.Detach
 	)
 
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to detach product from shop")
+		return nil, s.errorStatus(ctx, err, "unable to detach product from shop")
 	}
 
 	return &v1.DetachProductResponse{
 		Shop: shop,
 	}, nil
 }
+
+// ArchiveShop archives a shop
+func (s *EconomyServiceServer) ArchiveShop(ctx context.Context, req *v1.ArchiveShopRequest) (*v1.ArchiveShopResponse, error) {
+	if req.GetShopId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "no shop_id given")
+	}
+
+	shop, err := s.ShopRepository.Archive(ctx, req.GetShopId())
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to archive shop")
+	}
+
+	return &v1.ArchiveShopResponse{
+		Shop: shop,
+	}, nil
+}
+
+// DeleteShop deletes a shop
+func (s *EconomyServiceServer) DeleteShop(ctx context.Context, req *v1.DeleteShopRequest) (*v1.DeleteShopResponse, error) {
+	if req.GetShopId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "no shop_id given")
+	}
+
+	success, err := s.ShopRepository.Delete(ctx, req.GetShopId(), req.GetForce())
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to delete shop")
+	}
+
+	return &v1.DeleteShopResponse{
+		Success: success,
+	}, nil
+}
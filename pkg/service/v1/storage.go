@@ -2,20 +2,19 @@ package v1
 
 import (
 	"context"
-	"fmt"
 	"math"
-	"strconv"
 
 	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
 	"github.com/GameComponent/economy-service/pkg/helper/random"
+	metrics "github.com/GameComponent/economy-service/pkg/metrics"
+	repository "github.com/GameComponent/economy-service/pkg/repository"
+	tenant "github.com/GameComponent/economy-service/pkg/tenant"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
 // CreateStorage creates a new storage
 func (s *EconomyServiceServer) CreateStorage(ctx context.Context, req *v1.CreateStorageRequest) (*v1.CreateStorageResponse, error) {
-	fmt.Println("CreateStorage")
-
 	if req.GetName() == "" {
 		return nil, status.Error(codes.InvalidArgument, "no name given")
 	}
@@ -27,7 +26,7 @@ func (s *EconomyServiceServer) CreateStorage(ctx context.Context, req *v1.Create
 		req.GetMetadata(),
 	)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to create storage")
+		return nil, s.errorStatus(ctx, err, "unable to create storage")
 	}
 
 	return &v1.CreateStorageResponse{
@@ -37,8 +36,6 @@ func (s *EconomyServiceServer) CreateStorage(ctx context.Context, req *v1.Create
 
 // UpdateStorage updates an existing storage
 func (s *EconomyServiceServer) UpdateStorage(ctx context.Context, req *v1.UpdateStorageRequest) (*v1.UpdateStorageResponse, error) {
-	fmt.Println("UpdateStorage")
-
 	storage, err := s.StorageRepository.Update(
 		ctx,
 		req.GetStorageId(),
@@ -46,7 +43,7 @@ func (s *EconomyServiceServer) UpdateStorage(ctx context.Context, req *v1.Update
 		req.GetMetadata(),
 	)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to update storage")
+		return nil, s.errorStatus(ctx, err, "unable to update storage")
 	}
 
 	return &v1.UpdateStorageResponse{
@@ -56,8 +53,6 @@ func (s *EconomyServiceServer) UpdateStorage(ctx context.Context, req *v1.Update
 
 // GetStorage get a storage
 func (s *EconomyServiceServer) GetStorage(ctx context.Context, req *v1.GetStorageRequest) (*v1.GetStorageResponse, error) {
-	fmt.Println("GetStorage")
-
 	// Check if the request
 	if req.GetStorageId() == "" {
 		return nil, status.Error(codes.InvalidArgument, "no storage_id given")
@@ -65,7 +60,7 @@ func (s *EconomyServiceServer) GetStorage(ctx context.Context, req *v1.GetStorag
 
 	storage, err := s.StorageRepository.Get(ctx, req.GetStorageId())
 	if err != nil {
-		return nil, status.Error(codes.NotFound, "storage not found")
+		return nil, s.errorStatus(ctx, err, "unable to retrieve storage")
 	}
 
 	return &v1.GetStorageResponse{
@@ -75,47 +70,27 @@ func (s *EconomyServiceServer) GetStorage(ctx context.Context, req *v1.GetStorag
 
 // ListStorage lists storages
 func (s *EconomyServiceServer) ListStorage(ctx context.Context, req *v1.ListStorageRequest) (*v1.ListStorageResponse, error) {
-	fmt.Println("ListStorage")
-
-	// Parse the page token
-	var parsedToken int64
-	parsedToken, _ = strconv.ParseInt(req.GetPageToken(), 10, 32)
-
-	// Get the limit
-	limit := req.GetPageSize()
-	if limit == 0 {
-		limit = 100
-	}
-
-	// Get the offset
-	offset := int32(0)
-	if len(req.GetPageToken()) > 0 {
-		offset = int32(parsedToken) * limit
+	// Continue after the last row of the previous page
+	page, err := s.listPage(req)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "invalid page token")
 	}
 
 	// Get the players
-	storages, totalSize, err := s.StorageRepository.List(ctx, limit, offset)
+	storages, next, totalSize, err := s.StorageRepository.List(ctx, page.Page)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to retrieve storage list")
-	}
-
-	// Determine if there is a next page
-	var nextPageToken string
-	if totalSize > (offset + limit) {
-		nextPage := int32(parsedToken) + 1
-		nextPageToken = strconv.Itoa(int(nextPage))
+		return nil, s.errorStatus(ctx, err, "unable to retrieve storage list")
 	}
 
 	return &v1.ListStorageResponse{
 		Storages:      storages,
-		NextPageToken: nextPageToken,
+		TotalSize:     totalSize,
+		NextPageToken: page.nextPageToken(next),
 	}, nil
 }
 
 // GiveCurrency gives currency to a storage
 func (s *EconomyServiceServer) GiveCurrency(ctx context.Context, req *v1.GiveCurrencyRequest) (*v1.GiveCurrencyResponse, error) {
-	fmt.Println("GiveCurrency")
-
 	amount := random.GenerateRandomInt(
 		req.GetAmount().MinAmount,
 		req.GetAmount().MaxAmount,
@@ -128,9 +103,17 @@ func (s *EconomyServiceServer) GiveCurrency(ctx context.Context, req *v1.GiveCur
 		amount,
 	)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to give currency to storage")
+		return nil, s.errorStatus(ctx, err, "unable to give currency to storage")
 	}
 
+	// The repository recorded the ledger entry along with the balance
+	metrics.Ledger(tenant.FromContext(ctx), &v1.LedgerEntry{
+		StorageId:  req.GetStorageId(),
+		CurrencyId: req.GetCurrencyId(),
+		Amount:     amount,
+		Reason:     repository.LedgerReasonGiveCurrency,
+	})
+
 	return &v1.GiveCurrencyResponse{
 		Currency: storageCurrency,
 	}, nil
@@ -138,13 +121,11 @@ func (s *EconomyServiceServer) GiveCurrency(ctx context.Context, req *v1.GiveCur
 
 // SplitStack splits a stack in the storage
 func (s *EconomyServiceServer) SplitStack(ctx context.Context, req *v1.SplitStackRequest) (*v1.SplitStackResponse, error) {
-	fmt.Println("SplitStack")
-
 	// Find the selected StorageItem
 	selectedStorageItem := &v1.StorageItem{}
 	storage, err := s.StorageRepository.Get(ctx, req.GetStorageId())
 	if err != nil {
-		return nil, status.Error(codes.NotFound, "storage not found")
+		return nil, s.errorStatus(ctx, err, "unable to retrieve storage")
 	}
 
 	for _, storageItem := range storage.Items {
@@ -154,7 +135,7 @@ func (s *EconomyServiceServer) SplitStack(ctx context.Context, req *v1.SplitStac
 	}
 
 	if selectedStorageItem.Id == "" {
-		return nil, status.Error(codes.NotFound, "storage_item not found")
+		return nil, s.errorStatus(ctx, repository.NotFound("storage item", req.GetStorageItemId()), "storage_item not found")
 	}
 
 	// Make sure the items are stackable
@@ -211,7 +192,7 @@ func (s *EconomyServiceServer) SplitStack(ctx context.Context, req *v1.SplitStac
 		amounts,
 	)
 	if err != nil {
-		return nil, status.Error(codes.Aborted, "unable to split stacks")
+		return nil, s.errorStatus(ctx, err, "unable to split stacks")
 	}
 
 	return &v1.SplitStackResponse{
@@ -221,19 +202,17 @@ func (s *EconomyServiceServer) SplitStack(ctx context.Context, req *v1.SplitStac
 
 // MergeStack merges stacks in the storage
 func (s *EconomyServiceServer) MergeStack(ctx context.Context, req *v1.MergeStackRequest) (*v1.MergeStackResponse, error) {
-	fmt.Println("MergeStack")
-
 	toStorage, err := s.StorageRepository.Get(ctx, req.GetToStorageId())
 	if err != nil {
-		return nil, status.Error(codes.NotFound, "to_storage not found")
+		return nil, s.errorStatus(ctx, err, "unable to retrieve storage")
 	}
 
 	// Get the fromStorage if it is not the same as the toStorage
 	fromStorage := toStorage
 	if req.GetToStorageId() != req.GetFromStorageId() {
-		fromStorage, err = s.StorageRepository.Get(ctx, req.GetToStorageId())
+		fromStorage, err = s.StorageRepository.Get(ctx, req.GetFromStorageId())
 		if err != nil {
-			return nil, status.Error(codes.NotFound, "from_storage not found")
+			return nil, s.errorStatus(ctx, err, "unable to retrieve storage")
 		}
 	}
 
@@ -253,7 +232,7 @@ func (s *EconomyServiceServer) MergeStack(ctx context.Context, req *v1.MergeStac
 
 	// Make sure we found our toStorageItem
 	if toStorageItem.Id == "" {
-		return nil, status.Error(codes.NotFound, "to_storage_item not found")
+		return nil, s.errorStatus(ctx, repository.NotFound("storage item", req.GetToStorageItemId()), "to_storage_item not found")
 	}
 
 	// Get the fromStorageItem
@@ -267,7 +246,7 @@ func (s *EconomyServiceServer) MergeStack(ctx context.Context, req *v1.MergeStac
 
 	// Make sure we found our fromStorageItem
 	if fromStorageItem.Id == "" {
-		return nil, status.Error(codes.NotFound, "from_storage_item not found")
+		return nil, s.errorStatus(ctx, repository.NotFound("storage item", req.GetFromStorageItemId()), "from_storage_item not found")
 	}
 
 	// Make sure the item ids are the same
@@ -285,13 +264,19 @@ func (s *EconomyServiceServer) MergeStack(ctx context.Context, req *v1.MergeStac
 		return nil, status.Error(codes.Aborted, "item is not stackable")
 	}
 
+	// Make sure the merged stack fits
+	maxAmount := toStorageItem.Item.StackMaxAmount
+	if maxAmount > 0 && toStorageItem.Amount+fromStorageItem.Amount > maxAmount {
+		return nil, s.errorStatus(ctx, repository.StackFull(req.GetToStorageId(), toStorageItem.Item.Id), "unable to merge stacks")
+	}
+
 	storage, err := s.StorageRepository.MergeStack(
 		ctx,
 		req.GetToStorageItemId(),
 		req.GetFromStorageItemId(),
 	)
 	if err != nil {
-		return nil, status.Error(codes.Aborted, "unable to merge stacks")
+		return nil, s.errorStatus(ctx, err, "unable to merge stacks")
 	}
 
 	return &v1.MergeStackResponse{
@@ -301,8 +286,6 @@ func (s *EconomyServiceServer) MergeStack(ctx context.Context, req *v1.MergeStac
 
 // GiveItem gives an item to the storage
 func (s *EconomyServiceServer) GiveItem(ctx context.Context, req *v1.GiveItemRequest) (*v1.GiveItemResponse, error) {
-	fmt.Println("GiveItem")
-
 	amount := int64(1)
 
 	// Generate a random amount
@@ -319,11 +302,11 @@ func (s *EconomyServiceServer) GiveItem(ctx context.Context, req *v1.GiveItemReq
 	// Get the item
 	item, err := s.ItemRepository.Get(ctx, req.GetItemId())
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to give item to storage")
+		return nil, s.errorStatus(ctx, err, "unable to give item to storage")
 	}
 
 	// Increase existing storage_items
-	remainder, err = s.GiveToExistingStorageItems(
+	increases, remainder, err := s.GiveToExistingStorageItems(
 		ctx,
 		req.GetStorageId(),
 		req.GetItemId(),
@@ -331,25 +314,18 @@ func (s *EconomyServiceServer) GiveItem(ctx context.Context, req *v1.GiveItemReq
 		item,
 	)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to give item to storage")
+		return nil, s.errorStatus(ctx, err, "unable to give item to storage")
 	}
 
+	grant := repository.ItemGrant{Increases: increases}
+
 	// Create multiple unstackable items
 	if item.Stackable == false && remainder > 0 {
-		loops := int(remainder)
-		for i := 0; i < loops; i++ {
-			_, err := s.StorageRepository.GiveItem(
-				ctx,
-				req.GetStorageId(),
-				req.GetItemId(),
-				1,
-			)
-			if err != nil {
-				return nil, status.Error(codes.Internal, "unable to give item to storage")
-			}
-
-			remainder--
+		for i := int64(0); i < remainder; i++ {
+			grant.Stacks = append(grant.Stacks, 1)
 		}
+
+		remainder = 0
 	}
 
 	// For new stacks and full stack for unstackable items
@@ -376,24 +352,28 @@ func (s *EconomyServiceServer) GiveItem(ctx context.Context, req *v1.GiveItemReq
 				continue
 			}
 
-			_, err := s.StorageRepository.GiveItem(
-				ctx,
-				req.GetStorageId(),
-				req.GetItemId(),
-				resultAmount,
-			)
-			if err != nil {
-				return nil, status.Error(codes.Internal, "unable to give item to storage")
-			}
-
+			grant.Stacks = append(grant.Stacks, resultAmount)
 			remainder -= resultAmount
 		}
 	}
 
 	if remainder > 0 {
-		return nil, status.Error(codes.Internal, "unable to give item to storage")
+		return nil, s.errorStatus(ctx, repository.StackFull(req.GetStorageId(), req.GetItemId()), "unable to give item to storage")
 	}
 
+	// The stacks change together with the ledger entry, or not at all
+	err = s.StorageRepository.GiveItem(ctx, req.GetStorageId(), req.GetItemId(), grant)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to give item to storage")
+	}
+
+	metrics.Ledger(tenant.FromContext(ctx), &v1.LedgerEntry{
+		StorageId: req.GetStorageId(),
+		ItemId:    req.GetItemId(),
+		Amount:    amount,
+		Reason:    repository.LedgerReasonGiveItem,
+	})
+
 	return &v1.GiveItemResponse{
 		StorageId: req.GetStorageId(),
 		Amount:    amount,
@@ -425,15 +405,18 @@ func (s *EconomyServiceServer) GetExistingStorageItems(ctx context.Context, stor
 	return existingStorageItems, nil
 }
 
-// GiveToExistingStorageItem increases stack amount of existing storage item
-func (s *EconomyServiceServer) GiveToExistingStorageItems(ctx context.Context, storageID string, itemID string, remainder int64, item *v1.Item) (int64, error) {
+// GiveToExistingStorageItems spreads items over the existing stacks of a storage,
+// it returns the increases of the stacks and the amount that did not fit
+func (s *EconomyServiceServer) GiveToExistingStorageItems(ctx context.Context, storageID string, itemID string, remainder int64, item *v1.Item) ([]repository.StackIncrease, int64, error) {
+	increases := []repository.StackIncrease{}
+
 	if !item.Stackable {
-		return remainder, nil
+		return increases, remainder, nil
 	}
 
 	// Checks if item is stackable and new items should be added to existing stacks
 	if item.StackBalancingMethod != v1.StackBalancingMethod_BALANCED_FILL_EXISTING_STACKS && item.StackBalancingMethod != v1.StackBalancingMethod_UNBALANCED_FILL_EXISTING_STACKS {
-		return remainder, nil
+		return increases, remainder, nil
 	}
 
 	// Get existing storageItems with the same item_id
@@ -444,55 +427,79 @@ func (s *EconomyServiceServer) GiveToExistingStorageItems(ctx context.Context, s
 	)
 
 	if err != nil {
-		return remainder, err
+		return nil, remainder, err
 	}
 
 	if len(existingStorageItems) == 0 {
-		return remainder, nil
+		return increases, remainder, nil
 	}
 
 	// An existing stack already exists,
 	// It does not have a max_amount so lets increase that one instead
 	if item.StackMaxAmount == 0 {
-		storageItem := existingStorageItems[0]
-
-		err := s.StorageRepository.IncreaseItemAmount(
-			ctx,
-			storageItem.Id,
-			remainder,
-		)
-		if err != nil {
-			return remainder, err
-		}
+		increases = append(increases, repository.StackIncrease{
+			StorageItemID: existingStorageItems[0].Id,
+			Amount:        remainder,
+		})
 
-		remainder = 0
+		return increases, 0, nil
 	}
 
 	// Because there is a stack_max_amount we should not accidentally overflow it
 	// So we'll first try to spread if over the existing stacks
-	if item.StackMaxAmount > 0 {
-		for _, existingStorageItem := range existingStorageItems {
-			// Calculate the remaining space
-			existingStorageItemRemainder := item.StackMaxAmount - existingStorageItem.Amount
-
-			// Calculate the amount to increase
-			existingStorageItemIncrease := remainder
-			if remainder >= existingStorageItemRemainder {
-				existingStorageItemIncrease = existingStorageItemRemainder
-			}
-
-			err := s.StorageRepository.IncreaseItemAmount(
-				ctx,
-				existingStorageItem.Id,
-				existingStorageItemIncrease,
-			)
-			if err != nil {
-				return remainder, err
-			}
+	for _, existingStorageItem := range existingStorageItems {
+		// Calculate the remaining space
+		existingStorageItemRemainder := item.StackMaxAmount - existingStorageItem.Amount
+
+		// Calculate the amount to increase
+		existingStorageItemIncrease := remainder
+		if remainder >= existingStorageItemRemainder {
+			existingStorageItemIncrease = existingStorageItemRemainder
+		}
 
-			remainder -= existingStorageItemIncrease
+		if existingStorageItemIncrease <= 0 {
+			continue
 		}
+
+		increases = append(increases, repository.StackIncrease{
+			StorageItemID: existingStorageItem.Id,
+			Amount:        existingStorageItemIncrease,
+		})
+
+		remainder -= existingStorageItemIncrease
+	}
+
+	return increases, remainder, nil
+}
+
+// ArchiveStorage archives a storage
+func (s *EconomyServiceServer) ArchiveStorage(ctx context.Context, req *v1.ArchiveStorageRequest) (*v1.ArchiveStorageResponse, error) {
+	if req.GetStorageId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "no storage_id given")
+	}
+
+	storage, err := s.StorageRepository.Archive(ctx, req.GetStorageId())
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to archive storage")
+	}
+
+	return &v1.ArchiveStorageResponse{
+		Storage: storage,
+	}, nil
+}
+
+// DeleteStorage deletes a storage
+func (s *EconomyServiceServer) DeleteStorage(ctx context.Context, req *v1.DeleteStorageRequest) (*v1.DeleteStorageResponse, error) {
+	if req.GetStorageId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "no storage_id given")
 	}
 
-	return remainder, nil
+	success, err := s.StorageRepository.Delete(ctx, req.GetStorageId(), req.GetForce())
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to delete storage")
+	}
+
+	return &v1.DeleteStorageResponse{
+		Success: success,
+	}, nil
 }
@@ -0,0 +1,81 @@
+package v1
+
+import (
+	"context"
+
+	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
+	tenant "github.com/GameComponent/economy-service/pkg/tenant"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CreateTenant creates a new tenant
+func (s *EconomyServiceServer) CreateTenant(ctx context.Context, req *v1.CreateTenantRequest) (*v1.CreateTenantResponse, error) {
+	if !tenant.ValidID(req.GetTenantId()) {
+		return nil, status.Error(codes.InvalidArgument, "tenant_id should be lowercase letters, digits, dashes or underscores")
+	}
+
+	// Check if the tenant already exists
+	existingTenant, _ := s.TenantRepository.Get(ctx, req.GetTenantId())
+	if existingTenant != nil {
+		return nil, status.Error(codes.AlreadyExists, "tenant already exists")
+	}
+
+	createdTenant, err := s.TenantRepository.Create(ctx, req.GetTenantId(), req.GetName())
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to create tenant")
+	}
+
+	return &v1.CreateTenantResponse{
+		Tenant: createdTenant,
+	}, nil
+}
+
+// UpdateTenant updates a tenant
+func (s *EconomyServiceServer) UpdateTenant(ctx context.Context, req *v1.UpdateTenantRequest) (*v1.UpdateTenantResponse, error) {
+	if req.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "no name given")
+	}
+
+	updatedTenant, err := s.TenantRepository.Update(ctx, req.GetTenantId(), req.GetName())
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to update tenant")
+	}
+
+	return &v1.UpdateTenantResponse{
+		Tenant: updatedTenant,
+	}, nil
+}
+
+// GetTenant gets a tenant
+func (s *EconomyServiceServer) GetTenant(ctx context.Context, req *v1.GetTenantRequest) (*v1.GetTenantResponse, error) {
+	foundTenant, err := s.TenantRepository.Get(ctx, req.GetTenantId())
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to retrieve tenant")
+	}
+
+	return &v1.GetTenantResponse{
+		Tenant: foundTenant,
+	}, nil
+}
+
+// ListTenant lists tenants
+func (s *EconomyServiceServer) ListTenant(ctx context.Context, req *v1.ListTenantRequest) (*v1.ListTenantResponse, error) {
+	// Continue after the last row of the previous page
+	page, err := s.listPage(req)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "invalid page token")
+	}
+
+	// Get the tenants
+	tenants, next, totalSize, err := s.TenantRepository.List(ctx, page.Page)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to retrieve tenant list")
+	}
+
+	return &v1.ListTenantResponse{
+		Tenants:       tenants,
+		TotalSize:     totalSize,
+		NextPageToken: page.nextPageToken(next),
+	}, nil
+}
@@ -0,0 +1,227 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
+	repository "github.com/GameComponent/economy-service/pkg/repository"
+	tenant "github.com/GameComponent/economy-service/pkg/tenant"
+	totp "github.com/GameComponent/economy-service/pkg/totp"
+	jwt "github.com/dgrijalva/jwt-go"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+const (
+	// challengeExpiration is the time between the password and the code of a login
+	challengeExpiration = 5 * time.Minute
+
+	recoveryCodeCount = 10
+)
+
+// AuthenticateTotp finishes a login with the challenge token of Authenticate and a code
+// of the authenticator app or a recovery code
+func (s *EconomyServiceServer) AuthenticateTotp(ctx context.Context, req *v1.AuthenticateTotpRequest) (*v1.AuthenticateTotpResponse, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(req.GetChallengeToken(), claims, s.Keyring.Keyfunc)
+	if err != nil || !token.Valid || claims.StandardClaims.Audience != "totp" {
+		return nil, status.Error(codes.Unauthenticated, "invalid challenge_token")
+	}
+
+	if claims.Tenant != tenant.FromContext(ctx) {
+		return nil, status.Error(codes.Unauthenticated, "invalid challenge_token")
+	}
+
+	account, err := s.AccountRepository.Get(ctx, claims.Subject)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, s.errorStatus(ctx, err, "unable to find account")
+	}
+	if err != nil || !account.TotpEnabled {
+		return nil, status.Error(codes.Unauthenticated, "invalid challenge_token")
+	}
+
+	err = s.checkSecondFactor(ctx, account, req.GetCode())
+	if err != nil {
+		return nil, err
+	}
+
+	amr := []string{amrPassword, amrOTP}
+
+	// Start a session with a refresh token
+	session, refreshToken, err := s.startSession(ctx, account, amr)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to generate refresh_token")
+	}
+
+	// Generate a JWT token
+	accessToken, err := s.generateToken(account, amr, session.Id)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to generate access_token")
+	}
+
+	return &v1.AuthenticateTotpResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int32(s.Config.JWTExpiration),
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// EnrollTotp generates a new secret for two-factor authentication,
+// it is only used after it is confirmed with a code
+func (s *EconomyServiceServer) EnrollTotp(ctx context.Context, req *v1.EnrollTotpRequest) (*v1.EnrollTotpResponse, error) {
+	account, err := s.checkCredentials(ctx, req.GetEmail(), req.GetPassword())
+	if err != nil {
+		return nil, err
+	}
+
+	if account.TotpEnabled {
+		return nil, status.Error(codes.FailedPrecondition, "two-factor authentication is already enabled")
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to generate secret")
+	}
+
+	err = s.AccountRepository.SetTotpSecret(ctx, account.Id, secret)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to store secret")
+	}
+
+	return &v1.EnrollTotpResponse{
+		Secret: secret,
+		Uri:    totp.URI(s.Config.TotpIssuer, account.Email, secret),
+	}, nil
+}
+
+// ConfirmTotp enables two-factor authentication with a code of the enrolled secret
+func (s *EconomyServiceServer) ConfirmTotp(ctx context.Context, req *v1.ConfirmTotpRequest) (*v1.ConfirmTotpResponse, error) {
+	account, err := s.checkCredentials(ctx, req.GetEmail(), req.GetPassword())
+	if err != nil {
+		return nil, err
+	}
+
+	if account.TotpEnabled {
+		return nil, status.Error(codes.FailedPrecondition, "two-factor authentication is already enabled")
+	}
+
+	secret, err := s.AccountRepository.GetTotpSecret(ctx, account.Id)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to retrieve secret")
+	}
+
+	step, ok := totp.Validate(secret, req.GetCode(), time.Now())
+	if !ok || s.AccountRepository.UseTotpStep(ctx, account.Id, step) != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid code")
+	}
+
+	recoveryCodes, err := totp.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to generate recovery codes")
+	}
+
+	// Only the hashes of the recovery codes are stored
+	hashes := []string{}
+	for _, recoveryCode := range recoveryCodes {
+		hashes = append(hashes, hashToken(totp.NormalizeRecoveryCode(recoveryCode)))
+	}
+
+	_, err = s.AccountRepository.EnableTotp(ctx, account.Id, hashes)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to enable two-factor authentication")
+	}
+
+	// Sessions started with only a password have to log in again
+	err = s.SessionRepository.RevokeAll(ctx, account.Id)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to revoke sessions")
+	}
+
+	return &v1.ConfirmTotpResponse{
+		RecoveryCodes: recoveryCodes,
+	}, nil
+}
+
+// DisableTotp disables two-factor authentication with a code or a recovery code
+func (s *EconomyServiceServer) DisableTotp(ctx context.Context, req *v1.DisableTotpRequest) (*v1.DisableTotpResponse, error) {
+	account, err := s.checkCredentials(ctx, req.GetEmail(), req.GetPassword())
+	if err != nil {
+		return nil, err
+	}
+
+	if !account.TotpEnabled {
+		return nil, status.Error(codes.FailedPrecondition, "two-factor authentication is not enabled")
+	}
+
+	err = s.checkSecondFactor(ctx, account, req.GetCode())
+	if err != nil {
+		return nil, err
+	}
+
+	account, err = s.AccountRepository.DisableTotp(ctx, account.Id)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to disable two-factor authentication")
+	}
+
+	// Filter out the account's hash
+	account.Hash = ""
+
+	return &v1.DisableTotpResponse{
+		Account: account,
+	}, nil
+}
+
+// checkSecondFactor checks a code for an account, failures count towards the lockout of
+// the account so codes can't be guessed
+func (s *EconomyServiceServer) checkSecondFactor(ctx context.Context, account *v1.Account, code string) error {
+	err := s.checkLockout(ctx, account.Email)
+	if err != nil {
+		return err
+	}
+
+	if !s.checkTotp(ctx, account.Id, code) {
+		s.failLogin(ctx, account.Email)
+		return status.Error(codes.Unauthenticated, "invalid code")
+	}
+
+	s.succeedLogin(ctx, account.Email)
+
+	return nil
+}
+
+// checkTotp checks a code of the authenticator app or an unused recovery code,
+// every code can only be used once
+func (s *EconomyServiceServer) checkTotp(ctx context.Context, accountID string, code string) bool {
+	if code == "" {
+		return false
+	}
+
+	secret, err := s.AccountRepository.GetTotpSecret(ctx, accountID)
+	if err != nil {
+		return false
+	}
+
+	if step, ok := totp.Validate(secret, code, time.Now()); ok {
+		return s.AccountRepository.UseTotpStep(ctx, accountID, step) == nil
+	}
+
+	return s.AccountRepository.UseRecoveryCode(ctx, accountID, hashToken(totp.NormalizeRecoveryCode(code))) == nil
+}
+
+// generateChallengeToken returns a short lived token that proves the password was correct,
+// it can only be exchanged for an access token with AuthenticateTotp
+func (s *EconomyServiceServer) generateChallengeToken(account *v1.Account) (string, error) {
+	claims := &Claims{
+		Subject: account.Id,
+		Tenant:  account.TenantId,
+		StandardClaims: jwt.StandardClaims{
+			Audience:  "totp",
+			ExpiresAt: time.Now().Add(challengeExpiration).Unix(),
+		},
+	}
+
+	return s.Keyring.Sign(claims)
+}
@@ -0,0 +1,170 @@
+package v1
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
+	random "github.com/GameComponent/economy-service/pkg/helper/random"
+	mail "github.com/GameComponent/economy-service/pkg/mail"
+	repository "github.com/GameComponent/economy-service/pkg/repository"
+	tenant "github.com/GameComponent/economy-service/pkg/tenant"
+	"go.uber.org/zap"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+const (
+	purposePasswordReset = "password_reset"
+	purposeVerifyEmail   = "verify_email"
+
+	passwordResetExpiration = time.Hour
+	verifyEmailExpiration   = 24 * time.Hour
+)
+
+// RequestPasswordReset sends a password reset token to the email of an account,
+// it succeeds for unknown emails as well so it can't be used to find accounts
+func (s *EconomyServiceServer) RequestPasswordReset(ctx context.Context, req *v1.RequestPasswordResetRequest) (*v1.RequestPasswordResetResponse, error) {
+	if req.GetEmail() == "" {
+		return nil, status.Error(codes.InvalidArgument, "please enter email")
+	}
+
+	account, err := s.AccountRepository.GetByEmail(ctx, req.GetEmail())
+	if errors.Is(err, repository.ErrNotFound) {
+		return &v1.RequestPasswordResetResponse{}, nil
+	}
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to find account")
+	}
+
+	err = s.sendVerificationToken(
+		ctx,
+		account,
+		purposePasswordReset,
+		passwordResetExpiration,
+		"Reset your password",
+		"reset-password",
+	)
+	if err != nil {
+		s.log(ctx).Error("unable to send password reset email", zap.String("account_id", account.Id), zap.Error(err))
+	}
+
+	return &v1.RequestPasswordResetResponse{}, nil
+}
+
+// ResetPassword sets a new password with a password reset token
+func (s *EconomyServiceServer) ResetPassword(ctx context.Context, req *v1.ResetPasswordRequest) (*v1.ResetPasswordResponse, error) {
+	if req.GetToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "please enter token")
+	}
+
+	if req.GetNewPassword() == "" {
+		return nil, status.Error(codes.InvalidArgument, "please enter new_password")
+	}
+
+	accountID, err := s.AccountRepository.UseVerificationToken(ctx, purposePasswordReset, hashToken(req.GetToken()))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, status.Error(codes.PermissionDenied, "invalid or expired token")
+	}
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to use token")
+	}
+
+	// Hash the password
+	hash, err := hashPassword(req.GetNewPassword())
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to hash password")
+	}
+
+	_, err = s.AccountRepository.Update(ctx, accountID, hash)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to update account")
+	}
+
+	// Sign out everywhere, the old password might have been compromised
+	err = s.SessionRepository.RevokeAll(ctx, accountID)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to revoke sessions")
+	}
+
+	// Receiving the token proves the account owns the email
+	_, err = s.AccountRepository.VerifyEmail(ctx, accountID)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to verify email")
+	}
+
+	return &v1.ResetPasswordResponse{}, nil
+}
+
+// VerifyEmail verifies the email of an account with the token sent on registration
+func (s *EconomyServiceServer) VerifyEmail(ctx context.Context, req *v1.VerifyEmailRequest) (*v1.VerifyEmailResponse, error) {
+	if req.GetToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "please enter token")
+	}
+
+	accountID, err := s.AccountRepository.UseVerificationToken(ctx, purposeVerifyEmail, hashToken(req.GetToken()))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, status.Error(codes.PermissionDenied, "invalid or expired token")
+	}
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to use token")
+	}
+
+	account, err := s.AccountRepository.VerifyEmail(ctx, accountID)
+	if err != nil {
+		return nil, s.errorStatus(ctx, err, "unable to verify email")
+	}
+
+	// Filter out the account's hash
+	account.Hash = ""
+
+	return &v1.VerifyEmailResponse{
+		Account: account,
+	}, nil
+}
+
+// sendVerificationToken stores a single-use token for the account and emails it
+func (s *EconomyServiceServer) sendVerificationToken(ctx context.Context, account *v1.Account, purpose string, expiration time.Duration, subject string, page string) error {
+	token, err := random.GenerateRandomString(32)
+	if err != nil {
+		return err
+	}
+
+	// Only the hash is stored, a leaked database doesn't leak usable tokens
+	expires := time.Now().UTC().Add(expiration)
+	err = s.AccountRepository.CreateVerificationToken(ctx, account.Id, purpose, hashToken(token), expires)
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf(
+		"%s with the following token, it expires in %s:\n\n%s\n",
+		subject,
+		expiration,
+		token,
+	)
+
+	if s.Config.MailLinkURL != "" {
+		query := url.Values{}
+		query.Set("token", token)
+		query.Set("tenant", tenant.FromContext(ctx))
+
+		body += fmt.Sprintf("\nOr open %s/%s?%s\n", s.Config.MailLinkURL, page, query.Encode())
+	}
+
+	return s.Mailer.Send(ctx, mail.Message{
+		To:      account.Email,
+		Subject: subject,
+		Body:    body,
+	})
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,234 @@
+package signing
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+// Keyring signs tokens with the current key and verifies tokens with
+// every key that is still active, so keys can be rotated without
+// invalidating the tokens that are already handed out
+type Keyring struct {
+	method  jwt.SigningMethod
+	kid     string
+	private interface{}
+	secret  []byte
+	public  map[string]verificationKey
+	order   []string
+}
+
+type verificationKey struct {
+	method jwt.SigningMethod
+	key    interface{}
+}
+
+// JWK is a public key in the JSON Web Key format
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS is a set of JSON Web Keys
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewKeyring creates a keyring. Tokens are signed with the PEM encoded private key
+// when given and with the shared secret otherwise. The PEM encoded public keys are
+// previous keys that are still accepted. An empty secret disables shared secret tokens.
+func NewKeyring(secret string, privateKey []byte, publicKeys ...[]byte) (*Keyring, error) {
+	keyring := &Keyring{
+		public: map[string]verificationKey{},
+	}
+
+	if secret != "" {
+		keyring.secret = []byte(secret)
+	}
+
+	if len(privateKey) == 0 {
+		if keyring.secret == nil {
+			return nil, fmt.Errorf("a private key or a secret is required to sign tokens")
+		}
+
+		keyring.method = jwt.SigningMethodHS256
+		keyring.private = keyring.secret
+	} else {
+		method, private, public, err := parsePrivateKey(privateKey)
+		if err != nil {
+			return nil, err
+		}
+
+		kid, err := keyID(public)
+		if err != nil {
+			return nil, err
+		}
+
+		keyring.method = method
+		keyring.private = private
+		keyring.kid = kid
+		keyring.add(kid, method, public)
+	}
+
+	for _, publicKey := range publicKeys {
+		method, public, err := parsePublicKey(publicKey)
+		if err != nil {
+			return nil, err
+		}
+
+		kid, err := keyID(public)
+		if err != nil {
+			return nil, err
+		}
+
+		keyring.add(kid, method, public)
+	}
+
+	return keyring, nil
+}
+
+func (k *Keyring) add(kid string, method jwt.SigningMethod, key interface{}) {
+	if _, ok := k.public[kid]; ok {
+		return
+	}
+
+	k.public[kid] = verificationKey{method: method, key: key}
+	k.order = append(k.order, kid)
+}
+
+// Sign creates a signed token with the current key
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.method, claims)
+	if k.kid != "" {
+		token.Header["kid"] = k.kid
+	}
+
+	return token.SignedString(k.private)
+}
+
+// Keyfunc picks the key to verify a token with, use it with jwt.Parse
+func (k *Keyring) Keyfunc(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		if k.secret == nil {
+			return nil, fmt.Errorf("tokens signed with a shared secret are not accepted")
+		}
+
+		return k.secret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := k.public[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+
+	// Never verify with a key of another algorithm than the one it belongs to
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	return key.key, nil
+}
+
+// JWKS returns the public keys tokens are verified with
+func (k *Keyring) JWKS() JWKS {
+	set := JWKS{
+		Keys: []JWK{},
+	}
+
+	for _, kid := range k.order {
+		key := k.public[kid]
+		jwk := JWK{
+			Kid: kid,
+			Use: "sig",
+			Alg: key.method.Alg(),
+		}
+
+		switch public := key.key.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = encode(public.N.Bytes())
+			jwk.E = encode(big.NewInt(int64(public.E)).Bytes())
+		case *ecdsa.PublicKey:
+			size := (public.Curve.Params().BitSize + 7) / 8
+			jwk.Kty = "EC"
+			jwk.Crv = public.Curve.Params().Name
+			jwk.X = encode(pad(public.X.Bytes(), size))
+			jwk.Y = encode(pad(public.Y.Bytes(), size))
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	return set
+}
+
+func parsePrivateKey(data []byte) (jwt.SigningMethod, interface{}, interface{}, error) {
+	if key, err := jwt.ParseRSAPrivateKeyFromPEM(data); err == nil {
+		return jwt.SigningMethodRS256, key, &key.PublicKey, nil
+	}
+
+	if key, err := jwt.ParseECPrivateKeyFromPEM(data); err == nil {
+		if key.Curve != elliptic.P256() {
+			return nil, nil, nil, fmt.Errorf("only P-256 keys are supported for ES256")
+		}
+
+		return jwt.SigningMethodES256, key, &key.PublicKey, nil
+	}
+
+	return nil, nil, nil, fmt.Errorf("private key should be a PEM encoded RSA or P-256 EC key")
+}
+
+func parsePublicKey(data []byte) (jwt.SigningMethod, interface{}, error) {
+	if key, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+		return jwt.SigningMethodRS256, key, nil
+	}
+
+	if key, err := jwt.ParseECPublicKeyFromPEM(data); err == nil {
+		if key.Curve != elliptic.P256() {
+			return nil, nil, fmt.Errorf("only P-256 keys are supported for ES256")
+		}
+
+		return jwt.SigningMethodES256, key, nil
+	}
+
+	return nil, nil, fmt.Errorf("public key should be a PEM encoded RSA or P-256 EC key")
+}
+
+// keyID derives the kid from the public key, so it stays the same across restarts
+func keyID(public interface{}) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func pad(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b
+	}
+
+	return append(make([]byte, size-len(b)), b...)
+}
@@ -0,0 +1,150 @@
+package signing_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	signing "github.com/GameComponent/economy-service/pkg/signing"
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+func rsaKey(t *testing.T) ([]byte, []byte) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	public, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public})
+}
+
+func ecKey(t *testing.T) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	private, _ := x509.MarshalECPrivateKey(key)
+	public, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: private}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public})
+}
+
+func claims() jwt.Claims {
+	return jwt.StandardClaims{
+		Subject:   "account",
+		ExpiresAt: time.Now().Add(time.Minute).Unix(),
+	}
+}
+
+func verify(keyring *signing.Keyring, token string) error {
+	_, err := jwt.Parse(token, keyring.Keyfunc)
+	return err
+}
+
+func TestSignAndVerify(t *testing.T) {
+	rsaPrivate, _ := rsaKey(t)
+	ecPrivate, _ := ecKey(t)
+
+	for name, private := range map[string][]byte{"RS256": rsaPrivate, "ES256": ecPrivate} {
+		keyring, err := signing.NewKeyring("", private)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		token, err := keyring.Sign(claims())
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		parsed, err := jwt.Parse(token, keyring.Keyfunc)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if parsed.Method.Alg() != name {
+			t.Errorf("signed with %s, want %s", parsed.Method.Alg(), name)
+		}
+
+		if parsed.Header["kid"] == nil {
+			t.Errorf("%s: token has no kid", name)
+		}
+	}
+}
+
+func TestRotation(t *testing.T) {
+	oldPrivate, oldPublic := rsaKey(t)
+	newPrivate, _ := ecKey(t)
+
+	oldKeyring, _ := signing.NewKeyring("", oldPrivate)
+	oldToken, _ := oldKeyring.Sign(claims())
+
+	// The old key is still accepted after rotating
+	keyring, err := signing.NewKeyring("", newPrivate, oldPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := verify(keyring, oldToken); err != nil {
+		t.Errorf("token of the previous key should be accepted: %v", err)
+	}
+
+	if len(keyring.JWKS().Keys) != 2 {
+		t.Errorf("JWKS has %d keys, want 2", len(keyring.JWKS().Keys))
+	}
+
+	// Once the old key is removed its tokens are no longer accepted
+	keyring, _ = signing.NewKeyring("", newPrivate)
+	if err := verify(keyring, oldToken); err == nil {
+		t.Error("token of a removed key should not be accepted")
+	}
+}
+
+func TestSharedSecret(t *testing.T) {
+	hsKeyring, err := signing.NewKeyring("secret", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, _ := hsKeyring.Sign(claims())
+	if err := verify(hsKeyring, token); err != nil {
+		t.Errorf("shared secret token should be accepted: %v", err)
+	}
+
+	if len(hsKeyring.JWKS().Keys) != 0 {
+		t.Error("the shared secret should never be published")
+	}
+
+	// Without a secret shared secret tokens are refused
+	private, _ := rsaKey(t)
+	keyring, _ := signing.NewKeyring("", private)
+	if err := verify(keyring, token); err == nil {
+		t.Error("shared secret token should not be accepted without a secret")
+	}
+
+	if _, err := signing.NewKeyring("", nil); err == nil {
+		t.Error("a keyring without keys should not be created")
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	private, _ := ecKey(t)
+	keyring, _ := signing.NewKeyring("", private)
+
+	keys := keyring.JWKS().Keys
+	if len(keys) != 1 {
+		t.Fatalf("JWKS has %d keys, want 1", len(keys))
+	}
+
+	key := keys[0]
+	if key.Kty != "EC" || key.Crv != "P-256" || key.Alg != "ES256" || key.X == "" || key.Y == "" {
+		t.Errorf("unexpected JWK %+v", key)
+	}
+}
@@ -0,0 +1,41 @@
+package tenant
+
+import (
+	"context"
+	"regexp"
+)
+
+// Default is the tenant of everything created before tenants existed,
+// accounts of the default tenant manage all other tenants
+const Default = "default"
+
+// Header can be used to select a tenant when the token does not decide it
+const Header = "x-tenant-id"
+
+// SwitchPermission lets accounts and API keys of the default tenant use the header
+// to act within other tenants, it is matched like the permission of a method
+const SwitchPermission = "SwitchTenant"
+
+var validID = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+type contextKey struct{}
+
+// NewContext returns a context scoped to the given tenant
+func NewContext(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, contextKey{}, tenantID)
+}
+
+// FromContext returns the tenant of a context, the default tenant when none is set
+func FromContext(ctx context.Context) string {
+	tenantID, ok := ctx.Value(contextKey{}).(string)
+	if !ok || tenantID == "" {
+		return Default
+	}
+
+	return tenantID
+}
+
+// ValidID checks if an id can be used for a tenant
+func ValidID(tenantID string) bool {
+	return validID.MatchString(tenantID)
+}
@@ -0,0 +1,96 @@
+package totp_test
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	totp "github.com/GameComponent/economy-service/pkg/totp"
+)
+
+// The SHA1 test vectors of RFC 6238, the last six digits of the eight digit codes
+func TestCodeRFC6238(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	cases := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, c := range cases {
+		got, err := totp.Code(secret, time.Unix(c.unix, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != c.want {
+			t.Errorf("Code at %d = %s, want %s", c.unix, got, c.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Unix(1571875200, 0)
+	code, _ := totp.Code(secret, now)
+
+	step, ok := totp.Validate(secret, code, now.Add(totp.Period*time.Second))
+	if !ok || step != totp.Step(now) {
+		t.Errorf("code of the previous period should be accepted")
+	}
+
+	if _, ok := totp.Validate(secret, code, now.Add(3*totp.Period*time.Second)); ok {
+		t.Errorf("code of three periods ago should be refused")
+	}
+
+	if _, ok := totp.Validate(secret, "12345", now); ok {
+		t.Errorf("short code should be refused")
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := totp.URI("Economy Service", "admin@example.com", "ABC")
+
+	if !strings.HasPrefix(uri, "otpauth://totp/Economy%20Service:admin@example.com?") {
+		t.Errorf("unexpected label in %s", uri)
+	}
+
+	if !strings.Contains(uri, "secret=ABC") || !strings.Contains(uri, "issuer=Economy+Service") {
+		t.Errorf("missing parameters in %s", uri)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := totp.GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Errorf("unexpected format %q", code)
+		}
+
+		if seen[code] {
+			t.Errorf("duplicate code %q", code)
+		}
+		seen[code] = true
+
+		if totp.NormalizeRecoveryCode(strings.ToUpper(code)) != strings.Replace(code, "-", "", 1) {
+			t.Errorf("normalizing %q failed", code)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	otel "go.opentelemetry.io/otel"
+	attribute "go.opentelemetry.io/otel/attribute"
+	otlptracehttp "go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	stdouttrace "go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	propagation "go.opentelemetry.io/otel/propagation"
+	resource "go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	trace "go.opentelemetry.io/otel/trace"
+)
+
+// ServiceName is the name spans are reported under
+const ServiceName = "economy-service"
+
+// RequestIDKey is the span attribute holding the request id
+const RequestIDKey = attribute.Key("request.id")
+
+// Exporters spans can be sent to
+const (
+	ExporterNone   = ""
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// Init sets up the global tracer provider and the W3C trace context propagation,
+// the returned function flushes the remaining spans on shutdown
+func Init(ctx context.Context, exporter string, endpoint string, sampleRatio float64) (func(context.Context) error, error) {
+	// Trace context is always propagated, so traces of callers stay connected without an exporter
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var spanExporter sdktrace.SpanExporter
+	var err error
+
+	switch exporter {
+	case ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		spanExporter, err = stdouttrace.New(
+			stdouttrace.WithWriter(os.Stdout),
+			stdouttrace.WithPrettyPrint(),
+		)
+	case ExporterOTLP:
+		options := []otlptracehttp.Option{}
+		if endpoint != "" {
+			options = append(options, otlptracehttp.WithEndpoint(endpoint), otlptracehttp.WithInsecure())
+		}
+
+		spanExporter, err = otlptracehttp.New(ctx, options...)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q, use %q or %q", exporter, ExporterStdout, ExporterOTLP)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceName(ServiceName),
+		)),
+	)
+
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// SetRequestID adds the request id to the span of the context
+func SetRequestID(ctx context.Context, requestID string) {
+	if requestID == "" {
+		return
+	}
+
+	trace.SpanFromContext(ctx).SetAttributes(RequestIDKey.String(requestID))
+}
@@ -0,0 +1,50 @@
+package tracing_test
+
+import (
+	"context"
+	"testing"
+
+	tracing "github.com/GameComponent/economy-service/pkg/tracing"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	tracetest "go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestInitUnknownExporter(t *testing.T) {
+	_, err := tracing.Init(context.Background(), "zipkin", "", 1)
+	if err == nil {
+		t.Error("Init() with an unknown exporter should fail")
+	}
+}
+
+func TestInitWithoutExporter(t *testing.T) {
+	shutdown, err := tracing.Init(context.Background(), tracing.ExporterNone, "", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := shutdown(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSetRequestID(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	ctx, span := provider.Tracer("test").Start(context.Background(), "request")
+	tracing.SetRequestID(ctx, "host/abc-000001")
+	span.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("recorded %d spans, want 1", len(spans))
+	}
+
+	for _, attribute := range spans[0].Attributes() {
+		if attribute.Key == tracing.RequestIDKey && attribute.Value.AsString() == "host/abc-000001" {
+			return
+		}
+	}
+
+	t.Errorf("span attributes = %v, want the request id", spans[0].Attributes())
+}
@@ -0,0 +1,150 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"unicode/utf8"
+
+	role "github.com/GameComponent/economy-service/pkg/role"
+	tenant "github.com/GameComponent/economy-service/pkg/tenant"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// validUUID matches the canonical form of a UUID, CockroachDB refuses the query
+// of a UUID column compared to anything it can not parse
+var validUUID = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+func uuid() check {
+	return func(field protoreflect.FieldDescriptor, value protoreflect.Value) string {
+		if !validUUID.MatchString(value.String()) {
+			return "should be a UUID"
+		}
+
+		return ""
+	}
+}
+
+func atLeast(n int64) check {
+	return func(field protoreflect.FieldDescriptor, value protoreflect.Value) string {
+		if value.Int() < n {
+			return fmt.Sprintf("should be at least %d", n)
+		}
+
+		return ""
+	}
+}
+
+func atMost(n int64) check {
+	return func(field protoreflect.FieldDescriptor, value protoreflect.Value) string {
+		if value.Int() > n {
+			return fmt.Sprintf("should be at most %d", n)
+		}
+
+		return ""
+	}
+}
+
+// maxLength counts characters, not bytes
+func maxLength(n int) check {
+	return func(field protoreflect.FieldDescriptor, value protoreflect.Value) string {
+		if utf8.RuneCountInString(value.String()) > n {
+			return fmt.Sprintf("should be at most %d characters", n)
+		}
+
+		return ""
+	}
+}
+
+// jsonObject is used for metadata, which is stored in a JSONB column
+func jsonObject() check {
+	return func(field protoreflect.FieldDescriptor, value protoreflect.Value) string {
+		var object map[string]interface{}
+		if err := json.Unmarshal([]byte(value.String()), &object); err != nil {
+			return "should be a JSON object"
+		}
+
+		return ""
+	}
+}
+
+// jsonValue is used for config values, which can be any JSON
+func jsonValue() check {
+	return func(field protoreflect.FieldDescriptor, value protoreflect.Value) string {
+		if !json.Valid([]byte(value.String())) {
+			return "should be valid JSON"
+		}
+
+		return ""
+	}
+}
+
+// email only accepts a plain address, names and comments are refused
+func email() check {
+	return func(field protoreflect.FieldDescriptor, value protoreflect.Value) string {
+		address, err := mail.ParseAddress(value.String())
+		if err != nil || address.Address != value.String() {
+			return "should be a valid email address"
+		}
+
+		return ""
+	}
+}
+
+// definedEnum refuses numbers that are not a value of the enum, proto3 keeps them as unknown values
+func definedEnum() check {
+	return func(field protoreflect.FieldDescriptor, value protoreflect.Value) string {
+		if field.Enum().Values().ByNumber(value.Enum()) == nil {
+			return "should be a defined value"
+		}
+
+		return ""
+	}
+}
+
+func tenantID() check {
+	return func(field protoreflect.FieldDescriptor, value protoreflect.Value) string {
+		if !tenant.ValidID(value.String()) {
+			return "should be lowercase letters, digits, dashes or underscores"
+		}
+
+		return ""
+	}
+}
+
+func roleName() check {
+	return func(field protoreflect.FieldDescriptor, value protoreflect.Value) string {
+		if !role.ValidName(value.String()) {
+			return "should be lowercase letters, digits, dashes or underscores"
+		}
+
+		return ""
+	}
+}
+
+func permission() check {
+	return func(field protoreflect.FieldDescriptor, value protoreflect.Value) string {
+		if !role.ValidPermission(value.String()) {
+			return "should be a method name or pattern"
+		}
+
+		return ""
+	}
+}
+
+// amountRange checks that the minimum of an Amount does not exceed the maximum
+func amountRange() check {
+	return func(field protoreflect.FieldDescriptor, value protoreflect.Value) string {
+		amount := value.Message()
+		fields := amount.Descriptor().Fields()
+		minAmount := amount.Get(fields.ByName("min_amount")).Int()
+		maxAmount := amount.Get(fields.ByName("max_amount")).Int()
+
+		if minAmount > maxAmount {
+			return "min_amount should not be higher than max_amount"
+		}
+
+		return ""
+	}
+}
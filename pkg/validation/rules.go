@@ -0,0 +1,424 @@
+package validation
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	// maxNameLength limits names of resources, shown in game and in the admin tools
+	maxNameLength = 255
+
+	// maxShortNameLength limits the short name and symbol of currencies
+	maxShortNameLength = 16
+
+	// maxPageSize is the most items a list request can ask for
+	maxPageSize = 1000
+
+	// maxPasswordLength is the most bytes bcrypt hashes, longer passwords would be refused by it
+	maxPasswordLength = 72
+
+	// maxFilterLength limits the filter expressions of list requests, which the repository parses
+	maxFilterLength = 1024
+
+	// maxOrderByLength limits the order_by of list requests
+	maxOrderByLength = 255
+)
+
+// page are the rules of the page_size field of list requests
+var page = []rule{
+	optional("page_size", atLeast(0), atMost(maxPageSize)),
+}
+
+// filteredPage are the rules of list requests with a filter and order_by
+var filteredPage = append([]rule{
+	optional("filter", maxLength(maxFilterLength)),
+	optional("order_by", maxLength(maxOrderByLength)),
+}, page...)
+
+// rules of the requests, keyed by the full name of their message,
+// messages without fields to check are left out
+var rules = map[protoreflect.FullName][]rule{
+	// Storages
+	"v1.GiveItemRequest": {
+		required("storage_id", uuid()),
+		required("item_id", uuid()),
+		optional("amount", amountRange()),
+		optional("amount.min_amount", atLeast(0)),
+		optional("amount.max_amount", atLeast(0)),
+	},
+	"v1.GiveCurrencyRequest": {
+		required("storage_id", uuid()),
+		required("currency_id", uuid()),
+		required("amount", amountRange()),
+		optional("amount.min_amount", atLeast(0)),
+		optional("amount.max_amount", atLeast(0)),
+	},
+	"v1.GetStorageRequest": {
+		required("storage_id", uuid()),
+	},
+	"v1.ListStorageRequest": filteredPage,
+	"v1.CreateStorageRequest": {
+		required("player_id", maxLength(maxNameLength)),
+		required("name", maxLength(maxNameLength)),
+		optional("metadata", jsonObject()),
+	},
+	"v1.UpdateStorageRequest": {
+		required("storage_id", uuid()),
+		optional("name", maxLength(maxNameLength)),
+		optional("metadata", jsonObject()),
+	},
+	"v1.ArchiveStorageRequest": {
+		required("storage_id", uuid()),
+	},
+	"v1.DeleteStorageRequest": {
+		required("storage_id", uuid()),
+	},
+	"v1.SplitStackRequest": {
+		required("storage_id", uuid()),
+		required("storage_item_id", uuid()),
+		optional("chunks", atLeast(1)),
+		optional("amount", atLeast(1)),
+		optional("amounts", atLeast(1)),
+	},
+	"v1.MergeStackRequest": {
+		required("to_storage_id", uuid()),
+		required("to_storage_item_id", uuid()),
+		required("from_storage_id", uuid()),
+		required("from_storage_item_id", uuid()),
+	},
+
+	// Players, their ids are chosen by the game so they are not UUIDs
+	"v1.CreatePlayerRequest": {
+		required("player_id", maxLength(maxNameLength)),
+		required("name", maxLength(maxNameLength)),
+		optional("metadata", jsonObject()),
+	},
+	"v1.UpdatePlayerRequest": {
+		required("player_id", maxLength(maxNameLength)),
+		optional("name", maxLength(maxNameLength)),
+		optional("metadata", jsonObject()),
+	},
+	"v1.GetPlayerRequest": {
+		required("player_id"),
+	},
+	"v1.ListPlayerRequest": filteredPage,
+	"v1.SearchPlayerRequest": append([]rule{
+		required("query", maxLength(maxNameLength)),
+	}, page...),
+	"v1.ArchivePlayerRequest": {
+		required("player_id"),
+	},
+	"v1.DeletePlayerRequest": {
+		required("player_id"),
+	},
+	"v1.ExportPlayerDataRequest": {
+		required("player_id"),
+		optional("format", definedEnum()),
+	},
+	"v1.ErasePlayerRequest": {
+		required("player_id"),
+	},
+	"v1.CreatePlayerSessionRequest": {
+		required("player_id"),
+		optional("expires_in", atLeast(0)),
+	},
+
+	// Items
+	"v1.CreateItemRequest": {
+		required("name", maxLength(maxNameLength)),
+		optional("stack_max_amount", atLeast(0)),
+		optional("stack_balancing_method", definedEnum()),
+		optional("metadata", jsonObject()),
+	},
+	"v1.UpdateItemRequest": {
+		required("item_id", uuid()),
+		optional("name", maxLength(maxNameLength)),
+		optional("metadata", jsonObject()),
+	},
+	"v1.GetItemRequest": {
+		required("item_id", uuid()),
+	},
+	"v1.ListItemRequest": filteredPage,
+	"v1.SearchItemRequest": append([]rule{
+		required("query", maxLength(maxNameLength)),
+	}, page...),
+	"v1.ArchiveItemRequest": {
+		required("item_id", uuid()),
+	},
+	"v1.DeleteItemRequest": {
+		required("item_id", uuid()),
+	},
+
+	// Currencies
+	"v1.CreateCurrencyRequest": {
+		required("name", maxLength(maxNameLength)),
+		required("short_name", maxLength(maxShortNameLength)),
+		required("symbol", maxLength(maxShortNameLength)),
+	},
+	"v1.UpdateCurrencyRequest": {
+		required("currency_id", uuid()),
+		optional("name", maxLength(maxNameLength)),
+		optional("short_name", maxLength(maxShortNameLength)),
+		optional("symbol", maxLength(maxShortNameLength)),
+	},
+	"v1.GetCurrencyRequest": {
+		required("currency_id", uuid()),
+	},
+	"v1.ListCurrencyRequest": filteredPage,
+	"v1.ArchiveCurrencyRequest": {
+		required("currency_id", uuid()),
+	},
+	"v1.DeleteCurrencyRequest": {
+		required("currency_id", uuid()),
+	},
+
+	// Configs
+	"v1.GetConfigRequest": {
+		required("key", maxLength(maxNameLength)),
+	},
+	"v1.SetConfigRequest": {
+		required("key", maxLength(maxNameLength)),
+		required("value", jsonValue()),
+	},
+	"v1.ListConfigRequest": page,
+
+	// Shops
+	"v1.GetShopRequest": {
+		required("shop_id", uuid()),
+	},
+	"v1.CreateShopRequest": {
+		required("name", maxLength(maxNameLength)),
+		optional("metadata", jsonObject()),
+	},
+	"v1.UpdateShopRequest": {
+		required("shop_id", uuid()),
+		optional("name", maxLength(maxNameLength)),
+		optional("metadata", jsonObject()),
+	},
+	"v1.ListShopRequest": filteredPage,
+	"v1.ArchiveShopRequest": {
+		required("shop_id", uuid()),
+	},
+	"v1.DeleteShopRequest": {
+		required("shop_id", uuid()),
+	},
+	"v1.AttachProductRequest": {
+		required("shop_id", uuid()),
+		required("product_id", uuid()),
+	},
+	"v1.DetachProductRequest": {
+		required("shop_product_id", uuid()),
+	},
+
+	// Products
+	"v1.CreateProductRequest": {
+		required("name", maxLength(maxNameLength)),
+	},
+	"v1.UpdateProductRequest": {
+		required("product_id", uuid()),
+		optional("name", maxLength(maxNameLength)),
+	},
+	"v1.GetProductRequest": {
+		required("product_id", uuid()),
+	},
+	"v1.ListProductRequest": filteredPage,
+	"v1.ArchiveProductRequest": {
+		required("product_id", uuid()),
+	},
+	"v1.DeleteProductRequest": {
+		required("product_id", uuid()),
+	},
+	"v1.AttachItemRequest": {
+		required("product_id", uuid()),
+		required("item_id", uuid()),
+		required("amount", atLeast(1)),
+	},
+	"v1.DetachItemRequest": {
+		required("product_item_id", uuid()),
+	},
+	"v1.AttachCurrencyRequest": {
+		required("product_id", uuid()),
+		required("currency_id", uuid()),
+		required("amount", atLeast(1)),
+	},
+	"v1.DetachCurrencyRequest": {
+		required("product_currency_id", uuid()),
+	},
+	"v1.BuyProductRequest": {
+		required("product_id", uuid()),
+		required("price_id", uuid()),
+		required("receiving_storage_id", uuid()),
+		required("paying_storage_id", uuid()),
+	},
+
+	// Prices
+	"v1.GetPriceRequest": {
+		required("price_id", uuid()),
+	},
+	"v1.CreatePriceRequest": {
+		required("product_id", uuid()),
+	},
+	"v1.DeletePriceRequest": {
+		required("price_id", uuid()),
+	},
+	"v1.ListProductPriceRequest": {
+		required("product_id", uuid()),
+	},
+	"v1.AttachPriceCurrencyRequest": {
+		required("price_id", uuid()),
+		required("currency_id", uuid()),
+		required("amount", atLeast(1)),
+	},
+	"v1.DetachPriceCurrencyRequest": {
+		required("price_currency_id", uuid()),
+	},
+	"v1.AttachPriceItemRequest": {
+		required("price_id", uuid()),
+		required("item_id", uuid()),
+		required("amount", atLeast(1)),
+	},
+	"v1.DetachPriceItemRequest": {
+		required("price_item_id", uuid()),
+	},
+
+	// Catalog
+	"v1.ExportCatalogRequest": {
+		optional("format", definedEnum()),
+	},
+	"v1.ImportCatalogRequest": {
+		required("catalog"),
+	},
+	"v1.CreateCatalogVersionRequest": {
+		required("name", maxLength(maxNameLength)),
+	},
+	"v1.UpdateCatalogVersionRequest": {
+		required("catalog_version_id", uuid()),
+		optional("name", maxLength(maxNameLength)),
+	},
+	"v1.GetCatalogVersionRequest": {
+		required("catalog_version_id", uuid()),
+	},
+	"v1.ListCatalogVersionRequest": page,
+	"v1.PreviewCatalogVersionRequest": {
+		required("catalog_version_id", uuid()),
+	},
+	"v1.PublishCatalogVersionRequest": {
+		required("catalog_version_id", uuid()),
+	},
+
+	// Accounts
+	"v1.AuthenticateRequest": {
+		required("email"),
+		required("password"),
+	},
+	"v1.AuthenticateTotpRequest": {
+		required("challenge_token"),
+		required("code"),
+	},
+	"v1.AuthenticateOidcRequest": {
+		required("id_token"),
+	},
+	"v1.RegisterRequest": {
+		required("email", email()),
+		required("password", maxLength(maxPasswordLength)),
+	},
+	"v1.RefreshRequest": {
+		required("token"),
+	},
+	"v1.GetAccountRequest": {
+		required("account_id", uuid()),
+	},
+	"v1.ListAccountRequest": page,
+	"v1.ChangePasswordRequest": {
+		required("email"),
+		required("password"),
+		required("new_password", maxLength(maxPasswordLength)),
+	},
+	"v1.RequestPasswordResetRequest": {
+		required("email"),
+	},
+	"v1.ResetPasswordRequest": {
+		required("token"),
+		required("new_password", maxLength(maxPasswordLength)),
+	},
+	"v1.VerifyEmailRequest": {
+		required("token"),
+	},
+	"v1.EnrollTotpRequest": {
+		required("email"),
+		required("password"),
+	},
+	"v1.ConfirmTotpRequest": {
+		required("email"),
+		required("password"),
+		required("code"),
+	},
+	"v1.DisableTotpRequest": {
+		required("email"),
+		required("password"),
+		required("code"),
+	},
+	"v1.ListSessionsRequest": append([]rule{
+		optional("account_id", uuid()),
+	}, page...),
+	"v1.RevokeSessionRequest": {
+		required("session_id", uuid()),
+	},
+
+	// Permissions and roles
+	"v1.AssignPermissionRequest": {
+		required("account_id", uuid()),
+		required("permission", permission()),
+	},
+	"v1.RevokePermissionRequest": {
+		required("account_id", uuid()),
+		required("permission", permission()),
+	},
+	"v1.CreateRoleRequest": {
+		required("name", roleName()),
+		required("permissions", permission()),
+	},
+	"v1.AssignRoleRequest": {
+		required("account_id", uuid()),
+		required("role", roleName()),
+	},
+	"v1.RevokeRoleRequest": {
+		required("account_id", uuid()),
+		required("role", roleName()),
+	},
+
+	// API keys
+	"v1.CreateApiKeyRequest": {
+		required("account_id", uuid()),
+		optional("name", maxLength(maxNameLength)),
+		optional("permissions", permission()),
+		optional("roles", roleName()),
+		optional("expires_in", atLeast(0)),
+	},
+	"v1.ListApiKeysRequest": append([]rule{
+		optional("account_id", uuid()),
+	}, page...),
+	"v1.RevokeApiKeyRequest": {
+		required("api_key_id", uuid()),
+	},
+
+	// Tenants
+	"v1.CreateTenantRequest": {
+		required("tenant_id", tenantID()),
+		required("name", maxLength(maxNameLength)),
+	},
+	"v1.UpdateTenantRequest": {
+		required("tenant_id", tenantID()),
+		optional("name", maxLength(maxNameLength)),
+	},
+	"v1.GetTenantRequest": {
+		required("tenant_id", tenantID()),
+	},
+	"v1.ListTenantRequest": page,
+
+	// Audit
+	"v1.ListAuditEventsRequest": append([]rule{
+		optional("account_id", uuid()),
+		optional("method", permission()),
+	}, page...),
+}
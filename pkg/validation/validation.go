@@ -0,0 +1,106 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+
+	proto "github.com/golang/protobuf/proto"
+	errdetails "google.golang.org/genproto/googleapis/rpc/errdetails"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// check tests a single value of a field, it returns why the value is invalid
+// or an empty string when it is valid
+type check func(field protoreflect.FieldDescriptor, value protoreflect.Value) string
+
+// rule applies checks to a field of a request, the path is the proto name of the field
+// and reaches into nested messages with dots, like amount.min_amount
+type rule struct {
+	path     string
+	required bool
+	checks   []check
+}
+
+// required fields must be set, a string can not be empty and a list needs at least one element
+func required(path string, checks ...check) rule {
+	return rule{path: path, required: true, checks: checks}
+}
+
+// optional fields are only checked when they are set
+func optional(path string, checks ...check) rule {
+	return rule{path: path, checks: checks}
+}
+
+// Validate checks a request against the rules of its message,
+// messages without rules are always valid
+func Validate(msg proto.Message) []*errdetails.BadRequest_FieldViolation {
+	reflected := proto.MessageReflect(msg)
+
+	violations := []*errdetails.BadRequest_FieldViolation{}
+	for _, r := range rules[reflected.Descriptor().FullName()] {
+		violations = append(violations, r.validate(reflected)...)
+	}
+
+	return violations
+}
+
+func (r rule) validate(msg protoreflect.Message) []*errdetails.BadRequest_FieldViolation {
+	field, value, ok := lookup(msg, r.path)
+	if !ok {
+		if r.required {
+			return []*errdetails.BadRequest_FieldViolation{{Field: r.path, Description: "is required"}}
+		}
+
+		return nil
+	}
+
+	// The checks of a list apply to every element
+	if field.IsList() {
+		violations := []*errdetails.BadRequest_FieldViolation{}
+		list := value.List()
+		for i := 0; i < list.Len(); i++ {
+			violations = append(violations, r.run(field, fmt.Sprintf("%s[%d]", r.path, i), list.Get(i))...)
+		}
+
+		return violations
+	}
+
+	return r.run(field, r.path, value)
+}
+
+func (r rule) run(field protoreflect.FieldDescriptor, path string, value protoreflect.Value) []*errdetails.BadRequest_FieldViolation {
+	violations := []*errdetails.BadRequest_FieldViolation{}
+	for _, c := range r.checks {
+		if description := c(field, value); description != "" {
+			violations = append(violations, &errdetails.BadRequest_FieldViolation{Field: path, Description: description})
+		}
+	}
+
+	return violations
+}
+
+// lookup follows a dotted path through the message, it reports false when a field
+// on the way is not set, proto3 treats scalar fields with the zero value as not set
+func lookup(msg protoreflect.Message, path string) (protoreflect.FieldDescriptor, protoreflect.Value, bool) {
+	names := strings.Split(path, ".")
+
+	for i, name := range names {
+		field := msg.Descriptor().Fields().ByName(protoreflect.Name(name))
+		if field == nil {
+			panic(fmt.Sprintf("validation: %s has no field %s", msg.Descriptor().FullName(), path))
+		}
+
+		if !msg.Has(field) {
+			return field, protoreflect.Value{}, false
+		}
+
+		value := msg.Get(field)
+		if i == len(names)-1 {
+			return field, value, true
+		}
+
+		msg = value.Message()
+	}
+
+	return nil, protoreflect.Value{}, false
+}
@@ -0,0 +1,128 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+
+	v1 "github.com/GameComponent/economy-service/pkg/api/v1"
+	"github.com/stretchr/testify/assert"
+	errdetails "google.golang.org/genproto/googleapis/rpc/errdetails"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoregistry "google.golang.org/protobuf/reflect/protoregistry"
+)
+
+const itemID = "8b1d4b7c-5c1a-4a2e-9f57-3c1b8b3f0d2a"
+
+func fields(violations []*errdetails.BadRequest_FieldViolation) []string {
+	names := []string{}
+	for _, violation := range violations {
+		names = append(names, violation.Field)
+	}
+
+	return names
+}
+
+func TestRulesMatchMessages(t *testing.T) {
+	for name, messageRules := range rules {
+		messageType, err := protoregistry.GlobalTypes.FindMessageByName(name)
+		if !assert.NoError(t, err, "%s should be a message", name) {
+			continue
+		}
+
+		for _, r := range messageRules {
+			descriptor := messageType.Descriptor()
+			for _, fieldName := range strings.Split(r.path, ".") {
+				field := descriptor.Fields().ByName(protoreflect.Name(fieldName))
+				if !assert.NotNil(t, field, "%s should have field %s", name, r.path) {
+					break
+				}
+
+				descriptor = field.Message()
+			}
+		}
+	}
+}
+
+func TestValidateValid(t *testing.T) {
+	violations := Validate(&v1.CreateItemRequest{
+		Name:           "Sword",
+		StackMaxAmount: 10,
+		Metadata:       `{"damage": 5}`,
+	})
+
+	assert.Empty(t, violations)
+}
+
+func TestValidateCreateItem(t *testing.T) {
+	violations := Validate(&v1.CreateItemRequest{
+		StackMaxAmount:       -1,
+		StackBalancingMethod: v1.StackBalancingMethod(99),
+		Metadata:             "{",
+	})
+
+	assert.Equal(t, []string{"name", "stack_max_amount", "stack_balancing_method", "metadata"}, fields(violations))
+}
+
+func TestValidateUUID(t *testing.T) {
+	violations := Validate(&v1.GetItemRequest{ItemId: "sword"})
+
+	assert.Equal(t, []string{"item_id"}, fields(violations))
+	assert.Equal(t, "should be a UUID", violations[0].Description)
+
+	assert.Empty(t, Validate(&v1.GetItemRequest{ItemId: itemID}))
+}
+
+func TestValidateAmount(t *testing.T) {
+	violations := Validate(&v1.GiveCurrencyRequest{
+		StorageId:  itemID,
+		CurrencyId: itemID,
+		Amount:     &v1.Amount{MinAmount: -5, MaxAmount: -10},
+	})
+
+	assert.Equal(t, []string{"amount", "amount.min_amount", "amount.max_amount"}, fields(violations))
+
+	violations = Validate(&v1.GiveCurrencyRequest{
+		StorageId:  itemID,
+		CurrencyId: itemID,
+	})
+
+	assert.Equal(t, []string{"amount"}, fields(violations))
+
+	// Items without an amount give a single one
+	assert.Empty(t, Validate(&v1.GiveItemRequest{StorageId: itemID, ItemId: itemID}))
+	assert.Equal(t, []string{"amount"}, fields(Validate(&v1.GiveItemRequest{
+		StorageId: itemID,
+		ItemId:    itemID,
+		Amount:    &v1.Amount{MinAmount: 10, MaxAmount: 5},
+	})))
+}
+
+func TestValidateList(t *testing.T) {
+	violations := Validate(&v1.SplitStackRequest{
+		StorageId:     itemID,
+		StorageItemId: itemID,
+		Amounts:       []int64{5, 0, -1},
+	})
+
+	assert.Equal(t, []string{"amounts[1]", "amounts[2]"}, fields(violations))
+
+	violations = Validate(&v1.CreateRoleRequest{Name: "editor"})
+
+	assert.Equal(t, []string{"permissions"}, fields(violations))
+}
+
+func TestValidatePageSize(t *testing.T) {
+	assert.Empty(t, Validate(&v1.ListItemRequest{}))
+	assert.Equal(t, []string{"page_size"}, fields(Validate(&v1.ListItemRequest{PageSize: -1})))
+	assert.Equal(t, []string{"page_size"}, fields(Validate(&v1.ListItemRequest{PageSize: maxPageSize + 1})))
+}
+
+func TestValidateCatalogVersion(t *testing.T) {
+	// Versions without a catalog start from the live catalog
+	assert.Empty(t, Validate(&v1.CreateCatalogVersionRequest{Name: "Winter sale"}))
+	assert.Equal(t, []string{"name"}, fields(Validate(&v1.CreateCatalogVersionRequest{Catalog: "items: []"})))
+}
+
+func TestValidateWithoutRules(t *testing.T) {
+	assert.Empty(t, Validate(&v1.LogoutRequest{}))
+}